@@ -3,22 +3,402 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
 
+	"github.com/amalg/go-bomberman/internal/crash"
+	"github.com/amalg/go-bomberman/internal/discovery"
+	"github.com/amalg/go-bomberman/internal/doctor"
+	"github.com/amalg/go-bomberman/internal/game"
+	"github.com/amalg/go-bomberman/internal/logging"
+	"github.com/amalg/go-bomberman/internal/network"
+	"github.com/amalg/go-bomberman/internal/profile"
+	"github.com/amalg/go-bomberman/internal/stats"
 	"github.com/amalg/go-bomberman/internal/ui"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		runDoctor(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "season" {
+		runSeason(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		runServe(os.Args[2:])
+		return
+	}
+
 	name := flag.String("name", "", "Your player name")
 	port := flag.Int("port", 9999, "Game port (for hosting)")
+	wsPort := flag.Int("ws-port", 0, "Also take browser clients over WebSocket on this HTTP port, at /ws (for hosting; 0 disables)")
+	filter := flag.Bool("filter", false, "Reject offensive player names and mask chat (for hosting)")
+	filterWords := flag.String("filter-words", "", "File of extra blocked words, one per line (implies --filter)")
+	motd := flag.String("motd", "", "Message of the day shown to players joining your room")
+	rules := flag.String("rules", "", "House rules shown in the lobby to players joining your room")
+	compress := flag.String("compress", "snappy", "Compression of full states sent from your hosted room: snappy, gzip or none")
+	idleTimeout := flag.Duration("idle-timeout", network.DefaultIdleTimeout, "Drop players of your hosted room who send nothing for this long (0 never does)")
+	fps := flag.Int("fps", ui.DefaultFPS, "Maximum redraws per second (lower for slow terminals)")
+	debug := flag.Bool("debug", false, "Enable the F3 debug overlay, and log state invariant violations when hosting")
+	step := flag.Bool("step", false, "Developer mode: your hosted game only advances when you press . to step a tick")
+	statsPath := flag.String("stats", stats.DefaultPath(), "Statistics file recorded when hosting (empty disables)")
+	multicast := flag.Bool("multicast", false, "Stream your hosted game to LAN spectators over multicast")
+	profilePath := flag.String("profile", profile.DefaultPath(), "Profile file tracking your wins and cosmetic unlocks")
+	configPath := flag.String("config", "", "JSON file of game settings (for hosting)")
+	savePath := flag.String("save", game.DefaultSavePath(), "File Ctrl+S saves your hosted match to")
+	resume := flag.String("resume", "", "Resume a saved match when creating a room")
+	scriptPath := flag.String("script", "", "Lua script of custom game rules (for hosting)")
+	ghostDir := flag.String("ghosts", game.DefaultGhostDir(), "Directory your best daily challenge and practice runs are kept in, to race as ghosts (empty disables)")
+	practiceSeed := flag.Int64("practice-seed", 1, "Seed of the map Practice plays, on the --config settings")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate players must connect to your hosted room over TLS with (needs --tls-key)")
+	tlsKey := flag.String("tls-key", "", "PEM private key for --tls-cert")
+	tlsCA := flag.String("tls-ca", "", "PEM certificate to trust for tls:// rooms, such as a host's self-signed one")
+	udp := flag.Bool("udp", false, "Take states and send moves over UDP where the room offers it, for lossy Wi-Fi")
+	noTUI := flag.Bool("no-tui", false, "Play in plain-text mode (dumb terminals, editors, scripts)")
+	join := flag.String("join", "", "Room address to join in --no-tui mode, host:port or tls://host:port (default: first room found on the LAN)")
+	logLevel := flag.String("log-level", "info", "Least severe log messages written: debug, info, warn or error")
+	logFile := flag.String("log-file", "", "File to append logs to (default: stderr in --no-tui mode, nowhere in the TUI)")
 	flag.Parse()
 
-	model := ui.NewModel(*name, *port)
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	opts := ui.Options{
+		PlayerName: *name,
+		Port:       *port,
+		WSPort:     *wsPort,
+		UDP:        *udp,
+		TLSCert:    *tlsCert,
+		TLSKey:     *tlsKey,
+		MOTD:       *motd,
+		Rules:      *rules,
+		FPS:        *fps,
+		Debug:      *debug,
+		StepMode:   *step,
+		StatsPath:  *statsPath,
+		Multicast:  *multicast,
+		SavePath:   *savePath,
+		ResumePath: *resume,
+		ScriptPath: *scriptPath,
+		GhostDir:   *ghostDir,
+		Seed:       *practiceSeed,
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		fmt.Fprintln(os.Stderr, "Error: --tls-cert and --tls-key go together")
+		os.Exit(1)
+	}
+	compression, err := network.ParseCompression(*compress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts.Compress = compression
+	opts.IdleTimeout = *idleTimeout
+	if *tlsCA != "" {
+		if err := network.LoadTLSRoots(*tlsCA); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *configPath != "" {
+		config, err := game.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Config = config
+	}
+	if *profilePath != "" {
+		prof, err := profile.Load(*profilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Profile = prof
+	}
+	switch {
+	case *filterWords != "":
+		f, err := network.LoadWordFilter(*filterWords)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Filter = f
+	case *filter:
+		opts.Filter = network.NewWordFilter()
+	}
+
+	// A log line on stderr would corrupt the TUI's rendering
+	fallback := io.Discard
+	if *noTUI {
+		fallback = os.Stderr
+	}
+	setupLogging(*logLevel, *logFile, fallback)
+
+	if *noTUI {
+		if err := runText(opts, *join); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Bubbletea restores the terminal when it quits or its own code panics;
+	// crash covers panics in server and network goroutines
+	restore := terminalRestorer()
+	crash.OnCrash(restore)
+
+	model := ui.NewModel(opts)
+	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithFPS(*fps))
+
+	// Bubbletea handles SIGINT and SIGTERM, but not a closed terminal window
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		<-hup
+		p.Kill()
+	}()
+
 	if _, err := p.Run(); err != nil {
+		restore()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// setupLogging sends logs of the --log-level and above to the --log-file, or
+// to fallback without one, exiting on a bad flag.
+func setupLogging(levelName, path string, fallback io.Writer) {
+	level, err := logging.ParseLevel(levelName)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	w, err := logging.Open(path, fallback)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	logging.Setup(w, level)
+}
+
+// terminalRestorer captures the terminal mode now and returns a func that
+// puts it back, leaving the alternate screen with the cursor visible.
+func terminalRestorer() func() {
+	in, out := os.Stdin.Fd(), os.Stdout.Fd()
+	state, err := term.GetState(in)
+	return func() {
+		if err == nil {
+			term.Restore(in, state)
+		}
+		if term.IsTerminal(out) {
+			fmt.Fprint(os.Stdout, "\x1b[?1049l\x1b[?25h")
+		}
+	}
+}
+
+// runDoctor implements `bomberman doctor`, which diagnoses connection problems.
+func runDoctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	addr := fs.String("addr", "", "Room address to diagnose (host:port)")
+	fs.Parse(args)
+	if *addr == "" {
+		fmt.Fprintln(os.Stderr, "Usage: bomberman doctor --addr host:port")
+		os.Exit(2)
+	}
+
+	fmt.Printf("Diagnosing %s...\n", *addr)
+	if !doctor.Print(os.Stdout, doctor.Run(*addr)) {
+		os.Exit(1)
+	}
+}
+
+// runSeason implements `bomberman season`, which archives the host's
+// statistics as a named season and lists past seasons.
+func runSeason(args []string) {
+	fs := flag.NewFlagSet("season", flag.ExitOnError)
+	statsPath := fs.String("stats", stats.DefaultPath(), "Statistics file")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: bomberman season [--stats file] archive <name> | list")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	switch {
+	case fs.Arg(0) == "archive" && fs.NArg() == 2:
+		dest, err := stats.ArchiveSeason(*statsPath, fs.Arg(1))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Archived season %q to %s; recording starts fresh.\n", fs.Arg(1), dest)
+	case fs.Arg(0) == "list" && fs.NArg() == 1:
+		seasons, err := stats.Seasons(*statsPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(seasons) == 0 {
+			fmt.Println("No archived seasons.")
+		}
+		for _, name := range seasons {
+			fmt.Println(name)
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// runServe implements `bomberman serve`, a dedicated server hosting many
+// rooms on one port, which players list, create and join from the room
+// browser.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	port := fs.Int("port", 9999, "Port to host rooms on")
+	configPath := fs.String("config", "", "JSON file of game settings for every room")
+	motd := fs.String("motd", "", "Message of the day shown to players joining a room")
+	rules := fs.String("rules", "", "House rules shown in the lobby to players joining a room")
+	compress := fs.String("compress", "snappy", "Compression of full states: snappy, gzip or none")
+	idleTimeout := fs.Duration("idle-timeout", network.DefaultIdleTimeout, "Drop players who send nothing for this long (0 never does)")
+	filter := fs.Bool("filter", false, "Reject offensive player names and mask chat")
+	filterWords := fs.String("filter-words", "", "File of extra blocked words, one per line (implies --filter)")
+	tlsCert := fs.String("tls-cert", "", "PEM certificate players must connect over TLS with (needs --tls-key)")
+	tlsKey := fs.String("tls-key", "", "PEM private key for --tls-cert")
+	adminPort := fs.Int("admin-port", 0, "Port to serve the HTTP admin API on (0 disables it)")
+	adminToken := fs.String("admin-token", os.Getenv("BOMBERMAN_ADMIN_TOKEN"), "Bearer token the admin API requires (default $BOMBERMAN_ADMIN_TOKEN)")
+	metricsPort := fs.Int("metrics-port", 0, "Port to serve Prometheus metrics on, at /metrics (0 disables them)")
+	logLevel := fs.String("log-level", "info", "Least severe log messages written: debug, info, warn or error")
+	logFile := fs.String("log-file", "", "File to append logs to (default: stderr)")
+	fs.Parse(args)
+	setupLogging(*logLevel, *logFile, os.Stderr)
+
+	config := game.DefaultConfig()
+	if *configPath != "" {
+		var err error
+		if config, err = game.LoadConfig(*configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	compression, err := network.ParseCompression(*compress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		fmt.Fprintln(os.Stderr, "Error: --tls-cert and --tls-key go together")
+		os.Exit(1)
+	}
+	var moderation network.Filter
+	switch {
+	case *filterWords != "":
+		f, err := network.LoadWordFilter(*filterWords)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		moderation = f
+	case *filter:
+		moderation = network.NewWordFilter()
+	}
+
+	rooms := network.NewRoomServer(fmt.Sprintf("0.0.0.0:%d", *port), config)
+	rooms.Configure(func(s *network.Server) {
+		s.SetMOTD(*motd)
+		s.SetRules(*rules)
+		s.SetCompression(compression)
+		s.SetIdleTimeout(*idleTimeout)
+		if moderation != nil {
+			s.SetFilter(moderation)
+		}
+	})
+	if *tlsCert != "" {
+		if err := rooms.EnableTLS(*tlsCert, *tlsKey); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *adminPort != 0 {
+		if err := rooms.EnableAdmin(fmt.Sprintf("0.0.0.0:%d", *adminPort), *adminToken); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *metricsPort != 0 {
+		if err := rooms.EnableMetrics(fmt.Sprintf("0.0.0.0:%d", *metricsPort)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := rooms.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	<-stop
+	rooms.Stop()
+}
+
+// discoverTimeout is how long --no-tui mode looks for a room to join.
+const discoverTimeout = 5 * time.Second
+
+// runText joins a room and plays it with the plain-text client.
+func runText(opts ui.Options, addr string) error {
+	if addr == "" {
+		found, err := discoverRoom(discoverTimeout)
+		if err != nil {
+			return err
+		}
+		addr = found
+	}
+
+	name := opts.PlayerName
+	if name == "" {
+		name = "Player"
+	}
+	client, err := network.NewClient(addr, network.JoinMsg{Name: name, UDP: opts.UDP})
+	if err != nil {
+		return fmt.Errorf("join room: %w", err)
+	}
+	defer client.Leave()
+
+	// Read keys one at a time when attached to a terminal
+	fd := os.Stdin.Fd()
+	raw := term.IsTerminal(fd)
+	if raw {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("enter raw mode: %w", err)
+		}
+		defer term.Restore(fd, state)
+	}
+	return ui.RunText(client, os.Stdin, os.Stdout, raw)
+}
+
+// discoverRoom returns the address of the first joinable room heard on the LAN.
+func discoverRoom(timeout time.Duration) (string, error) {
+	l := discovery.NewListener()
+	if err := l.Start(); err != nil {
+		return "", err
+	}
+	defer l.Stop()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		for _, r := range l.Rooms() {
+			if r.Joinable() {
+				fmt.Printf("Joining %q at %s\n", r.RoomName, r.JoinAddr())
+				return r.JoinAddr(), nil
+			}
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return "", fmt.Errorf("no joinable room found on the LAN, use --join host:port")
 }
@@ -0,0 +1,35 @@
+// Command protogen writes the JSON Schema and bot bindings for the wire
+// protocol described by network.Messages. Run it with
+// `go generate ./internal/network`.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/amalg/go-bomberman/internal/protogen"
+)
+
+func main() {
+	out := flag.String("out", "protocol", "Directory to write the schema and bindings to")
+	flag.Parse()
+
+	files, err := protogen.Generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "protogen: %v\n", err)
+		os.Exit(1)
+	}
+	for name, data := range files {
+		path := filepath.Join(*out, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, "protogen: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "protogen: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package ai implements computer-controlled players that run inside the
+// server. A Bot reads the game state every tick and answers with the same
+// actions a human client would send. Bots are built on pkg/bot, like bots
+// written by anyone else.
+package ai
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/game"
+	"github.com/amalg/go-bomberman/pkg/bot"
+)
+
+// Difficulty sets how quickly and how well a bot plays.
+type Difficulty string
+
+const (
+	Easy   Difficulty = "easy"
+	Medium Difficulty = "medium"
+	Hard   Difficulty = "hard"
+)
+
+// Difficulties lists every difficulty, easiest first.
+var Difficulties = []Difficulty{Easy, Medium, Hard}
+
+// skill holds the knobs each difficulty turns.
+type skill struct {
+	thinkEvery int     // Ticks between decisions
+	blunder    float64 // Chance of a random move instead of a planned one
+	hunt       bool    // Go after other players, not just walls and pickups
+	escape     int     // Longest escape, in tiles, the bot trusts after placing a bomb
+}
+
+var skills = map[Difficulty]skill{
+	Easy:   {thinkEvery: 8, blunder: 0.25, escape: 3},
+	Medium: {thinkEvery: 4, blunder: 0.05, escape: 4},
+	Hard:   {thinkEvery: 2, hunt: true, escape: 6},
+}
+
+// maxSearch bounds every path search, in tiles.
+const maxSearch = 32
+
+// Bot plays as one player. It implements bot.Bot.
+type Bot struct {
+	ID         string
+	Difficulty Difficulty
+
+	skill     skill
+	ammoMode  bool // Bombs also need ammo
+	tickRate  int
+	rng       *rand.Rand
+	nextThink uint64 // Tick of the next decision
+}
+
+var _ bot.Bot = (*Bot)(nil)
+
+// NewBot creates a bot playing as the player with the given ID in a game
+// with config. An unknown difficulty plays as Medium.
+func NewBot(id string, d Difficulty, config game.GameConfig) *Bot {
+	sk, ok := skills[d]
+	if !ok {
+		d, sk = Medium, skills[Medium]
+	}
+	return &Bot{
+		ID:         id,
+		Difficulty: d,
+		skill:      sk,
+		ammoMode:   config.AmmoMode,
+		tickRate:   config.TickRate,
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Observe returns the bot's action in response to state. It decides at most
+// once every few ticks, depending on difficulty; between decisions, and
+// while the bot's player isn't alive in a running game, it does nothing.
+//
+// In order, the bot:
+//  1. Flees to the nearest safe tile when standing in fire's way.
+//  2. Places a bomb if it would hit a wall or opponent and there's a way out.
+//  3. Walks toward the nearest pickup, wall to bomb or, on Hard, opponent,
+//     staying out of blast zones.
+func (b *Bot) Observe(state *game.GameState) (game.Action, bool) {
+	me, ok := state.Players[b.ID]
+	if !ok || !me.Alive || state.Status != game.StatusRunning || state.Tick < b.nextThink {
+		return game.Action{}, false
+	}
+	b.nextThink = state.Tick + uint64(b.skill.thinkEvery)
+
+	w := bot.NewWorld(state, me)
+
+	if w.Danger[me.Pos] {
+		if path, ok := w.NearestSafe(me.Pos, maxSearch); ok {
+			return b.move(me, state.Tick, path[0])
+		}
+		return game.Action{}, false
+	}
+
+	if b.rng.Float64() < b.skill.blunder {
+		if dirs := w.OpenDirs(me.Pos); len(dirs) > 0 {
+			return b.move(me, state.Tick, dirs[b.rng.Intn(len(dirs))])
+		}
+	}
+
+	if b.hasBomb(me) && !w.Bombs[me.Pos] && worthBombing(w, me.Pos, me.BombRange, b.skill.hunt) &&
+		w.CanEscape(me.Pos, me.BombRange, b.skill.escape) {
+		return game.Action{PlayerID: b.ID, Type: game.ActionPlaceBomb}, true
+	}
+
+	target := func(pos game.Position) bool {
+		return w.Pickups[pos] || (b.hasBomb(me) && worthBombing(w, pos, me.BombRange, b.skill.hunt))
+	}
+	if path, ok := w.Path(me.Pos, target, false, maxSearch); ok {
+		return b.move(me, state.Tick, path[0])
+	}
+
+	// Nothing worth doing: wander without walking into danger
+	var safe []game.Direction
+	for _, d := range w.OpenDirs(me.Pos) {
+		if w.Safe(bot.Step(me.Pos, d)) {
+			safe = append(safe, d)
+		}
+	}
+	if len(safe) > 0 {
+		return b.move(me, state.Tick, safe[b.rng.Intn(len(safe))])
+	}
+	return game.Action{}, false
+}
+
+// move steps p one tile in direction dir. The bot then waits until the
+// player's speed allows another move: an early move would be held back by
+// the engine and still go ahead after the bot had changed its mind.
+func (b *Bot) move(p *game.Player, tick uint64, dir game.Direction) (game.Action, bool) {
+	speed := max(p.MoveSpeed, 1)
+	b.nextThink = max(b.nextThink, tick+uint64((b.tickRate+speed-1)/speed))
+	if p.HasEffect(game.EffectReverse) {
+		dir = dir.Opposite()
+	}
+	return game.Action{PlayerID: b.ID, Type: game.ActionMove, Dir: dir}, true
+}
+
+// hasBomb reports whether p can place another bomb.
+func (b *Bot) hasBomb(p *game.Player) bool {
+	return p.BombsUsed < p.BombMax && (!b.ammoMode || p.Ammo > 0)
+}
@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// newState returns a running game on an open board with one player at pos.
+func newState(t *testing.T, pos game.Position) *game.GameState {
+	t.Helper()
+	config := game.DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := game.NewEngine(config)
+	engine.AddPlayer("bot", "Bot")
+	state := engine.GetStateCopy()
+	state.Status = game.StatusRunning
+	state.Tick = 100
+	state.Players["bot"].Pos = pos
+	return &state
+}
+
+func TestBotFleesBomb(t *testing.T) {
+	state := newState(t, game.Position{X: 1, Y: 1})
+	state.Bombs = append(state.Bombs, &game.Bomb{OwnerID: "bot", Pos: game.Position{X: 1, Y: 1}, Range: 2, ExpiresTick: state.Tick + 20, FuseLeft: time.Second})
+	bot := NewBot("bot", Hard, game.DefaultConfig())
+
+	a, ok := bot.Observe(state)
+	if !ok || a.Type != game.ActionMove {
+		t.Fatalf("expected the bot to run from its bomb, got %+v", a)
+	}
+	if again, ok := bot.Observe(state); ok {
+		t.Fatalf("expected the bot to wait for its move to land, got %+v", again)
+	}
+}
+
+func TestBotBombsWalls(t *testing.T) {
+	state := newState(t, game.Position{X: 3, Y: 1})
+	state.Board[1][4] = game.SoftWall
+	bot := NewBot("bot", Medium, game.DefaultConfig())
+	bot.skill.blunder = 0
+
+	a, ok := bot.Observe(state)
+	if !ok || a.Type != game.ActionPlaceBomb {
+		t.Fatalf("expected a bomb next to the wall, got %+v", a)
+	}
+
+	// Walled into a dead end, placing a bomb would be suicide
+	state.Board[1][2] = game.SoftWall
+	state.Board[2][3] = game.SoftWall
+	bot.nextThink = 0
+	if a, ok := bot.Observe(state); ok && a.Type == game.ActionPlaceBomb {
+		t.Fatal("the bot bombed itself into a corner")
+	}
+}
@@ -0,0 +1,23 @@
+package ai
+
+import (
+	"github.com/amalg/go-bomberman/internal/game"
+	"github.com/amalg/go-bomberman/pkg/bot"
+)
+
+// worthBombing reports whether a bomb at pos would destroy a wall or, when
+// hunting, catch an opponent.
+func worthBombing(w *bot.World, pos game.Position, rng int, hunt bool) bool {
+	for _, p := range w.Blast(pos, rng, false) {
+		if p == pos {
+			continue
+		}
+		if tile := w.State.Board[p.Y][p.X]; tile.Solid() && tile != game.HardWall {
+			return true
+		}
+		if hunt && w.OpponentAt(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+// Package crash turns a panic in any background goroutine into a clean exit.
+//
+// Bubbletea restores the terminal when its own loop or a command panics, but
+// not when a goroutine it doesn't know about does, such as the server's. Those
+// goroutines are started with Go so registered cleanups (restoring the
+// terminal) run before the process exits.
+package crash
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+)
+
+var (
+	mu       sync.Mutex
+	cleanups []func()
+	once     sync.Once
+)
+
+// OnCrash registers fn to run before the process exits on a panic.
+// Cleanups run in reverse order of registration.
+func OnCrash(fn func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	cleanups = append(cleanups, fn)
+}
+
+// Go runs fn in a new goroutine, handling a panic with Recover.
+func Go(fn func()) {
+	go func() {
+		defer Recover()
+		fn()
+	}()
+}
+
+// Recover must be deferred. On a panic it runs the cleanups, prints the panic
+// and its stack to stderr, and exits with status 2.
+func Recover() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	// Only the first panicking goroutine reports; others wait for the exit
+	once.Do(func() {
+		RunCleanups()
+		fmt.Fprintf(os.Stderr, "panic: %v\n\n%s", r, debug.Stack())
+		os.Exit(2)
+	})
+	select {}
+}
+
+// RunCleanups runs the registered cleanups now, for exits that skip Recover.
+// Each cleanup runs at most once.
+func RunCleanups() {
+	mu.Lock()
+	fns := cleanups
+	cleanups = nil
+	mu.Unlock()
+
+	for i := len(fns) - 1; i >= 0; i-- {
+		fns[i]()
+	}
+}
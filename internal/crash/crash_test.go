@@ -0,0 +1,35 @@
+package crash
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoRunsCleanupsOnPanic(t *testing.T) {
+	if os.Getenv("CRASH_CHILD") == "1" {
+		OnCrash(func() { os.Stdout.WriteString("first\n") })
+		OnCrash(func() { os.Stdout.WriteString("second\n") })
+		Go(func() { panic("boom") })
+		time.Sleep(5 * time.Second)
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestGoRunsCleanupsOnPanic")
+	cmd.Env = append(os.Environ(), "CRASH_CHILD=1")
+	out, err := cmd.CombinedOutput()
+
+	exit, ok := err.(*exec.ExitError)
+	if !ok || exit.ExitCode() != 2 {
+		t.Fatalf("expected exit status 2, got %v\n%s", err, out)
+	}
+	got := string(out)
+	if !strings.Contains(got, "second\nfirst\n") {
+		t.Errorf("cleanups should run in reverse order before exiting:\n%s", got)
+	}
+	if !strings.Contains(got, "panic: boom") {
+		t.Errorf("panic should be reported:\n%s", got)
+	}
+}
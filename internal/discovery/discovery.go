@@ -1,12 +1,17 @@
 package discovery
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/amalg/go-bomberman/internal/crash"
 )
 
 const (
@@ -18,13 +23,56 @@ const (
 	RoomExpiry = 4 * time.Second
 )
 
+// RoomStatus is the advertised phase of a room.
+type RoomStatus string
+
+const (
+	RoomLobby  RoomStatus = "lobby"   // Waiting for players, can be joined
+	RoomInGame RoomStatus = "in_game" // Match running or showing results
+)
+
 // RoomInfo describes an available game room on the network.
 type RoomInfo struct {
-	RoomName    string `json:"room_name"`
-	HostName    string `json:"host_name"`
-	PlayerCount int    `json:"player_count"`
-	MaxPlayers  int    `json:"max_players"`
-	GameAddr    string `json:"game_addr"` // TCP host:port to connect to
+	RoomID      string     `json:"room_id"` // Unique per hosted room, even for rooms sharing a host
+	RoomName    string     `json:"room_name"`
+	HostName    string     `json:"host_name"`
+	PlayerCount int        `json:"player_count"`
+	MaxPlayers  int        `json:"max_players"`
+	GameAddr    string     `json:"game_addr"` // TCP host:port to connect to
+	Status      RoomStatus `json:"status"`
+	TLS         bool       `json:"tls,omitempty"` // Connect over TLS, with JoinAddr
+}
+
+// OpenSeats returns how many more players the room can take.
+func (r RoomInfo) OpenSeats() int {
+	if r.PlayerCount >= r.MaxPlayers {
+		return 0
+	}
+	return r.MaxPlayers - r.PlayerCount
+}
+
+// key identifies the room in a Listener. Older hosts don't send a RoomID,
+// so their game address stands in for it.
+func (r RoomInfo) key() string {
+	if r.RoomID != "" {
+		return r.RoomID
+	}
+	return r.GameAddr
+}
+
+// JoinAddr returns the address to join the room at: GameAddr, as a tls://
+// address if the room needs TLS.
+func (r RoomInfo) JoinAddr() string {
+	if r.TLS {
+		return "tls://" + r.GameAddr
+	}
+	return r.GameAddr
+}
+
+// Joinable reports whether the room is in its lobby with a free seat.
+// Rooms from older hosts don't advertise a status and are assumed open.
+func (r RoomInfo) Joinable() bool {
+	return r.Status != RoomInGame && r.OpenSeats() > 0
 }
 
 // --- Broadcaster ---
@@ -37,7 +85,11 @@ type Broadcaster struct {
 }
 
 // NewBroadcaster creates a new room broadcaster.
+// A random RoomID is assigned if info doesn't have one.
 func NewBroadcaster(info RoomInfo) *Broadcaster {
+	if info.RoomID == "" {
+		info.RoomID = newRoomID()
+	}
 	return &Broadcaster{
 		info: info,
 		done: make(chan struct{}),
@@ -51,9 +103,25 @@ func (b *Broadcaster) UpdatePlayerCount(count int) {
 	b.info.PlayerCount = count
 }
 
+// UpdateStatus updates the advertised room phase.
+func (b *Broadcaster) UpdateStatus(status RoomStatus) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.info.Status = status
+}
+
+// newRoomID returns a random room identifier.
+func newRoomID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
 // Start begins broadcasting room info via UDP.
 func (b *Broadcaster) Start() error {
-	go b.broadcastLoop()
+	crash.Go(b.broadcastLoop)
 	return nil
 }
 
@@ -71,7 +139,7 @@ func (b *Broadcaster) broadcastLoop() {
 	// DialUDP to 255.255.255.255 silently fails without SO_BROADCAST.
 	conn, err := net.ListenPacket("udp4", ":0")
 	if err != nil {
-		log.Printf("[DISCOVERY] Failed to create broadcast socket: %v", err)
+		slog.Error("Failed to create discovery broadcast socket", "err", err)
 		return
 	}
 	defer conn.Close()
@@ -164,7 +232,7 @@ type discoveredRoom struct {
 
 // Listener listens for UDP broadcast room advertisements.
 type Listener struct {
-	rooms map[string]*discoveredRoom // keyed by GameAddr
+	rooms map[string]*discoveredRoom // keyed by RoomInfo.key
 	mu    sync.RWMutex
 	conn  *net.UDPConn
 	done  chan struct{}
@@ -191,8 +259,8 @@ func (l *Listener) Start() error {
 		return fmt.Errorf("listen UDP on port %d: %w (is another instance browsing?)", BroadcastPort, err)
 	}
 
-	go l.listenLoop()
-	go l.cleanupLoop()
+	crash.Go(l.listenLoop)
+	crash.Go(l.cleanupLoop)
 
 	return nil
 }
@@ -209,7 +277,8 @@ func (l *Listener) Stop() {
 	}
 }
 
-// Rooms returns a snapshot of currently visible rooms.
+// Rooms returns a snapshot of currently visible rooms, sorted by name so the
+// list doesn't reorder between refreshes.
 func (l *Listener) Rooms() []RoomInfo {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
@@ -218,9 +287,25 @@ func (l *Listener) Rooms() []RoomInfo {
 	for _, dr := range l.rooms {
 		rooms = append(rooms, dr.Info)
 	}
+	sort.Slice(rooms, func(i, j int) bool {
+		if rooms[i].RoomName != rooms[j].RoomName {
+			return rooms[i].RoomName < rooms[j].RoomName
+		}
+		return rooms[i].key() < rooms[j].key()
+	})
 	return rooms
 }
 
+// record stores a room advertisement.
+func (l *Listener) record(info RoomInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rooms[info.key()] = &discoveredRoom{
+		Info:     info,
+		LastSeen: time.Now(),
+	}
+}
+
 func (l *Listener) listenLoop() {
 	buf := make([]byte, 4096)
 	for {
@@ -241,12 +326,7 @@ func (l *Listener) listenLoop() {
 			continue
 		}
 
-		l.mu.Lock()
-		l.rooms[info.GameAddr] = &discoveredRoom{
-			Info:     info,
-			LastSeen: time.Now(),
-		}
-		l.mu.Unlock()
+		l.record(info)
 	}
 }
 
@@ -261,9 +341,9 @@ func (l *Listener) cleanupLoop() {
 		case <-ticker.C:
 			l.mu.Lock()
 			now := time.Now()
-			for addr, dr := range l.rooms {
+			for key, dr := range l.rooms {
 				if now.Sub(dr.LastSeen) > RoomExpiry {
-					delete(l.rooms, addr)
+					delete(l.rooms, key)
 				}
 			}
 			l.mu.Unlock()
@@ -0,0 +1,34 @@
+package discovery
+
+import "testing"
+
+func TestListenerKeysRoomsByID(t *testing.T) {
+	l := NewListener()
+
+	// Two rooms behind the same address must not clobber each other
+	l.record(RoomInfo{RoomID: "b", RoomName: "Beta", GameAddr: "10.0.0.2:9999"})
+	l.record(RoomInfo{RoomID: "a", RoomName: "Alpha", GameAddr: "10.0.0.2:9999"})
+	// A repeat advertisement updates the existing entry
+	l.record(RoomInfo{RoomID: "a", RoomName: "Alpha", GameAddr: "10.0.0.2:9999", PlayerCount: 2})
+	// Older hosts without an ID are keyed by address
+	l.record(RoomInfo{RoomName: "Legacy", GameAddr: "10.0.0.3:9999"})
+
+	rooms := l.Rooms()
+	if len(rooms) != 3 {
+		t.Fatalf("expected 3 rooms, got %d", len(rooms))
+	}
+	if rooms[0].RoomName != "Alpha" || rooms[1].RoomName != "Beta" || rooms[2].RoomName != "Legacy" {
+		t.Errorf("rooms should be sorted by name, got %s, %s, %s", rooms[0].RoomName, rooms[1].RoomName, rooms[2].RoomName)
+	}
+	if rooms[0].PlayerCount != 2 {
+		t.Errorf("expected the latest advertisement, got %d players", rooms[0].PlayerCount)
+	}
+}
+
+func TestBroadcasterAssignsRoomID(t *testing.T) {
+	a := NewBroadcaster(RoomInfo{RoomName: "Same"})
+	b := NewBroadcaster(RoomInfo{RoomName: "Same"})
+	if a.info.RoomID == "" || a.info.RoomID == b.info.RoomID {
+		t.Errorf("expected distinct room IDs, got %q and %q", a.info.RoomID, b.info.RoomID)
+	}
+}
@@ -0,0 +1,204 @@
+// Package doctor diagnoses why a client can't see or join a room: it checks
+// TCP reachability, the protocol version, round-trip time and LAN discovery,
+// and suggests fixes for the usual environment problems.
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/discovery"
+	"github.com/amalg/go-bomberman/internal/network"
+)
+
+const (
+	dialTimeout = 3 * time.Second
+	pingCount   = 5
+	// listenFor covers a few broadcast intervals so one lost packet doesn't matter.
+	listenFor = 3*discovery.BroadcastInterval + 500*time.Millisecond
+)
+
+// Check is the outcome of one diagnostic.
+type Check struct {
+	Name   string
+	OK     bool
+	Detail string
+	Hint   string // What to try when the check failed
+}
+
+// Run diagnoses the room at addr (host:port).
+func Run(addr string) []Check {
+	var checks []Check
+
+	tcp, conn := checkTCP(addr)
+	checks = append(checks, tcp)
+	if conn != nil {
+		proto, rtt := checkProtocol(conn)
+		conn.Close()
+		checks = append(checks, proto)
+		if rtt != nil {
+			checks = append(checks, *rtt)
+		}
+	}
+
+	return append(checks, checkDiscovery(addr, listenFor))
+}
+
+// Print writes checks as a report and reports whether all of them passed.
+func Print(w io.Writer, checks []Check) bool {
+	ok := true
+	for _, c := range checks {
+		mark := "✓"
+		if !c.OK {
+			mark = "✗"
+			ok = false
+		}
+		fmt.Fprintf(w, "%s %-10s %s\n", mark, c.Name, c.Detail)
+		if !c.OK && c.Hint != "" {
+			fmt.Fprintf(w, "  hint: %s\n", c.Hint)
+		}
+	}
+	return ok
+}
+
+// checkTCP connects to addr, returning the open connection on success.
+func checkTCP(addr string) (Check, net.Conn) {
+	c := Check{Name: "tcp"}
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		c.Detail = fmt.Sprintf("can't connect to %s: %v", addr, err)
+		c.Hint = dialHint(addr, err)
+		return c, nil
+	}
+	c.OK = true
+	c.Detail = fmt.Sprintf("connected to %s in %s", addr, time.Since(start).Round(time.Millisecond))
+	return c, conn
+}
+
+// dialHint explains a failed connection.
+func dialHint(addr string, err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "the host is reachable but nothing is listening on that port: check the room is still open and the port matches the host's --port"
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		if hint := subnetHint(addr); hint != "" {
+			return hint
+		}
+		return "no route to the host: check you're on the same network"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		if hint := subnetHint(addr); hint != "" {
+			return hint
+		}
+		return "the connection timed out: a firewall on the host is probably blocking the TCP port"
+	}
+	return ""
+}
+
+// subnetHint warns when addr isn't on any of this machine's local networks.
+func subnetHint(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() {
+		return ""
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && ipnet.Contains(ip) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%s is not on any of your local subnets: join the same Wi-Fi/LAN as the host, or check for guest networks and AP isolation", host)
+}
+
+// checkProtocol probes the server for its version and round-trip time.
+// The RTT check is nil if the probe failed.
+func checkProtocol(conn net.Conn) (Check, *Check) {
+	c := Check{Name: "protocol"}
+	result, err := network.Probe(conn, pingCount)
+	switch {
+	case errors.Is(err, network.ErrProbeUnsupported):
+		c.Detail = "the server is running an older version"
+		c.Hint = "update the host and all players to the same release"
+		return c, nil
+	case err != nil:
+		c.Detail = fmt.Sprintf("probe failed: %v", err)
+		c.Hint = "something other than a bomberman server may be listening on that port"
+		return c, nil
+	case result.Version != network.ProtocolVersion:
+		c.Detail = fmt.Sprintf("server speaks version %d, you speak %d", result.Version, network.ProtocolVersion)
+		c.Hint = "update the host and all players to the same release"
+	default:
+		c.OK = true
+		c.Detail = fmt.Sprintf("version %d", result.Version)
+	}
+	return c, rttCheck(result.RTTs)
+}
+
+// slowRTT is where a LAN round trip starts to be noticeable in play.
+const slowRTT = 100 * time.Millisecond
+
+func rttCheck(rtts []time.Duration) *Check {
+	lo, hi, sum := rtts[0], rtts[0], time.Duration(0)
+	for _, r := range rtts {
+		lo, hi = min(lo, r), max(hi, r)
+		sum += r
+	}
+	avg := sum / time.Duration(len(rtts))
+	c := &Check{
+		Name:   "rtt",
+		OK:     avg < slowRTT,
+		Detail: fmt.Sprintf("min %s  avg %s  max %s", lo.Round(time.Microsecond), avg.Round(time.Microsecond), hi.Round(time.Microsecond)),
+	}
+	if !c.OK {
+		c.Hint = "high latency for a LAN: move closer to the access point or use a wired connection"
+	}
+	return c
+}
+
+// checkDiscovery listens for room broadcasts and looks for one from addr's host.
+func checkDiscovery(addr string, wait time.Duration) Check {
+	c := Check{Name: "discovery"}
+	host, port, _ := net.SplitHostPort(addr)
+	local := host == "localhost" || net.ParseIP(host).IsLoopback()
+
+	l := discovery.NewListener()
+	if err := l.Start(); err != nil {
+		c.Detail = err.Error()
+		c.Hint = fmt.Sprintf("close other bomberman windows on the Join Room screen, they hold UDP port %d", discovery.BroadcastPort)
+		return c
+	}
+	time.Sleep(wait)
+	rooms := l.Rooms()
+	l.Stop()
+
+	for _, r := range rooms {
+		roomHost, roomPort, _ := net.SplitHostPort(r.GameAddr)
+		// Rooms advertise their LAN address, even when we dialed loopback
+		if roomHost == host || (local && roomPort == port) {
+			c.OK = true
+			c.Detail = fmt.Sprintf("found room %q", r.RoomName)
+			return c
+		}
+	}
+
+	if len(rooms) == 0 {
+		c.Detail = "no rooms heard on the LAN"
+		c.Hint = fmt.Sprintf("UDP broadcasts aren't reaching you: allow UDP port %d in your firewall, and check the network doesn't isolate clients", discovery.BroadcastPort)
+	} else {
+		c.Detail = fmt.Sprintf("heard %d room(s), none from %s", len(rooms), host)
+		c.Hint = "the host's broadcasts don't reach you: it may be on another subnet, or its firewall blocks outgoing broadcasts"
+	}
+	return c
+}
@@ -0,0 +1,59 @@
+package doctor
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/game"
+	"github.com/amalg/go-bomberman/internal/network"
+)
+
+// freeAddr returns a loopback address with a port nothing is listening on.
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestCheckServer(t *testing.T) {
+	addr := freeAddr(t)
+	server := network.NewServer(addr, game.DefaultConfig())
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	time.Sleep(50 * time.Millisecond)
+
+	tcp, conn := checkTCP(addr)
+	if !tcp.OK {
+		t.Fatalf("tcp check failed: %s", tcp.Detail)
+	}
+	defer conn.Close()
+
+	proto, rtt := checkProtocol(conn)
+	if !proto.OK {
+		t.Errorf("protocol check failed: %s", proto.Detail)
+	}
+	if rtt == nil || !rtt.OK {
+		t.Errorf("expected a passing rtt check, got %+v", rtt)
+	}
+	if server.Engine().PlayerCount() != 0 {
+		t.Error("probing should not take a player slot")
+	}
+}
+
+func TestCheckRefused(t *testing.T) {
+	tcp, conn := checkTCP(freeAddr(t))
+	if tcp.OK || conn != nil {
+		t.Fatal("expected the connection to be refused")
+	}
+	if !strings.Contains(tcp.Hint, "nothing is listening") {
+		t.Errorf("unexpected hint: %q", tcp.Hint)
+	}
+}
@@ -9,9 +9,14 @@ import (
 // Layout rules:
 //   - Border is all HardWall
 //   - HardWall at every position where both X and Y are even
-//   - Random SoftWall fill at the given density
+//   - Random SoftWall fill at the given density, some of it CrackedWall
+//   - Random Barrel fill of the remaining empty tiles at the barrel density
+//   - Random Ice fill of the tiles still empty at the ice density
 //   - Player spawn corners (and their adjacent 2 tiles) are kept clear
-func NewBoard(config GameConfig) [][]TileType {
+//   - In boss mode, the arena around the boss spawn is cleared
+//
+// All the randomness comes from rng, so the same seed makes the same board.
+func NewBoard(config GameConfig, rng *rand.Rand) [][]TileType {
 	board := make([][]TileType, config.Height)
 	for y := 0; y < config.Height; y++ {
 		board[y] = make([]TileType, config.Width)
@@ -43,12 +48,26 @@ func NewBoard(config GameConfig) [][]TileType {
 			if safeSet[pos] {
 				continue
 			}
-			if rand.Float64() < config.SoftWallDensity {
+			if rng.Float64() < config.SoftWallDensity {
 				board[y][x] = SoftWall
+				if config.CrackedWalls > 0 && rng.Float64() < config.CrackedWalls {
+					board[y][x] = CrackedWall
+				}
+			} else if rng.Float64() < config.BarrelDensity {
+				board[y][x] = Barrel
+			} else if config.IceDensity > 0 && rng.Float64() < config.IceDensity {
+				board[y][x] = Ice
 			}
 		}
 	}
 
+	if config.BossMode {
+		clearBossArena(board, config)
+	}
+	if config.HillMode {
+		clearHill(board, config)
+	}
+
 	return board
 }
 
@@ -1,7 +1,7 @@
 package game
 
 import (
-	"math/rand"
+	"sort"
 	"time"
 )
 
@@ -12,54 +12,147 @@ func (e *Engine) placeBomb(playerID string) {
 		return
 	}
 
-	// Check bomb limit
-	if p.BombsUsed >= p.BombMax {
+	// Check if bomb already exists at this position
+	for _, b := range e.State.Bombs {
+		if b.Pos == p.Pos {
+			return
+		}
+	}
+	e.layBomb(p, p.Pos)
+}
+
+// placeLineBomb lays as many of a line-bomb player's bombs as it can in a
+// row: under them, unless a bomb is already there, then on along the way
+// they're facing until something blocks the row.
+func (e *Engine) placeLineBomb(playerID string) {
+	p, ok := e.State.Players[playerID]
+	if !ok || !p.Alive || !p.LineBomb {
 		return
 	}
 
-	// Check if bomb already exists at this position
+	pos := p.Pos
 	for _, b := range e.State.Bombs {
-		if b.Pos == p.Pos {
+		if b.Pos == pos {
+			pos = applyDirection(pos, p.Facing)
+			if e.blocksBomb(pos) {
+				return
+			}
+			break
+		}
+	}
+	for e.layBomb(p, pos) {
+		pos = applyDirection(pos, p.Facing)
+		if e.blocksBomb(pos) {
 			return
 		}
 	}
+}
+
+// layBomb places one of p's bombs at pos, if p has one left to place.
+// Returns false if it didn't.
+func (e *Engine) layBomb(p *Player, pos Position) bool {
+	// Check bomb limit
+	if p.BombsUsed >= p.BombMax || !p.StunnedUntil.IsZero() {
+		return false
+	}
+
+	// In ammo mode every bomb comes out of the stock
+	if e.Config.AmmoMode && p.Ammo <= 0 {
+		return false
+	}
+
+	bombRange := p.BombRange
+	if p.HasEffect(EffectShortRange) {
+		bombRange = 1
+	}
+	fullFire := p.FullFire
+	if fullFire {
+		// Nothing but a hard wall or the board's edge stops it
+		bombRange = max(e.State.Width, e.State.Height)
+		p.FullFire = false
+	}
 
-	now := time.Now()
 	bomb := &Bomb{
-		OwnerID:   playerID,
-		Pos:       p.Pos,
-		Range:     p.BombRange,
-		PlacedAt:  now,
-		ExpiresAt: now.Add(e.Config.BombTimer),
+		OwnerID:     p.ID,
+		Pos:         pos,
+		Range:       bombRange,
+		PlacedAt:    e.now(),
+		ExpiresTick: e.State.Tick + e.ticksIn(e.Config.BombTimer),
+		FullFire:    fullFire,
+		OwnerOn:     pos == p.Pos,
+		FuseLeft:    e.Config.BombTimer,
+	}
+	if p.Mines > 0 {
+		p.Mines--
+		bomb.Mine = true
+		bomb.HiddenAt = e.State.Tick + MineHideTicks
+		bomb.FuseLeft = 0
 	}
 
 	e.State.Bombs = append(e.State.Bombs, bomb)
 	p.BombsUsed++
+	if e.Config.AmmoMode {
+		p.Ammo--
+	}
+	return true
+}
+
+// clearBombsLocked takes every bomb off the board, giving them back to
+// their owners.
+// MUST be called while e.mu is held.
+func (e *Engine) clearBombsLocked() {
+	e.State.Bombs = make([]*Bomb, 0)
+	for _, p := range e.State.Players {
+		p.BombsUsed = 0
+	}
 }
 
-// tickBombs checks all active bombs and detonates any whose timer has expired.
+// tickBombs slides kicked bombs and detonates any whose timer has expired
+// or that slid into fire.
 func (e *Engine) tickBombs() {
-	now := time.Now()
+	e.slideBombs()
+
 	detonated := make(map[int]bool)
+	var due []int // Bombs going off this tick, in board order
+	fires := make(map[Position]bool, len(e.State.Fires))
+	for _, f := range e.State.Fires {
+		fires[f.Pos] = true
+	}
 
-	// First pass: find bombs that need to detonate
+	// First pass: find bombs that need to detonate. Bombs in fire go off
+	// after the chain delay.
 	for i, b := range e.State.Bombs {
-		if now.After(b.ExpiresAt) {
+		if fires[b.Pos] && e.Config.ChainDelay > 0 {
+			e.chain(b)
+		}
+		if (fires[b.Pos] && e.Config.ChainDelay == 0) || (b.Mine && e.mineTriggered(b)) ||
+			((b.Chained || !b.Mine) && e.State.Tick > b.ExpiresTick) {
 			detonated[i] = true
+			due = append(due, i)
 		}
 	}
 
-	// Explode all detonated bombs (may chain-react to more)
-	for i := range detonated {
+	// Explode the bombs that are due in the order they were laid, so the
+	// same seed always drops the same pickups (they may chain-react to more,
+	// or set them off after the chain delay)
+	for _, i := range due {
 		e.explode(e.State.Bombs[i], detonated)
 	}
 
-	// Remove detonated bombs
+	// Remove detonated bombs and count down the fuses of the rest
 	remaining := make([]*Bomb, 0, len(e.State.Bombs))
 	for i, b := range e.State.Bombs {
-		if !detonated[i] {
-			remaining = append(remaining, b)
+		if detonated[i] {
+			if owner, ok := e.State.Players[b.OwnerID]; ok && owner.BombsUsed > 0 {
+				owner.BombsUsed--
+			}
+			continue
 		}
+		b.FuseLeft = 0
+		if b.Chained || !b.Mine {
+			b.FuseLeft = e.fuseLeft(b.ExpiresTick)
+		}
+		remaining = append(remaining, b)
 	}
 	e.State.Bombs = remaining
 }
@@ -67,13 +160,14 @@ func (e *Engine) tickBombs() {
 // explode processes a bomb explosion in the 4 cardinal directions.
 // It can trigger chain reactions on other bombs.
 func (e *Engine) explode(bomb *Bomb, detonated map[int]bool) {
-	now := time.Now()
-	fireExpiry := now.Add(e.Config.FireDuration)
+	fireExpiry := e.State.Tick + e.ticksIn(e.Config.FireDuration)
+	e.emit(Event{Type: EventBombExploded, ByID: bomb.OwnerID, Pos: bomb.Pos})
 
 	// Fire at bomb center
 	e.State.Fires = append(e.State.Fires, Fire{
-		Pos:       bomb.Pos,
-		ExpiresAt: fireExpiry,
+		Pos:         bomb.Pos,
+		ExpiresTick: fireExpiry,
+		OwnerID:     bomb.OwnerID,
 	})
 
 	// Expand in 4 directions
@@ -104,39 +198,83 @@ func (e *Engine) explode(bomb *Bomb, detonated map[int]bool) {
 				break
 			}
 
+			// The boss blocks fire like a wall, but its weak point is hurt
+			// by any explosion other than its own
+			if e.bossCovers(pos) {
+				if bomb.OwnerID != BossID {
+					e.hitBoss(pos)
+				}
+				break
+			}
+
+			// Cracked wall: the first explosion only cracks it further
+			if tile == CrackedWall {
+				e.State.Board[pos.Y][pos.X] = CrackedWallHit
+				if bomb.FullFire {
+					continue
+				}
+				break
+			}
+
 			// Soft wall: destroy it, place fire, but stop further expansion
-			if tile == SoftWall {
+			// unless the bomb is full fire. A cracked wall that was already
+			// hit goes the same way.
+			if tile == SoftWall || tile == CrackedWallHit {
 				e.State.Board[pos.Y][pos.X] = Empty
+				e.emit(Event{Type: EventWallDestroyed, ByID: bomb.OwnerID, Pos: pos})
+				revealed := e.State.Level > 0 && pos == e.exit
+				if revealed {
+					e.State.Board[pos.Y][pos.X] = ExitDoor
+				} else {
+					revealed = e.revealTrap(pos)
+				}
+				if owner, ok := e.State.Players[bomb.OwnerID]; ok {
+					owner.WallsDestroyed++
+				}
 				e.State.Fires = append(e.State.Fires, Fire{
-					Pos:       pos,
-					ExpiresAt: fireExpiry,
+					Pos:         pos,
+					ExpiresTick: fireExpiry,
+					OwnerID:     bomb.OwnerID,
 				})
-				// Random pickup drop
-				roll := rand.Float64()
-				if roll < PickupBombDropChance {
-					e.State.Pickups = append(e.State.Pickups, Pickup{
-						Pos: pos, Type: PickupBomb,
-					})
-				} else if roll < PickupBombDropChance+PickupRangeDropChance {
-					e.State.Pickups = append(e.State.Pickups, Pickup{
-						Pos: pos, Type: PickupRange,
-					})
+				if !revealed {
+					e.dropPickup(pos)
+				}
+				if bomb.FullFire {
+					continue
+				}
+				break
+			}
+
+			// Barrel: destroy it and set off its own explosion, which stops
+			// this arm like a soft wall but may carry the chain further
+			if tile == Barrel {
+				e.State.Board[pos.Y][pos.X] = Empty
+				e.explode(&Bomb{OwnerID: bomb.OwnerID, Pos: pos, Range: BarrelRange}, detonated)
+				if bomb.FullFire {
+					continue
 				}
 				break
 			}
 
 			// Place fire on empty tile
 			e.State.Fires = append(e.State.Fires, Fire{
-				Pos:       pos,
-				ExpiresAt: fireExpiry,
+				Pos:         pos,
+				ExpiresTick: fireExpiry,
+				OwnerID:     bomb.OwnerID,
 			})
 
-			// Chain reaction: if fire hits another bomb, detonate it immediately
+			// Chain reaction: if fire hits another bomb, it goes off after the
+			// chain delay, or immediately without one
 			for i, otherBomb := range e.State.Bombs {
-				if otherBomb.Pos == pos && !detonated[i] {
-					detonated[i] = true
-					e.explode(otherBomb, detonated)
+				if otherBomb.Pos != pos || detonated[i] {
+					continue
+				}
+				if e.Config.ChainDelay > 0 {
+					e.chain(otherBomb)
+					continue
 				}
+				detonated[i] = true
+				e.explode(otherBomb, detonated)
 			}
 		}
 	}
@@ -146,26 +284,198 @@ func (e *Engine) explode(bomb *Bomb, detonated map[int]bool) {
 	e.damageEnemiesInFire()
 }
 
-// damagePlayersInFire kills any alive player standing on a fire tile.
+// chain sets off a bomb caught in an explosion Config.ChainDelay from now,
+// unless its fuse runs out sooner. The delay gives players a moment to see
+// a chain coming and get out of its way.
+func (e *Engine) chain(b *Bomb) {
+	if b.Chained {
+		return
+	}
+	// Mines have no fuse to run out
+	if at := e.State.Tick + e.ticksIn(e.Config.ChainDelay); b.Mine || at < b.ExpiresTick {
+		b.ExpiresTick = at
+	}
+	b.Chained = true
+}
+
+// bombSlideInterval is how many ticks a kicked bomb takes to slide one tile.
+const bombSlideInterval = 2
+
+// kickBomb sends b sliding in direction d, unless the way is blocked.
+func (e *Engine) kickBomb(b *Bomb, d Position) {
+	if !e.blocksBomb(Position{X: b.Pos.X + d.X, Y: b.Pos.Y + d.Y}) {
+		b.Velocity = d
+	}
+}
+
+// slideBombs moves kicked bombs one tile every bombSlideInterval ticks.
+// A bomb stops in front of walls, players, enemies, the boss and other bombs.
+func (e *Engine) slideBombs() {
+	if e.State.Tick%bombSlideInterval != 0 {
+		return
+	}
+	for _, b := range e.State.Bombs {
+		if b.Velocity == (Position{}) {
+			continue
+		}
+		next := Position{X: b.Pos.X + b.Velocity.X, Y: b.Pos.Y + b.Velocity.Y}
+		if e.blocksBomb(next) {
+			b.Velocity = Position{}
+			continue
+		}
+		b.Pos = next
+		b.OwnerOn = false
+	}
+}
+
+// blocksBomb reports whether a sliding bomb can't enter pos.
+func (e *Engine) blocksBomb(pos Position) bool {
+	if pos.X < 0 || pos.X >= e.State.Width || pos.Y < 0 || pos.Y >= e.State.Height ||
+		e.State.Board[pos.Y][pos.X].Solid() || e.bossCovers(pos) {
+		return true
+	}
+	for _, b := range e.State.Bombs {
+		if b.Pos == pos {
+			return true
+		}
+	}
+	for _, p := range e.State.Players {
+		if p.Alive && p.Pos == pos {
+			return true
+		}
+	}
+	for _, en := range e.State.Enemies {
+		if en.Alive && en.Pos == pos {
+			return true
+		}
+	}
+	return false
+}
+
+// pickupDrop is the chance of a destroyed wall dropping a pickup.
+type pickupDrop struct {
+	typ    PickupType
+	chance float64
+}
+
+// pickupDrops are the default chances of a destroyed wall dropping each
+// pickup. At most one drops.
+var pickupDrops = []pickupDrop{
+	{PickupBomb, PickupBombDropChance},
+	{PickupRange, PickupRangeDropChance},
+	{PickupSpeed, PickupSpeedDropChance},
+	{PickupKick, PickupKickDropChance},
+	{PickupShield, PickupShieldDropChance},
+	{PickupSkull, PickupSkullDropChance},
+	{PickupFullFire, PickupFullFireDropChance},
+	{PickupLineBomb, PickupLineBombDropChance},
+	{PickupMine, PickupMineDropChance},
+	{PickupCloak, PickupCloakDropChance},
+}
+
+// PickupNames maps the names of pickups in GameConfig.DropTable, the same
+// as on the wire, to their types.
+var PickupNames = map[string]PickupType{
+	"bomb":      PickupBomb,
+	"range":     PickupRange,
+	"ammo":      PickupAmmo,
+	"speed":     PickupSpeed,
+	"kick":      PickupKick,
+	"shield":    PickupShield,
+	"skull":     PickupSkull,
+	"full_fire": PickupFullFire,
+	"line_bomb": PickupLineBomb,
+	"mine":      PickupMine,
+	"cloak":     PickupCloak,
+}
+
+// dropTable returns the drop chances for config: pickupDrops, or
+// config.DropTable scaled down to add up to 1 if it adds up to more.
+func dropTable(config GameConfig) []pickupDrop {
+	if config.DropTable == nil {
+		return pickupDrops
+	}
+	names := make([]string, 0, len(config.DropTable))
+	total := 0.0
+	for name, chance := range config.DropTable {
+		names = append(names, name)
+		total += chance
+	}
+	sort.Strings(names)
+	table := make([]pickupDrop, 0, len(names))
+	for _, name := range names {
+		chance := config.DropTable[name]
+		if total > 1 {
+			chance /= total
+		}
+		table = append(table, pickupDrop{PickupNames[name], chance})
+	}
+	return table
+}
+
+// dropPickup randomly leaves a pickup where a soft wall was destroyed.
+func (e *Engine) dropPickup(pos Position) {
+	// Ammo mode: walls are the crates that restock players
+	if e.Config.AmmoMode && e.rng.Float64() < PickupAmmoDropChance {
+		e.State.Pickups = append(e.State.Pickups, Pickup{Pos: pos, Type: PickupAmmo})
+		return
+	}
+
+	roll := e.rng.Float64()
+	for _, d := range e.drops {
+		if roll < d.chance {
+			e.State.Pickups = append(e.State.Pickups, Pickup{Pos: pos, Type: d.typ})
+			return
+		}
+		roll -= d.chance
+	}
+}
+
+// damagePlayersInFire burns any alive player standing on a fire tile that
+// hurts them, in player ID order.
 func (e *Engine) damagePlayersInFire() {
-	fireSet := make(map[Position]bool, len(e.State.Fires))
+	fireOwner := make(map[Position]string, len(e.State.Fires))
 	for _, f := range e.State.Fires {
-		fireSet[f.Pos] = true
+		fireOwner[f.Pos] = f.OwnerID
 	}
 
+	for _, p := range e.playersByID() {
+		if owner, ok := fireOwner[p.Pos]; ok && p.Alive && e.hurtBy(p, owner) {
+			e.burnPlayer(p, owner)
+		}
+	}
+}
+
+// burnPlayer takes a heart from p for a fire hit, with HitProtection from
+// the next, and kills them when it was their last.
+func (e *Engine) burnPlayer(p *Player, ownerID string) {
+	p.HP--
+	if p.HP > 0 {
+		p.InvulnerableUntil = e.now().Add(HitProtection)
+		return
+	}
+	e.killPlayer(p, ownerID)
+}
+
+// invulnerable reports whether p is immune to fire right now.
+func (e *Engine) invulnerable(p *Player) bool {
+	return e.now().Before(p.InvulnerableUntil)
+}
+
+// tickInvulnerability clears fire immunity that has run out.
+func (e *Engine) tickInvulnerability() {
 	for _, p := range e.State.Players {
-		if p.Alive && fireSet[p.Pos] {
-			p.Alive = false
+		if !p.InvulnerableUntil.IsZero() && !e.invulnerable(p) {
+			p.InvulnerableUntil = time.Time{}
 		}
 	}
 }
 
 // clearExpiredFires removes fire tiles that have expired.
 func (e *Engine) clearExpiredFires() {
-	now := time.Now()
 	remaining := make([]Fire, 0, len(e.State.Fires))
 	for _, f := range e.State.Fires {
-		if now.Before(f.ExpiresAt) {
+		if f.Permanent || e.State.Tick < f.ExpiresTick {
 			remaining = append(remaining, f)
 		}
 	}
@@ -0,0 +1,280 @@
+package game
+
+import (
+	"math"
+)
+
+const (
+	// BossSize is the width and height of the boss in tiles.
+	BossSize = 2
+
+	// BossID owns the bombs and fire of boss attacks.
+	BossID = "boss"
+
+	// bossArenaMargin is how many tiles around the boss spawn are cleared of
+	// walls, so the boss has room to turn and players can reach it.
+	bossArenaMargin = 1
+
+	bossMoveInterval   = 10 // Ticks between boss steps, half a player's speed
+	bossAttackInterval = 60 // Ticks between attacks (3s at 20 ticks/sec)
+	bossWarnTicks      = 20 // Telegraph time before an attack lands
+	bossBombCount      = 3
+	bossBombRadius     = 3 // Manhattan distance from the boss bombs land within
+)
+
+// BossSpawn returns the top-left tile of the boss at the start of a match:
+// the middle of the board.
+func BossSpawn(width, height int) Position {
+	return Position{X: (width - BossSize) / 2, Y: (height - BossSize) / 2}
+}
+
+// clearBossArena empties the tiles around the boss spawn, pillars included.
+func clearBossArena(board [][]TileType, config GameConfig) {
+	spawn := BossSpawn(config.Width, config.Height)
+	for y := spawn.Y - bossArenaMargin; y < spawn.Y+BossSize+bossArenaMargin; y++ {
+		for x := spawn.X - bossArenaMargin; x < spawn.X+BossSize+bossArenaMargin; x++ {
+			if x > 0 && y > 0 && x < config.Width-1 && y < config.Height-1 {
+				board[y][x] = Empty
+			}
+		}
+	}
+}
+
+// spawnBoss puts the boss in the middle of the board for a boss mode match.
+func (e *Engine) spawnBoss() {
+	if !e.Config.BossMode {
+		return
+	}
+	hp := max(e.Config.BossHP, 1)
+	e.State.Boss = &Boss{
+		Pos:       BossSpawn(e.State.Width, e.State.Height),
+		Alive:     true,
+		HP:        hp,
+		MaxHP:     hp,
+		WeakPoint: Position{X: e.rng.Intn(BossSize), Y: e.rng.Intn(BossSize)},
+		Timer:     bossAttackInterval,
+	}
+}
+
+// bossCovers reports whether pos is under a live boss.
+func (e *Engine) bossCovers(pos Position) bool {
+	b := e.State.Boss
+	return b != nil && b.Alive && covers(b.Pos, pos)
+}
+
+// covers reports whether pos is inside a boss whose top-left tile is at.
+func covers(at, pos Position) bool {
+	return pos.X >= at.X && pos.X < at.X+BossSize &&
+		pos.Y >= at.Y && pos.Y < at.Y+BossSize
+}
+
+// hitBoss handles fire reaching pos, a tile under the boss. Only its weak
+// point takes damage; each hit moves the weak point to another tile.
+func (e *Engine) hitBoss(pos Position) {
+	b := e.State.Boss
+	weak := Position{X: b.Pos.X + b.WeakPoint.X, Y: b.Pos.Y + b.WeakPoint.Y}
+	if pos != weak {
+		return
+	}
+	b.HP--
+	if b.HP <= 0 {
+		b.HP = 0
+		b.Alive = false
+		b.Attack = BossIdle
+		b.Warning = nil
+		return
+	}
+	for old := b.WeakPoint; b.WeakPoint == old; {
+		b.WeakPoint = Position{X: e.rng.Intn(BossSize), Y: e.rng.Intn(BossSize)}
+	}
+}
+
+// tickBoss advances the boss: it alternates between walking toward the
+// nearest player and standing still to telegraph an attack, which lands
+// bossWarnTicks later on the tiles in Warning.
+func (e *Engine) tickBoss() {
+	b := e.State.Boss
+	if b == nil || !b.Alive {
+		return
+	}
+
+	b.Timer--
+	if b.Timer <= 0 {
+		if b.Attack == BossIdle {
+			e.telegraphBossAttack(b)
+			b.Timer = bossWarnTicks
+		} else {
+			e.unleashBossAttack(b)
+			b.Attack = BossIdle
+			b.Warning = nil
+			b.Timer = bossAttackInterval
+			if b.HP*2 <= b.MaxHP {
+				b.Timer /= 2 // Enraged
+			}
+		}
+	}
+
+	if b.Attack == BossIdle {
+		b.MoveTimer++
+		if b.MoveTimer >= bossMoveInterval {
+			b.MoveTimer = 0
+			e.moveBoss(b)
+		}
+	}
+
+	// Players caught under the boss are crushed
+	for _, p := range e.playersByID() {
+		if p.Alive && covers(b.Pos, p.Pos) {
+			e.killPlayer(p, BossID)
+		}
+	}
+}
+
+// nearestPlayer returns the alive player closest to pos, or nil.
+func (e *Engine) nearestPlayer(pos Position) *Player {
+	var nearest *Player
+	nearestDist := math.MaxInt32
+	for _, p := range e.playersByID() {
+		if !p.Alive {
+			continue
+		}
+		if dist := abs(pos.X-p.Pos.X) + abs(pos.Y-p.Pos.Y); dist < nearestDist {
+			nearest, nearestDist = p, dist
+		}
+	}
+	return nearest
+}
+
+// moveBoss steps the boss one tile toward the nearest player, if its whole
+// body fits in the new place.
+func (e *Engine) moveBoss(b *Boss) {
+	target := e.nearestPlayer(b.Pos)
+	if target == nil {
+		return
+	}
+	dx, dy := target.Pos.X-b.Pos.X, target.Pos.Y-b.Pos.Y
+	steps := []Position{{X: sign(dx)}, {Y: sign(dy)}}
+	if abs(dy) > abs(dx) {
+		steps[0], steps[1] = steps[1], steps[0]
+	}
+	for _, s := range steps {
+		if s == (Position{}) {
+			continue
+		}
+		next := Position{X: b.Pos.X + s.X, Y: b.Pos.Y + s.Y}
+		if e.bossFits(next) {
+			b.Pos = next
+			return
+		}
+	}
+}
+
+// bossFits reports whether the boss can stand with its top-left tile at pos.
+func (e *Engine) bossFits(pos Position) bool {
+	for y := pos.Y; y < pos.Y+BossSize; y++ {
+		for x := pos.X; x < pos.X+BossSize; x++ {
+			if x < 0 || y < 0 || x >= e.State.Width || y >= e.State.Height ||
+				e.State.Board[y][x].Solid() {
+				return false
+			}
+		}
+	}
+	for _, bomb := range e.State.Bombs {
+		if covers(pos, bomb.Pos) {
+			return false
+		}
+	}
+	for _, en := range e.State.Enemies {
+		if en.Alive && covers(pos, en.Pos) {
+			return false
+		}
+	}
+	return true
+}
+
+// telegraphBossAttack picks the next attack and the tiles it will hit.
+func (e *Engine) telegraphBossAttack(b *Boss) {
+	target := e.nearestPlayer(b.Pos)
+	if target == nil {
+		return
+	}
+	b.Warning = nil
+	if e.rng.Intn(2) == 0 {
+		b.Attack = BossFireSweep
+		horizontal := e.rng.Intn(2) == 0
+		for i := 0; ; i++ {
+			pos := Position{X: i, Y: target.Pos.Y}
+			if !horizontal {
+				pos = Position{X: target.Pos.X, Y: i}
+			}
+			if pos.X >= e.State.Width || pos.Y >= e.State.Height {
+				break
+			}
+			if !e.State.Board[pos.Y][pos.X].Solid() && !covers(b.Pos, pos) {
+				b.Warning = append(b.Warning, pos)
+			}
+		}
+		return
+	}
+
+	b.Attack = BossBombs
+	var candidates []Position
+	for y := b.Pos.Y - bossBombRadius; y < b.Pos.Y+BossSize+bossBombRadius; y++ {
+		for x := b.Pos.X - bossBombRadius; x < b.Pos.X+BossSize+bossBombRadius; x++ {
+			pos := Position{X: x, Y: y}
+			if x < 0 || y < 0 || x >= e.State.Width || y >= e.State.Height ||
+				e.State.Board[y][x].Solid() || covers(b.Pos, pos) {
+				continue
+			}
+			candidates = append(candidates, pos)
+		}
+	}
+	e.rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	b.Warning = candidates[:min(bossBombCount, len(candidates))]
+}
+
+// unleashBossAttack lands the telegraphed attack.
+func (e *Engine) unleashBossAttack(b *Boss) {
+	now := e.now()
+	switch b.Attack {
+	case BossFireSweep:
+		for _, pos := range b.Warning {
+			e.State.Fires = append(e.State.Fires, Fire{
+				Pos:         pos,
+				ExpiresTick: e.State.Tick + e.ticksIn(e.Config.FireDuration),
+				OwnerID:     BossID,
+			})
+		}
+		e.damagePlayersInFire()
+		e.damageEnemiesInFire()
+	case BossBombs:
+	outer:
+		for _, pos := range b.Warning {
+			for _, bomb := range e.State.Bombs {
+				if bomb.Pos == pos {
+					continue outer
+				}
+			}
+			e.State.Bombs = append(e.State.Bombs, &Bomb{
+				OwnerID:     BossID,
+				Pos:         pos,
+				Range:       StartRange,
+				PlacedAt:    now,
+				ExpiresTick: e.State.Tick + e.ticksIn(e.Config.BombTimer),
+				FuseLeft:    e.Config.BombTimer,
+			})
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,125 @@
+package game
+
+import (
+	"time"
+)
+
+// Level is one stage of the campaign.
+type Level struct {
+	Name            string  `json:"name"`
+	SoftWallDensity float64 `json:"soft_wall_density"`
+	Enemies         int     `json:"enemies"`
+}
+
+// Levels are the campaign's stages, played in order.
+var Levels = []Level{
+	{Name: "Outskirts", SoftWallDensity: 0.3, Enemies: 2},
+	{Name: "Warehouse", SoftWallDensity: 0.4, Enemies: 3},
+	{Name: "Catacombs", SoftWallDensity: 0.45, Enemies: 4},
+	{Name: "Fortress", SoftWallDensity: 0.5, Enemies: 6},
+}
+
+// LevelIntermission is the pause after a level is cleared.
+const LevelIntermission = 3 * time.Second
+
+// level returns the campaign level being played, or nil outside the campaign.
+func (e *Engine) level() *Level {
+	if e.State.Level < 1 || e.State.Level > len(Levels) {
+		return nil
+	}
+	return &Levels[e.State.Level-1]
+}
+
+// loadLevelLocked builds the board for the current level and hides its exit
+// under one of the soft walls.
+// MUST be called while e.mu is held.
+func (e *Engine) loadLevelLocked() {
+	config := e.Config
+	config.SoftWallDensity = e.level().SoftWallDensity
+	e.resetBoardLocked()
+	e.State.Board = NewBoard(config, e.rng)
+	e.hideTrapsLocked()
+
+	var walls []Position
+	safe := makeSafeSet(SpawnPositions(e.Config.Width, e.Config.Height))
+	for y := 1; y < e.State.Height-1; y++ {
+		for x := 1; x < e.State.Width-1; x++ {
+			pos := Position{X: x, Y: y}
+			if e.State.Board[y][x] == SoftWall || (e.State.Board[y][x] == Empty && !safe[pos]) {
+				walls = append(walls, pos)
+			}
+		}
+	}
+	// Prefer an existing soft wall; on a bare board, build one
+	e.rng.Shuffle(len(walls), func(i, j int) { walls[i], walls[j] = walls[j], walls[i] })
+	for _, pos := range walls {
+		if e.State.Board[pos.Y][pos.X] == SoftWall {
+			e.exit = pos
+			return
+		}
+	}
+	if len(walls) > 0 {
+		e.exit = walls[0]
+		e.State.Board[e.exit.Y][e.exit.X] = SoftWall
+	}
+}
+
+// checkLevelLocked clears the level once every monster is dead and a player
+// stands on the exit. Clearing the last level wins the campaign; losing
+// every player loses it.
+// MUST be called while e.mu is held.
+func (e *Engine) checkLevelLocked(alive []*Player) {
+	if len(alive) == 0 {
+		e.State.Status = StatusOver
+		return
+	}
+	if e.monstersLeft() {
+		return
+	}
+	for _, p := range alive {
+		if p.Alive && e.State.Board[p.Pos.Y][p.Pos.X] == ExitDoor {
+			if e.State.Level == len(Levels) {
+				e.State.Status = StatusOver
+				return
+			}
+			e.State.Status = StatusLevelComplete
+			e.nextRoundAt = e.now().Add(LevelIntermission)
+			return
+		}
+	}
+}
+
+// nextLevelLocked starts the next level with every player back at their
+// corner, dead players included.
+// MUST be called while e.mu is held.
+func (e *Engine) nextLevelLocked() {
+	e.State.Level++
+	spawns := SpawnPositions(e.Config.Width, e.Config.Height)
+	for _, p := range e.State.Players {
+		e.resetPlayer(p, spawns[p.Color%len(spawns)])
+	}
+	e.nextRoundAt = time.Time{}
+	e.discardActionsLocked()
+
+	e.State.Status = StatusRunning
+	e.startRoundLocked()
+}
+
+// CampaignWon reports whether state is a finished campaign whose last level
+// was cleared.
+func CampaignWon(state *GameState) bool {
+	if state.Level != len(Levels) || state.Status != StatusOver {
+		return false
+	}
+	for _, en := range state.Enemies {
+		if en.Alive {
+			return false
+		}
+	}
+	for _, p := range state.Players {
+		if p.Alive && state.Board[p.Pos.Y][p.Pos.X] == ExitDoor {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package game
+
+// curses are the effects a skull pickup can inflict.
+var curses = []Effect{EffectReverse, EffectAutoBomb, EffectShortRange}
+
+// HasEffect reports whether p is under the given effect.
+func (p *Player) HasEffect(kind Effect) bool {
+	for _, ef := range p.Effects {
+		if ef.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// addEffect puts p under ef, replacing any earlier effect of the same kind.
+func addEffect(p *Player, ef StatusEffect) {
+	for i := range p.Effects {
+		if p.Effects[i].Kind == ef.Kind {
+			p.Effects[i] = ef
+			return
+		}
+	}
+	p.Effects = append(p.Effects, ef)
+}
+
+// curse inflicts a random curse on p.
+func (e *Engine) curse(p *Player) {
+	addEffect(p, StatusEffect{
+		Kind:      curses[e.rng.Intn(len(curses))],
+		ExpiresAt: e.now().Add(CurseDuration),
+	})
+}
+
+// passCurses hands curses on when p bumps into q: a cursed player infects a
+// clean one and is cured. Curses only move on contact, so two players
+// standing together don't pass them back and forth.
+func (e *Engine) passCurses(p, q *Player) {
+	from, to := p, q
+	if len(p.Effects) == 0 {
+		from, to = q, p
+	}
+	if len(from.Effects) == 0 || len(to.Effects) != 0 {
+		return
+	}
+	to.Effects, from.Effects = from.Effects, nil
+}
+
+// tickEffects removes expired effects and makes cursed players drop bombs.
+func (e *Engine) tickEffects() {
+	now := e.now()
+	for id, p := range e.State.Players {
+		var kept []StatusEffect
+		for _, ef := range p.Effects {
+			if now.Before(ef.ExpiresAt) {
+				kept = append(kept, ef)
+			}
+		}
+		p.Effects = kept
+
+		if p.Alive && p.HasEffect(EffectAutoBomb) {
+			e.placeBomb(id)
+		}
+	}
+}
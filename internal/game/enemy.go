@@ -3,7 +3,6 @@ package game
 import (
 	"fmt"
 	"math"
-	"math/rand"
 	"time"
 )
 
@@ -31,18 +30,25 @@ func (e *Engine) spawnEnemies() {
 	for y := 1; y < e.State.Height-1; y++ {
 		for x := 1; x < e.State.Width-1; x++ {
 			pos := Position{X: x, Y: y}
-			if e.State.Board[y][x] == Empty && !safeSet[pos] {
+			if e.State.Board[y][x] == Empty && !safeSet[pos] && !e.bossCovers(pos) {
 				candidates = append(candidates, pos)
 			}
 		}
 	}
 
 	// Shuffle and pick up to EnemyCount positions
-	rand.Shuffle(len(candidates), func(i, j int) {
+	e.rng.Shuffle(len(candidates), func(i, j int) {
 		candidates[i], candidates[j] = candidates[j], candidates[i]
 	})
 
 	count := e.Config.EnemyCount
+	if lvl := e.level(); lvl != nil {
+		count = lvl.Enemies
+	}
+	if e.State.PvE {
+		// A PvE match needs something to clear
+		count = max(count, 1)
+	}
 	if count > len(candidates) {
 		count = len(candidates)
 	}
@@ -52,8 +58,8 @@ func (e *Engine) spawnEnemies() {
 			ID:        fmt.Sprintf("enemy_%d", i),
 			Pos:       candidates[i],
 			Alive:     true,
-			Dir:       Direction(rand.Intn(4)),
-			MoveTimer: rand.Intn(enemyMoveInterval), // stagger start times
+			Dir:       Direction(e.rng.Intn(4)),
+			MoveTimer: e.rng.Intn(enemyMoveInterval), // stagger start times
 		}
 		e.State.Enemies = append(e.State.Enemies, enemy)
 	}
@@ -84,10 +90,17 @@ func (e *Engine) buildDangerSet() map[Position]bool {
 		danger[f.Pos] = true
 	}
 
+	// Tiles the boss is about to attack
+	if b := e.State.Boss; b != nil && b.Alive {
+		for _, pos := range b.Warning {
+			danger[pos] = true
+		}
+	}
+
 	// Bomb blast zones: for each bomb, mark the cross pattern as dangerous
 	for _, b := range e.State.Bombs {
 		// Only worry about bombs that will explode soon (within 2 seconds)
-		if time.Until(b.ExpiresAt) > 2*time.Second {
+		if b.ExpiresTick > e.State.Tick+e.ticksIn(2*time.Second) {
 			continue
 		}
 		danger[b.Pos] = true
@@ -110,7 +123,7 @@ func (e *Engine) buildDangerSet() map[Position]bool {
 					break
 				}
 				danger[pos] = true
-				if tile == SoftWall {
+				if tile == SoftWall || tile == Barrel {
 					break
 				}
 			}
@@ -161,7 +174,7 @@ func (e *Engine) tickSingleEnemy(enemy *Enemy, dangerSet map[Position]bool) {
 	}
 
 	// --- Priority 2: Chase nearest player ---
-	if rand.Float64() < chaseChance {
+	if e.rng.Float64() < chaseChance {
 		dir, ok := e.pickChaseDirection(enemy, safeDirs)
 		if ok {
 			e.moveEnemy(enemy, dir)
@@ -188,9 +201,12 @@ func (e *Engine) getValidDirections(enemy *Enemy) []Direction {
 			continue
 		}
 
-		// Wall collision
-		tile := e.State.Board[newPos.Y][newPos.X]
-		if tile == HardWall || tile == SoftWall {
+		// Wall and barrel collision
+		if e.State.Board[newPos.Y][newPos.X].Solid() {
+			continue
+		}
+
+		if e.bossCovers(newPos) {
 			continue
 		}
 
@@ -250,8 +266,9 @@ func (e *Engine) pickChaseDirection(enemy *Enemy, dirs []Direction) (Direction,
 	// Find nearest alive player
 	var nearest *Player
 	nearestDist := math.MaxInt32
-	for _, p := range e.State.Players {
-		if !p.Alive {
+	for _, p := range e.playersByID() {
+		// Enemies can't chase what they can't see
+		if !p.Alive || !p.InvisibleUntil.IsZero() {
 			continue
 		}
 		dist := abs(enemy.Pos.X-p.Pos.X) + abs(enemy.Pos.Y-p.Pos.Y)
@@ -282,7 +299,7 @@ func (e *Engine) pickChaseDirection(enemy *Enemy, dirs []Direction) (Direction,
 // 60% chance to keep going the same direction, otherwise pick randomly.
 func (e *Engine) pickWanderDirection(enemy *Enemy, dirs []Direction) Direction {
 	// Try to keep current direction (momentum) 60% of the time
-	if rand.Float64() < 0.6 {
+	if e.rng.Float64() < 0.6 {
 		for _, d := range dirs {
 			if d == enemy.Dir {
 				return d
@@ -290,7 +307,7 @@ func (e *Engine) pickWanderDirection(enemy *Enemy, dirs []Direction) Direction {
 		}
 	}
 	// Random from available
-	return dirs[rand.Intn(len(dirs))]
+	return dirs[e.rng.Intn(len(dirs))]
 }
 
 // moveEnemy applies a direction to the enemy's position.
@@ -330,11 +347,21 @@ func (e *Engine) checkEnemyPlayerCollisions() {
 		}
 	}
 
-	for _, p := range e.State.Players {
+	for _, p := range e.playersByID() {
 		if p.Alive && enemySet[p.Pos] {
-			p.Alive = false
+			e.killPlayer(p, "")
+		}
+	}
+}
+
+// monstersLeft reports whether any enemy is still alive.
+func (e *Engine) monstersLeft() bool {
+	for _, enemy := range e.State.Enemies {
+		if enemy.Alive {
+			return true
 		}
 	}
+	return false
 }
 
 // damageEnemiesInFire kills any alive enemy standing on a fire tile.
@@ -1,8 +1,13 @@
 package game
 
 import (
+	"cmp"
 	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,12 +19,63 @@ type Engine struct {
 	done    chan struct{}
 	mu      sync.Mutex
 	onTick  func(GameState) // Callback after each tick with a COPY of state
+	overAt  time.Time       // When the current game ended; zero while not over
+	drops   []pickupDrop    // Config.DropTable, or the default drop chances
+	rng     *rand.Rand      // All the game's randomness, seeded from Config.Seed
+	source  *countingSource // rng's source, counting draws for Snapshot
+	seed    int64           // Config.Seed, or the one picked for it
+	mode    GameMode        // The rules of Config.Mode
+
+	savedAt   time.Time       // When a loaded save was written; zero unless resuming
+	unclaimed map[string]bool // Saved player IDs nobody has reclaimed yet
+
+	stepping bool      // Step mode: the game only advances on Step
+	running  bool      // Run is driving the game
+	epoch    time.Time // Engine time at tick 0; see now
+
+	startAt time.Time // When the countdown ends; zero until RunCountdown
+
+	nextRoundAt time.Time // When the intermission or level-complete pause ends; zero otherwise
+	exit        Position  // Campaign: where the current level's exit is hidden
+	endsAt      time.Time // When the round's time limit runs out; zero without one
+
+	zoneShrinksAt time.Time // Battle royale: when the zone next closes in; zero once it's done
+
+	rainAt    time.Time     // Bomb rain: when the next bomb falls; zero until it starts
+	rainEvery time.Duration // Bomb rain: the gap before the bomb after next
+
+	traps map[Position]bool // Soft walls hiding a spike trap, kept off the wire
+
+	subscribers []func(Event) // OnEvent callbacks
+	events      []Event       // Emitted since the last tick's publish
+
+	hooks  Hooks // Custom rules; nil for none
+	hooked int   // How many of events the hooks have seen
+
+	actionSeq uint64 // Actions drained so far, numbering them in order of arrival
+
+	recordRuns bool       // Record each match for ghost racing; see RecordRuns
+	recording  *recording // The match being recorded; nil between matches
+	lastRun    *Run       // The last match recorded to its end
+
+	checking   bool   // Check the state's invariants after every tick
+	violations string // The last invariant violations logged
+
+	metrics Metrics       // See Metrics; DroppedActions is kept in dropped
+	dropped atomic.Uint64 // Actions EnqueueAction dropped, counted without e.mu
 }
 
 // NewEngine creates a new game engine with the given config.
 func NewEngine(config GameConfig) *Engine {
+	config = normalizeConfig(config)
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	source := newCountingSource(seed)
+	rng := rand.New(source)
 	state := &GameState{
-		Board:   NewBoard(config),
+		Board:   NewBoard(config, rng),
 		Players: make(map[string]*Player),
 		Bombs:   make([]*Bomb, 0),
 		Fires:   make([]Fire, 0),
@@ -28,14 +84,35 @@ func NewEngine(config GameConfig) *Engine {
 		Width:   config.Width,
 		Height:  config.Height,
 		Status:  StatusLobby,
+		PvE:     config.PvEMode,
 	}
 
-	return &Engine{
+	e := &Engine{
 		State:   state,
 		Config:  config,
 		actions: make(chan Action, 256),
 		done:    make(chan struct{}),
+		drops:   dropTable(config),
+		rng:     rng,
+		source:  source,
+		seed:    seed,
+		mode:    modes[config.Mode],
+		epoch:   time.Now(),
 	}
+	e.hideTrapsLocked()
+	return e
+}
+
+// normalizeConfig settles what config leaves to the engine: the overtime
+// the lava flag asks for, the mode and how many players the board fits.
+func normalizeConfig(config GameConfig) GameConfig {
+	if config.Lava && (config.Overtime == "" || config.Overtime == OvertimeNone) {
+		config.Overtime = OvertimeLava
+	}
+	config = normalizeMode(config)
+	// Every player needs a spawn of their own
+	config.MaxPlayers = min(config.MaxPlayers, len(SpawnPositions(config.Width, config.Height)))
+	return config
 }
 
 // OnTick sets a callback that is invoked after every game tick with a copy of the state.
@@ -47,9 +124,18 @@ func (e *Engine) OnTick(fn func(GameState)) {
 // Run starts the game loop at the configured tick rate.
 // This blocks until Stop() is called.
 func (e *Engine) Run() {
-	ticker := time.NewTicker(time.Second / time.Duration(e.Config.TickRate))
+	ticker := time.NewTicker(e.tickDuration())
 	defer ticker.Stop()
 
+	e.mu.Lock()
+	e.running = true
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.running = false
+		e.mu.Unlock()
+	}()
+
 	for {
 		select {
 		case <-e.done:
@@ -65,67 +151,342 @@ func (e *Engine) Stop() {
 	close(e.done)
 }
 
-// EnqueueAction sends a player action to be processed on the next tick.
+// now returns the engine's clock, used for the timers kept as times. It
+// counts ticks rather than wall time, so every timer runs out after a whole
+// number of ticks however late they run, and the same inputs always play out
+// the same way. In step mode it stands still until ticks are stepped.
+func (e *Engine) now() time.Time {
+	return e.epoch.Add(time.Duration(e.State.Tick) * e.tickDuration())
+}
+
+// tickDuration is how much engine time one tick covers.
+func (e *Engine) tickDuration() time.Duration {
+	return time.Second / time.Duration(max(e.Config.TickRate, 1))
+}
+
+// ticksIn returns how many ticks d of engine time spans, rounded up so a
+// timer never runs out early. Bombs and fires keep their expiry as the tick
+// it falls on rather than as a time.
+func (e *Engine) ticksIn(d time.Duration) uint64 {
+	td := e.tickDuration()
+	return uint64(max((d+td-1)/td, 0))
+}
+
+// fuseLeft returns the engine time left until the tick expiry, or 0 once it
+// has come.
+func (e *Engine) fuseLeft(expiry uint64) time.Duration {
+	if expiry <= e.State.Tick {
+		return 0
+	}
+	return time.Duration(expiry-e.State.Tick) * e.tickDuration()
+}
+
+// SetStepMode turns step mode on or off. In step mode the game loop stops
+// advancing the game and only Step does, for reproducing timing-sensitive
+// bugs and for tests.
+func (e *Engine) SetStepMode(on bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stepping = on
+}
+
+// Step runs n ticks of the game right away, for driving the engine without
+// Run: headless simulations, bot training and tests. Without Run, the
+// OnTick and OnEvent callbacks are called after every tick as Run would.
+// While Run is going, Step only works in step mode, and the game loop
+// broadcasts the result on its next tick.
+func (e *Engine) Step(n int) {
+	for range n {
+		e.mu.Lock()
+		if e.running {
+			if e.stepping {
+				e.advanceLocked()
+			}
+			e.mu.Unlock()
+			continue
+		}
+		e.advanceLocked()
+		stateCopy := e.copyStateLocked()
+		events := e.takeEventsLocked()
+		e.mu.Unlock()
+		e.publishTick(stateCopy, events)
+	}
+}
+
+// RunFor runs d of game time right away, rounded down to whole ticks. See
+// Step.
+func (e *Engine) RunFor(d time.Duration) {
+	e.Step(int(d / e.tickDuration()))
+}
+
+// EnqueueAction sends a player action to be processed on the next tick. If
+// the queue is full the action is dropped, which Metrics counts.
 func (e *Engine) EnqueueAction(a Action) {
 	select {
 	case e.actions <- a:
 	default:
 		// Drop action if buffer is full (prevents blocking)
+		e.dropped.Add(1)
 	}
 }
 
-// AddPlayer adds a new player to the game.
-// Returns an error if the game is full or already running.
+// AddPlayer adds a new player to the game. A name another player has is
+// made unique with " (2)", " (3)" and so on; PlayerName returns the name the
+// player got. Returns an error if the game is full or already running.
 func (e *Engine) AddPlayer(id, name string) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	if e.State.Status == StatusRunning {
+	if e.State.Status == StatusRunning || e.State.Status == StatusCountdown || e.State.Status == StatusPaused ||
+		e.State.Status == StatusIntermission || e.State.Status == StatusLevelComplete {
 		return fmt.Errorf("game already in progress")
 	}
-	if len(e.State.Players) >= e.Config.MaxPlayers {
-		return fmt.Errorf("game is full (%d/%d players)", len(e.State.Players), e.Config.MaxPlayers)
-	}
 	if _, exists := e.State.Players[id]; exists {
 		return fmt.Errorf("player %s already exists", id)
 	}
+	if e.reclaimLocked(id, name) {
+		e.State.Version++
+		return nil
+	}
+	if len(e.State.Players) >= e.Config.MaxPlayers {
+		return fmt.Errorf("game is full (%d/%d players)", len(e.State.Players), e.Config.MaxPlayers)
+	}
 
 	spawns := SpawnPositions(e.Config.Width, e.Config.Height)
-	spawnIdx := len(e.State.Players)
-	if spawnIdx >= len(spawns) {
-		spawnIdx = spawnIdx % len(spawns)
+	p := &Player{
+		ID:    id,
+		Name:  e.uniqueNameLocked(name),
+		Color: e.freeColorLocked(),
 	}
-
-	e.State.Players[id] = &Player{
-		ID:        id,
-		Name:      name,
-		Pos:       spawns[spawnIdx],
-		Alive:     true,
-		BombMax:   3,
-		BombRange: 2,
-		BombsUsed: 0,
-		Color:     spawnIdx,
+	if e.Config.TeamMode {
+		p.TeamID = e.smallestTeamLocked()
 	}
+	e.resetPlayer(p, spawns[p.Color%len(spawns)])
+	e.State.Players[id] = p
+	e.State.Version++
 	return nil
 }
 
+// uniqueNameLocked returns name, with " (2)", " (3)" and so on appended if
+// a player has it already, so the HUD tells players apart.
+// MUST be called while e.mu is held.
+func (e *Engine) uniqueNameLocked(name string) string {
+	taken := make(map[string]bool, len(e.State.Players))
+	for _, p := range e.State.Players {
+		taken[p.Name] = true
+	}
+	unique := name
+	for n := 2; taken[unique]; n++ {
+		unique = fmt.Sprintf("%s (%d)", name, n)
+	}
+	return unique
+}
+
+// freeColorLocked returns the lowest color no player has. A player's color
+// also picks their spawn, so players who leave free theirs for the next to
+// join, and those who stay keep their own.
+// MUST be called while e.mu is held.
+func (e *Engine) freeColorLocked() int {
+	taken := make(map[int]bool, len(e.State.Players))
+	for _, p := range e.State.Players {
+		taken[p.Color] = true
+	}
+	color := 0
+	for taken[color] {
+		color++
+	}
+	return color
+}
+
+// resetPlayer puts a player back at a spawn point with starting stats.
+func (e *Engine) resetPlayer(p *Player, spawn Position) {
+	p.Pos = spawn
+	e.leaveBombs(p)
+	p.Alive = true
+	p.BombMax = cmp.Or(p.Handicap.Bombs, e.Config.StartBombs)
+	p.BombRange = cmp.Or(p.Handicap.Range, e.Config.StartRange)
+	p.BombsUsed = 0
+	for _, b := range e.State.Bombs {
+		// Bombs laid before dying still count until they go off
+		if b.OwnerID == p.ID {
+			p.BombsUsed++
+		}
+	}
+	p.Sprinting = false
+	p.Stamina = 0
+	if e.Config.SprintEnabled {
+		p.Stamina = MaxStamina
+	}
+	p.Ammo = 0
+	if e.Config.AmmoMode {
+		p.Ammo = e.Config.StartAmmo
+	}
+	p.KilledBy = ""
+	p.DiedAt = 0
+	p.MoveSpeed = cmp.Or(p.Handicap.Speed, StartSpeed)
+	p.CanKick = false
+	p.FullFire = false
+	p.LineBomb = false
+	p.Mines = 0
+	p.Facing = DirDown
+	p.Sliding = false
+	p.InvulnerableUntil = time.Time{}
+	p.InvisibleUntil = time.Time{}
+	p.StunnedUntil = time.Time{}
+	p.Effects = nil
+	p.Lives = max(e.Config.Lives, 1)
+	p.HP = max(e.Config.Hearts, 1)
+	p.RespawnAt = time.Time{}
+	p.moveCredit = e.Config.TickRate
+	p.hasPending = false
+}
+
+// SetCosmetics sets a player's cosmetic choices.
+func (e *Engine) SetCosmetics(id string, c Cosmetics) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if p, ok := e.State.Players[id]; ok {
+		p.Cosmetics = c
+		e.State.Version++
+	}
+}
+
+// SetRTT records the round trip to a player's client, for the HUD.
+func (e *Engine) SetRTT(id string, rtt time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if p, ok := e.State.Players[id]; ok && p.RTT != rtt {
+		p.RTT = rtt
+		e.State.Version++
+	}
+}
+
+// SetDisconnected marks a player whose connection dropped, or clears the
+// mark once they are back. It reports whether the player is in the game.
+func (e *Engine) SetDisconnected(id string, disconnected bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	p, ok := e.State.Players[id]
+	if ok && p.Disconnected != disconnected {
+		p.Disconnected = disconnected
+		e.State.Version++
+	}
+	return ok
+}
+
 // RemovePlayer removes a player from the game.
 func (e *Engine) RemovePlayer(id string) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	delete(e.State.Players, id)
+	if !e.savedAt.IsZero() {
+		// Keep the saved character for whoever reclaims it next
+		e.unclaimed[id] = true
+		return
+	}
+	if _, ok := e.State.Players[id]; ok {
+		delete(e.State.Players, id)
+		e.State.Version++
+	}
+}
+
+// PlayerName returns a player's name, or "" if there's no such player.
+func (e *Engine) PlayerName(id string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if p, ok := e.State.Players[id]; ok {
+		return p.Name
+	}
+	return ""
+}
+
+// PlayerInfo returns a player's name and color, without copying the state,
+// or false if there's no such player.
+func (e *Engine) PlayerInfo(id string) (name string, color int, ok bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if p, ok := e.State.Players[id]; ok {
+		return p.Name, p.Color, true
+	}
+	return "", 0, false
+}
+
+// PlayerCount returns the number of players currently in the game.
+func (e *Engine) PlayerCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.State.Players)
 }
 
 // StartGame transitions the game from lobby to running.
 func (e *Engine) StartGame() error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	return e.startLocked()
+}
 
+// BeginCountdown moves the lobby into StatusCountdown. The countdown itself
+// only runs once RunCountdown is called, so the server can first wait for
+// every client to acknowledge it.
+func (e *Engine) BeginCountdown() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.State.Status != StatusLobby {
+		return fmt.Errorf("game already in progress")
+	}
+	if err := e.checkStartLocked(); err != nil {
+		return err
+	}
+	e.State.Status = StatusCountdown
+	e.State.Version++
+	e.startAt = time.Time{}
+	return nil
+}
+
+// RunCountdown starts the countdown begun by BeginCountdown. The game starts
+// Config.StartCountdown from now.
+func (e *Engine) RunCountdown() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.State.Status == StatusCountdown && e.startAt.IsZero() {
+		e.startAt = e.now().Add(e.Config.StartCountdown)
+	}
+}
+
+// checkStartLocked reports why the game can't start yet, if it can't.
+// MUST be called while e.mu is held.
+func (e *Engine) checkStartLocked() error {
+	if !e.savedAt.IsZero() {
+		if len(e.State.Players) == len(e.unclaimed) {
+			return fmt.Errorf("no saved player has rejoined yet")
+		}
+		return nil
+	}
 	if len(e.State.Players) < 1 {
 		return fmt.Errorf("need at least 1 player to start")
 	}
+	return nil
+}
+
+// startLocked starts the match from the lobby, or resumes a loaded save.
+// MUST be called while e.mu is held.
+func (e *Engine) startLocked() error {
+	if e.State.Status != StatusLobby {
+		return fmt.Errorf("game already in progress")
+	}
+	if err := e.checkStartLocked(); err != nil {
+		return err
+	}
+	e.startAt = time.Time{}
+	e.State.Version++
+	if !e.savedAt.IsZero() {
+		e.resumeLocked()
+		return nil
+	}
 	e.State.Status = StatusRunning
-	e.spawnEnemies()
+	e.State.Round = 1
+	e.startRoundLocked()
+	e.beginRunLocked()
 	return nil
 }
 
@@ -135,69 +496,316 @@ func (e *Engine) StartGame() error {
 func (e *Engine) tick() {
 	e.mu.Lock()
 
+	// In step mode only Step advances the game, but the state is still
+	// broadcast so steps and players joining the lobby show up
+	if !e.stepping {
+		e.advanceLocked()
+	}
+
+	// Copy state while still holding the lock
+	stateCopy := e.copyStateLocked()
+	events := e.takeEventsLocked()
+
+	// Release lock BEFORE calling the callback
+	e.mu.Unlock()
+
+	e.publishTick(stateCopy, events)
+}
+
+// publishTick hands a tick's state copy and events to the callbacks.
+// MUST be called without e.mu held, as the callbacks may call back into the
+// engine.
+func (e *Engine) publishTick(state GameState, events []Event) {
+	if e.onTick != nil {
+		e.onTick(state)
+	}
+	e.publish(events)
+}
+
+// advanceLocked runs one tick of game logic.
+// MUST be called while e.mu is held.
+func (e *Engine) advanceLocked() {
+	defer e.recordTickLocked(time.Now())
+	e.metrics.LastActions = 0
+	status := e.State.Status
+
+	if e.State.Status == StatusPaused {
+		// The clock counts ticks, so skipping them freezes every timer
+		e.discardActionsLocked()
+		return
+	}
+
+	e.State.Tick++
+
 	if e.State.Status == StatusRunning {
-		// Process game logic while holding the lock
+		e.tickMovement()
+		e.tickSliding()
+		e.tickTraps()
 		e.drainActions()
+		e.tickStamina()
 		e.tickBombs()
 		e.tickEnemies()
+		e.mode.tick(e)
+		e.tickInvulnerability()
+		e.tickInvisibility()
+		e.tickStuns()
+		e.tickEffects()
+		e.tickRespawns()
 		e.clearExpiredFires()
+		e.tickOvertime()
+		e.tickClock()
+		e.runHooksLocked()
 		e.checkWinCondition()
+		if e.State.Status == StatusOver {
+			e.endRoundLocked()
+		}
+		if e.State.Status == StatusOver {
+			e.endRunLocked()
+		}
+	} else if e.State.Status == StatusCountdown {
+		if e.startAt.IsZero() || e.now().Before(e.startAt) {
+			// No false starts: input sent during the countdown is dropped
+			e.discardActionsLocked()
+		} else {
+			// Input from the countdown's last tick is kept for the first
+			// tick of play, so a move timed to arrive at the start isn't lost
+			// to a few milliseconds of jitter. Back to the lobby to start
+			// from there, or to stay there if everyone left during the
+			// countdown.
+			e.State.Status = StatusLobby
+			if e.startLocked() != nil {
+				e.discardActionsLocked()
+			}
+		}
+	} else if e.State.Status == StatusIntermission {
+		e.discardActionsLocked()
+		if !e.now().Before(e.nextRoundAt) {
+			e.nextRoundLocked()
+		}
+	} else if e.State.Status == StatusLevelComplete {
+		e.discardActionsLocked()
+		if !e.now().Before(e.nextRoundAt) {
+			e.nextLevelLocked()
+		}
+	} else if e.State.Status == StatusOver && e.Config.LobbyReturn > 0 &&
+		e.now().Sub(e.overAt) >= e.Config.LobbyReturn {
+		e.resetToLobbyLocked()
 	}
 
-	// Copy state while still holding the lock
-	stateCopy := e.copyStateLocked()
+	// A running match changes nearly every tick; otherwise only moving on
+	// to the next stage does
+	if e.State.Status == StatusRunning || e.State.Status != status {
+		e.State.Version++
+	}
 
-	// Release lock BEFORE calling the callback
-	e.mu.Unlock()
+	if e.checking {
+		e.checkInvariantsLocked()
+	}
+}
 
-	// Broadcast the copy — safe, no lock held
-	if e.onTick != nil {
-		e.onTick(stateCopy)
+// resetBoardLocked clears the board and everything on it for a new round.
+// MUST be called while e.mu is held.
+func (e *Engine) resetBoardLocked() {
+	e.State.Board = NewBoard(e.Config, e.rng)
+	e.clearBombsLocked()
+	e.State.Fires = make([]Fire, 0)
+	e.State.Enemies = make([]*Enemy, 0)
+	e.State.Pickups = make([]Pickup, 0)
+	e.State.Winner = ""
+	e.State.WinningTeam = 0
+	e.State.SuddenDeath = false
+	e.State.Boss = nil
+	e.State.Hill = nil
+	e.State.Zone = nil
+	e.zoneShrinksAt = time.Time{}
+	e.State.OvertimeAt = time.Time{}
+	e.hideTrapsLocked()
+}
+
+// resetToLobbyLocked returns a finished game to the lobby with a fresh board,
+// so the room can be joined again and a new match started.
+// MUST be called while e.mu is held.
+func (e *Engine) resetToLobbyLocked() {
+	e.resetBoardLocked()
+	e.State.Status = StatusLobby
+	e.State.Round = 0
+	e.State.Level = 0
+	e.overAt = time.Time{}
+	e.nextRoundAt = time.Time{}
+	e.endsAt = time.Time{}
+	e.State.TimeLeft = 0
+
+	spawns := SpawnPositions(e.Config.Width, e.Config.Height)
+	for _, p := range e.State.Players {
+		e.resetPlayer(p, spawns[p.Color%len(spawns)])
+		p.Kills = 0
+		p.WallsDestroyed = 0
+		p.RoundScore = 0
+		p.HillPoints = 0
 	}
+
+	// Discard input left over from the previous match, and the recording
+	// of it if it never finished
+	e.discardActionsLocked()
+	e.recording = nil
 }
 
-// drainActions processes all queued player actions.
+// discardActionsLocked drops all queued player actions.
+// MUST be called while e.mu is held.
+func (e *Engine) discardActionsLocked() {
+	for {
+		select {
+		case a := <-e.actions:
+			e.handled(a)
+		default:
+			e.ackLocked()
+			return
+		}
+	}
+}
+
+// handled records that the engine is done with a, whether it was carried
+// out or not; see Player.LastSeq.
+func (e *Engine) handled(a Action) {
+	if p, ok := e.State.Players[a.PlayerID]; ok && a.Seq != 0 {
+		p.handledSeq = a.Seq
+	}
+}
+
+// ackLocked sets every player's LastSeq from the actions handled so far,
+// holding it back before a move that is still waiting to be made.
+// MUST be called while e.mu is held.
+func (e *Engine) ackLocked() {
+	for _, p := range e.State.Players {
+		last := p.LastSeq
+		p.LastSeq = p.handledSeq
+		if p.hasPending && p.pendingAck != 0 {
+			p.LastSeq = p.pendingAck - 1
+		}
+		if p.LastSeq != last {
+			e.State.Version++
+		}
+	}
+}
+
+// MaxMovesPerTick is how many moves a player may ask for in one tick. The
+// rest are dropped, so flooding moves gets a client nowhere faster.
+const MaxMovesPerTick = 2
+
+// drainActions processes all queued player actions in the order they
+// arrived, so when two players go for the same tile the first to ask gets
+// it. Moves beyond MaxMovesPerTick from one player are dropped.
 func (e *Engine) drainActions() {
+	moves := make(map[string]int)
 	for {
 		select {
 		case a := <-e.actions:
+			e.handled(a)
+			e.recordInputLocked(a)
+			if a.Type == ActionMove {
+				moves[a.PlayerID]++
+				if moves[a.PlayerID] > MaxMovesPerTick {
+					e.metrics.ThrottledActions++
+					continue
+				}
+			}
+			e.actionSeq++
+			e.metrics.LastActions++
+			e.metrics.Actions++
 			switch a.Type {
 			case ActionMove:
-				e.movePlayer(a.PlayerID, a.Dir)
+				e.requestMove(a.PlayerID, a.Dir)
+				if p, ok := e.State.Players[a.PlayerID]; ok && p.hasPending && p.pendingSeq == e.actionSeq {
+					p.pendingAck = a.Seq
+				}
 			case ActionPlaceBomb:
 				e.placeBomb(a.PlayerID)
+			case ActionSprint:
+				e.toggleSprint(a.PlayerID)
+			case ActionLineBomb:
+				e.placeLineBomb(a.PlayerID)
 			}
 		default:
+			e.ackLocked()
 			return
 		}
 	}
 }
 
-// checkWinCondition checks if the game is over.
+// checkWinCondition checks if the game is over, by the rules of the mode.
 func (e *Engine) checkWinCondition() {
 	if e.State.Status != StatusRunning {
 		return
 	}
 
+	// Players waiting to respawn are still in the game
 	alive := make([]*Player, 0)
 	for _, p := range e.State.Players {
-		if p.Alive {
+		if p.Alive || !p.RespawnAt.IsZero() {
 			alive = append(alive, p)
 		}
 	}
+	e.mode.checkWin(e, alive)
+}
+
+// killPlayer marks p dead, crediting the kill to the owner of the bomb
+// responsible, or in stun mode stuns them. killerID is "" for deaths not
+// caused by a bomb.
+func (e *Engine) killPlayer(p *Player, killerID string) {
+	if e.Config.StunMode {
+		e.stunPlayer(p, killerID)
+		return
+	}
+	p.Alive = false
+	p.KilledBy = killerID
+	p.DiedAt = e.State.Tick
+	e.emit(Event{Type: EventPlayerKilled, PlayerID: p.ID, ByID: killerID, Pos: p.Pos})
+	if killer, ok := e.State.Players[killerID]; ok && killerID != p.ID && !e.teammates(p, killer) {
+		killer.Kills++
+	}
+	p.Lives--
+	if p.Lives > 0 {
+		p.RespawnAt = e.now().Add(RespawnDelay)
+	}
+}
+
+// tickRespawns brings back dead players whose respawn delay has passed. They
+// lose their power-ups and return to their corner with brief fire immunity,
+// or to the next free spawn if someone is standing on it. With every spawn
+// taken they wait for one to clear.
+func (e *Engine) tickRespawns() {
+	now := e.now()
+	spawns := SpawnPositions(e.Config.Width, e.Config.Height)
+	for _, p := range e.playersByID() {
+		if p.Alive || p.RespawnAt.IsZero() || now.Before(p.RespawnAt) {
+			continue
+		}
+		spawn, ok := e.freeSpawn(spawns, p.Color)
+		if !ok {
+			continue
+		}
+		lives := p.Lives
+		e.resetPlayer(p, spawn)
+		p.Lives = lives
+		p.InvulnerableUntil = now.Add(SpawnProtection)
+	}
+}
 
-	switch len(alive) {
-	case 0:
-		// Draw — everyone died simultaneously
-		e.State.Status = StatusOver
-		e.State.Winner = ""
-	case 1:
-		// We have a winner, but only if there were multiple players
-		if len(e.State.Players) > 1 {
-			e.State.Status = StatusOver
-			e.State.Winner = alive[0].ID
+// freeSpawn returns the first spawn nobody is standing on, starting from
+// the one of the given color.
+func (e *Engine) freeSpawn(spawns []Position, color int) (Position, bool) {
+	taken := make(map[Position]bool, len(e.State.Players))
+	for _, p := range e.State.Players {
+		if p.Alive {
+			taken[p.Pos] = true
 		}
 	}
+	for i := range spawns {
+		if spawn := spawns[(color+i)%len(spawns)]; !taken[spawn] {
+			return spawn, true
+		}
+	}
+	return Position{}, false
 }
 
 // GetStateCopy returns a deep copy of the game state safe for serialization.
@@ -207,55 +815,66 @@ func (e *Engine) GetStateCopy() GameState {
 	return e.copyStateLocked()
 }
 
-// copyStateLocked creates a deep copy of the game state.
+// GetConfig returns a copy of the engine's config, which Restore may
+// replace while the game runs.
+func (e *Engine) GetConfig() GameConfig {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	config := e.Config
+	config.DropTable = maps.Clone(e.Config.DropTable)
+	return config
+}
+
+// copyStateLocked creates a deep copy of the game state. The struct is
+// copied whole, so new fields come along, and then everything it shares
+// with the engine's state through a slice, map or pointer is copied again.
 // MUST be called while e.mu is held.
 func (e *Engine) copyStateLocked() GameState {
-	// Copy board
-	boardCopy := make([][]TileType, e.State.Height)
-	for y := range boardCopy {
-		boardCopy[y] = make([]TileType, e.State.Width)
-		copy(boardCopy[y], e.State.Board[y])
+	cp := *e.State
+
+	cp.Board = make([][]TileType, len(e.State.Board))
+	for y, row := range e.State.Board {
+		cp.Board[y] = slices.Clone(row)
 	}
 
-	// Copy players
-	playersCopy := make(map[string]*Player, len(e.State.Players))
+	cp.Players = make(map[string]*Player, len(e.State.Players))
 	for id, p := range e.State.Players {
-		cp := *p
-		playersCopy[id] = &cp
+		cpl := *p
+		cpl.Effects = append([]StatusEffect(nil), p.Effects...)
+		cp.Players[id] = &cpl
 	}
 
-	// Copy bombs
-	bombsCopy := make([]*Bomb, len(e.State.Bombs))
+	cp.Bombs = make([]*Bomb, len(e.State.Bombs))
 	for i, b := range e.State.Bombs {
 		cb := *b
-		bombsCopy[i] = &cb
+		cp.Bombs[i] = &cb
 	}
 
-	// Copy fires
-	firesCopy := make([]Fire, len(e.State.Fires))
-	copy(firesCopy, e.State.Fires)
+	cp.Fires = make([]Fire, len(e.State.Fires))
+	copy(cp.Fires, e.State.Fires)
 
-	// Copy enemies
-	enemiesCopy := make([]*Enemy, len(e.State.Enemies))
+	cp.Enemies = make([]*Enemy, len(e.State.Enemies))
 	for i, en := range e.State.Enemies {
 		ce := *en
-		enemiesCopy[i] = &ce
+		cp.Enemies[i] = &ce
 	}
 
-	// Copy pickups
-	pickupsCopy := make([]Pickup, len(e.State.Pickups))
-	copy(pickupsCopy, e.State.Pickups)
+	cp.Pickups = make([]Pickup, len(e.State.Pickups))
+	copy(cp.Pickups, e.State.Pickups)
 
-	return GameState{
-		Board:   boardCopy,
-		Players: playersCopy,
-		Bombs:   bombsCopy,
-		Fires:   firesCopy,
-		Enemies: enemiesCopy,
-		Pickups: pickupsCopy,
-		Width:   e.State.Width,
-		Height:  e.State.Height,
-		Status:  e.State.Status,
-		Winner:  e.State.Winner,
+	if e.State.Boss != nil {
+		cb := *e.State.Boss
+		cb.Warning = append([]Position(nil), cb.Warning...)
+		cp.Boss = &cb
+	}
+	if e.State.Hill != nil {
+		ch := *e.State.Hill
+		cp.Hill = &ch
+	}
+	if e.State.Zone != nil {
+		cz := *e.State.Zone
+		cp.Zone = &cz
 	}
+	cp.Spectators = slices.Clone(e.State.Spectators)
+	return cp
 }
@@ -1,12 +1,20 @@
 package game
 
 import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestNewBoard(t *testing.T) {
 	config := DefaultConfig()
-	board := NewBoard(config)
+	board := NewBoard(config, rand.New(rand.NewSource(1)))
 
 	// Check dimensions
 	if len(board) != config.Height {
@@ -174,7 +182,7 @@ func TestExplosion(t *testing.T) {
 	p.Pos = Position{X: 5, Y: 5}
 
 	// Manually trigger the bomb
-	engine.State.Bombs[0].ExpiresAt = engine.State.Bombs[0].PlacedAt
+	engine.State.Bombs[0].ExpiresTick = engine.State.Tick
 
 	detonated := make(map[int]bool)
 	detonated[0] = true
@@ -203,7 +211,7 @@ func TestPlayerDamage(t *testing.T) {
 	engine.placeBomb("p1")
 
 	// Force detonate
-	engine.State.Bombs[0].ExpiresAt = engine.State.Bombs[0].PlacedAt
+	engine.State.Bombs[0].ExpiresTick = engine.State.Tick
 	detonated := make(map[int]bool)
 	detonated[0] = true
 	engine.explode(engine.State.Bombs[0], detonated)
@@ -232,7 +240,7 @@ func TestSoftWallDestruction(t *testing.T) {
 	engine.movePlayer("p1", DirLeft) // (1,1)
 
 	// Detonate
-	engine.State.Bombs[0].ExpiresAt = engine.State.Bombs[0].PlacedAt
+	engine.State.Bombs[0].ExpiresTick = engine.State.Tick
 	detonated := make(map[int]bool)
 	detonated[0] = true
 	engine.explode(engine.State.Bombs[0], detonated)
@@ -272,6 +280,33 @@ func TestAddPlayer(t *testing.T) {
 	}
 }
 
+func TestDuplicateNames(t *testing.T) {
+	engine := NewEngine(DefaultConfig())
+	for _, id := range []string{"p1", "p2", "p3"} {
+		if err := engine.AddPlayer(id, "Player"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id, want := range map[string]string{"p1": "Player", "p2": "Player (2)", "p3": "Player (3)"} {
+		if got := engine.PlayerName(id); got != want {
+			t.Errorf("expected %s to be called %q, got %q", id, want, got)
+		}
+	}
+
+	// A name freed by a player who left can be taken again
+	engine.RemovePlayer("p2")
+	engine.AddPlayer("p4", "Player")
+	if got := engine.PlayerName("p4"); got != "Player (2)" {
+		t.Errorf("expected the freed name, got %q", got)
+	}
+	if name, color, ok := engine.PlayerInfo("p4"); !ok || name != "Player (2)" || color != engine.State.Players["p4"].Color {
+		t.Errorf("expected p4's name and color, got %q, %d, %v", name, color, ok)
+	}
+	if _, _, ok := engine.PlayerInfo("p2"); ok {
+		t.Error("expected no info on a player who left")
+	}
+}
+
 func TestWinCondition(t *testing.T) {
 	config := DefaultConfig()
 	config.SoftWallDensity = 0
@@ -430,3 +465,2380 @@ func TestEnemyNotCountedInWin(t *testing.T) {
 	}
 }
 
+func TestReturnToLobby(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.LobbyReturn = time.Millisecond
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+
+	// p1 picks up some power and moves, then p2 dies
+	p1 := engine.State.Players["p1"]
+	p1.BombRange = MaxRange
+	p1.Pos = Position{X: 3, Y: 1}
+	engine.State.Players["p2"].Alive = false
+
+	engine.tick()
+	if engine.State.Status != StatusOver {
+		t.Fatalf("expected game over, got status %d", engine.State.Status)
+	}
+
+	engine.tick()
+	if engine.State.Status != StatusLobby {
+		t.Fatalf("expected return to lobby, got status %d", engine.State.Status)
+	}
+	if engine.State.Winner != "" {
+		t.Errorf("winner should be cleared, got %s", engine.State.Winner)
+	}
+
+	spawns := SpawnPositions(config.Width, config.Height)
+	for _, p := range engine.State.Players {
+		if !p.Alive {
+			t.Errorf("player %s should be revived in the lobby", p.ID)
+		}
+		if p.Pos != spawns[p.Color] {
+			t.Errorf("player %s should be back at spawn %v, got %v", p.ID, spawns[p.Color], p.Pos)
+		}
+	}
+	if p1.BombRange != StartRange {
+		t.Errorf("power-ups should reset, got range %d", p1.BombRange)
+	}
+
+	// The room is joinable again
+	if err := engine.AddPlayer("p3", "Charlie"); err != nil {
+		t.Errorf("should be able to join after returning to lobby: %v", err)
+	}
+}
+
+func TestSprint(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.State.Status = StatusRunning
+
+	p := engine.State.Players["p1"]
+	if p.Stamina != MaxStamina {
+		t.Fatalf("expected full stamina at spawn, got %d", p.Stamina)
+	}
+
+	// Sprinting moves cover two tiles: (1,1) -> (3,1)
+	engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionSprint})
+	engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionMove, Dir: DirRight})
+	engine.drainActions()
+	if p.Pos != (Position{X: 3, Y: 1}) {
+		t.Errorf("sprint move should cover two tiles, got (%d,%d)", p.Pos.X, p.Pos.Y)
+	}
+
+	// Stamina drains until the sprint ends on its own
+	for i := 0; i < MaxStamina/SprintDrain; i++ {
+		engine.tickStamina()
+	}
+	if p.Sprinting || p.Stamina != 0 {
+		t.Errorf("sprint should end when stamina runs out, got sprinting=%v stamina=%d", p.Sprinting, p.Stamina)
+	}
+
+	// Can't restart until enough stamina regenerates
+	engine.toggleSprint("p1")
+	if p.Sprinting {
+		t.Error("should not be able to sprint with no stamina")
+	}
+	for i := 0; i < SprintMinStamina/StaminaRegen; i++ {
+		engine.tickStamina()
+	}
+	engine.toggleSprint("p1")
+	if !p.Sprinting {
+		t.Error("should be able to sprint again after regenerating")
+	}
+}
+
+func TestAmmoMode(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.AmmoMode = true
+	config.StartAmmo = 1
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.State.Status = StatusRunning
+
+	p := engine.State.Players["p1"]
+	if p.Ammo != 1 {
+		t.Fatalf("expected starting ammo 1, got %d", p.Ammo)
+	}
+
+	engine.placeBomb("p1")
+	if len(engine.State.Bombs) != 1 || p.Ammo != 0 {
+		t.Fatalf("placing a bomb should consume ammo, got %d bombs, ammo %d", len(engine.State.Bombs), p.Ammo)
+	}
+
+	// Out of ammo: no more bombs even though BombMax allows it
+	engine.movePlayer("p1", DirRight)
+	engine.placeBomb("p1")
+	if len(engine.State.Bombs) != 1 {
+		t.Errorf("should not place a bomb without ammo, got %d bombs", len(engine.State.Bombs))
+	}
+
+	// Collecting a crate restocks
+	engine.State.Pickups = append(engine.State.Pickups, Pickup{Pos: Position{X: 3, Y: 1}, Type: PickupAmmo})
+	engine.movePlayer("p1", DirRight)
+	if p.Ammo != AmmoPerPickup {
+		t.Errorf("expected ammo %d after pickup, got %d", AmmoPerPickup, p.Ammo)
+	}
+}
+
+func TestBarrelChain(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.State.Status = StatusRunning
+
+	// Bomb at (1,1) reaches the barrel at (3,1), which reaches the one at
+	// (5,1), which blows up the soft wall at (7,1) beyond the bomb's range
+	engine.State.Board[1][3] = Barrel
+	engine.State.Board[1][5] = Barrel
+	engine.State.Board[1][7] = SoftWall
+
+	engine.placeBomb("p1")
+	engine.State.Players["p1"].Pos = Position{X: 1, Y: 5}
+
+	detonated := map[int]bool{0: true}
+	engine.explode(engine.State.Bombs[0], detonated)
+
+	for _, x := range []int{3, 5, 7} {
+		if engine.State.Board[1][x] != Empty {
+			t.Errorf("expected (%d,1) destroyed, got tile %d", x, engine.State.Board[1][x])
+		}
+	}
+	for _, f := range engine.State.Fires {
+		if f.Pos == (Position{X: 8, Y: 1}) {
+			t.Error("soft wall should stop the barrel's blast")
+		}
+	}
+	if !engine.State.Players["p1"].Alive {
+		t.Error("player out of range should survive")
+	}
+}
+
+func TestSaveAndResume(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+	engine.movePlayer("p1", DirRight)
+	engine.placeBomb("p1")
+	fuse := engine.State.Bombs[0].ExpiresTick - engine.State.Tick
+
+	path := filepath.Join(t.TempDir(), "save.json")
+	if err := engine.Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	resumed, err := LoadSave(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !resumed.Resuming() || resumed.State.Status != StatusLobby {
+		t.Fatal("loaded save should wait in the lobby")
+	}
+	if err := resumed.StartGame(); err == nil {
+		t.Error("should not resume before anyone reclaims a character")
+	}
+
+	// Alice rejoins on a new connection; Bob never comes back
+	if err := resumed.AddPlayer("new1", "Alice"); err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+	p, ok := resumed.State.Players["new1"]
+	if !ok || p.Pos != (Position{X: 2, Y: 1}) {
+		t.Fatalf("Alice should reclaim the saved character, got %+v", p)
+	}
+	if resumed.State.Bombs[0].OwnerID != "new1" {
+		t.Error("reclaimed player should own their saved bombs")
+	}
+
+	// However long the match waits to resume, the bomb's fuse picks up
+	// where it was
+	resumed.Step(5)
+	if err := resumed.StartGame(); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if left := resumed.State.Bombs[0].ExpiresTick - resumed.State.Tick; left != fuse {
+		t.Errorf("expected %d ticks of fuse left after resuming, got %d", fuse, left)
+	}
+	if resumed.State.Status != StatusRunning || resumed.Resuming() {
+		t.Error("match should be running again")
+	}
+	if len(resumed.State.Players) != 1 {
+		t.Errorf("unclaimed players should be dropped, got %d players", len(resumed.State.Players))
+	}
+}
+
+func TestStepMode(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.BombTimer = 2 * time.Second / time.Duration(config.TickRate)
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+	engine.SetStepMode(true)
+	engine.placeBomb("p1")
+
+	// Without steps nothing advances, however long we wait
+	tick := engine.State.Tick
+	time.Sleep(3 * config.BombTimer)
+	engine.tick()
+	if engine.State.Tick != tick || len(engine.State.Bombs) != 1 {
+		t.Fatalf("game advanced without a step: tick %d, %d bombs", engine.State.Tick, len(engine.State.Bombs))
+	}
+
+	// The bomb's two-tick timer runs out only as ticks are stepped
+	for i := 1; i <= 2; i++ {
+		engine.Step(1)
+		engine.tick()
+		if engine.State.Tick != tick+uint64(i) || len(engine.State.Bombs) != 1 {
+			t.Fatalf("step %d: tick %d, %d bombs", i, engine.State.Tick, len(engine.State.Bombs))
+		}
+	}
+	engine.Step(1)
+	if len(engine.State.Bombs) != 0 {
+		t.Error("bomb should explode once its timer has passed")
+	}
+}
+
+func TestTiebreakers(t *testing.T) {
+	// Alice's bomb kills both Alice and Bob on the same tick
+	setup := func(tb Tiebreaker) *Engine {
+		config := DefaultConfig()
+		config.SoftWallDensity = 0
+		config.EnemyCount = 0
+		config.Tiebreaker = tb
+		engine := NewEngine(config)
+		engine.AddPlayer("p1", "Alice")
+		engine.AddPlayer("p2", "Bob")
+		engine.StartGame()
+		engine.State.Players["p2"].Pos = Position{X: 2, Y: 1}
+		engine.placeBomb("p1")
+		engine.explode(engine.State.Bombs[0], map[int]bool{0: true})
+		engine.checkWinCondition()
+		return engine
+	}
+
+	if e := setup(TiebreakDraw); e.State.Status != StatusOver || e.State.Winner != "" {
+		t.Errorf("draw: expected a draw, got status %d winner %q", e.State.Status, e.State.Winner)
+	}
+	if e := setup(TiebreakBombOwner); e.State.Winner != "p1" {
+		t.Errorf("bomb_owner: expected p1 to win, got %q", e.State.Winner)
+	}
+	if e := setup(TiebreakKills); e.State.Winner != "p1" || e.State.Players["p1"].Kills != 1 {
+		t.Errorf("kills: expected p1 to win with 1 kill, got %q", e.State.Winner)
+	}
+
+	e := setup(TiebreakSuddenDeath)
+	if e.State.Status != StatusRunning || !e.State.SuddenDeath {
+		t.Fatal("sudden_death: expected a rematch to start")
+	}
+	for id, p := range e.State.Players {
+		if !p.Alive {
+			t.Errorf("sudden_death: %s should be revived", id)
+		}
+	}
+}
+
+func TestStartCountdown(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.StartCountdown = 2 * time.Second / time.Duration(config.TickRate)
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+
+	if err := engine.BeginCountdown(); err != nil {
+		t.Fatalf("begin countdown: %v", err)
+	}
+	if err := engine.AddPlayer("p2", "Bob"); err == nil {
+		t.Error("should not join during the countdown")
+	}
+
+	// Waits for RunCountdown however long it takes, and drops early input
+	engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionMove, Dir: DirRight})
+	for range 4 {
+		engine.tick()
+	}
+	if engine.State.Status != StatusCountdown {
+		t.Fatalf("game started before the countdown ran, status %d", engine.State.Status)
+	}
+
+	// The two-tick countdown runs out on the second tick
+	engine.RunCountdown()
+	engine.tick()
+	if engine.State.Status != StatusCountdown {
+		t.Fatal("game started before the countdown ended")
+	}
+	engine.tick()
+	if engine.State.Status != StatusRunning {
+		t.Fatalf("expected running after the countdown, got status %d", engine.State.Status)
+	}
+	if p := engine.State.Players["p1"]; p.Pos != (Position{X: 1, Y: 1}) {
+		t.Errorf("input sent during the countdown should be dropped, player at %v", p.Pos)
+	}
+}
+
+func TestCountdownKeepsLastTickInput(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.StartCountdown = 2 * time.Second / time.Duration(config.TickRate)
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.BeginCountdown()
+	engine.RunCountdown()
+
+	// A move arriving in the countdown's last tick is made on the first tick
+	// of play, not dropped as a false start
+	engine.tick()
+	engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionMove, Dir: DirRight})
+	engine.tick()
+	if engine.State.Status != StatusRunning {
+		t.Fatalf("expected running after the countdown, got status %d", engine.State.Status)
+	}
+	engine.tick()
+	if p := engine.State.Players["p1"]; p.Pos != (Position{X: 2, Y: 1}) {
+		t.Errorf("expected the move timed to the start to be made, player at %v", p.Pos)
+	}
+}
+
+func TestBossMode(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.BossMode = true
+	config.BossHP = 2
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+
+	boss := engine.State.Boss
+	if boss == nil || boss.Pos != BossSpawn(config.Width, config.Height) {
+		t.Fatalf("expected the boss at its spawn, got %+v", boss)
+	}
+	above := Position{X: boss.Pos.X, Y: boss.Pos.Y - 2}
+	p1 := engine.State.Players["p1"]
+
+	// The boss blocks players like a wall
+	p1.Pos = Position{X: boss.Pos.X, Y: boss.Pos.Y - 1}
+	if engine.movePlayer("p1", DirDown) {
+		t.Error("player should not walk into the boss")
+	}
+
+	blast := func() {
+		p1.Pos = above
+		engine.placeBomb("p1")
+		p1.Pos = Position{X: 1, Y: 1}
+		bomb := engine.State.Bombs[len(engine.State.Bombs)-1]
+		engine.explode(bomb, map[int]bool{len(engine.State.Bombs) - 1: true})
+		engine.State.Bombs = nil
+		p1.BombsUsed = 0
+	}
+
+	// Fire on armored tiles doesn't hurt
+	boss.WeakPoint = Position{X: 1, Y: 1}
+	blast()
+	if boss.HP != 2 {
+		t.Fatalf("armor should absorb the blast, HP %d", boss.HP)
+	}
+	for _, f := range engine.State.Fires {
+		if f.Pos == boss.Pos {
+			t.Fatal("fire should not pass into the boss")
+		}
+	}
+
+	// The weak point does, and moves after each hit
+	boss.WeakPoint = Position{X: 0, Y: 0}
+	blast()
+	if boss.HP != 1 || boss.WeakPoint == (Position{}) {
+		t.Fatalf("expected a hit to cost 1 HP and move the weak point, got %+v", boss)
+	}
+	boss.WeakPoint = Position{X: 0, Y: 0}
+	blast()
+	engine.checkWinCondition()
+	if boss.Alive || engine.State.Status != StatusOver || engine.State.Winner != "" {
+		t.Fatalf("expected a co-op win, got status %d boss %+v", engine.State.Status, boss)
+	}
+
+	// A telegraphed fire sweep lands on the warned tiles
+	engine.resetToLobbyLocked()
+	engine.StartGame()
+	boss = engine.State.Boss
+	for _, p := range engine.State.Players {
+		boss.Warning = append(boss.Warning, p.Pos)
+	}
+	boss.Attack = BossFireSweep
+	boss.Timer = 1
+	engine.tickBoss()
+	for _, p := range engine.State.Players {
+		if p.Alive || p.KilledBy != BossID {
+			t.Errorf("expected %s killed by the sweep, got %+v", p.Name, p)
+		}
+	}
+	if boss.Attack != BossIdle || boss.Warning != nil {
+		t.Error("expected the boss to go back to idle")
+	}
+	engine.checkWinCondition()
+	if engine.State.Status != StatusOver {
+		t.Error("expected the match lost once every player is dead")
+	}
+}
+
+func TestMoveSpeed(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.SprintEnabled = false
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.State.Status = StatusRunning
+	p := engine.State.Players["p1"]
+	p.MoveSpeed = 5 // One move every 4 ticks at 20 ticks/sec
+
+	// The first move is free, the next waits for the cooldown
+	engine.requestMove("p1", DirRight)
+	engine.requestMove("p1", DirRight)
+	if p.Pos != (Position{X: 2, Y: 1}) {
+		t.Fatalf("expected one move, player at %v", p.Pos)
+	}
+	for i := 0; i < 3; i++ {
+		engine.tickMovement()
+	}
+	if p.Pos != (Position{X: 2, Y: 1}) {
+		t.Fatalf("moved before the cooldown ended, player at %v", p.Pos)
+	}
+	engine.tickMovement()
+	if p.Pos != (Position{X: 3, Y: 1}) {
+		t.Fatalf("expected the waiting move once the cooldown ended, player at %v", p.Pos)
+	}
+
+	// Speed pickups raise the rate up to the cap
+	engine.State.Pickups = append(engine.State.Pickups, Pickup{Pos: Position{X: 3, Y: 2}, Type: PickupSpeed})
+	p.MoveSpeed = MaxSpeed
+	engine.movePlayer("p1", DirDown)
+	if p.MoveSpeed != MaxSpeed {
+		t.Errorf("speed should be capped at %d, got %d", MaxSpeed, p.MoveSpeed)
+	}
+	p.MoveSpeed = StartSpeed
+	engine.State.Pickups = append(engine.State.Pickups, Pickup{Pos: Position{X: 3, Y: 3}, Type: PickupSpeed})
+	engine.movePlayer("p1", DirDown)
+	if p.MoveSpeed != StartSpeed+1 {
+		t.Errorf("expected speed %d after a pickup, got %d", StartSpeed+1, p.MoveSpeed)
+	}
+}
+
+func TestBombKick(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.ChainDelay = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.State.Status = StatusRunning
+	p1, p2 := engine.State.Players["p1"], engine.State.Players["p2"]
+	p1.Pos = Position{X: 2, Y: 1}
+	p2.Pos = Position{X: 8, Y: 1}
+
+	bomb := &Bomb{OwnerID: "p1", Pos: Position{X: 3, Y: 1}, ExpiresTick: engine.State.Tick + engine.ticksIn(time.Minute)}
+	engine.State.Bombs = append(engine.State.Bombs, bomb)
+
+	// Without the pickup a bomb is just in the way
+	if engine.movePlayer("p1", DirRight) || bomb.Velocity != (Position{}) {
+		t.Fatal("bomb should block a player who can't kick")
+	}
+
+	p1.CanKick = true
+	engine.movePlayer("p1", DirRight)
+	if p1.Pos != (Position{X: 2, Y: 1}) || bomb.Velocity != (Position{X: 1, Y: 0}) {
+		t.Fatalf("expected the bomb kicked right, player %v bomb %+v", p1.Pos, bomb)
+	}
+
+	// It slides one tile per slide step until Bob is in the way
+	for i := 0; i < 20; i++ {
+		engine.State.Tick += bombSlideInterval
+		engine.tickBombs()
+	}
+	if bomb.Pos != (Position{X: 7, Y: 1}) || bomb.Velocity != (Position{}) {
+		t.Fatalf("expected the bomb to stop in front of Bob, got %+v", bomb)
+	}
+
+	// A bomb kicked into fire goes off
+	p2.Pos = Position{X: 13, Y: 11}
+	engine.kickBomb(bomb, Position{X: 1, Y: 0})
+	engine.State.Fires = append(engine.State.Fires, Fire{Pos: Position{X: 8, Y: 1}, ExpiresTick: engine.State.Tick + engine.ticksIn(time.Minute)})
+	engine.State.Tick += bombSlideInterval
+	engine.tickBombs()
+	if len(engine.State.Bombs) != 0 {
+		t.Fatal("expected the bomb to explode on sliding into fire")
+	}
+}
+
+func TestShield(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.State.Status = StatusRunning
+	p1 := engine.State.Players["p1"]
+	p1.Pos = Position{X: 2, Y: 1}
+	engine.State.Pickups = append(engine.State.Pickups, Pickup{Pos: Position{X: 3, Y: 1}, Type: PickupShield})
+
+	engine.movePlayer("p1", DirRight)
+	if !engine.invulnerable(p1) {
+		t.Fatal("expected the shield pickup to grant immunity")
+	}
+
+	engine.State.Fires = append(engine.State.Fires, Fire{Pos: p1.Pos, ExpiresTick: engine.State.Tick + engine.ticksIn(time.Minute)})
+	engine.damagePlayersInFire()
+	if !p1.Alive {
+		t.Fatal("a shielded player should survive fire")
+	}
+
+	// Once it runs out the deadline is cleared and fire kills again
+	p1.InvulnerableUntil = engine.now().Add(-time.Second)
+	engine.tickInvulnerability()
+	if !p1.InvulnerableUntil.IsZero() {
+		t.Fatalf("expected an expired shield to be cleared, got %v", p1.InvulnerableUntil)
+	}
+	engine.damagePlayersInFire()
+	if p1.Alive {
+		t.Fatal("expected fire to kill once the shield expired")
+	}
+}
+
+func TestCurses(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.State.Status = StatusRunning
+	p1, p2 := engine.State.Players["p1"], engine.State.Players["p2"]
+	p1.Pos = Position{X: 3, Y: 1}
+	p2.Pos = Position{X: 5, Y: 1}
+
+	// Reversed controls
+	addEffect(p1, StatusEffect{Kind: EffectReverse, ExpiresAt: engine.now().Add(time.Minute)})
+	engine.requestMove("p1", DirLeft)
+	if p1.Pos != (Position{X: 4, Y: 1}) {
+		t.Fatalf("expected a reversed move right, got %v", p1.Pos)
+	}
+
+	// Bumping into Bob hands the curse over
+	p1.moveCredit = config.TickRate
+	engine.requestMove("p1", DirLeft)
+	if len(p1.Effects) != 0 || !p2.HasEffect(EffectReverse) {
+		t.Fatalf("expected the curse to pass to Bob, Alice %v Bob %v", p1.Effects, p2.Effects)
+	}
+
+	// Short range and auto bombs
+	p2.Effects = nil
+	addEffect(p2, StatusEffect{Kind: EffectShortRange, ExpiresAt: engine.now().Add(time.Minute)})
+	addEffect(p2, StatusEffect{Kind: EffectAutoBomb, ExpiresAt: engine.now().Add(time.Minute)})
+	engine.tickEffects()
+	if len(engine.State.Bombs) != 1 || engine.State.Bombs[0].Range != 1 {
+		t.Fatalf("expected one auto-dropped range 1 bomb, got %+v", engine.State.Bombs)
+	}
+
+	// Expired effects are removed
+	p2.Effects[0].ExpiresAt = engine.now().Add(-time.Second)
+	engine.tickEffects()
+	if p2.HasEffect(EffectShortRange) || !p2.HasEffect(EffectAutoBomb) {
+		t.Fatalf("expected only the short range curse to expire, got %v", p2.Effects)
+	}
+}
+
+func TestLives(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.Lives = 2
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.State.Status = StatusRunning
+	p1 := engine.State.Players["p1"]
+	spawn := p1.Pos
+	p1.Pos = Position{X: 5, Y: 5}
+	p1.BombRange = MaxRange
+
+	engine.killPlayer(p1, "p2")
+	engine.checkWinCondition()
+	if engine.State.Status != StatusRunning || p1.Lives != 1 || p1.RespawnAt.IsZero() {
+		t.Fatalf("a player with lives left shouldn't end the match: status %v lives %d", engine.State.Status, p1.Lives)
+	}
+
+	p1.RespawnAt = engine.now().Add(-time.Millisecond)
+	engine.tickRespawns()
+	if !p1.Alive || p1.Pos != spawn || p1.BombRange != StartRange || !engine.invulnerable(p1) || p1.Lives != 1 {
+		t.Fatalf("expected a protected respawn in the corner, got %+v", p1)
+	}
+
+	// Bob standing in Alice's corner sends her to the next free spawn
+	spawns := SpawnPositions(config.Width, config.Height)
+	p2 := engine.State.Players["p2"]
+	engine.killPlayer(p1, "p2")
+	p1.Lives = 2
+	p2.Pos = spawn
+	p1.RespawnAt = engine.now().Add(-time.Millisecond)
+	engine.tickRespawns()
+	if !p1.Alive || p1.Pos != spawns[1] {
+		t.Fatalf("expected Alice to respawn at %v, away from Bob, got %+v", spawns[1], p1)
+	}
+
+	// Out of lives
+	p1.Lives = 1
+	engine.killPlayer(p1, "p2")
+	engine.checkWinCondition()
+	if engine.State.Status != StatusOver || engine.State.Winner != "p2" {
+		t.Fatalf("expected Bob to win once Alice is out of lives, got %v %q", engine.State.Status, engine.State.Winner)
+	}
+}
+
+func TestRounds(t *testing.T) {
+	config := DefaultConfig()
+	config.EnemyCount = 0
+	config.StartCountdown = 0
+	config.Rounds = 3
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	if err := engine.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+	p1, p2 := engine.State.Players["p1"], engine.State.Players["p2"]
+
+	winRound := func(winner, loser *Player) {
+		t.Helper()
+		engine.killPlayer(loser, winner.ID)
+		engine.advanceLocked()
+	}
+
+	winRound(p1, p2)
+	if engine.State.Status != StatusIntermission || p1.RoundScore != 1 || engine.State.Winner != "p1" {
+		t.Fatalf("expected an intermission after Alice's round, got %v score %d", engine.State.Status, p1.RoundScore)
+	}
+
+	engine.nextRoundAt = engine.now()
+	engine.advanceLocked()
+	if engine.State.Status != StatusRunning || engine.State.Round != 2 || !p2.Alive || p1.RoundScore != 1 {
+		t.Fatalf("expected round 2 with everyone back, got %v round %d", engine.State.Status, engine.State.Round)
+	}
+
+	winRound(p1, p2)
+	if engine.State.Status != StatusOver || engine.State.Winner != "p1" || p1.RoundScore != 2 {
+		t.Fatalf("expected Alice to take the match 2-0, got %v %q", engine.State.Status, engine.State.Winner)
+	}
+}
+
+func TestTimeLimit(t *testing.T) {
+	config := DefaultConfig()
+	config.EnemyCount = 0
+	config.StartCountdown = 0
+	config.MatchDuration = time.Minute
+	config.TimeUp = TimeUpWalls
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	if err := engine.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+	if engine.State.TimeLeft != time.Minute {
+		t.Fatalf("expected a full clock, got %v", engine.State.TimeLeft)
+	}
+
+	engine.State.Players["p2"].WallsDestroyed = 3
+	engine.advanceLocked()
+	if engine.State.Status != StatusRunning || engine.State.TimeLeft >= time.Minute {
+		t.Fatalf("expected the clock to run, got %v %v", engine.State.Status, engine.State.TimeLeft)
+	}
+
+	engine.endsAt = engine.now()
+	engine.advanceLocked()
+	if engine.State.Status != StatusOver || engine.State.Winner != "p2" || engine.State.TimeLeft != 0 {
+		t.Fatalf("expected Bob to win on walls at time up, got %v %q", engine.State.Status, engine.State.Winner)
+	}
+}
+
+func TestTeamMode(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.TeamMode = true
+	engine := NewEngine(config)
+	for _, id := range []string{"p1", "p2", "p3", "p4"} {
+		engine.AddPlayer(id, id)
+	}
+	players := engine.State.Players
+	if players["p1"].TeamID != 1 || players["p2"].TeamID != 2 || players["p3"].TeamID != 1 || players["p4"].TeamID != 2 {
+		t.Fatal("expected joining players to alternate between teams")
+	}
+	if err := engine.SwitchTeam("p4"); err != nil || players["p4"].TeamID != 1 {
+		t.Fatalf("expected p4 to switch to team 1, got %v", err)
+	}
+	engine.SwitchTeam("p4")
+	engine.State.Status = StatusRunning
+	if err := engine.SwitchTeam("p4"); err == nil {
+		t.Fatal("teams shouldn't change mid-match")
+	}
+
+	// Without friendly fire a teammate's bomb is harmless
+	p1, p3 := players["p1"], players["p3"]
+	engine.State.Fires = append(engine.State.Fires, Fire{Pos: p3.Pos, ExpiresTick: engine.State.Tick + engine.ticksIn(time.Minute), OwnerID: "p1"})
+	engine.damagePlayersInFire()
+	if !p3.Alive || p1.Kills != 0 {
+		t.Fatal("teammate fire shouldn't kill without friendly fire")
+	}
+	engine.Config.FriendlyFire = true
+	engine.damagePlayersInFire()
+	if p3.Alive || p1.Kills != 0 {
+		t.Fatal("expected friendly fire to kill a teammate without counting a kill")
+	}
+
+	// Team 1 wins once team 2 is out, even with a member down
+	engine.killPlayer(players["p2"], "p1")
+	engine.checkWinCondition()
+	if engine.State.Status != StatusRunning {
+		t.Fatal("the match should go on while both teams have players")
+	}
+	engine.killPlayer(players["p4"], "p1")
+	engine.checkWinCondition()
+	if engine.State.Status != StatusOver || engine.State.WinningTeam != 1 || engine.State.Winner != "" {
+		t.Fatalf("expected team 1 to win, got %v team %d winner %q", engine.State.Status, engine.State.WinningTeam, engine.State.Winner)
+	}
+}
+
+func TestPvEMode(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.PvEMode = true
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+
+	// Even without enemy_count there is a monster to clear
+	if len(engine.State.Enemies) != 1 {
+		t.Fatalf("expected 1 monster, got %d", len(engine.State.Enemies))
+	}
+
+	// Losing a player doesn't end a co-op match
+	engine.killPlayer(engine.State.Players["p2"], "")
+	engine.checkWinCondition()
+	if engine.State.Status != StatusRunning {
+		t.Fatalf("expected the match to go on, got %v", engine.State.Status)
+	}
+
+	if !engine.GetStateCopy().PvE {
+		t.Error("expected clients to be told it's a PvE match")
+	}
+
+	engine.State.Enemies[0].Alive = false
+	engine.checkWinCondition()
+	if engine.State.Status != StatusOver || engine.State.Winner != "" {
+		t.Errorf("expected a shared win once the monsters are cleared, got %v winner %q",
+			engine.State.Status, engine.State.Winner)
+	}
+}
+
+func TestCampaign(t *testing.T) {
+	config := DefaultConfig()
+	config.Campaign = true
+	config.Seed = 1 // Some maps hide the exit where the fire below would catch Alice
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.StartGame()
+
+	if engine.State.Level != 1 || len(engine.State.Enemies) != Levels[0].Enemies {
+		t.Fatalf("expected level 1 with %d monsters, got level %d with %d",
+			Levels[0].Enemies, engine.State.Level, len(engine.State.Enemies))
+	}
+	exit := engine.exit
+	if engine.State.Board[exit.Y][exit.X] != SoftWall {
+		t.Fatalf("expected the exit hidden under a soft wall, got %v", engine.State.Board[exit.Y][exit.X])
+	}
+	if engine.GetStateCopy().Level != 1 {
+		t.Error("expected clients to be told the level")
+	}
+
+	// Blowing up the wall reveals the exit instead of a pickup
+	from := Position{X: exit.X, Y: exit.Y - 1}
+	if engine.State.Board[from.Y][from.X] == HardWall {
+		from = Position{X: exit.X - 1, Y: exit.Y}
+	}
+	engine.State.Board[from.Y][from.X] = Empty
+	engine.explode(&Bomb{Pos: from, Range: 1}, map[int]bool{})
+	if engine.State.Board[exit.Y][exit.X] != ExitDoor {
+		t.Fatalf("expected the exit revealed, got %v", engine.State.Board[exit.Y][exit.X])
+	}
+	engine.State.Fires = nil
+	engine.State.Pickups = nil
+
+	// The exit stays shut while monsters are left
+	p1 := engine.State.Players["p1"]
+	p1.Pos = exit
+	engine.checkWinCondition()
+	if engine.State.Status != StatusRunning {
+		t.Fatalf("expected the level to go on, got %v", engine.State.Status)
+	}
+
+	for level := 1; level <= len(Levels); level++ {
+		for _, en := range engine.State.Enemies {
+			en.Alive = false
+		}
+		exit := engine.exit
+		engine.State.Board[exit.Y][exit.X] = ExitDoor
+		p1.Pos = exit
+		engine.checkWinCondition()
+		if level == len(Levels) {
+			break
+		}
+		if engine.State.Status != StatusLevelComplete {
+			t.Fatalf("level %d: expected it cleared, got %v", level, engine.State.Status)
+		}
+		engine.nextLevelLocked()
+		if engine.State.Level != level+1 || engine.State.Status != StatusRunning {
+			t.Fatalf("expected level %d running, got level %d %v", level+1, engine.State.Level, engine.State.Status)
+		}
+	}
+	if engine.State.Status != StatusOver || !CampaignWon(engine.State) {
+		t.Errorf("expected the campaign won, got %v", engine.State.Status)
+	}
+}
+
+func TestConfigurableStats(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.StartBombs = 5
+	config.StartRange = 6
+	config.MaxBombs = 6
+	config.MaxRange = 6
+	config.MaxSpeed = StartSpeed + 1
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.State.Status = StatusRunning
+
+	p1 := engine.State.Players["p1"]
+	if p1.BombMax != 5 || p1.BombRange != 6 {
+		t.Fatalf("expected 5 bombs with range 6, got %d with range %d", p1.BombMax, p1.BombRange)
+	}
+
+	// Pickups stop at the configured caps
+	for _, typ := range []PickupType{PickupBomb, PickupBomb, PickupRange, PickupSpeed, PickupSpeed} {
+		engine.State.Pickups = []Pickup{{Pos: Position{X: 2, Y: 1}, Type: typ}}
+		p1.Pos = Position{X: 1, Y: 1}
+		engine.movePlayer("p1", DirRight)
+	}
+	if p1.BombMax != 6 || p1.BombRange != 6 || p1.MoveSpeed != StartSpeed+1 {
+		t.Errorf("expected stats capped at 6/6/%d, got %d/%d/%d",
+			StartSpeed+1, p1.BombMax, p1.BombRange, p1.MoveSpeed)
+	}
+}
+
+func TestFullFire(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.State.Status = StatusRunning
+
+	p1 := engine.State.Players["p1"]
+	engine.State.Pickups = []Pickup{{Pos: Position{X: 2, Y: 1}, Type: PickupFullFire}}
+	engine.movePlayer("p1", DirRight)
+	if !p1.FullFire {
+		t.Fatal("expected the pickup to arm full fire")
+	}
+
+	// Soft walls along the row don't stop it
+	engine.State.Board[1][4] = SoftWall
+	engine.State.Board[1][7] = SoftWall
+	engine.placeBomb("p1")
+	bomb := engine.State.Bombs[0]
+	if !bomb.FullFire || p1.FullFire {
+		t.Fatalf("expected a full-fire bomb and full fire used up, got bomb %v player %v", bomb.FullFire, p1.FullFire)
+	}
+	p1.Pos = Position{X: 1, Y: 3}
+	engine.explode(bomb, map[int]bool{0: true})
+
+	if engine.State.Board[1][4] != Empty || engine.State.Board[1][7] != Empty {
+		t.Error("expected both soft walls destroyed")
+	}
+	burned := make(map[Position]bool)
+	for _, f := range engine.State.Fires {
+		burned[f.Pos] = true
+	}
+	if !burned[Position{X: config.Width - 2, Y: 1}] {
+		t.Error("expected the fire to reach the far end of the row")
+	}
+
+	// The next bomb is a normal one again
+	engine.State.Bombs = nil
+	engine.placeBomb("p1")
+	if engine.State.Bombs[0].FullFire || engine.State.Bombs[0].Range != StartRange {
+		t.Errorf("expected a normal bomb, got %+v", engine.State.Bombs[0])
+	}
+}
+
+func TestLineBomb(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.State.Status = StatusRunning
+
+	p1 := engine.State.Players["p1"]
+
+	// Nothing happens without the pickup
+	engine.placeLineBomb("p1")
+	if len(engine.State.Bombs) != 0 {
+		t.Fatalf("expected no bombs without the pickup, got %d", len(engine.State.Bombs))
+	}
+
+	engine.State.Pickups = []Pickup{{Pos: Position{X: 2, Y: 1}, Type: PickupLineBomb}}
+	engine.movePlayer("p1", DirRight)
+	if !p1.LineBomb || p1.Facing != DirRight {
+		t.Fatalf("expected line bomb facing right, got %v facing %v", p1.LineBomb, p1.Facing)
+	}
+
+	// One bomb is already down: the row starts in front of it and stops at
+	// the soft wall
+	engine.placeBomb("p1")
+	engine.State.Board[1][4] = SoftWall
+	engine.placeLineBomb("p1")
+	want := []Position{{X: 2, Y: 1}, {X: 3, Y: 1}}
+	if len(engine.State.Bombs) != len(want) {
+		t.Fatalf("expected %d bombs, got %d", len(want), len(engine.State.Bombs))
+	}
+	for i, b := range engine.State.Bombs {
+		if b.Pos != want[i] {
+			t.Errorf("bomb %d: expected at %v, got %v", i, want[i], b.Pos)
+		}
+	}
+
+	// With the way clear it lays every bomb left
+	engine.State.Bombs = nil
+	p1.BombsUsed = 0
+	p1.Pos = Position{X: 1, Y: 3}
+	engine.movePlayer("p1", DirDown)
+	engine.placeLineBomb("p1")
+	if len(engine.State.Bombs) != StartBombs || engine.State.Bombs[StartBombs-1].Pos != (Position{X: 1, Y: 4 + StartBombs - 1}) {
+		t.Errorf("expected %d bombs down the column, got %d", StartBombs, len(engine.State.Bombs))
+	}
+}
+
+func TestWalkOffOwnBomb(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.State.Status = StatusRunning
+
+	p1, p2 := engine.State.Players["p1"], engine.State.Players["p2"]
+	engine.placeBomb("p1")
+	bomb := engine.State.Bombs[0]
+	if !bomb.OwnerOn {
+		t.Fatal("expected the bomb to know its owner is standing on it")
+	}
+
+	// Other players are blocked even while the owner is still on it
+	p2.Pos = Position{X: 1, Y: 2}
+	if engine.movePlayer("p2", DirUp) {
+		t.Error("another player should not walk onto the bomb")
+	}
+
+	if !engine.movePlayer("p1", DirRight) {
+		t.Fatal("owner should walk off their bomb")
+	}
+	if bomb.OwnerOn {
+		t.Error("expected the flag cleared once the owner stepped off")
+	}
+	if engine.movePlayer("p1", DirLeft) || p1.Pos != (Position{X: 2, Y: 1}) {
+		t.Error("owner should not walk back onto their bomb")
+	}
+}
+
+func TestMines(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.State.Status = StatusRunning
+
+	p1, p2 := engine.State.Players["p1"], engine.State.Players["p2"]
+	p1.Mines = 1
+	engine.placeBomb("p1")
+	mine := engine.State.Bombs[0]
+	if !mine.Mine || p1.Mines != 0 {
+		t.Fatalf("expected the bomb to be a mine, got mine=%v with %d mines left", mine.Mine, p1.Mines)
+	}
+	engine.movePlayer("p1", DirRight)
+
+	// Visible to everyone until it arms, then only to its owner
+	if len(engine.State.ViewFor("p2").Bombs) != 1 {
+		t.Error("expected a fresh mine to be visible")
+	}
+	engine.State.Tick = mine.HiddenAt
+	if len(engine.State.ViewFor("p2").Bombs) != 0 || len(engine.State.ViewFor("").Bombs) != 0 {
+		t.Error("expected an armed mine hidden from opponents")
+	}
+	if len(engine.State.ViewFor("p1").Bombs) != 1 {
+		t.Error("expected the owner to still see their mine")
+	}
+
+	// No fuse: it waits for an opponent
+	engine.epoch = engine.epoch.Add(time.Hour)
+	engine.tickBombs()
+	if len(engine.State.Bombs) != 1 {
+		t.Fatal("expected the mine to wait")
+	}
+
+	// The owner walks over it; an opponent sets it off
+	engine.movePlayer("p1", DirLeft)
+	engine.tickBombs()
+	if len(engine.State.Bombs) != 1 || !p1.Alive {
+		t.Fatal("expected the owner to walk over their mine safely")
+	}
+	p1.Pos = Position{X: 5, Y: 1}
+	p2.Pos = Position{X: 1, Y: 2}
+	if !engine.movePlayer("p2", DirUp) {
+		t.Fatal("expected the opponent to walk onto the mine")
+	}
+	engine.tickBombs()
+	if len(engine.State.Bombs) != 0 || p2.Alive || p2.KilledBy != "p1" {
+		t.Errorf("expected the mine to kill Bob, got %d bombs, alive=%v killed by %q",
+			len(engine.State.Bombs), p2.Alive, p2.KilledBy)
+	}
+}
+
+func TestIce(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.State.Status = StatusRunning
+
+	p1 := engine.State.Players["p1"]
+	for x := 2; x <= 4; x++ {
+		engine.State.Board[1][x] = Ice
+	}
+	engine.State.Board[1][6] = SoftWall
+
+	engine.movePlayer("p1", DirRight)
+	if !p1.Sliding {
+		t.Fatal("expected the player to slide on ice")
+	}
+
+	// No steering while sliding
+	engine.requestMove("p1", DirLeft)
+	engine.tickSliding()
+	if p1.Pos != (Position{X: 3, Y: 1}) {
+		t.Fatalf("expected the slide to carry on to x=3, got %v", p1.Pos)
+	}
+
+	// Off the ice the slide ends
+	engine.tickSliding()
+	engine.tickSliding()
+	if p1.Pos != (Position{X: 5, Y: 1}) || p1.Sliding {
+		t.Fatalf("expected to stop on the floor at x=5, got %v sliding=%v", p1.Pos, p1.Sliding)
+	}
+
+	// Running into something stops it too
+	engine.State.Board[1][5] = Ice
+	p1.Pos = Position{X: 4, Y: 1}
+	engine.movePlayer("p1", DirRight)
+	engine.tickSliding()
+	if p1.Pos != (Position{X: 5, Y: 1}) || p1.Sliding {
+		t.Errorf("expected to stop against the wall at x=5, got %v sliding=%v", p1.Pos, p1.Sliding)
+	}
+}
+
+func TestCrackedWall(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.State.Status = StatusRunning
+
+	wall := Position{X: 3, Y: 1}
+	engine.State.Board[wall.Y][wall.X] = CrackedWall
+	blast := func() {
+		engine.explode(&Bomb{Pos: Position{X: 5, Y: 1}, Range: 2}, map[int]bool{})
+		engine.State.Fires = nil
+		engine.State.Pickups = nil
+	}
+
+	blast()
+	if got := engine.State.Board[wall.Y][wall.X]; got != CrackedWallHit {
+		t.Fatalf("expected the first explosion to damage the wall, got %v", got)
+	}
+	blast()
+	if got := engine.State.Board[wall.Y][wall.X]; got != Empty {
+		t.Errorf("expected the second explosion to destroy the wall, got %v", got)
+	}
+}
+
+func TestLava(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.Lava = true
+	config.LavaAfter = time.Hour
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+
+	if engine.GetStateCopy().OvertimeAt.IsZero() {
+		t.Error("expected the lava timer in state snapshots")
+	}
+
+	engine.State.Tick = LavaSpreadTicks
+	engine.tickOvertime()
+	if len(engine.State.Fires) != 0 {
+		t.Fatal("expected no lava before lava_after")
+	}
+
+	// Leave Alice's tile the only open one
+	for y := range engine.State.Board {
+		for x := range engine.State.Board[y] {
+			engine.State.Board[y][x] = HardWall
+		}
+	}
+	engine.State.Board[1][1] = Empty
+	engine.State.OvertimeAt = time.Now().Add(-time.Second)
+	engine.tickOvertime()
+	if len(engine.State.Fires) != 1 || !engine.State.Fires[0].Permanent || engine.State.Fires[0].Pos != (Position{X: 1, Y: 1}) {
+		t.Fatalf("expected lava at Alice's tile, got %+v", engine.State.Fires)
+	}
+	if engine.State.Players["p1"].Alive {
+		t.Error("expected the lava to kill Alice")
+	}
+
+	engine.clearExpiredFires()
+	if len(engine.State.Fires) != 1 {
+		t.Error("expected lava never to go out")
+	}
+}
+
+func TestFogView(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.State.Status = StatusRunning
+	engine.State.Board[1][3] = SoftWall
+	engine.State.Board[1][9] = SoftWall
+	engine.State.Bombs = []*Bomb{{OwnerID: "p2", Pos: Position{X: 9, Y: 2}}}
+
+	state := engine.GetStateCopy()
+	view := state.FogView("p1", 3)
+
+	if view.Board[1][3] != SoftWall || view.Board[1][9] != Fog || view.Board[0][9] != HardWall {
+		t.Errorf("expected near tiles and hard walls shown and the rest fogged, got %v %v %v",
+			view.Board[1][3], view.Board[1][9], view.Board[0][9])
+	}
+	if len(view.Bombs) != 0 {
+		t.Error("expected the far bomb hidden")
+	}
+	if bob := view.Players["p2"]; !bob.OutOfSight || bob.Pos != (Position{}) {
+		t.Errorf("expected Bob out of sight, got %+v", bob)
+	}
+	if state.Board[1][9] != SoftWall || state.Players["p2"].OutOfSight {
+		t.Error("expected the original state untouched")
+	}
+
+	// Dead players see everything
+	state.Players["p1"].Alive = false
+	if view := state.FogView("p1", 3); view.Board[1][9] != SoftWall {
+		t.Error("expected a dead player to see the whole board")
+	}
+}
+
+func TestInvisibility(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.State.Status = StatusRunning
+
+	p1 := engine.State.Players["p1"]
+	engine.State.Pickups = []Pickup{{Pos: Position{X: 2, Y: 1}, Type: PickupCloak}}
+	engine.movePlayer("p1", DirRight)
+	if p1.InvisibleUntil.IsZero() {
+		t.Fatal("expected the pickup to make Alice invisible")
+	}
+
+	state := engine.GetStateCopy()
+	if alice := state.ViewFor("p2").Players["p1"]; !alice.OutOfSight || alice.Pos != (Position{}) {
+		t.Errorf("expected Alice hidden from Bob, got %+v", alice)
+	}
+	if alice := state.ViewFor("p1").Players["p1"]; alice.OutOfSight || alice.Pos != p1.Pos {
+		t.Errorf("expected Alice visible in their own view, got %+v", alice)
+	}
+	pickedUp := Event{Type: EventItemPickedUp, PlayerID: "p1", Pos: p1.Pos, Pickup: PickupBomb}
+	if _, ok := state.EventFor(pickedUp, "p2"); ok {
+		t.Error("expected Alice's pickups hidden from Bob")
+	}
+	if ev, ok := state.EventFor(pickedUp, "p1"); !ok || ev != pickedUp {
+		t.Errorf("expected Alice to see their own pickup, got %+v", ev)
+	}
+
+	p1.InvisibleUntil = time.Now().Add(-time.Second)
+	engine.tickInvisibility()
+	if !p1.InvisibleUntil.IsZero() {
+		t.Error("expected invisibility to wear off")
+	}
+}
+
+func TestKingOfTheHill(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.HillMode = true
+	config.HillScore = 2
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+
+	hill := engine.GetStateCopy().Hill
+	if hill == nil || hill.Pos != HillSpawn(config.Width, config.Height) {
+		t.Fatalf("expected the hill in the middle of the board, got %+v", hill)
+	}
+	for y := hill.Pos.Y; y < hill.Pos.Y+HillSize; y++ {
+		for x := hill.Pos.X; x < hill.Pos.X+HillSize; x++ {
+			if engine.State.Board[y][x] != Empty {
+				t.Fatalf("expected the hill to be clear, got tile %v at (%d,%d)", engine.State.Board[y][x], x, y)
+			}
+		}
+	}
+
+	// A contested hill scores nothing
+	alice, bob := engine.State.Players["p1"], engine.State.Players["p2"]
+	alice.Pos, bob.Pos = hill.Pos, Position{X: hill.Pos.X + 1, Y: hill.Pos.Y}
+	engine.tickHill()
+	if alice.HillPoints != 0 || bob.HillPoints != 0 {
+		t.Fatalf("expected no points on a contested hill, got %d and %d", alice.HillPoints, bob.HillPoints)
+	}
+
+	bob.Pos = Position{X: 1, Y: 1}
+	engine.tickHill()
+	if alice.HillPoints != 1 || engine.State.Status != StatusRunning {
+		t.Fatalf("expected Alice to score a point, got %d", alice.HillPoints)
+	}
+	engine.tickHill()
+	if engine.State.Status != StatusOver || engine.State.Winner != "p1" {
+		t.Errorf("expected Alice to win at %d points, got status %v winner %q", config.HillScore, engine.State.Status, engine.State.Winner)
+	}
+}
+
+func TestSpawnPositionsLargeBoard(t *testing.T) {
+	for _, tc := range []struct{ width, height, want int }{
+		{15, 13, 4},
+		{17, 17, 8},
+		{31, 31, 16},
+	} {
+		spawns := SpawnPositions(tc.width, tc.height)
+		if len(spawns) != tc.want {
+			t.Errorf("%dx%d: expected %d spawns, got %d", tc.width, tc.height, tc.want, len(spawns))
+		}
+		seen := make(map[Position]bool)
+		board := NewBoard(GameConfig{Width: tc.width, Height: tc.height, SoftWallDensity: 1}, rand.New(rand.NewSource(1)))
+		for _, sp := range spawns {
+			if seen[sp] {
+				t.Errorf("%dx%d: spawn %+v listed twice", tc.width, tc.height, sp)
+			}
+			seen[sp] = true
+			if board[sp.Y][sp.X] != Empty {
+				t.Errorf("%dx%d: spawn %+v isn't clear", tc.width, tc.height, sp)
+			}
+		}
+	}
+}
+
+func TestBattleRoyaleZone(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.BattleRoyale = true
+	config.ZoneShrink = time.Hour
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+
+	zone := engine.GetStateCopy().Zone
+	if zone == nil || zone.Width != config.Width-2 || zone.Height != config.Height-2 {
+		t.Fatalf("expected the zone to cover the board, got %+v", zone)
+	}
+
+	engine.tickZone()
+	if len(engine.State.Fires) != 0 {
+		t.Fatal("expected the zone to wait before closing in")
+	}
+
+	// Alice stays in the corner, which the zone leaves first
+	bob := engine.State.Players["p2"]
+	bob.Pos = Position{X: config.Width / 2, Y: config.Height / 2}
+	engine.zoneShrinksAt = time.Now().Add(-time.Second)
+	engine.tickZone()
+	z := engine.State.Zone
+	if z.Pos != (Position{X: 2, Y: 2}) || z.Width != config.Width-4 || z.Height != config.Height-4 {
+		t.Fatalf("expected the zone to close in by a tile, got %+v", z)
+	}
+	if engine.State.Players["p1"].Alive {
+		t.Error("expected the fire outside the zone to kill Alice")
+	}
+	if !bob.Alive {
+		t.Error("expected Bob to be safe inside the zone")
+	}
+	for _, f := range engine.State.Fires {
+		if !f.Permanent || z.Contains(f.Pos) {
+			t.Fatalf("expected only permanent fire outside the zone, got %+v", f)
+		}
+	}
+	if !z.OnEdge(Position{X: 2, Y: 2}) || z.OnEdge(bob.Pos) {
+		t.Error("expected the zone's edge to be its outer ring")
+	}
+}
+
+func TestHandicap(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+
+	if err := engine.SetHandicap("p2", Handicap{Bombs: config.MaxBombs + 1}); err == nil {
+		t.Error("expected a handicap past the caps to be rejected")
+	}
+	if err := engine.SetHandicap("p2", Handicap{Bombs: 5, Range: 4, Speed: 8}); err != nil {
+		t.Fatal(err)
+	}
+	engine.StartGame()
+	if err := engine.SetHandicap("p1", Handicap{Bombs: 1}); err == nil {
+		t.Error("expected handicaps to be locked once the match starts")
+	}
+
+	alice, bob := engine.State.Players["p1"], engine.State.Players["p2"]
+	if alice.BombMax != config.StartBombs || alice.BombRange != config.StartRange {
+		t.Errorf("expected Alice to keep the default stats, got %d bombs range %d", alice.BombMax, alice.BombRange)
+	}
+	if bob.BombMax != 5 || bob.BombRange != 4 || bob.MoveSpeed != 8 {
+		t.Errorf("expected Bob's handicap stats, got %d bombs range %d speed %d", bob.BombMax, bob.BombRange, bob.MoveSpeed)
+	}
+
+	// The handicap applies again when Bob respawns
+	engine.resetPlayer(bob, bob.Pos)
+	if bob.BombMax != 5 {
+		t.Errorf("expected the handicap to survive a respawn, got %d bombs", bob.BombMax)
+	}
+}
+
+func TestDropTable(t *testing.T) {
+	config := DefaultConfig()
+	config.DropTable = map[string]float64{"kick": 1}
+	engine := NewEngine(config)
+	for i := 0; i < 20; i++ {
+		engine.dropPickup(Position{X: i, Y: 1})
+	}
+	if len(engine.State.Pickups) != 20 {
+		t.Fatalf("expected every wall to drop a pickup, got %d of 20", len(engine.State.Pickups))
+	}
+	for _, pk := range engine.State.Pickups {
+		if pk.Type != PickupKick {
+			t.Fatalf("expected only kicks, got %+v", pk)
+		}
+	}
+
+	table := dropTable(GameConfig{DropTable: map[string]float64{"bomb": 3, "skull": 1}})
+	if len(table) != 2 || table[0].typ != PickupBomb || table[0].chance != 0.75 || table[1].chance != 0.25 {
+		t.Errorf("expected chances past 1 to be scaled down, got %+v", table)
+	}
+}
+
+func TestLoadConfigRejectsUnknownPickup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"drop_table": {"kick": 0.5, "rocket": 0.5}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected an unknown pickup in drop_table to be rejected")
+	}
+}
+
+func TestMaxPlayersFitSpawns(t *testing.T) {
+	dir := t.TempDir()
+	load := func(body string) (GameConfig, error) {
+		path := filepath.Join(dir, "config.json")
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return LoadConfig(path)
+	}
+
+	config, err := load(`{"battle_royale": true, "width": 31, "height": 31}`)
+	if err != nil || config.MaxPlayers != 16 {
+		t.Errorf("expected a battle royale to fill all 16 spawns, got %d, %v", config.MaxPlayers, err)
+	}
+	if _, err := load(`{"max_players": 8}`); err == nil {
+		t.Error("expected more players than the board has spawns to be rejected")
+	}
+
+	config = DefaultConfig()
+	config.MaxPlayers = 8
+	if engine := NewEngine(config); engine.Config.MaxPlayers != 4 {
+		t.Errorf("expected the engine to take only as many players as spawns, got %d", engine.Config.MaxPlayers)
+	}
+}
+
+func TestChainDelay(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.State.Status = StatusRunning
+	engine.SetStepMode(true)
+	engine.State.Players["p1"].Pos = Position{X: 1, Y: 11}
+
+	engine.State.Tick = 1
+	tick, delay := engine.State.Tick, engine.ticksIn(config.ChainDelay)
+	first := &Bomb{OwnerID: "p1", Pos: Position{X: 1, Y: 1}, Range: 2, ExpiresTick: tick - 1}
+	second := &Bomb{OwnerID: "p1", Pos: Position{X: 3, Y: 1}, Range: 2, ExpiresTick: tick + engine.ticksIn(time.Minute)}
+	engine.State.Bombs = append(engine.State.Bombs, first, second)
+
+	engine.tickBombs()
+	if len(engine.State.Bombs) != 1 || !second.Chained {
+		t.Fatalf("expected the second bomb to wait for the chain delay, got %+v", engine.State.Bombs)
+	}
+	if want := tick + delay; second.ExpiresTick != want {
+		t.Fatalf("expected the second bomb's fuse to run out on tick %d, got %d", want, second.ExpiresTick)
+	}
+
+	// Still in the fire, it doesn't wait any longer
+	engine.State.Tick += delay / 2
+	engine.tickBombs()
+	if second.ExpiresTick != tick+delay || len(engine.State.Bombs) != 1 {
+		t.Fatal("expected the chain delay to be counted from the first explosion")
+	}
+
+	engine.State.Tick += delay
+	engine.tickBombs()
+	if len(engine.State.Bombs) != 0 {
+		t.Error("expected the second bomb to go off after the chain delay")
+	}
+}
+
+func TestBombFuseLeft(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.State.Status = StatusRunning
+	engine.SetStepMode(true)
+
+	engine.placeBomb("p1")
+	engine.State.Tick += engine.ticksIn(time.Second)
+	engine.tickBombs()
+	if left := engine.GetStateCopy().Bombs[0].FuseLeft; left != config.BombTimer-time.Second {
+		t.Errorf("expected %v of fuse left, got %v", config.BombTimer-time.Second, left)
+	}
+}
+
+func TestStunMode(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.StunMode = true
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.SetStepMode(true)
+	engine.StartGame()
+	if engine.State.TimeLeft != StunModeDuration {
+		t.Fatalf("expected a %v round without a match duration, got %v", StunModeDuration, engine.State.TimeLeft)
+	}
+
+	bob := engine.State.Players["p2"]
+	bob.Pos = Position{X: 5, Y: 5}
+	bob.BombRange = config.StartRange + 2
+	bob.CanKick = true
+	engine.State.Fires = append(engine.State.Fires, Fire{Pos: bob.Pos, OwnerID: "p1", ExpiresTick: engine.State.Tick + engine.ticksIn(time.Second)})
+	engine.damagePlayersInFire()
+
+	if !bob.Alive || bob.StunnedUntil.IsZero() {
+		t.Fatal("expected fire to stun Bob instead of killing them")
+	}
+	if engine.State.Players["p1"].Kills != 1 {
+		t.Error("expected the stun to score for Alice")
+	}
+	if bob.BombRange != config.StartRange+1 || bob.CanKick || bob.BombMax != config.StartBombs-config.StartBombs/2 {
+		t.Errorf("expected Bob to lose half their power-ups, got range %d kick %v bombs %d", bob.BombRange, bob.CanKick, bob.BombMax)
+	}
+	if want := config.StartBombs/2 + 2; len(engine.State.Pickups) != want {
+		t.Errorf("expected %d scattered pickups, got %d", want, len(engine.State.Pickups))
+	}
+
+	engine.requestMove("p2", DirRight)
+	engine.placeBomb("p2")
+	if bob.Pos != (Position{X: 5, Y: 5}) || len(engine.State.Bombs) != 0 {
+		t.Error("expected a stunned player not to move or bomb")
+	}
+
+	engine.epoch = engine.epoch.Add(StunDuration)
+	engine.tickStuns()
+	if !bob.StunnedUntil.IsZero() || !engine.invulnerable(bob) {
+		t.Error("expected Bob to come round with brief protection")
+	}
+}
+
+func TestHearts(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.Hearts = 3
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.SetStepMode(true)
+	engine.State.Status = StatusRunning
+
+	bob := engine.State.Players["p2"]
+	if bob.HP != 3 {
+		t.Fatalf("expected Bob to start with 3 hearts, got %d", bob.HP)
+	}
+	bob.Pos = Position{X: 5, Y: 5}
+	engine.State.Fires = append(engine.State.Fires, Fire{Pos: bob.Pos, OwnerID: "p1", ExpiresTick: engine.State.Tick + engine.ticksIn(5*time.Second)})
+	engine.damagePlayersInFire()
+	if !bob.Alive || bob.HP != 2 {
+		t.Fatalf("expected a hit to cost Bob a heart, got alive %v hp %d", bob.Alive, bob.HP)
+	}
+
+	engine.damagePlayersInFire()
+	if bob.HP != 2 {
+		t.Errorf("expected hit protection to stop the next hit, got hp %d", bob.HP)
+	}
+
+	for range 2 {
+		engine.epoch = engine.epoch.Add(HitProtection)
+		engine.damagePlayersInFire()
+	}
+	if bob.Alive || bob.HP != 0 {
+		t.Errorf("expected the last heart to kill Bob, got alive %v hp %d", bob.Alive, bob.HP)
+	}
+}
+
+func TestBombRain(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.Overtime = OvertimeBombRain
+	config.LavaAfter = time.Minute
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.SetStepMode(true)
+	engine.StartGame()
+	engine.State.Status = StatusRunning
+
+	engine.tickOvertime()
+	if len(engine.State.Bombs) != 0 {
+		t.Fatal("expected no bombs before overtime")
+	}
+
+	engine.epoch = engine.epoch.Add(time.Minute)
+	engine.tickOvertime()
+	if len(engine.State.Bombs) != 1 {
+		t.Fatalf("expected a bomb to fall when overtime starts, got %d", len(engine.State.Bombs))
+	}
+	b := engine.State.Bombs[0]
+	if b.OwnerID != SkyID || engine.State.Board[b.Pos.Y][b.Pos.X] != Empty {
+		t.Errorf("expected a sky bomb on an open tile, got %+v", b)
+	}
+	for _, p := range engine.State.Players {
+		if p.Pos == b.Pos {
+			t.Errorf("expected the bomb not to land on %s", p.Name)
+		}
+	}
+
+	engine.tickOvertime()
+	if len(engine.State.Bombs) != 1 {
+		t.Error("expected a gap before the next bomb")
+	}
+	engine.epoch = engine.epoch.Add(BombRainEvery)
+	engine.tickOvertime()
+	if len(engine.State.Bombs) != 2 {
+		t.Fatalf("expected the next bomb after %v, got %d bombs", BombRainEvery, len(engine.State.Bombs))
+	}
+	if engine.rainEvery >= BombRainEvery*9/10 {
+		t.Errorf("expected the rain to speed up, got a gap of %v", engine.rainEvery)
+	}
+}
+
+func TestSpikeTraps(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 1
+	config.TrapDensity = 1
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.SetStepMode(true)
+	engine.StartGame()
+	engine.State.Status = StatusRunning
+
+	wall := Position{X: 3, Y: 1}
+	if engine.State.Board[wall.Y][wall.X] != SoftWall || !engine.traps[wall] {
+		t.Fatal("expected a trap under every soft wall")
+	}
+	for _, row := range engine.GetStateCopy().Board {
+		for _, tile := range row {
+			if tile == Trap {
+				t.Fatal("expected hidden traps to stay off the wire")
+			}
+		}
+	}
+
+	alice, bob := engine.State.Players["p1"], engine.State.Players["p2"]
+	alice.Pos = Position{X: 1, Y: 2}
+	engine.explode(&Bomb{OwnerID: "p1", Pos: Position{X: 2, Y: 1}, Range: 1}, map[int]bool{})
+	if got := engine.State.Board[wall.Y][wall.X]; got != Trap {
+		t.Fatalf("expected the destroyed wall to reveal a trap, got %v", got)
+	}
+	for _, pk := range engine.State.Pickups {
+		if pk.Pos == wall {
+			t.Error("expected no pickup on a revealed trap")
+		}
+	}
+	engine.State.Fires = nil
+
+	bob.Pos = wall
+	engine.tickTraps()
+	if bob.Alive || bob.KilledBy != TrapID {
+		t.Fatal("expected the trap to kill Bob")
+	}
+	if engine.State.Board[wall.Y][wall.X] != Empty {
+		t.Fatal("expected the trap to be sprung")
+	}
+	alice.Pos = wall
+	engine.tickTraps()
+	if !alice.Alive {
+		t.Error("expected a sprung trap not to hurt the next player")
+	}
+}
+
+func TestSeedReplaysBoard(t *testing.T) {
+	config := DefaultConfig()
+	config.Seed = 42
+	config.TrapDensity = 0.2
+	a, b := NewEngine(config), NewEngine(config)
+	for y := range a.State.Board {
+		for x := range a.State.Board[y] {
+			if a.State.Board[y][x] != b.State.Board[y][x] {
+				t.Fatalf("expected the same board from the same seed, differs at %d,%d", x, y)
+			}
+		}
+	}
+	if len(a.traps) == 0 || len(a.traps) != len(b.traps) {
+		t.Errorf("expected the same traps from the same seed, got %d and %d", len(a.traps), len(b.traps))
+	}
+	for pos := range a.traps {
+		if !b.traps[pos] {
+			t.Errorf("expected a trap at %+v in both games", pos)
+		}
+	}
+	if a.rng.Int63() != b.rng.Int63() {
+		t.Error("expected both games to roll the same drops")
+	}
+}
+
+func TestStepIsDeterministic(t *testing.T) {
+	play := func() *Engine {
+		config := DefaultConfig()
+		config.Seed = 7
+		engine := NewEngine(config)
+		engine.AddPlayer("p1", "Alice")
+		engine.AddPlayer("p2", "Bob")
+		engine.SetStepMode(true)
+		engine.StartGame()
+		engine.placeBomb("p1")
+		engine.requestMove("p1", DirDown)
+		engine.Step(3 * config.TickRate)
+		return engine
+	}
+	a, b := play(), play()
+
+	if a.State.Tick != b.State.Tick || a.now() != a.epoch.Add(time.Duration(a.State.Tick)*a.tickDuration()) {
+		t.Fatalf("expected the clock to follow the ticks, got tick %d", a.State.Tick)
+	}
+	if len(a.State.Bombs) != len(b.State.Bombs) || a.State.Status != b.State.Status || a.State.Winner != b.State.Winner {
+		t.Errorf("expected the same outcome, got status %d and %d", a.State.Status, b.State.Status)
+	}
+	for i := range a.State.Enemies {
+		if a.State.Enemies[i].Pos != b.State.Enemies[i].Pos || a.State.Enemies[i].Alive != b.State.Enemies[i].Alive {
+			t.Errorf("enemy %d: expected the same moves from the same seed, got %+v and %+v", i, a.State.Enemies[i], b.State.Enemies[i])
+		}
+	}
+	for id, p := range a.State.Players {
+		if q := b.State.Players[id]; p.Pos != q.Pos || p.Alive != q.Alive {
+			t.Errorf("%s: expected the same outcome, got %+v and %+v", p.Name, p.Pos, q.Pos)
+		}
+	}
+}
+
+func TestSameTickExplosionsAreDeterministic(t *testing.T) {
+	play := func() []Pickup {
+		config := DefaultConfig()
+		config.Seed = 11
+		config.SoftWallDensity = 1
+		config.EnemyCount = 0
+		engine := NewEngine(config)
+		engine.AddPlayer("p1", "Alice")
+		engine.AddPlayer("p2", "Bob")
+		engine.StartGame()
+		expires := engine.State.Tick + engine.ticksIn(config.BombTimer)
+		for _, pos := range []Position{{X: 3, Y: 3}, {X: 7, Y: 3}, {X: 11, Y: 3}, {X: 3, Y: 7}, {X: 7, Y: 7}, {X: 11, Y: 7}} {
+			engine.State.Board[pos.Y][pos.X] = Empty
+			engine.State.Bombs = append(engine.State.Bombs, &Bomb{OwnerID: "p1", Pos: pos, Range: 1, ExpiresTick: expires})
+		}
+		engine.Step(int(config.BombTimer/engine.tickDuration()) + 1)
+		if len(engine.State.Bombs) != 0 {
+			t.Fatalf("expected every bomb to go off on the same tick, %d left", len(engine.State.Bombs))
+		}
+		return engine.State.Pickups
+	}
+
+	want := play()
+	for range 10 {
+		if got := play(); !slices.Equal(got, want) {
+			t.Fatalf("expected the same pickups from the same seed, got %v and %v", got, want)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.SetStepMode(true)
+	engine.StartGame()
+
+	var events []Event
+	engine.OnEvent(func(ev Event) { events = append(events, ev) })
+
+	engine.State.Board[3][5] = SoftWall
+	engine.State.Pickups = append(engine.State.Pickups, Pickup{Pos: Position{X: 1, Y: 2}, Type: PickupKick})
+	engine.movePlayer("p1", DirDown)
+	bob := engine.State.Players["p2"]
+	bob.Pos = Position{X: 3, Y: 5}
+	bob.InvulnerableUntil = time.Time{}
+	engine.explode(&Bomb{OwnerID: "p1", Pos: Position{X: 3, Y: 3}, Range: 2}, map[int]bool{})
+	if len(events) != 0 {
+		t.Fatal("expected events to wait for the end of the tick")
+	}
+	engine.Step(1)
+
+	want := []Event{
+		{Type: EventItemPickedUp, PlayerID: "p1", Pos: Position{X: 1, Y: 2}, Pickup: PickupKick},
+		{Type: EventBombExploded, ByID: "p1", Pos: Position{X: 3, Y: 3}},
+		{Type: EventWallDestroyed, ByID: "p1", Pos: Position{X: 5, Y: 3}},
+		{Type: EventPlayerKilled, PlayerID: "p2", ByID: "p1", Pos: bob.Pos},
+		{Type: EventGameOver, Winner: "p1"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), events)
+	}
+	for i, ev := range events {
+		ev.Tick = 0
+		if ev != want[i] {
+			t.Errorf("event %d: expected %+v, got %+v", i, want[i], ev)
+		}
+	}
+}
+
+func TestPause(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	if err := engine.Pause(); err == nil {
+		t.Error("expected no pausing in the lobby")
+	}
+	engine.StartGame()
+	engine.placeBomb("p1")
+
+	if err := engine.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.StartGame(); err == nil || engine.State.Status != StatusPaused {
+		t.Fatalf("expected no restarting a paused match, got status %d", engine.State.Status)
+	}
+	tick := engine.State.Tick
+	engine.EnqueueAction(Action{PlayerID: "p2", Type: ActionMove, Dir: DirLeft})
+	for range 5 * config.TickRate {
+		engine.tick()
+	}
+	if engine.State.Tick != tick || len(engine.State.Bombs) != 1 {
+		t.Fatalf("expected the match to freeze, got tick %d and %d bombs", engine.State.Tick, len(engine.State.Bombs))
+	}
+	if engine.State.Bombs[0].FuseLeft != config.BombTimer {
+		t.Errorf("expected the fuse to stop, got %v left", engine.State.Bombs[0].FuseLeft)
+	}
+
+	if err := engine.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	engine.tick()
+	if engine.State.Status != StatusRunning || engine.State.Tick != tick+1 {
+		t.Fatalf("expected the match to carry on, got status %d tick %d", engine.State.Status, engine.State.Tick)
+	}
+	spawn := SpawnPositions(config.Width, config.Height)[1]
+	if bob := engine.State.Players["p2"]; bob.Pos != spawn {
+		t.Errorf("expected input sent while paused to be dropped, Bob at %v", bob.Pos)
+	}
+}
+
+func TestRunForHeadless(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+
+	ticks := 0
+	engine.OnTick(func(GameState) { ticks++ })
+	exploded := false
+	engine.OnEvent(func(ev Event) { exploded = exploded || ev.Type == EventBombExploded })
+
+	// Bombs go off on the first tick after their timer runs out
+	engine.placeBomb("p1")
+	d := config.BombTimer + engine.tickDuration()
+	engine.RunFor(d)
+	if want := int(d / engine.tickDuration()); ticks != want {
+		t.Errorf("expected %d ticks, got %d", want, ticks)
+	}
+	if len(engine.State.Bombs) != 0 || !exploded {
+		t.Error("expected the bomb to go off without Run")
+	}
+}
+
+func TestGameModes(t *testing.T) {
+	// The older switches pick their mode, and the mode sets its switch
+	config := DefaultConfig()
+	config.HillMode = true
+	if engine := NewEngine(config); engine.Config.Mode != ModeKOTH {
+		t.Errorf("expected hill_mode to mean koth, got %q", engine.Config.Mode)
+	}
+	config = DefaultConfig()
+	config.Mode = ModeBattleRoyale
+	config.TeamMode = true
+	engine := NewEngine(config)
+	if !engine.Config.BattleRoyale || !engine.Config.TeamMode {
+		t.Errorf("expected battle royale with teams, got %+v", engine.Config)
+	}
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+	if engine.State.Zone == nil || engine.State.Hill != nil {
+		t.Error("expected a battle royale round to open the zone and nothing else")
+	}
+
+	// A stun round has a clock even without a match duration
+	config = DefaultConfig()
+	config.Mode = ModeStun
+	engine = NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.StartGame()
+	if engine.State.TimeLeft != StunModeDuration {
+		t.Errorf("expected a %v stun round, got %v", StunModeDuration, engine.State.TimeLeft)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"mode": "tag"}`), 0o644)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected an unknown mode to be rejected")
+	}
+}
+
+func TestPlayersBlockEachOther(t *testing.T) {
+	config := DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+	alice, bob := engine.State.Players["p1"], engine.State.Players["p2"]
+
+	// Both go for the tile between them: Bob asked first, so it's his
+	alice.Pos, bob.Pos = Position{X: 3, Y: 1}, Position{X: 5, Y: 1}
+	engine.EnqueueAction(Action{PlayerID: "p2", Type: ActionMove, Dir: DirLeft})
+	engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionMove, Dir: DirRight})
+	engine.Step(1)
+	if bob.Pos != (Position{X: 4, Y: 1}) || alice.Pos != (Position{X: 3, Y: 1}) {
+		t.Fatalf("expected Bob to take the tile and Alice to be blocked, got %v and %v", bob.Pos, alice.Pos)
+	}
+
+	// Nor can they swap places
+	alice.moveCredit, bob.moveCredit = config.TickRate, config.TickRate
+	engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionMove, Dir: DirRight})
+	engine.EnqueueAction(Action{PlayerID: "p2", Type: ActionMove, Dir: DirLeft})
+	engine.Step(1)
+	if alice.Pos != (Position{X: 3, Y: 1}) || bob.Pos != (Position{X: 4, Y: 1}) {
+		t.Errorf("expected neither to pass through the other, got %v and %v", alice.Pos, bob.Pos)
+	}
+}
+
+func TestInvariants(t *testing.T) {
+	config := DefaultConfig()
+	config.EnemyCount = 0
+	config.TickRate = 10
+	config.BombTimer = 200 * time.Millisecond
+	config.SoftWallDensity = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+	alice := engine.State.Players["p1"]
+
+	// A bomb going off gives its owner the bomb back
+	alice.BombRange = 1
+	engine.placeBomb("p1")
+	engine.movePlayer("p1", DirRight)
+	engine.movePlayer("p1", DirRight)
+	if v := engine.invariantViolations(); len(v) != 0 {
+		t.Fatalf("expected no violations with a bomb down, got %v", v)
+	}
+	engine.Step(3)
+	if len(engine.State.Bombs) != 0 || alice.BombsUsed != 0 {
+		t.Fatalf("expected the bomb to go off and be given back, %d bombs and BombsUsed %d", len(engine.State.Bombs), alice.BombsUsed)
+	}
+	if v := engine.invariantViolations(); len(v) != 0 {
+		t.Fatalf("expected no violations after the blast, got %v", v)
+	}
+
+	// Breaking each invariant is caught
+	engine.State.Board[alice.Pos.Y][alice.Pos.X] = SoftWall
+	alice.BombsUsed = 2
+	engine.State.Fires = append(engine.State.Fires, Fire{Pos: Position{X: -1, Y: 0}})
+	if v := engine.invariantViolations(); len(v) != 3 {
+		t.Errorf("expected 3 violations, got %v", v)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	config := DefaultConfig()
+	config.EnemyCount = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+
+	// The queue holds 256 actions; the rest are dropped
+	for range 300 {
+		engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionPlaceBomb})
+	}
+	engine.Step(1)
+	m := engine.Metrics()
+	if m.Ticks != 1 || m.LastActions != 256 || m.Actions != 256 || m.DroppedActions != 44 {
+		t.Fatalf("expected 256 actions drained and 44 dropped in 1 tick, got %+v", m)
+	}
+	if m.LastTick <= 0 || m.MaxTick < m.LastTick || m.MeanTick() != m.TickTime {
+		t.Errorf("expected the tick to be timed, got %+v", m)
+	}
+
+	engine.Step(1)
+	if m := engine.Metrics(); m.Ticks != 2 || m.LastActions != 0 || m.Actions != 256 {
+		t.Errorf("expected an idle second tick, got %+v", m)
+	}
+}
+
+func TestMovesThrottled(t *testing.T) {
+	config := DefaultConfig()
+	config.EnemyCount = 0
+	config.SoftWallDensity = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+	alice := engine.State.Players["p1"]
+	start := alice.Pos
+
+	// However many moves Alice floods in, she covers one tile, and only the
+	// first MaxMovesPerTick moves are looked at
+	alice.MoveSpeed = config.TickRate
+	for range 10 {
+		engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionMove, Dir: DirRight})
+	}
+	engine.EnqueueAction(Action{PlayerID: "p2", Type: ActionMove, Dir: DirLeft})
+	engine.Step(1)
+	if alice.Pos != (Position{X: start.X + 1, Y: start.Y}) {
+		t.Errorf("expected Alice to move one tile, at %v", alice.Pos)
+	}
+	if m := engine.Metrics(); m.LastActions != MaxMovesPerTick+1 || m.ThrottledActions != uint64(10-MaxMovesPerTick) {
+		t.Errorf("expected %d of Alice's moves throttled and Bob's let through, got %+v", 10-MaxMovesPerTick, m)
+	}
+}
+
+func TestManager(t *testing.T) {
+	m := NewManager()
+	var closed []string
+	m.OnClose(func(id string) { closed = append(closed, id) })
+	defer m.CloseAll()
+
+	config := DefaultConfig()
+	config.EnemyCount = 0
+	for _, id := range []string{"b", "a"} {
+		if _, err := m.Create(id, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.Create("a", config); err == nil {
+		t.Error("expected a second room a to be refused")
+	}
+	if rooms := m.Rooms(); !slices.Equal(rooms, []string{"a", "b"}) {
+		t.Errorf("expected rooms a and b, got %v", rooms)
+	}
+
+	// The rooms are separate matches
+	for _, id := range []string{"p1", "p2"} {
+		if err := m.Join("a", id, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Start("a"); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := m.Room("a")
+	b, _ := m.Room("b")
+	if a.GetStateCopy().Status != StatusRunning || b.GetStateCopy().Status != StatusLobby || b.PlayerCount() != 0 {
+		t.Error("expected only room a to be playing")
+	}
+
+	// A room closes once the last player leaves it
+	m.Leave("a", "p1")
+	if _, ok := m.Room("a"); !ok {
+		t.Fatal("expected room a to stay open with p2 in it")
+	}
+	m.Leave("a", "p2")
+	if _, ok := m.Room("a"); ok || !slices.Equal(closed, []string{"a"}) {
+		t.Errorf("expected room a to close when empty, closed %v", closed)
+	}
+	if err := m.Join("a", "p3", "p3"); err == nil {
+		t.Error("expected joining a closed room to fail")
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	config := DefaultConfig()
+	config.EnemyCount = 0
+	config.BombTimer = time.Second
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+	engine.Step(5)
+	engine.placeBomb("p1")
+	engine.Step(3)
+
+	snap, err := engine.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewEngine(DefaultConfig())
+	if err := restored.Restore(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both play on the same, the bomb going off on the same tick and the
+	// random drops landing the same
+	for _, e := range []*Engine{engine, restored} {
+		e.EnqueueAction(Action{PlayerID: "p2", Type: ActionMove, Dir: DirLeft})
+		e.Step(2 * config.TickRate)
+	}
+	want, _ := json.Marshal(engine.GetStateCopy())
+	got, _ := json.Marshal(restored.GetStateCopy())
+	if string(got) != string(want) {
+		t.Errorf("restored engine played on differently:\n got %s\nwant %s", got, want)
+	}
+	if len(restored.State.Bombs) != 0 || restored.State.Players["p2"].moveCredit != engine.State.Players["p2"].moveCredit {
+		t.Error("expected the bomb to have gone off and move credit to carry over")
+	}
+
+	if err := restored.Restore([]byte("{")); err == nil {
+		t.Error("expected a broken snapshot to be refused")
+	}
+
+	// A snapshot that would have the engine index off the board is refused,
+	// leaving the game as it was
+	before, _ := json.Marshal(restored.GetStateCopy())
+	for name, corrupt := range map[string]func(*Snapshot){
+		"short row":     func(s *Snapshot) { s.State.Board[4] = s.State.Board[4][:3] },
+		"player off":    func(s *Snapshot) { s.State.Players["p1"].Pos = Position{X: -1, Y: 1} },
+		"bomb off":      func(s *Snapshot) { s.State.Bombs = []*Bomb{{Pos: Position{X: 1, Y: s.State.Height}}} },
+		"fire off":      func(s *Snapshot) { s.State.Fires = []Fire{{Pos: Position{X: s.State.Width, Y: 1}}} },
+		"pickup off":    func(s *Snapshot) { s.State.Pickups = []Pickup{{Pos: Position{X: 1, Y: -3}}} },
+		"missing enemy": func(s *Snapshot) { s.State.Enemies = []*Enemy{nil} },
+		"config size":   func(s *Snapshot) { s.Config.Width += 2 },
+		"tiny board":    func(s *Snapshot) { s.Config.Width, s.State.Width, s.State.Board = 1, 1, nil },
+		"color":         func(s *Snapshot) { s.State.Players["p1"].Color = -1 },
+		"draws":         func(s *Snapshot) { s.Draws = math.MaxUint64 },
+	} {
+		var s Snapshot
+		if err := json.Unmarshal(snap, &s); err != nil {
+			t.Fatal(err)
+		}
+		corrupt(&s)
+		bad, _ := json.Marshal(s)
+		if err := restored.Restore(bad); err == nil {
+			t.Errorf("%s: expected the snapshot to be refused", name)
+		}
+	}
+	if after, _ := json.Marshal(restored.GetStateCopy()); string(after) != string(before) {
+		t.Error("a refused snapshot shouldn't touch the game")
+	}
+
+	// The config is settled as NewEngine would
+	var s Snapshot
+	if err := json.Unmarshal(snap, &s); err != nil {
+		t.Fatal(err)
+	}
+	s.Config.MaxPlayers = 8
+	fits, _ := json.Marshal(s)
+	if err := restored.Restore(fits); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Config.MaxPlayers != 4 {
+		t.Errorf("expected max players capped at the board's 4 spawns, got %d", restored.Config.MaxPlayers)
+	}
+}
+
+func TestRejoinSpawns(t *testing.T) {
+	engine := NewEngine(DefaultConfig())
+	for _, id := range []string{"p1", "p2", "p3"} {
+		engine.AddPlayer(id, id)
+	}
+
+	// p2 leaving frees their corner for the next to join, rather than
+	// putting them on p3's
+	engine.RemovePlayer("p2")
+	engine.AddPlayer("p4", "p4")
+	seen := make(map[Position]bool)
+	for _, id := range []string{"p1", "p3", "p4"} {
+		p := engine.State.Players[id]
+		if seen[p.Pos] {
+			t.Errorf("%s shares a spawn at %v", id, p.Pos)
+		}
+		seen[p.Pos] = true
+	}
+	if c := engine.State.Players["p4"].Color; c != 1 {
+		t.Errorf("expected p4 to take p2's color 1, got %d", c)
+	}
+}
+
+func TestActionAcks(t *testing.T) {
+	config := DefaultConfig()
+	config.EnemyCount = 0
+	config.SoftWallDensity = 0
+	engine := NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+	alice := engine.State.Players["p1"]
+
+	engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionMove, Dir: DirRight, Seq: 1})
+	engine.Step(1)
+	if alice.LastSeq != 1 {
+		t.Fatalf("expected the move made at once to be acked, got %d", alice.LastSeq)
+	}
+
+	// A move waiting for move credit holds the ack back, even past a bomb
+	// placed after it, until it is made
+	engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionMove, Dir: DirRight, Seq: 2})
+	engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionPlaceBomb, Seq: 3})
+	engine.Step(1)
+	if alice.LastSeq != 1 {
+		t.Errorf("expected the waiting move to hold the ack at 1, got %d", alice.LastSeq)
+	}
+	for i := 0; i < config.TickRate && alice.LastSeq != 3; i++ {
+		engine.Step(1)
+	}
+	if alice.LastSeq != 3 || alice.Pos.X != 3 {
+		t.Errorf("expected both actions acked once the move was made, acked %d at %v", alice.LastSeq, alice.Pos)
+	}
+}
+
+func TestStateCopyKeepsEveryField(t *testing.T) {
+	engine := NewEngine(DefaultConfig())
+	engine.AddPlayer("p1", "Alice")
+	engine.StartGame()
+
+	// Give every plain field of the state a value, so one the copy leaves
+	// out shows up however it's named
+	state := reflect.ValueOf(engine.State).Elem()
+	for i := range state.NumField() {
+		f := state.Field(i)
+		if !f.IsZero() {
+			continue
+		}
+		switch f.Kind() {
+		case reflect.Bool:
+			f.SetBool(true)
+		case reflect.Int, reflect.Int64:
+			f.SetInt(7)
+		case reflect.Uint64:
+			f.SetUint(7)
+		case reflect.String:
+			f.SetString("x")
+		case reflect.Struct:
+			if f.Type() == reflect.TypeFor[time.Time]() {
+				f.Set(reflect.ValueOf(time.Unix(7, 0)))
+			}
+		}
+	}
+
+	cp := engine.GetStateCopy()
+	got := reflect.ValueOf(cp)
+	for i := range state.NumField() {
+		if !reflect.DeepEqual(got.Field(i).Interface(), state.Field(i).Interface()) {
+			t.Errorf("state copy lost %s", state.Type().Field(i).Name)
+		}
+	}
+
+	cp.Board[1][1] = HardWall
+	cp.Players["p1"].Alive = false
+	if engine.State.Board[1][1] == HardWall || !engine.State.Players["p1"].Alive {
+		t.Error("state copy shares the board or players with the engine")
+	}
+}
+
+func TestGhostReplaysRun(t *testing.T) {
+	config := DailyConfig(DefaultConfig(), time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC))
+	config.EnemyCount = 2
+	engine := NewEngine(config)
+	engine.RecordRuns(true)
+	engine.AddPlayer("p1", "Alice")
+	engine.Step(7) // Lobby ticks before the start don't count
+	engine.StartGame()
+
+	// Wander off, then sit on a bomb until it goes off
+	var states []string
+	moves := []Direction{DirRight, DirRight, DirDown, DirDown, DirLeft}
+	for i := 0; i < 20*config.TickRate; i++ {
+		if i < len(moves)*4 && i%4 == 0 {
+			engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionMove, Dir: moves[i/4], Seq: uint32(i/4 + 1)})
+		}
+		if i == len(moves)*4 {
+			engine.EnqueueAction(Action{PlayerID: "p1", Type: ActionPlaceBomb})
+		}
+		engine.Step(1)
+		state, _ := json.Marshal(engine.GetStateCopy())
+		states = append(states, string(state))
+		if engine.GetStateCopy().Status == StatusOver {
+			break
+		}
+	}
+	run, ok := engine.LastRun()
+	if !ok {
+		t.Fatal("expected the match to be recorded once it was over")
+	}
+	if run.Won || run.Ticks != uint64(len(states)) || len(run.Inputs) != len(moves)+1 {
+		t.Fatalf("expected a lost run of %d ticks and %d inputs, got won %v, %d ticks, %d inputs",
+			len(states), len(moves)+1, run.Won, run.Ticks, len(run.Inputs))
+	}
+
+	// Saved and loaded, the run replays tick for tick
+	path := filepath.Join(t.TempDir(), "ghost.json")
+	if err := run.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if run, err := LoadRun(path); err != nil {
+		t.Fatal(err)
+	} else {
+		ghost, err := NewGhost(run)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range states {
+			ghost.AdvanceTo(uint64(i + 1))
+			got, _ := json.Marshal(ghost.e.GetStateCopy())
+			if string(got) != want {
+				t.Fatalf("ghost went its own way on tick %d:\n got %s\nwant %s", i+1, got, want)
+			}
+		}
+		ghost.AdvanceTo(run.Ticks + 100)
+		if ghost.e.GetStateCopy().Tick != run.Start.State.Tick+run.Ticks || len(ghost.Players()) != 0 {
+			t.Error("expected the ghost to stop where the run ended, its player dead")
+		}
+	}
+
+	won := Run{Won: true, Ticks: 500}
+	if !won.Beats(Run{Ticks: 100}) || !(Run{Won: true, Ticks: 400}).Beats(won) || (Run{Ticks: 100}).Beats(Run{Ticks: 200}) {
+		t.Error("expected wins to beat losses, faster wins slower ones and longer losses shorter ones")
+	}
+}
+
+func TestStateVersion(t *testing.T) {
+	engine := NewEngine(DefaultConfig())
+	engine.AddPlayer("p1", "Alice")
+	v := engine.GetStateCopy().Version
+	engine.Step(3)
+	if got := engine.GetStateCopy().Version; got != v {
+		t.Errorf("expected lobby ticks to leave the version at %d, got %d", v, got)
+	}
+	engine.SetCosmetics("p1", Cosmetics{Glyph: "x"})
+	if got := engine.GetStateCopy().Version; got <= v {
+		t.Errorf("expected a change to bump the version past %d, got %d", v, got)
+	}
+
+	// A restored state takes a version after the engine's, never one it had
+	v = engine.GetStateCopy().Version
+	snap, _ := engine.Snapshot()
+	engine.Step(1)
+	engine.Restore(snap)
+	if got := engine.GetStateCopy().Version; got <= v {
+		t.Errorf("expected restoring to bump the version past %d, got %d", v, got)
+	}
+}
@@ -0,0 +1,59 @@
+package game
+
+// EventType identifies what happened in an Event.
+type EventType string
+
+const (
+	EventPlayerKilled  EventType = "player_killed"  // PlayerID died to ByID's fire, enemy or trap
+	EventBombExploded  EventType = "bomb_exploded"  // ByID's bomb, or a barrel, went off at Pos
+	EventWallDestroyed EventType = "wall_destroyed" // ByID's fire destroyed the wall at Pos
+	EventItemPickedUp  EventType = "item_picked_up" // PlayerID picked up Pickup at Pos
+	EventGameOver      EventType = "game_over"      // The match ended; Winner or WinningTeam won, or neither for a draw
+)
+
+// Event is something that happened in the game, for kill feeds, stats and
+// other subscribers that would otherwise have to diff states. Fields that
+// don't apply to the Type are left empty.
+type Event struct {
+	Type        EventType  `json:"type"`
+	Tick        uint64     `json:"tick"`
+	PlayerID    string     `json:"player_id,omitempty"`
+	ByID        string     `json:"by_id,omitempty"` // A player's ID, BossID, SkyID or TrapID; empty for monsters
+	Pos         Position   `json:"pos"`
+	Pickup      PickupType `json:"pickup,omitempty"`
+	Winner      string     `json:"winner,omitempty"`
+	WinningTeam int        `json:"winning_team,omitempty"`
+}
+
+// OnEvent subscribes fn to every event from now on. Subscribers are called
+// in order after each tick, outside the engine lock, so they may call back
+// into the engine. Must be called before Run.
+func (e *Engine) OnEvent(fn func(Event)) {
+	e.subscribers = append(e.subscribers, fn)
+}
+
+// emit records an event for the subscribers.
+func (e *Engine) emit(ev Event) {
+	if len(e.subscribers) == 0 && e.hooks == nil {
+		return
+	}
+	ev.Tick = e.State.Tick
+	e.events = append(e.events, ev)
+}
+
+// takeEventsLocked returns the events recorded since the last call.
+// MUST be called while e.mu is held.
+func (e *Engine) takeEventsLocked() []Event {
+	events := e.events
+	e.events, e.hooked = nil, 0
+	return events
+}
+
+// publish sends events to every subscriber.
+func (e *Engine) publish(events []Event) {
+	for _, ev := range events {
+		for _, fn := range e.subscribers {
+			fn(ev)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package game
+
+// FogView returns the state as the player with the given ID sees it in a
+// fog-of-war game with the given sight radius. Outside radius tiles of them
+// and their living teammates, everything but hard walls is replaced by Fog
+// and bombs, fires, enemies and pickups are left out; other players stay on
+// the list but are marked OutOfSight with their position cleared. Players
+// who are dead, or not playing, see everything, as does everyone outside a
+// running game. s itself is left untouched.
+func (s GameState) FogView(playerID string, radius int) GameState {
+	viewer, ok := s.Players[playerID]
+	if !ok || !viewer.Alive || (s.Status != StatusRunning && s.Status != StatusPaused) {
+		return s
+	}
+
+	eyes := []Position{viewer.Pos}
+	for _, p := range s.Players {
+		if p.Alive && p.ID != viewer.ID && p.TeamID != 0 && p.TeamID == viewer.TeamID {
+			eyes = append(eyes, p.Pos)
+		}
+	}
+	seen := func(pos Position) bool {
+		for _, eye := range eyes {
+			if max(abs(pos.X-eye.X), abs(pos.Y-eye.Y)) <= radius {
+				return true
+			}
+		}
+		return false
+	}
+
+	board := make([][]TileType, len(s.Board))
+	for y, row := range s.Board {
+		board[y] = make([]TileType, len(row))
+		for x, tile := range row {
+			if tile != HardWall && !seen(Position{X: x, Y: y}) {
+				tile = Fog
+			}
+			board[y][x] = tile
+		}
+	}
+	s.Board = board
+
+	players := make(map[string]*Player, len(s.Players))
+	for id, p := range s.Players {
+		if p.Alive && !seen(p.Pos) {
+			hidden := *p
+			hidden.Pos = Position{}
+			hidden.OutOfSight = true
+			p = &hidden
+		}
+		players[id] = p
+	}
+	s.Players = players
+
+	bombs := make([]*Bomb, 0, len(s.Bombs))
+	for _, b := range s.Bombs {
+		if seen(b.Pos) {
+			bombs = append(bombs, b)
+		}
+	}
+	s.Bombs = bombs
+
+	fires := make([]Fire, 0, len(s.Fires))
+	for _, f := range s.Fires {
+		if seen(f.Pos) {
+			fires = append(fires, f)
+		}
+	}
+	s.Fires = fires
+
+	enemies := make([]*Enemy, 0, len(s.Enemies))
+	for _, en := range s.Enemies {
+		if seen(en.Pos) {
+			enemies = append(enemies, en)
+		}
+	}
+	s.Enemies = enemies
+
+	pickups := make([]Pickup, 0, len(s.Pickups))
+	for _, pk := range s.Pickups {
+		if seen(pk.Pos) {
+			pickups = append(pickups, pk)
+		}
+	}
+	s.Pickups = pickups
+	return s
+}
@@ -0,0 +1,228 @@
+package game
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Run is a match recorded for ghost racing: the engine as the match started
+// and every action it handled from then on. The engine is deterministic, so
+// replaying the actions on the snapshot plays the match out again exactly;
+// see Ghost.
+type Run struct {
+	Start  Snapshot `json:"start"`  // The engine on the tick the match started
+	Inputs []Input  `json:"inputs"` // In the order the engine handled them
+	Ticks  uint64   `json:"ticks"`  // How long the match lasted
+	Won    bool     `json:"won"`    // The match ended with a player standing, as co-op matches are won
+}
+
+// Input is an action a Run's engine handled, with the tick it handled it on,
+// counted from the start of the match.
+type Input struct {
+	Tick   uint64 `json:"tick"`
+	Action Action `json:"action"`
+}
+
+// Beats reports whether r is a better run than best: a win beats a loss, a
+// faster win a slower one, and of two losses the one that held out longer.
+func (r Run) Beats(best Run) bool {
+	if r.Won != best.Won {
+		return r.Won
+	}
+	if r.Won {
+		return r.Ticks < best.Ticks
+	}
+	return r.Ticks > best.Ticks
+}
+
+// recording is the Run being recorded while a match is played.
+type recording struct {
+	run   Run
+	start uint64 // The tick the match started on
+}
+
+// RecordRuns turns recording matches for ghost racing on or off. While it's
+// on, each match is recorded from its start, and LastRun returns the last one
+// to finish. Matches resumed from a save aren't recorded.
+func (e *Engine) RecordRuns(on bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recordRuns = on
+	if !on {
+		e.recording = nil
+	}
+}
+
+// LastRun returns the last match recorded to its end, if any has been.
+func (e *Engine) LastRun() (Run, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lastRun == nil {
+		return Run{}, false
+	}
+	return *e.lastRun, true
+}
+
+// RunStart returns the tick the match being recorded started on, for keeping
+// a ghost in step with it.
+func (e *Engine) RunStart() (uint64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.recording == nil {
+		return 0, false
+	}
+	return e.recording.start, true
+}
+
+// beginRunLocked starts recording the match that just started.
+// MUST be called while e.mu is held.
+func (e *Engine) beginRunLocked() {
+	e.recording = nil
+	if e.recordRuns {
+		e.recording = &recording{run: Run{Start: e.snapshotLocked()}, start: e.State.Tick}
+	}
+}
+
+// recordInputLocked adds an action the engine is handling to the recording.
+// MUST be called while e.mu is held.
+func (e *Engine) recordInputLocked(a Action) {
+	if r := e.recording; r != nil {
+		r.run.Inputs = append(r.run.Inputs, Input{Tick: e.State.Tick - r.start, Action: a})
+	}
+}
+
+// endRunLocked finishes the recording once the match is over.
+// MUST be called while e.mu is held.
+func (e *Engine) endRunLocked() {
+	r := e.recording
+	if r == nil {
+		return
+	}
+	r.run.Ticks = e.State.Tick - r.start
+	for _, p := range e.State.Players {
+		r.run.Won = r.run.Won || p.Alive
+	}
+	e.lastRun = &r.run
+	e.recording = nil
+}
+
+// Ghost replays a Run alongside a live attempt at the same match.
+type Ghost struct {
+	e     *Engine
+	run   Run
+	ticks uint64 // Ticks replayed so far
+	next  int    // The next of run.Inputs to replay
+}
+
+// NewGhost sets up a replay of run from the start of its match.
+func NewGhost(run Run) (*Ghost, error) {
+	if !slices.IsSortedFunc(run.Inputs, func(a, b Input) int { return cmp.Compare(a.Tick, b.Tick) }) {
+		return nil, fmt.Errorf("ghost inputs are out of order")
+	}
+	raw, err := json.Marshal(run.Start)
+	if err != nil {
+		return nil, fmt.Errorf("encode ghost start: %w", err)
+	}
+	e := NewEngine(run.Start.Config)
+	if err := e.Restore(raw); err != nil {
+		return nil, fmt.Errorf("ghost: %w", err)
+	}
+	return &Ghost{e: e, run: run}, nil
+}
+
+// Run returns the run the ghost replays.
+func (g *Ghost) Run() Run {
+	return g.run
+}
+
+// AdvanceTo replays the run up to ticks into the match, or to its end if the
+// run was over sooner. A ghost only goes forward.
+func (g *Ghost) AdvanceTo(ticks uint64) {
+	for g.ticks < min(ticks, g.run.Ticks) {
+		g.ticks++
+		for g.next < len(g.run.Inputs) && g.run.Inputs[g.next].Tick <= g.ticks {
+			g.e.EnqueueAction(g.run.Inputs[g.next].Action)
+			g.next++
+		}
+		g.e.Step(1)
+	}
+}
+
+// Players returns the ghost's players still standing where the replay is.
+func (g *Ghost) Players() []Player {
+	g.e.mu.Lock()
+	defer g.e.mu.Unlock()
+	var players []Player
+	for _, p := range g.e.playersByID() {
+		if p.Alive {
+			players = append(players, *p)
+		}
+	}
+	return players
+}
+
+// DailyConfig returns the daily challenge for day, built on base: a solo
+// match against the monsters on a map seeded from the date, so it's the
+// same for everyone on the day and every attempt at it.
+func DailyConfig(base GameConfig, day time.Time) GameConfig {
+	y, m, d := day.Date()
+	base.Seed = int64(y*10000 + int(m)*100 + d)
+	return PracticeConfig(base)
+}
+
+// PracticeConfig returns base as a solo match against the monsters, on the
+// map base.Seed makes, for practising a seed with a ghost.
+func PracticeConfig(base GameConfig) GameConfig {
+	base.Mode = ModePvE
+	base.MaxPlayers = 1
+	base.FillWithBots = false
+	base.Rounds = 1
+	// Another attempt needs the same map, which a fresh room has and a
+	// reused one doesn't
+	base.LobbyReturn = 0
+	return base
+}
+
+// DefaultGhostDir returns where best runs are kept, in the user's config
+// directory.
+func DefaultGhostDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "bomberman-ghosts"
+	}
+	return filepath.Join(dir, "bomberman", "ghosts")
+}
+
+// LoadRun reads a run written by Run.Save.
+func LoadRun(path string) (Run, error) {
+	var run Run
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return run, err
+	}
+	if err := json.Unmarshal(raw, &run); err != nil {
+		return run, fmt.Errorf("parse run %s: %w", path, err)
+	}
+	return run, nil
+}
+
+// Save writes the run to path, for LoadRun.
+func (r Run) Save(path string) error {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encode run: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create ghost dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return fmt.Errorf("write run: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
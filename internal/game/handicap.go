@@ -0,0 +1,29 @@
+package game
+
+import "fmt"
+
+// SetHandicap overrides a player's starting stats from the next spawn on.
+// Handicaps can only change in the lobby, and must stay within the
+// config's caps.
+func (e *Engine) SetHandicap(id string, h Handicap) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.State.Status != StatusLobby {
+		return fmt.Errorf("handicaps can only change in the lobby")
+	}
+	p, ok := e.State.Players[id]
+	if !ok {
+		return fmt.Errorf("player %s not found", id)
+	}
+	if h.Bombs < 0 || h.Bombs > e.Config.MaxBombs ||
+		h.Range < 0 || h.Range > e.Config.MaxRange ||
+		h.Speed < 0 || h.Speed > e.Config.MaxSpeed {
+		return fmt.Errorf("handicap out of range: up to %d bombs, range %d and speed %d",
+			e.Config.MaxBombs, e.Config.MaxRange, e.Config.MaxSpeed)
+	}
+	p.Handicap = h
+	e.resetPlayer(p, p.Pos)
+	e.State.Version++
+	return nil
+}
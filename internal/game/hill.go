@@ -0,0 +1,84 @@
+package game
+
+// HillSize is the width and height of the king-of-the-hill zone in tiles.
+const HillSize = 3
+
+// HillSpawn returns the top-left tile of the hill: the middle of the board.
+func HillSpawn(width, height int) Position {
+	return Position{X: (width - HillSize) / 2, Y: (height - HillSize) / 2}
+}
+
+// clearHill empties the hill, pillars included, so it can be walked onto
+// from every side.
+func clearHill(board [][]TileType, config GameConfig) {
+	hill := HillSpawn(config.Width, config.Height)
+	for y := hill.Y; y < hill.Y+HillSize; y++ {
+		for x := hill.X; x < hill.X+HillSize; x++ {
+			if x > 0 && y > 0 && x < config.Width-1 && y < config.Height-1 {
+				board[y][x] = Empty
+			}
+		}
+	}
+}
+
+// Contains reports whether pos is inside the hill.
+func (h *Hill) Contains(pos Position) bool {
+	return pos.X >= h.Pos.X && pos.X < h.Pos.X+h.Size &&
+		pos.Y >= h.Pos.Y && pos.Y < h.Pos.Y+h.Size
+}
+
+// spawnHill puts the hill in the middle of the board for a king-of-the-hill
+// round and clears everyone's points.
+func (e *Engine) spawnHill() {
+	if !e.Config.HillMode {
+		return
+	}
+	e.State.Hill = &Hill{Pos: HillSpawn(e.State.Width, e.State.Height), Size: HillSize}
+	for _, p := range e.State.Players {
+		p.HillPoints = 0
+	}
+}
+
+// tickHill gives a point to every player on the hill when it isn't
+// contested: nobody else is on it but their teammates. The first player to
+// Config.HillScore points wins.
+func (e *Engine) tickHill() {
+	h := e.State.Hill
+	if h == nil {
+		return
+	}
+
+	var holders []*Player
+	for _, p := range e.State.Players {
+		if !p.Alive || !h.Contains(p.Pos) {
+			continue
+		}
+		if len(holders) > 0 && !e.teammates(p, holders[0]) {
+			return
+		}
+		holders = append(holders, p)
+	}
+
+	for _, p := range holders {
+		p.HillPoints++
+		if p.HillPoints >= max(e.Config.HillScore, 1) {
+			e.State.Status = StatusOver
+			e.setWinnerLocked(p.ID)
+		}
+	}
+}
+
+// hillTiebreak picks the player with the most hill points.
+// Returns "" if the top score is shared.
+func hillTiebreak(players []*Player) string {
+	best, winner := -1, ""
+	for _, p := range players {
+		switch {
+		case p.HillPoints > best:
+			best, winner = p.HillPoints, p.ID
+		case p.HillPoints == best:
+			winner = ""
+		}
+	}
+	return winner
+}
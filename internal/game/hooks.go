@@ -0,0 +1,110 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Hooks add custom rules to the engine, such as a host's script. They are
+// called during the tick with the engine locked, so they must not call the
+// Engine's methods; they change the game through the Rules they are given.
+type Hooks interface {
+	// Event is called for every event of a running match, on the tick it
+	// happens, including those the hooks cause themselves.
+	Event(r Rules, ev Event)
+
+	// Tick is called once every tick of a running match, after the built-in
+	// rules and before the win condition is checked.
+	Tick(r Rules)
+}
+
+// SetHooks installs custom rules; nil removes them. Must be called before
+// Run.
+func (e *Engine) SetHooks(h Hooks) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.hooks = h
+}
+
+// runHooksLocked passes the tick's new events and then the tick to the hooks.
+// MUST be called while e.mu is held.
+func (e *Engine) runHooksLocked() {
+	if e.hooks == nil {
+		return
+	}
+	r := Rules{e}
+	for ; e.hooked < len(e.events); e.hooked++ {
+		e.hooks.Event(r, e.events[e.hooked])
+	}
+	e.hooks.Tick(r)
+	for ; e.hooked < len(e.events); e.hooked++ {
+		e.hooks.Event(r, e.events[e.hooked])
+	}
+}
+
+// Rules is what Hooks may do to the game. It is only valid during the hook
+// call it was passed to.
+type Rules struct {
+	e *Engine
+}
+
+// State returns the live game state. Hooks may read it, but should change
+// it only through Rules.
+func (r Rules) State() *GameState {
+	return r.e.State
+}
+
+// Rand returns the game's randomness, so random rules play out the same for
+// the same seed, as the rest of the game does.
+func (r Rules) Rand() *rand.Rand {
+	return r.e.rng
+}
+
+// Kill kills a player outright, whatever their hearts or shields.
+func (r Rules) Kill(id string) error {
+	p, ok := r.e.State.Players[id]
+	if !ok {
+		return fmt.Errorf("player %s not found", id)
+	}
+	if p.Alive {
+		r.e.killPlayer(p, "")
+	}
+	return nil
+}
+
+// Win ends the round with id, or their team, winning.
+func (r Rules) Win(id string) error {
+	if _, ok := r.e.State.Players[id]; !ok {
+		return fmt.Errorf("player %s not found", id)
+	}
+	r.e.State.Status = StatusOver
+	r.e.setWinnerLocked(id)
+	return nil
+}
+
+// Draw ends the round with nobody winning.
+func (r Rules) Draw() {
+	r.e.State.Status = StatusOver
+	r.e.setWinnerLocked("")
+}
+
+// SetTile changes the tile at pos. The border walls can't be changed.
+func (r Rules) SetTile(pos Position, tile TileType) error {
+	if pos.X <= 0 || pos.Y <= 0 || pos.X >= r.e.State.Width-1 || pos.Y >= r.e.State.Height-1 {
+		return fmt.Errorf("%v is not inside the border walls", pos)
+	}
+	if tile == Fog {
+		return fmt.Errorf("fog is not a tile on the board")
+	}
+	r.e.State.Board[pos.Y][pos.X] = tile
+	return nil
+}
+
+// DropPickup puts a pickup of type typ at pos.
+func (r Rules) DropPickup(pos Position, typ PickupType) error {
+	if pos.X <= 0 || pos.Y <= 0 || pos.X >= r.e.State.Width-1 || pos.Y >= r.e.State.Height-1 {
+		return fmt.Errorf("%v is not inside the border walls", pos)
+	}
+	r.e.State.Pickups = append(r.e.State.Pickups, Pickup{Pos: pos, Type: typ})
+	return nil
+}
@@ -0,0 +1,68 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// SetCheckInvariants turns the invariant checker on or off. While it is on,
+// the state is checked after every tick, and whenever it breaks an invariant
+// the violations are logged with a full state dump for bug reports. It costs
+// a pass over the state every tick, so it is meant for debugging.
+func (e *Engine) SetCheckInvariants(on bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.checking = on
+	e.violations = ""
+}
+
+// invariantViolations returns every invariant the state breaks, in a stable
+// order: players inside walls, bombs miscounted and fires off the board.
+// MUST be called while e.mu is held.
+func (e *Engine) invariantViolations() []string {
+	var violations []string
+	s := e.State
+	live := make(map[string]int, len(s.Players))
+	for _, b := range s.Bombs {
+		live[b.OwnerID]++
+	}
+	for _, p := range e.playersByID() {
+		if p.Alive && s.inBounds(p.Pos) && s.Board[p.Pos.Y][p.Pos.X].Solid() {
+			violations = append(violations, fmt.Sprintf("player %s is inside a wall at %v", p.ID, p.Pos))
+		}
+		if p.BombsUsed != live[p.ID] {
+			violations = append(violations, fmt.Sprintf("player %s has BombsUsed %d but %d live bombs", p.ID, p.BombsUsed, live[p.ID]))
+		}
+	}
+	for _, f := range s.Fires {
+		if !s.inBounds(f.Pos) {
+			violations = append(violations, fmt.Sprintf("fire at %v is off the board", f.Pos))
+		}
+	}
+	return violations
+}
+
+// checkInvariantsLocked logs the state's invariant violations, if any, with
+// a dump of the state. The same violations are only logged once, so a broken
+// state doesn't flood the log every tick.
+// MUST be called while e.mu is held.
+func (e *Engine) checkInvariantsLocked() {
+	violations := strings.Join(e.invariantViolations(), "; ")
+	if violations == "" || violations == e.violations {
+		e.violations = violations
+		return
+	}
+	e.violations = violations
+	dump, err := json.Marshal(e.State)
+	if err != nil {
+		dump = []byte(err.Error())
+	}
+	slog.Error("Tick broke invariants", "tick", e.State.Tick, "violations", violations, "state", string(dump))
+}
+
+// inBounds reports whether pos is on the board.
+func (s *GameState) inBounds(pos Position) bool {
+	return pos.X >= 0 && pos.Y >= 0 && pos.X < s.Width && pos.Y < s.Height
+}
@@ -0,0 +1,26 @@
+package game
+
+import "time"
+
+// InvisibilityDuration is how long an invisibility pickup hides a player.
+const InvisibilityDuration = 10 * time.Second
+
+// invisibleTo reports whether p is hidden from the player with the given ID:
+// an invisible player is, unless that's themselves or a teammate.
+func (s *GameState) invisibleTo(p *Player, playerID string) bool {
+	if p.InvisibleUntil.IsZero() || !p.Alive || p.ID == playerID {
+		return false
+	}
+	viewer := s.Players[playerID]
+	return viewer == nil || p.TeamID == 0 || p.TeamID != viewer.TeamID
+}
+
+// tickInvisibility clears invisibility that has run out.
+func (e *Engine) tickInvisibility() {
+	now := e.now()
+	for _, p := range e.State.Players {
+		if !p.InvisibleUntil.IsZero() && !now.Before(p.InvisibleUntil) {
+			p.InvisibleUntil = time.Time{}
+		}
+	}
+}
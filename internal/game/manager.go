@@ -0,0 +1,141 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/amalg/go-bomberman/internal/crash"
+)
+
+// Manager hosts several matches at once in one process, each on its own
+// Engine and keyed by room ID. A room lives from Add or Create until it is
+// closed, which Leave does once the last player has left. The network layer
+// routes each client to its room's engine.
+type Manager struct {
+	mu      sync.Mutex
+	rooms   map[string]*Engine
+	onClose func(id string)
+}
+
+// NewManager returns a manager with no rooms.
+func NewManager() *Manager {
+	return &Manager{rooms: make(map[string]*Engine)}
+}
+
+// OnClose sets a callback invoked with a room's ID after it is closed, so
+// the network layer can let go of it too. Must be called before any room is
+// added.
+func (m *Manager) OnClose(fn func(id string)) {
+	m.onClose = fn
+}
+
+// Add hosts e as room id and starts its game loop. Callbacks such as OnTick
+// must be set on e before it is added.
+func (m *Manager) Add(id string, e *Engine) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.rooms[id]; exists {
+		return fmt.Errorf("room %s already exists", id)
+	}
+	m.rooms[id] = e
+	crash.Go(e.Run)
+	return nil
+}
+
+// Create hosts a new room id playing config's game, for rooms that need no
+// callbacks; see Add.
+func (m *Manager) Create(id string, config GameConfig) (*Engine, error) {
+	e := NewEngine(config)
+	if err := m.Add(id, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Room returns room id's engine, if the room exists.
+func (m *Manager) Room(id string) (*Engine, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.rooms[id]
+	return e, ok
+}
+
+// Rooms returns the IDs of every room, sorted.
+func (m *Manager) Rooms() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]string, 0, len(m.rooms))
+	for id := range m.rooms {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Start starts the match in room id.
+func (m *Manager) Start(id string) error {
+	e, ok := m.Room(id)
+	if !ok {
+		return fmt.Errorf("room %s not found", id)
+	}
+	return e.StartGame()
+}
+
+// Join adds a player to room id. Players should join and leave rooms
+// through the manager, so nobody joins a room as it closes.
+func (m *Manager) Join(id, playerID, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.rooms[id]
+	if !ok {
+		return fmt.Errorf("room %s not found", id)
+	}
+	return e.AddPlayer(playerID, name)
+}
+
+// Leave removes a player from room id, closing the room if they were the
+// last one in it.
+func (m *Manager) Leave(id, playerID string) {
+	m.mu.Lock()
+	e, ok := m.rooms[id]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	e.RemovePlayer(playerID)
+	empty := e.PlayerCount() == 0
+	if empty {
+		delete(m.rooms, id)
+	}
+	m.mu.Unlock()
+	if empty {
+		m.stop(id, e)
+	}
+}
+
+// Close stops room id's game loop and removes the room.
+func (m *Manager) Close(id string) {
+	m.mu.Lock()
+	e, ok := m.rooms[id]
+	delete(m.rooms, id)
+	m.mu.Unlock()
+	if ok {
+		m.stop(id, e)
+	}
+}
+
+// stop stops a room's game loop once it has been removed.
+func (m *Manager) stop(id string, e *Engine) {
+	e.Stop()
+	if m.onClose != nil {
+		m.onClose(id)
+	}
+}
+
+// CloseAll closes every room, for shutting the server down.
+func (m *Manager) CloseAll() {
+	for _, id := range m.Rooms() {
+		m.Close(id)
+	}
+}
@@ -0,0 +1,45 @@
+package game
+
+import "time"
+
+// Metrics is a snapshot of how the engine is keeping up, for the server to
+// log or export.
+type Metrics struct {
+	Ticks          uint64        // Ticks processed
+	LastTick       time.Duration // How long the last tick took to process
+	MaxTick        time.Duration // How long the slowest tick took
+	TickTime       time.Duration // How long every tick took together
+	LastActions    int           // Actions drained on the last tick
+	Actions        uint64        // Actions drained on every tick together
+	DroppedActions uint64        // Actions dropped because the queue was full
+
+	// ThrottledActions counts moves dropped for going over MaxMovesPerTick.
+	ThrottledActions uint64
+}
+
+// MeanTick returns how long a tick takes to process on average.
+func (m Metrics) MeanTick() time.Duration {
+	if m.Ticks == 0 {
+		return 0
+	}
+	return m.TickTime / time.Duration(m.Ticks)
+}
+
+// Metrics returns a snapshot of the engine's metrics.
+func (e *Engine) Metrics() Metrics {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	m := e.metrics
+	m.DroppedActions = e.dropped.Load()
+	return m
+}
+
+// recordTickLocked records a tick that started processing at start.
+// MUST be called while e.mu is held.
+func (e *Engine) recordTickLocked(start time.Time) {
+	d := time.Since(start)
+	e.metrics.Ticks++
+	e.metrics.LastTick = d
+	e.metrics.MaxTick = max(e.metrics.MaxTick, d)
+	e.metrics.TickTime += d
+}
@@ -0,0 +1,40 @@
+package game
+
+// MinesPerPickup is how many of a player's next bombs a mine pickup turns
+// into mines.
+const MinesPerPickup = 2
+
+// MineHideTicks is how long a new mine stays visible to opponents. It arms
+// as it disappears.
+const MineHideTicks = 20
+
+// armed reports whether mine b is live and hidden from its owner's
+// opponents.
+func (s *GameState) armed(b *Bomb) bool {
+	return b.Mine && s.Tick >= b.HiddenAt
+}
+
+// mineTriggered reports whether an opponent of the owner of armed mine b
+// stands on it.
+func (e *Engine) mineTriggered(b *Bomb) bool {
+	if !e.State.armed(b) {
+		return false
+	}
+	owner := e.State.Players[b.OwnerID]
+	for _, p := range e.State.Players {
+		if p.Alive && p.Pos == b.Pos && p.ID != b.OwnerID && (owner == nil || !e.teammates(p, owner)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hiddenFrom reports whether mine b is hidden from the player with the
+// given ID: armed mines are, unless that player or a teammate placed them.
+func (s *GameState) hiddenFrom(b *Bomb, playerID string) bool {
+	if !s.armed(b) || b.OwnerID == playerID {
+		return false
+	}
+	viewer, owner := s.Players[playerID], s.Players[b.OwnerID]
+	return viewer == nil || owner == nil || viewer.TeamID == 0 || viewer.TeamID != owner.TeamID
+}
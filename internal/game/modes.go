@@ -0,0 +1,228 @@
+package game
+
+import (
+	"cmp"
+	"time"
+)
+
+// Mode names a game mode: the rules a round is won by. See GameMode.
+type Mode string
+
+const (
+	ModeClassic      Mode = "classic"       // Last player standing
+	ModeTeams        Mode = "teams"         // Last team standing
+	ModeKOTH         Mode = "koth"          // King of the hill: first to HillScore points for holding the middle
+	ModeBattleRoyale Mode = "battle_royale" // Last player standing in a safe zone that closes in
+	ModeBoss         Mode = "boss"          // Co-op: everyone against a boss
+	ModePvE          Mode = "pve"           // Co-op: clear every monster
+	ModeCampaign     Mode = "campaign"      // Co-op: clear the Levels in turn, reaching each one's exit
+	ModeStun         Mode = "stun"          // Fire stuns instead of killing; most stuns when time runs out wins
+)
+
+// GameMode is the rules of a Mode: what it adds to a round, when the round
+// is over and who won it. New modes implement it and are listed in modes,
+// instead of adding special cases to the engine.
+//
+// Team mode isn't a GameMode of its own but a setting the other modes
+// honour, so teams can hold the hill or outlast the zone together; Mode
+// "teams" is classic with Config.TeamMode on.
+type GameMode interface {
+	// start sets up a new round, or campaign level, on a fresh board.
+	start(e *Engine)
+
+	// tick runs the mode's own rules for one tick of a running round.
+	tick(e *Engine)
+
+	// checkWin ends the round, with its winner, once it has been decided.
+	// alive are the players still in it, counting those about to respawn.
+	checkWin(e *Engine, alive []*Player)
+
+	// timeLimit is how long a round lasts; 0 for no limit.
+	timeLimit(e *Engine) time.Duration
+
+	// timeUp picks the winner among the survivors when the time limit runs
+	// out; "" for a draw.
+	timeUp(e *Engine, survivors []*Player) string
+}
+
+var modes = map[Mode]GameMode{
+	ModeClassic:      classic{},
+	ModeTeams:        classic{},
+	ModeKOTH:         koth{},
+	ModeBattleRoyale: battleRoyale{},
+	ModeBoss:         boss{},
+	ModePvE:          pve{},
+	ModeCampaign:     campaign{},
+	ModeStun:         stun{},
+}
+
+// normalizeMode sets config.Mode from the per-mode switches, such as
+// HillMode, when it is unset or classic, and then sets the switches to
+// match, so code reading either agrees.
+func normalizeMode(config GameConfig) GameConfig {
+	if config.Mode == "" || config.Mode == ModeClassic {
+		switch {
+		case config.Campaign:
+			config.Mode = ModeCampaign
+		case config.BossMode:
+			config.Mode = ModeBoss
+		case config.PvEMode:
+			config.Mode = ModePvE
+		case config.HillMode:
+			config.Mode = ModeKOTH
+		case config.BattleRoyale:
+			config.Mode = ModeBattleRoyale
+		case config.StunMode:
+			config.Mode = ModeStun
+		case config.TeamMode:
+			config.Mode = ModeTeams
+		default:
+			config.Mode = ModeClassic
+		}
+	}
+	if _, ok := modes[config.Mode]; !ok {
+		config.Mode = ModeClassic
+	}
+	config.Campaign = config.Mode == ModeCampaign
+	config.BossMode = config.Mode == ModeBoss
+	config.PvEMode = config.Mode == ModePvE
+	config.HillMode = config.Mode == ModeKOTH
+	config.BattleRoyale = config.Mode == ModeBattleRoyale
+	config.StunMode = config.Mode == ModeStun
+	config.TeamMode = config.TeamMode || config.Mode == ModeTeams
+	return config
+}
+
+// startRoundLocked sets up the mode and starts the clock for a round, or
+// campaign level, on a freshly reset board.
+// MUST be called while e.mu is held.
+func (e *Engine) startRoundLocked() {
+	e.mode.start(e)
+	e.startClockLocked()
+	e.spawnEnemies()
+}
+
+// classic is last player, or team, standing. The other modes build on it.
+type classic struct{}
+
+func (classic) start(e *Engine) {}
+
+func (classic) tick(e *Engine) {}
+
+func (classic) checkWin(e *Engine, alive []*Player) {
+	// In team mode the last team standing wins. A lone team plays on until
+	// everyone is dead, like a lone player.
+	if e.Config.TeamMode {
+		left := make(map[int]bool)
+		for _, p := range alive {
+			left[p.TeamID] = true
+		}
+		switch {
+		case len(alive) == 0:
+			e.resolveTieLocked()
+		case len(left) == 1 && e.teamsPlaying() > 1:
+			e.State.Status = StatusOver
+			e.setWinnerLocked(alive[0].ID)
+		}
+		return
+	}
+
+	switch len(alive) {
+	case 0:
+		// Everyone left died on the same tick
+		e.resolveTieLocked()
+	case 1:
+		// We have a winner, but only if there were multiple players
+		if len(e.State.Players) > 1 {
+			e.State.Status = StatusOver
+			e.State.Winner = alive[0].ID
+		}
+	}
+}
+
+func (classic) timeLimit(e *Engine) time.Duration {
+	return e.Config.MatchDuration
+}
+
+func (classic) timeUp(e *Engine, survivors []*Player) string {
+	switch e.Config.TimeUp {
+	case TimeUpKills:
+		return killsTiebreak(survivors)
+	case TimeUpWalls:
+		return wallsTiebreak(survivors)
+	}
+	return ""
+}
+
+// koth is king of the hill: holding the hill scores, and the first to
+// Config.HillScore wins. Being the last one standing still wins too.
+type koth struct{ classic }
+
+func (koth) start(e *Engine) { e.spawnHill() }
+
+func (koth) tick(e *Engine) { e.tickHill() }
+
+func (koth) timeUp(e *Engine, survivors []*Player) string {
+	// Holding the hill is the point of the mode, whatever Config.TimeUp says
+	return hillTiebreak(survivors)
+}
+
+// battleRoyale is classic in a zone that closes in.
+type battleRoyale struct{ classic }
+
+func (battleRoyale) start(e *Engine) { e.spawnZone() }
+
+func (battleRoyale) tick(e *Engine) { e.tickZone() }
+
+// boss is co-op: everyone wins when the boss dies and loses when the last
+// player does.
+type boss struct{ classic }
+
+func (boss) start(e *Engine) { e.spawnBoss() }
+
+func (boss) tick(e *Engine) { e.tickBoss() }
+
+func (m boss) checkWin(e *Engine, alive []*Player) {
+	b := e.State.Boss
+	if b == nil {
+		m.classic.checkWin(e, alive)
+		return
+	}
+	if !b.Alive || len(alive) == 0 {
+		e.State.Status = StatusOver
+	}
+}
+
+func (boss) timeUp(e *Engine, survivors []*Player) string { return "" }
+
+// pve is co-op as well: everyone wins when the last monster dies.
+type pve struct{ classic }
+
+func (pve) checkWin(e *Engine, alive []*Player) {
+	if !e.monstersLeft() || len(alive) == 0 {
+		e.State.Status = StatusOver
+	}
+}
+
+// campaign plays the Levels in turn; see checkLevelLocked.
+type campaign struct{ classic }
+
+func (campaign) start(e *Engine) {
+	e.State.Level = max(e.State.Level, 1)
+	e.loadLevelLocked()
+}
+
+func (campaign) checkWin(e *Engine, alive []*Player) { e.checkLevelLocked(alive) }
+
+// stun is classic where fire stuns rather than kills, so rounds are won on
+// stuns, counted as kills, when time runs out.
+type stun struct{ classic }
+
+func (stun) timeLimit(e *Engine) time.Duration {
+	// Nobody dies, so only the clock ends a stun mode round
+	return cmp.Or(e.Config.MatchDuration, StunModeDuration)
+}
+
+func (stun) timeUp(e *Engine, survivors []*Player) string {
+	return killsTiebreak(survivors)
+}
@@ -1,12 +1,19 @@
 package game
 
+import (
+	"cmp"
+	"slices"
+)
+
 // movePlayer attempts to move a player in the given direction.
-// Movement is blocked by hard walls, soft walls, bombs, and board edges.
-func (e *Engine) movePlayer(playerID string, dir Direction) {
+// Movement is blocked by hard walls, soft walls, bombs, other players and
+// board edges. Returns true if the player moved and is still alive.
+func (e *Engine) movePlayer(playerID string, dir Direction) bool {
 	p, ok := e.State.Players[playerID]
 	if !ok || !p.Alive {
-		return
+		return false
 	}
+	p.Facing = dir
 
 	newPos := p.Pos
 	switch dir {
@@ -23,38 +30,59 @@ func (e *Engine) movePlayer(playerID string, dir Direction) {
 	// Bounds check
 	if newPos.X < 0 || newPos.X >= e.State.Width ||
 		newPos.Y < 0 || newPos.Y >= e.State.Height {
-		return
+		return false
 	}
 
-	// Wall collision
-	tile := e.State.Board[newPos.Y][newPos.X]
-	if tile == HardWall || tile == SoftWall {
-		return
+	// Wall and barrel collision
+	if e.State.Board[newPos.Y][newPos.X].Solid() {
+		return false
+	}
+
+	// The boss is as solid as a wall
+	if e.bossCovers(newPos) {
+		return false
+	}
+
+	// So are other players, so two players never share a tile or pass
+	// through each other. When two want the same tile on one tick, the
+	// first to ask gets it; see drainActions. Bumping into a player passes
+	// curses on.
+	for _, other := range e.State.Players {
+		if other != p && other.Alive && other.Pos == newPos {
+			e.passCurses(p, other)
+			return false
+		}
 	}
 
-	// Bomb collision — players can't walk through bombs
-	// (except the bomb they just placed, which is handled by standing on it)
+	// Bomb collision — players can't walk through bombs (except one they
+	// placed and haven't stepped off yet, or a mine, which is walked onto),
+	// but players with the kick pickup send them sliding
 	for _, b := range e.State.Bombs {
-		if b.Pos == newPos {
-			return
+		if b.Pos == newPos && !b.Mine && !(b.OwnerOn && b.OwnerID == p.ID) {
+			if p.CanKick {
+				e.kickBomb(b, Position{X: newPos.X - p.Pos.X, Y: newPos.Y - p.Pos.Y})
+			}
+			return false
 		}
 	}
 
 	p.Pos = newPos
+	p.Sliding = e.State.Board[newPos.Y][newPos.X] == Ice
+	e.leaveBombs(p)
 
 	// Check if player walked into fire
 	for _, f := range e.State.Fires {
-		if f.Pos == newPos {
-			p.Alive = false
-			return
+		if f.Pos == newPos && e.hurtBy(p, f.OwnerID) {
+			e.burnPlayer(p, f.OwnerID)
+			return p.Alive
 		}
 	}
 
 	// Check if player walked into an enemy
 	for _, en := range e.State.Enemies {
 		if en.Alive && en.Pos == newPos {
-			p.Alive = false
-			return
+			e.killPlayer(p, "")
+			return false
 		}
 	}
 
@@ -63,17 +91,148 @@ func (e *Engine) movePlayer(playerID string, dir Direction) {
 		if pk.Pos == newPos {
 			switch pk.Type {
 			case PickupBomb:
-				if p.BombMax < MaxBombs {
+				if p.BombMax < e.Config.MaxBombs {
 					p.BombMax++
 				}
 			case PickupRange:
-				if p.BombRange < MaxRange {
+				if p.BombRange < e.Config.MaxRange {
 					p.BombRange++
 				}
+			case PickupAmmo:
+				p.Ammo = min(p.Ammo+AmmoPerPickup, MaxAmmo)
+			case PickupSpeed:
+				p.MoveSpeed = max(min(p.MoveSpeed+1, e.Config.MaxSpeed), p.MoveSpeed)
+			case PickupKick:
+				p.CanKick = true
+			case PickupShield:
+				p.InvulnerableUntil = e.now().Add(ShieldDuration)
+			case PickupSkull:
+				e.curse(p)
+			case PickupFullFire:
+				p.FullFire = true
+			case PickupLineBomb:
+				p.LineBomb = true
+			case PickupMine:
+				p.Mines += MinesPerPickup
+			case PickupCloak:
+				p.InvisibleUntil = e.now().Add(InvisibilityDuration)
 			}
+			e.emit(Event{Type: EventItemPickedUp, PlayerID: p.ID, Pos: pk.Pos, Pickup: pk.Type})
 			// Remove collected pickup
 			e.State.Pickups = append(e.State.Pickups[:i], e.State.Pickups[i+1:]...)
 			break
 		}
 	}
+
+	return true
+}
+
+// requestMove moves a player now if their move speed allows another move,
+// and otherwise keeps the move until it does. Only the latest move waits,
+// so held keys don't pile up a backlog. Sprinting moves cover two tiles.
+func (e *Engine) requestMove(playerID string, dir Direction) {
+	p, ok := e.State.Players[playerID]
+	if !ok || !p.Alive || p.Sliding || !p.StunnedUntil.IsZero() {
+		return
+	}
+	if p.moveCredit < e.Config.TickRate {
+		p.pendingMove, p.pendingSeq, p.hasPending = dir, e.actionSeq, true
+		return
+	}
+	p.moveCredit -= e.Config.TickRate
+	p.hasPending = false
+	if p.HasEffect(EffectReverse) {
+		dir = dir.Opposite()
+	}
+	if e.movePlayer(playerID, dir) && p.Sprinting {
+		e.movePlayer(playerID, dir)
+	}
+}
+
+// tickSliding moves every player sliding on ice one more tile, in player ID
+// order. A player stops once blocked or off the ice.
+func (e *Engine) tickSliding() {
+	for _, p := range e.playersByID() {
+		if p.Alive && p.Sliding && !e.movePlayer(p.ID, p.Facing) {
+			p.Sliding = false
+		}
+	}
+}
+
+// playersByID returns the players sorted by ID, for handling them in an
+// order that doesn't change from run to run.
+func (e *Engine) playersByID() []*Player {
+	players := make([]*Player, 0, len(e.State.Players))
+	for _, p := range e.State.Players {
+		players = append(players, p)
+	}
+	slices.SortFunc(players, func(a, b *Player) int { return cmp.Compare(a.ID, b.ID) })
+	return players
+}
+
+// leaveBombs makes p's bombs block p once p is off their tile.
+func (e *Engine) leaveBombs(p *Player) {
+	for _, b := range e.State.Bombs {
+		if b.OwnerID == p.ID && b.Pos != p.Pos {
+			b.OwnerOn = false
+		}
+	}
+}
+
+// tickMovement refills each player's move credit by their speed, so a
+// player with MoveSpeed s moves at most s times per second, and makes moves
+// that were waiting for it, in the order they were asked for.
+func (e *Engine) tickMovement() {
+	var ready []*Player
+	for _, p := range e.State.Players {
+		if !p.Alive {
+			continue
+		}
+		p.moveCredit = min(p.moveCredit+p.MoveSpeed, e.Config.TickRate)
+		if p.hasPending && p.moveCredit >= e.Config.TickRate {
+			ready = append(ready, p)
+		}
+	}
+	slices.SortFunc(ready, func(a, b *Player) int { return cmp.Compare(a.pendingSeq, b.pendingSeq) })
+	for _, p := range ready {
+		e.requestMove(p.ID, p.pendingMove)
+	}
+}
+
+// toggleSprint starts or stops a player's sprint.
+// A sprint can only start with at least SprintMinStamina.
+func (e *Engine) toggleSprint(playerID string) {
+	p, ok := e.State.Players[playerID]
+	if !ok || !p.Alive || !e.Config.SprintEnabled {
+		return
+	}
+	if p.Sprinting {
+		p.Sprinting = false
+		return
+	}
+	if p.Stamina >= SprintMinStamina {
+		p.Sprinting = true
+	}
+}
+
+// tickStamina drains stamina of sprinting players and regenerates the rest.
+// Sprinting stops when stamina runs out.
+func (e *Engine) tickStamina() {
+	if !e.Config.SprintEnabled {
+		return
+	}
+	for _, p := range e.State.Players {
+		if !p.Alive {
+			continue
+		}
+		if p.Sprinting {
+			p.Stamina -= SprintDrain
+			if p.Stamina <= 0 {
+				p.Stamina = 0
+				p.Sprinting = false
+			}
+		} else if p.Stamina < MaxStamina {
+			p.Stamina = min(p.Stamina+StaminaRegen, MaxStamina)
+		}
+	}
 }
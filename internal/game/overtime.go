@@ -0,0 +1,127 @@
+package game
+
+import (
+	"time"
+)
+
+// LavaSpreadTicks is how often, in ticks, creeping lava claims another tile.
+const LavaSpreadTicks = 10
+
+const (
+	// SkyID owns the bombs that fall in bomb rain overtime.
+	SkyID = "sky"
+
+	// BombRainEvery is the gap between the first falling bombs. Each bomb
+	// cuts the gap by a tenth, down to BombRainMinEvery.
+	BombRainEvery    = time.Second
+	BombRainMinEvery = 100 * time.Millisecond
+)
+
+// startOvertimeLocked sets when this round's overtime starts, if the config
+// has one.
+// MUST be called while e.mu is held.
+func (e *Engine) startOvertimeLocked() {
+	e.State.OvertimeAt = time.Time{}
+	e.rainAt, e.rainEvery = time.Time{}, 0
+	if e.Config.Overtime != "" && e.Config.Overtime != OvertimeNone {
+		e.State.OvertimeAt = e.now().Add(e.Config.LavaAfter)
+	}
+}
+
+// tickOvertime runs the config's overtime once the round has run past
+// Config.LavaAfter.
+func (e *Engine) tickOvertime() {
+	if e.State.OvertimeAt.IsZero() || e.now().Before(e.State.OvertimeAt) {
+		return
+	}
+	switch e.Config.Overtime {
+	case OvertimeLava:
+		e.tickLava()
+	case OvertimeBombRain:
+		e.tickBombRain()
+	}
+}
+
+// openTiles returns the tiles inside the border that overtime can claim:
+// anything walkable but the exit, the boss and permanent fire.
+func (e *Engine) openTiles() []Position {
+	burning := make(map[Position]bool, len(e.State.Fires))
+	for _, f := range e.State.Fires {
+		if f.Permanent {
+			burning[f.Pos] = true
+		}
+	}
+	var open []Position
+	for y := 1; y < e.State.Height-1; y++ {
+		for x := 1; x < e.State.Width-1; x++ {
+			pos := Position{X: x, Y: y}
+			if tile := e.State.Board[y][x]; !tile.Solid() && tile != ExitDoor && !burning[pos] && !e.bossCovers(pos) {
+				open = append(open, pos)
+			}
+		}
+	}
+	return open
+}
+
+// tickLava turns a random open tile into permanent fire every
+// LavaSpreadTicks, killing whoever stands there.
+func (e *Engine) tickLava() {
+	if e.State.Tick%LavaSpreadTicks != 0 {
+		return
+	}
+	open := e.openTiles()
+	if len(open) == 0 {
+		return
+	}
+
+	e.State.Fires = append(e.State.Fires, Fire{Pos: open[e.rng.Intn(len(open))], Permanent: true})
+	e.damagePlayersInFire()
+	e.damageEnemiesInFire()
+}
+
+// tickBombRain drops a bomb on a random open tile with nobody on it, at a
+// rate that keeps rising until the round ends.
+func (e *Engine) tickBombRain() {
+	now := e.now()
+	if e.rainAt.IsZero() {
+		e.rainAt, e.rainEvery = now, BombRainEvery
+	}
+	if now.Before(e.rainAt) {
+		return
+	}
+	e.rainAt = now.Add(e.rainEvery)
+	e.rainEvery = max(e.rainEvery*9/10, BombRainMinEvery)
+
+	taken := make(map[Position]bool)
+	for _, b := range e.State.Bombs {
+		taken[b.Pos] = true
+	}
+	for _, p := range e.State.Players {
+		if p.Alive {
+			taken[p.Pos] = true
+		}
+	}
+	for _, en := range e.State.Enemies {
+		if en.Alive {
+			taken[en.Pos] = true
+		}
+	}
+	var open []Position
+	for _, pos := range e.openTiles() {
+		if !taken[pos] {
+			open = append(open, pos)
+		}
+	}
+	if len(open) == 0 {
+		return
+	}
+
+	e.State.Bombs = append(e.State.Bombs, &Bomb{
+		OwnerID:     SkyID,
+		Pos:         open[e.rng.Intn(len(open))],
+		Range:       StartRange,
+		PlacedAt:    now,
+		ExpiresTick: e.State.Tick + e.ticksIn(e.Config.BombTimer),
+		FuseLeft:    e.Config.BombTimer,
+	})
+}
@@ -0,0 +1,28 @@
+package game
+
+import "fmt"
+
+// Pause freezes a running match: nothing moves and no timer runs down until
+// Resume. Input sent while paused is dropped.
+func (e *Engine) Pause() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.State.Status != StatusRunning {
+		return fmt.Errorf("no match in progress to pause")
+	}
+	e.State.Status = StatusPaused
+	e.State.Version++
+	return nil
+}
+
+// Resume carries on a paused match where it left off.
+func (e *Engine) Resume() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.State.Status != StatusPaused {
+		return fmt.Errorf("the match isn't paused")
+	}
+	e.State.Status = StatusRunning
+	e.State.Version++
+	return nil
+}
@@ -0,0 +1,70 @@
+package game
+
+import "time"
+
+// RoundIntermission is the pause between the rounds of a best-of-N match.
+const RoundIntermission = 3 * time.Second
+
+// roundsToWin is how many round wins take a best-of-N match.
+func (e *Engine) roundsToWin() int {
+	return max(e.Config.Rounds, 1)/2 + 1
+}
+
+// endRoundLocked scores a finished round, then either starts the
+// intermission before the next round or ends the match with the overall
+// winner in State.Winner. A match whose rounds run out without a majority
+// goes to the player with the most round wins, or is a draw.
+// MUST be called while e.mu is held.
+func (e *Engine) endRoundLocked() {
+	for _, p := range e.State.Players {
+		if p.ID == e.State.Winner || (e.State.WinningTeam != 0 && p.TeamID == e.State.WinningTeam) {
+			p.RoundScore++
+		}
+	}
+
+	// The campaign plays its levels instead of rounds
+	if e.Config.Rounds > 1 && e.State.Level == 0 {
+		leader, best, tied := "", 0, false
+		for id, p := range e.State.Players {
+			switch {
+			case p.RoundScore > best:
+				leader, best, tied = id, p.RoundScore, false
+			case p.RoundScore == best:
+				// Teammates share their round wins
+				if l := e.State.Players[leader]; l == nil || !e.teammates(p, l) {
+					tied = true
+				}
+			}
+		}
+		if best < e.roundsToWin() && e.State.Round < e.Config.Rounds {
+			e.State.Status = StatusIntermission
+			e.nextRoundAt = e.now().Add(RoundIntermission)
+			return
+		}
+		if tied {
+			leader = ""
+		}
+		e.setWinnerLocked(leader)
+	}
+
+	e.State.Status = StatusOver
+	e.overAt = e.now()
+	e.emit(Event{Type: EventGameOver, Winner: e.State.Winner, WinningTeam: e.State.WinningTeam})
+}
+
+// nextRoundLocked starts the next round on a fresh board and clock. Round
+// wins, kills and walls destroyed carry over; everything else is reset.
+// MUST be called while e.mu is held.
+func (e *Engine) nextRoundLocked() {
+	e.resetBoardLocked()
+	spawns := SpawnPositions(e.Config.Width, e.Config.Height)
+	for _, p := range e.State.Players {
+		e.resetPlayer(p, spawns[p.Color%len(spawns)])
+	}
+	e.nextRoundAt = time.Time{}
+	e.discardActionsLocked()
+
+	e.State.Round++
+	e.State.Status = StatusRunning
+	e.startRoundLocked()
+}
@@ -0,0 +1,193 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SaveFile is a match in progress written to disk by Engine.Save.
+type SaveFile struct {
+	SavedAt time.Time  `json:"saved_at"`
+	Config  GameConfig `json:"config"`
+	State   GameState  `json:"state"`
+	Exit    *Position  `json:"exit,omitempty"`  // Campaign: the hidden exit, kept off the wire
+	Traps   []Position `json:"traps,omitempty"` // Soft walls hiding a spike trap, kept off the wire
+}
+
+// DefaultSavePath returns the save location in the user's config directory.
+func DefaultSavePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "bomberman-save.json"
+	}
+	return filepath.Join(dir, "bomberman", "save.json")
+}
+
+// Save writes the running match to path so it can be resumed with LoadSave.
+func (e *Engine) Save(path string) error {
+	e.mu.Lock()
+	if e.State.Status != StatusRunning && e.State.Status != StatusPaused {
+		e.mu.Unlock()
+		return fmt.Errorf("no match in progress")
+	}
+	save := SaveFile{
+		SavedAt: e.now(),
+		Config:  e.Config,
+		State:   e.copyStateLocked(),
+	}
+	if e.State.Level > 0 {
+		exit := e.exit
+		save.Exit = &exit
+	}
+	for pos := range e.traps {
+		save.Traps = append(save.Traps, pos)
+	}
+	e.mu.Unlock()
+
+	raw, err := json.MarshalIndent(save, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode save: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create save dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return fmt.Errorf("write save: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadSave creates an engine from a save written by Engine.Save.
+//
+// The match waits in the lobby until the host starts it again. Players who
+// join with the name of a saved player reclaim that character; saved players
+// nobody reclaimed are dropped when the match resumes.
+func LoadSave(path string) (*Engine, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read save: %w", err)
+	}
+	var save SaveFile
+	if err := json.Unmarshal(raw, &save); err != nil {
+		return nil, fmt.Errorf("parse save %s: %w", path, err)
+	}
+
+	if save.Config.MaxBombs == 0 {
+		// Saved before starting stats and caps were configurable
+		defaults := DefaultConfig()
+		save.Config.StartBombs, save.Config.StartRange = defaults.StartBombs, defaults.StartRange
+		save.Config.MaxBombs, save.Config.MaxRange, save.Config.MaxSpeed = defaults.MaxBombs, defaults.MaxRange, defaults.MaxSpeed
+	}
+	e := NewEngine(save.Config)
+	state := save.State
+	state.Status = StatusLobby
+	if state.Players == nil {
+		state.Players = make(map[string]*Player)
+	}
+	e.State = &state
+	e.savedAt = save.SavedAt
+	// Carry on the saved match's clock, so the time spent waiting to resume
+	// is a whole number of ticks
+	e.epoch = save.SavedAt.Add(-time.Duration(state.Tick) * e.tickDuration())
+	if save.Exit != nil {
+		e.exit = *save.Exit
+	}
+	e.traps = make(map[Position]bool, len(save.Traps))
+	for _, pos := range save.Traps {
+		e.traps[pos] = true
+	}
+	e.unclaimed = make(map[string]bool, len(state.Players))
+	for id, p := range state.Players {
+		e.unclaimed[id] = true
+		if p.MoveSpeed == 0 {
+			p.MoveSpeed = StartSpeed // Saved before move speed existed
+		}
+	}
+	return e, nil
+}
+
+// Resuming reports whether the engine holds a loaded save that hasn't been
+// started yet.
+func (e *Engine) Resuming() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !e.savedAt.IsZero()
+}
+
+// reclaimLocked hands the unclaimed saved player called name over to id.
+// Returns false if there is no such player.
+// MUST be called while e.mu is held.
+func (e *Engine) reclaimLocked(id, name string) bool {
+	for oldID := range e.unclaimed {
+		p := e.State.Players[oldID]
+		if p.Name != name {
+			continue
+		}
+		delete(e.unclaimed, oldID)
+		delete(e.State.Players, oldID)
+		p.ID = id
+		e.State.Players[id] = p
+		for _, b := range e.State.Bombs {
+			if b.OwnerID == oldID {
+				b.OwnerID = id
+			}
+		}
+		if e.State.Winner == oldID {
+			e.State.Winner = id
+		}
+		return true
+	}
+	return false
+}
+
+// resumeLocked continues a loaded save: unclaimed players are dropped and
+// timers pick up where they were when the match was saved.
+// MUST be called while e.mu is held.
+func (e *Engine) resumeLocked() {
+	for id := range e.unclaimed {
+		delete(e.State.Players, id)
+	}
+	e.unclaimed = nil
+
+	shift := e.now().Sub(e.savedAt)
+	ticks := uint64(max(shift/e.tickDuration(), 0))
+	for _, b := range e.State.Bombs {
+		b.PlacedAt = b.PlacedAt.Add(shift)
+		b.ExpiresTick += ticks
+	}
+	for i := range e.State.Fires {
+		e.State.Fires[i].ExpiresTick += ticks
+	}
+	for _, p := range e.State.Players {
+		if !p.InvulnerableUntil.IsZero() {
+			p.InvulnerableUntil = p.InvulnerableUntil.Add(shift)
+		}
+		if !p.InvisibleUntil.IsZero() {
+			p.InvisibleUntil = p.InvisibleUntil.Add(shift)
+		}
+		if !p.StunnedUntil.IsZero() {
+			p.StunnedUntil = p.StunnedUntil.Add(shift)
+		}
+		if !p.RespawnAt.IsZero() {
+			p.RespawnAt = p.RespawnAt.Add(shift)
+		}
+		for i := range p.Effects {
+			p.Effects[i].ExpiresAt = p.Effects[i].ExpiresAt.Add(shift)
+		}
+	}
+	if !e.State.OvertimeAt.IsZero() {
+		e.State.OvertimeAt = e.State.OvertimeAt.Add(shift)
+	}
+	if e.State.TimeLeft > 0 {
+		e.endsAt = e.now().Add(e.State.TimeLeft)
+	}
+	if z := e.State.Zone; z != nil && z.ShrinkIn > 0 {
+		e.zoneShrinksAt = e.now().Add(z.ShrinkIn)
+	}
+	e.savedAt = time.Time{}
+	e.State.Status = StatusRunning
+}
@@ -0,0 +1,283 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Snapshot is everything the engine holds at the end of a tick, for crash
+// recovery, handing a match to another host and debugging dumps. Unlike a
+// SaveFile, which only keeps what a resumed match needs, it includes what the
+// engine keeps off the wire, so a restored engine picks up exactly where the
+// snapshot was taken.
+//
+// Its times are engine times, which count ticks from Epoch rather than
+// following the wall clock (see Engine.now), so timers run out on the same
+// tick however much later, or wherever, the snapshot is restored.
+type Snapshot struct {
+	Config GameConfig `json:"config"`
+	State  GameState  `json:"state"`
+	Epoch  time.Time  `json:"epoch"` // Engine time at tick 0
+
+	Seed  int64  `json:"seed"`  // The game's random seed, Config.Seed or the one picked for it
+	Draws uint64 `json:"draws"` // Random numbers drawn from Seed so far
+
+	Players map[string]PlayerSnapshot `json:"players,omitempty"` // What State leaves out of each player
+
+	StartAt       time.Time     `json:"start_at"`
+	NextRoundAt   time.Time     `json:"next_round_at"`
+	EndsAt        time.Time     `json:"ends_at"`
+	OverAt        time.Time     `json:"over_at"`
+	ZoneShrinksAt time.Time     `json:"zone_shrinks_at"`
+	RainAt        time.Time     `json:"rain_at"`
+	RainEvery     time.Duration `json:"rain_every,omitempty"`
+
+	Exit      Position   `json:"exit"`
+	Traps     []Position `json:"traps,omitempty"`
+	SavedAt   time.Time  `json:"saved_at"`            // A loaded save's, while it waits to resume
+	Unclaimed []string   `json:"unclaimed,omitempty"` // Saved players nobody has reclaimed yet
+	ActionSeq uint64     `json:"action_seq"`
+}
+
+// Bounds Restore holds a snapshot to. It replays the snapshot's random draws
+// one by one to bring the engine's randomness back, so it refuses more than
+// maxSnapshotDraws, which a match takes days to draw, rather than hang.
+const (
+	minSnapshotSize  = 3 // The smallest board with a spawn
+	maxSnapshotDraws = 1 << 28
+)
+
+// PlayerSnapshot is the part of a player kept off the wire.
+type PlayerSnapshot struct {
+	MoveCredit  int       `json:"move_credit"`
+	PendingMove Direction `json:"pending_move"`
+	PendingSeq  uint64    `json:"pending_seq"`
+	HasPending  bool      `json:"has_pending,omitempty"`
+	PendingAck  uint32    `json:"pending_ack,omitempty"`
+	HandledSeq  uint32    `json:"handled_seq,omitempty"`
+}
+
+// countingSource is a random source that counts the numbers drawn from it,
+// so a snapshot can bring the engine's randomness back to the same point.
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
+
+// Snapshot returns the engine's full state as JSON, for Restore.
+func (e *Engine) Snapshot() ([]byte, error) {
+	e.mu.Lock()
+	snap := e.snapshotLocked()
+	e.mu.Unlock()
+
+	raw, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode snapshot: %w", err)
+	}
+	return raw, nil
+}
+
+// snapshotLocked takes a Snapshot of the engine as it is.
+// MUST be called while e.mu is held.
+func (e *Engine) snapshotLocked() Snapshot {
+	snap := Snapshot{
+		Config:        e.Config,
+		State:         e.copyStateLocked(),
+		Epoch:         e.epoch,
+		Seed:          e.seed,
+		Draws:         e.source.draws,
+		Players:       make(map[string]PlayerSnapshot, len(e.State.Players)),
+		StartAt:       e.startAt,
+		NextRoundAt:   e.nextRoundAt,
+		EndsAt:        e.endsAt,
+		OverAt:        e.overAt,
+		ZoneShrinksAt: e.zoneShrinksAt,
+		RainAt:        e.rainAt,
+		RainEvery:     e.rainEvery,
+		Exit:          e.exit,
+		SavedAt:       e.savedAt,
+		ActionSeq:     e.actionSeq,
+	}
+	for id, p := range e.State.Players {
+		snap.Players[id] = PlayerSnapshot{
+			MoveCredit:  p.moveCredit,
+			PendingMove: p.pendingMove,
+			PendingSeq:  p.pendingSeq,
+			HasPending:  p.hasPending,
+			PendingAck:  p.pendingAck,
+			HandledSeq:  p.handledSeq,
+		}
+	}
+	for pos := range e.traps {
+		snap.Traps = append(snap.Traps, pos)
+	}
+	for id := range e.unclaimed {
+		snap.Unclaimed = append(snap.Unclaimed, id)
+	}
+	return snap
+}
+
+// Restore replaces the engine's game with a snapshot taken by Snapshot, on
+// this engine or another. Callbacks and hooks stay as they are; actions
+// queued but not yet handled are dropped.
+func (e *Engine) Restore(data []byte) error {
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("parse snapshot: %w", err)
+	}
+	snap.Config = normalizeConfig(snap.Config)
+	state := snap.State
+	if state.Players == nil {
+		state.Players = make(map[string]*Player)
+	}
+	if err := checkSnapshot(&snap); err != nil {
+		return err
+	}
+	for id, p := range state.Players {
+		ps := snap.Players[id]
+		p.moveCredit, p.pendingMove, p.pendingSeq, p.hasPending = ps.MoveCredit, ps.PendingMove, ps.PendingSeq, ps.HasPending
+		p.pendingAck, p.handledSeq = ps.PendingAck, ps.HandledSeq
+	}
+	source := newCountingSource(snap.Seed)
+	for range snap.Draws {
+		source.Uint64()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.discardActionsLocked()
+	e.Config = snap.Config
+	e.mode = modes[e.Config.Mode]
+	e.drops = dropTable(e.Config)
+	// Versions only go up, or the restored state could pass for one from
+	// before it
+	state.Version = e.State.Version + 1
+	e.State = &state
+	e.epoch = snap.Epoch
+	e.seed, e.source, e.rng = snap.Seed, source, rand.New(source)
+	e.startAt = snap.StartAt
+	e.nextRoundAt = snap.NextRoundAt
+	e.endsAt = snap.EndsAt
+	e.overAt = snap.OverAt
+	e.zoneShrinksAt = snap.ZoneShrinksAt
+	e.rainAt, e.rainEvery = snap.RainAt, snap.RainEvery
+	e.exit = snap.Exit
+	e.traps = make(map[Position]bool, len(snap.Traps))
+	for _, pos := range snap.Traps {
+		e.traps[pos] = true
+	}
+	e.savedAt = snap.SavedAt
+	e.unclaimed = nil
+	if len(snap.Unclaimed) > 0 {
+		e.unclaimed = make(map[string]bool, len(snap.Unclaimed))
+		for _, id := range snap.Unclaimed {
+			e.unclaimed[id] = true
+		}
+	}
+	e.actionSeq = snap.ActionSeq
+	e.events, e.hooked = nil, 0
+	e.recording = nil
+	return nil
+}
+
+// checkSnapshot reports the first thing in snap that the engine would trip
+// over: a board that isn't the config's size or has a row of the wrong
+// width, anything placed off the board, a player color with no spawn, or
+// more random draws than Restore will replay.
+func checkSnapshot(snap *Snapshot) error {
+	state := &snap.State
+	if state.Width != snap.Config.Width || state.Height != snap.Config.Height {
+		return fmt.Errorf("snapshot board is %dx%d, but its config's is %dx%d",
+			state.Width, state.Height, snap.Config.Width, snap.Config.Height)
+	}
+	if min(state.Width, state.Height) < minSnapshotSize {
+		return fmt.Errorf("snapshot board is %dx%d, too small to play on", state.Width, state.Height)
+	}
+	if snap.Draws > maxSnapshotDraws {
+		return fmt.Errorf("snapshot has %d random draws, more than the %d Restore replays", snap.Draws, maxSnapshotDraws)
+	}
+	if len(state.Board) != state.Height {
+		return fmt.Errorf("snapshot board isn't %dx%d", state.Width, state.Height)
+	}
+	for y, row := range state.Board {
+		if len(row) != state.Width {
+			return fmt.Errorf("snapshot board isn't %dx%d: row %d is %d wide", state.Width, state.Height, y, len(row))
+		}
+	}
+	offBoard := func(what string, pos Position) error {
+		return fmt.Errorf("snapshot has %s off the board at %v", what, pos)
+	}
+	for id, p := range state.Players {
+		if p == nil {
+			return fmt.Errorf("snapshot has an empty player %s", id)
+		}
+		if !state.inBounds(p.Pos) {
+			return offBoard("player "+id, p.Pos)
+		}
+		if spawns := len(SpawnPositions(state.Width, state.Height)); p.Color < 0 || p.Color >= spawns {
+			return fmt.Errorf("snapshot has player %s with color %d, but the board has %d spawns", id, p.Color, spawns)
+		}
+	}
+	for _, b := range state.Bombs {
+		if b == nil {
+			return fmt.Errorf("snapshot has an empty bomb")
+		}
+		if !state.inBounds(b.Pos) {
+			return offBoard("a bomb", b.Pos)
+		}
+	}
+	for _, f := range state.Fires {
+		if !state.inBounds(f.Pos) {
+			return offBoard("a fire", f.Pos)
+		}
+	}
+	for _, en := range state.Enemies {
+		if en == nil {
+			return fmt.Errorf("snapshot has an empty enemy")
+		}
+		if !state.inBounds(en.Pos) {
+			return offBoard("enemy "+en.ID, en.Pos)
+		}
+	}
+	for _, pk := range state.Pickups {
+		if !state.inBounds(pk.Pos) {
+			return offBoard("a pickup", pk.Pos)
+		}
+	}
+	for _, pos := range snap.Traps {
+		if !state.inBounds(pos) {
+			return offBoard("a trap", pos)
+		}
+	}
+	if state.Boss != nil && !state.inBounds(state.Boss.Pos) {
+		return offBoard("the boss", state.Boss.Pos)
+	}
+	if state.Hill != nil && !state.inBounds(state.Hill.Pos) {
+		return offBoard("the hill", state.Hill.Pos)
+	}
+	if !state.inBounds(snap.Exit) {
+		return offBoard("the exit", snap.Exit)
+	}
+	return nil
+}
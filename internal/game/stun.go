@@ -0,0 +1,103 @@
+package game
+
+import (
+	"cmp"
+	"time"
+)
+
+const (
+	// StunDuration is how long fire stuns a player in stun mode.
+	StunDuration = 3 * time.Second
+
+	// StunModeDuration is the length of a stun mode round without a
+	// Config.MatchDuration; the round is won on score when it runs out.
+	StunModeDuration = 3 * time.Minute
+
+	// scatterRadius is how far from a stunned player their dropped
+	// power-ups land, in tiles.
+	scatterRadius = 3
+)
+
+// stunPlayer is what fire does to p in stun mode instead of killing them:
+// p can't move or bomb for StunDuration and drops half their power-ups
+// nearby. Stunning an opponent scores like a kill. A player who is already
+// stunned, or protected after a stun, isn't stunned again.
+func (e *Engine) stunPlayer(p *Player, byID string) {
+	if !p.StunnedUntil.IsZero() || e.invulnerable(p) {
+		return
+	}
+	p.StunnedUntil = e.now().Add(StunDuration)
+	p.Sprinting = false
+	if by, ok := e.State.Players[byID]; ok && byID != p.ID && !e.teammates(p, by) {
+		by.Kills++
+	}
+
+	var drops []PickupType
+	bombs := (p.BombMax - p.BombsUsed) / 2
+	p.BombMax -= bombs
+	for range bombs {
+		drops = append(drops, PickupBomb)
+	}
+	ranges := max(p.BombRange-cmp.Or(p.Handicap.Range, e.Config.StartRange)+1, 0) / 2
+	p.BombRange -= ranges
+	for range ranges {
+		drops = append(drops, PickupRange)
+	}
+	speeds := max(p.MoveSpeed-cmp.Or(p.Handicap.Speed, StartSpeed)+1, 0) / 2
+	p.MoveSpeed -= speeds
+	for range speeds {
+		drops = append(drops, PickupSpeed)
+	}
+	if p.CanKick {
+		p.CanKick = false
+		drops = append(drops, PickupKick)
+	}
+	e.scatterPickups(p.Pos, drops)
+}
+
+// scatterPickups drops pickups on random open tiles within scatterRadius of
+// around. Pickups that don't find a free tile are lost.
+func (e *Engine) scatterPickups(around Position, drops []PickupType) {
+	if len(drops) == 0 {
+		return
+	}
+	taken := make(map[Position]bool)
+	for _, pk := range e.State.Pickups {
+		taken[pk.Pos] = true
+	}
+	for _, b := range e.State.Bombs {
+		taken[b.Pos] = true
+	}
+	for _, f := range e.State.Fires {
+		taken[f.Pos] = true
+	}
+
+	var open []Position
+	for y := around.Y - scatterRadius; y <= around.Y+scatterRadius; y++ {
+		for x := around.X - scatterRadius; x <= around.X+scatterRadius; x++ {
+			pos := Position{X: x, Y: y}
+			if x <= 0 || y <= 0 || x >= e.State.Width-1 || y >= e.State.Height-1 ||
+				pos == around || taken[pos] || e.State.Board[y][x] != Empty {
+				continue
+			}
+			open = append(open, pos)
+		}
+	}
+	e.rng.Shuffle(len(open), func(i, j int) { open[i], open[j] = open[j], open[i] })
+	for i, typ := range drops[:min(len(drops), len(open))] {
+		e.State.Pickups = append(e.State.Pickups, Pickup{Pos: open[i], Type: typ})
+	}
+}
+
+// tickStuns ends stuns that have run out. A player coming round gets their
+// hearts back and SpawnProtection to get out of the fire that stunned them.
+func (e *Engine) tickStuns() {
+	now := e.now()
+	for _, p := range e.State.Players {
+		if !p.StunnedUntil.IsZero() && !now.Before(p.StunnedUntil) {
+			p.StunnedUntil = time.Time{}
+			p.HP = max(e.Config.Hearts, 1)
+			p.InvulnerableUntil = now.Add(SpawnProtection)
+		}
+	}
+}
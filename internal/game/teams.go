@@ -0,0 +1,80 @@
+package game
+
+import "fmt"
+
+// TeamCount is the number of teams in team mode.
+const TeamCount = 2
+
+// smallestTeamLocked returns the team with the fewest players, so joining
+// players are spread evenly.
+// MUST be called while e.mu is held.
+func (e *Engine) smallestTeamLocked() int {
+	size := make([]int, TeamCount+1)
+	for _, p := range e.State.Players {
+		if p.TeamID > 0 && p.TeamID <= TeamCount {
+			size[p.TeamID]++
+		}
+	}
+	best := 1
+	for t := 2; t <= TeamCount; t++ {
+		if size[t] < size[best] {
+			best = t
+		}
+	}
+	return best
+}
+
+// SwitchTeam moves a player to the next team. Teams can only change in the
+// lobby.
+func (e *Engine) SwitchTeam(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.Config.TeamMode {
+		return fmt.Errorf("team mode is off")
+	}
+	if e.State.Status != StatusLobby {
+		return fmt.Errorf("teams can only change in the lobby")
+	}
+	p, ok := e.State.Players[id]
+	if !ok {
+		return fmt.Errorf("player %s not found", id)
+	}
+	p.TeamID = p.TeamID%TeamCount + 1
+	e.State.Version++
+	return nil
+}
+
+// teammates reports whether a and b are different players on the same team.
+func (e *Engine) teammates(a, b *Player) bool {
+	return e.Config.TeamMode && a != b && a.TeamID != 0 && a.TeamID == b.TeamID
+}
+
+// teamsPlaying counts the teams with at least one player.
+func (e *Engine) teamsPlaying() int {
+	teams := make(map[int]bool)
+	for _, p := range e.State.Players {
+		teams[p.TeamID] = true
+	}
+	return len(teams)
+}
+
+// hurtBy reports whether fire from ownerID's bomb hurts p: not while p is
+// invulnerable, nor from a teammate's bomb unless friendly fire is on.
+func (e *Engine) hurtBy(p *Player, ownerID string) bool {
+	if e.invulnerable(p) {
+		return false
+	}
+	owner, ok := e.State.Players[ownerID]
+	return !ok || e.Config.FriendlyFire || !e.teammates(p, owner)
+}
+
+// setWinnerLocked records the player with the given ID as the winner, or
+// in team mode their team. An empty ID records a draw.
+// MUST be called while e.mu is held.
+func (e *Engine) setWinnerLocked(id string) {
+	e.State.Winner, e.State.WinningTeam = id, 0
+	if p, ok := e.State.Players[id]; ok && e.Config.TeamMode && p.TeamID != 0 {
+		e.State.Winner, e.State.WinningTeam = "", p.TeamID
+	}
+}
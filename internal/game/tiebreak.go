@@ -0,0 +1,98 @@
+package game
+
+// resolveTieLocked ends a game where every remaining player died on the
+// same tick, applying the configured tiebreaker.
+// MUST be called while e.mu is held.
+func (e *Engine) resolveTieLocked() {
+	tied := e.lastToDie()
+	winner := ""
+
+	if len(e.State.Players) > 1 {
+		switch e.Config.Tiebreaker {
+		case TiebreakBombOwner:
+			winner = bombOwnerTiebreak(tied)
+		case TiebreakKills:
+			winner = killsTiebreak(tied)
+		case TiebreakSuddenDeath:
+			if len(tied) > 1 {
+				e.startSuddenDeathLocked(tied)
+				return
+			}
+		}
+	}
+
+	e.State.Status = StatusOver
+	e.setWinnerLocked(winner)
+}
+
+// lastToDie returns the players who died on the most recent death tick.
+func (e *Engine) lastToDie() []*Player {
+	var last uint64
+	for _, p := range e.State.Players {
+		if p.DiedAt > last {
+			last = p.DiedAt
+		}
+	}
+	var tied []*Player
+	for _, p := range e.State.Players {
+		if p.DiedAt == last {
+			tied = append(tied, p)
+		}
+	}
+	return tied
+}
+
+// bombOwnerTiebreak picks the tied player whose bombs killed the others.
+// Returns "" if the kills came from more than one tied player, or none.
+func bombOwnerTiebreak(tied []*Player) string {
+	ids := make(map[string]bool, len(tied))
+	for _, p := range tied {
+		ids[p.ID] = true
+	}
+	owner := ""
+	for _, p := range tied {
+		if !ids[p.KilledBy] {
+			continue
+		}
+		if owner != "" && owner != p.KilledBy {
+			return ""
+		}
+		owner = p.KilledBy
+	}
+	return owner
+}
+
+// killsTiebreak picks the tied player with the most kills.
+// Returns "" if the top score is shared.
+func killsTiebreak(tied []*Player) string {
+	best, winner := -1, ""
+	for _, p := range tied {
+		switch {
+		case p.Kills > best:
+			best, winner = p.Kills, p.ID
+		case p.Kills == best:
+			winner = ""
+		}
+	}
+	return winner
+}
+
+// startSuddenDeathLocked replays the match on a fresh board with only the
+// tied players alive. Everyone else stays out.
+// MUST be called while e.mu is held.
+func (e *Engine) startSuddenDeathLocked(tied []*Player) {
+	e.State.Board = NewBoard(e.Config, e.rng)
+	e.hideTrapsLocked()
+	e.clearBombsLocked()
+	e.State.Fires = make([]Fire, 0)
+	e.State.Enemies = make([]*Enemy, 0)
+	e.State.Pickups = make([]Pickup, 0)
+	e.State.SuddenDeath = true
+	e.spawnZone()
+
+	spawns := SpawnPositions(e.Config.Width, e.Config.Height)
+	for _, p := range tied {
+		e.resetPlayer(p, spawns[p.Color%len(spawns)])
+		p.Lives = 1
+	}
+}
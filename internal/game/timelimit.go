@@ -0,0 +1,53 @@
+package game
+
+import "time"
+
+// startClockLocked starts the time limit for a round, if there is one, and
+// the overtime timer.
+// MUST be called while e.mu is held.
+func (e *Engine) startClockLocked() {
+	e.startOvertimeLocked()
+	e.endsAt = time.Time{}
+	e.State.TimeLeft = 0
+	if duration := e.mode.timeLimit(e); duration > 0 {
+		e.endsAt = e.now().Add(duration)
+		e.State.TimeLeft = duration
+	}
+}
+
+// tickClock counts down the time limit. When it runs out the game ends and
+// the mode picks the winner from the players still in it.
+func (e *Engine) tickClock() {
+	if e.endsAt.IsZero() {
+		return
+	}
+	e.State.TimeLeft = max(e.endsAt.Sub(e.now()), 0)
+	if e.State.TimeLeft > 0 {
+		return
+	}
+	e.endsAt = time.Time{}
+
+	var survivors []*Player
+	for _, p := range e.State.Players {
+		if p.Alive || !p.RespawnAt.IsZero() {
+			survivors = append(survivors, p)
+		}
+	}
+	e.State.Status = StatusOver
+	e.setWinnerLocked(e.mode.timeUp(e, survivors))
+}
+
+// wallsTiebreak picks the player who destroyed the most walls.
+// Returns "" if the top score is shared.
+func wallsTiebreak(players []*Player) string {
+	best, winner := -1, ""
+	for _, p := range players {
+		switch {
+		case p.WallsDestroyed > best:
+			best, winner = p.WallsDestroyed, p.ID
+		case p.WallsDestroyed == best:
+			winner = ""
+		}
+	}
+	return winner
+}
@@ -0,0 +1,48 @@
+package game
+
+// TrapID is who kills a player caught in a spike trap.
+const TrapID = "trap"
+
+// hideTrapsLocked hides a spike trap under Config.TrapDensity of the soft
+// walls on the current board. Where they are stays on the server until the
+// walls are destroyed.
+// MUST be called while e.mu is held.
+func (e *Engine) hideTrapsLocked() {
+	e.traps = nil
+	if e.Config.TrapDensity <= 0 {
+		return
+	}
+	e.traps = make(map[Position]bool)
+	for y, row := range e.State.Board {
+		for x, tile := range row {
+			if (tile == SoftWall || tile == CrackedWall) && e.rng.Float64() < e.Config.TrapDensity {
+				e.traps[Position{X: x, Y: y}] = true
+			}
+		}
+	}
+}
+
+// revealTrap turns the just-destroyed wall at pos into a spike trap if it
+// was hiding one, and reports whether it was.
+func (e *Engine) revealTrap(pos Position) bool {
+	if !e.traps[pos] {
+		return false
+	}
+	delete(e.traps, pos)
+	e.State.Board[pos.Y][pos.X] = Trap
+	return true
+}
+
+// tickTraps springs any trap a player has stepped onto, killing them. A
+// sprung trap is gone, so only the first player onto it is hurt.
+func (e *Engine) tickTraps() {
+	for _, p := range e.playersByID() {
+		if !p.Alive || e.State.Board[p.Pos.Y][p.Pos.X] != Trap {
+			continue
+		}
+		e.State.Board[p.Pos.Y][p.Pos.X] = Empty
+		if !e.invulnerable(p) {
+			e.killPlayer(p, TrapID)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package game
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
 	"time"
 )
 
@@ -8,11 +11,23 @@ import (
 type TileType int
 
 const (
-	Empty    TileType = iota
-	HardWall          // Indestructible
-	SoftWall          // Destructible by bombs
+	Empty          TileType = iota
+	HardWall                // Indestructible
+	SoftWall                // Destructible by bombs
+	Barrel                  // Explodes with BarrelRange when hit by fire
+	ExitDoor                // Campaign exit, revealed by destroying the soft wall hiding it
+	Ice                     // Players who step onto it slide on until something stops them
+	CrackedWall             // Destructible, but takes two explosions
+	CrackedWallHit          // A CrackedWall that has taken one explosion
+	Fog                     // Out of sight in fog of war; only ever sent to clients
+	Trap                    // Spike trap, revealed by destroying the soft wall hiding it; kills the first player onto it
 )
 
+// Solid reports whether the tile blocks movement.
+func (t TileType) Solid() bool {
+	return t == HardWall || t == SoftWall || t == Barrel || t == CrackedWall || t == CrackedWallHit
+}
+
 // Direction represents a movement direction.
 type Direction int
 
@@ -23,12 +38,28 @@ const (
 	DirRight
 )
 
+// Opposite returns the direction pointing the other way.
+func (d Direction) Opposite() Direction {
+	switch d {
+	case DirUp:
+		return DirDown
+	case DirDown:
+		return DirUp
+	case DirLeft:
+		return DirRight
+	default:
+		return DirLeft
+	}
+}
+
 // ActionType represents the type of player action.
 type ActionType int
 
 const (
 	ActionMove ActionType = iota
 	ActionPlaceBomb
+	ActionSprint   // Toggle sprinting
+	ActionLineBomb // Lay a row of bombs in the facing direction; needs the line-bomb pickup
 )
 
 // Action represents a player's input action.
@@ -36,6 +67,7 @@ type Action struct {
 	PlayerID string
 	Type     ActionType
 	Dir      Direction // Only relevant for ActionMove
+	Seq      uint32    // Numbers the player's actions from 1 for Player.LastSeq; 0 if unnumbered
 }
 
 // Position represents a coordinate on the board.
@@ -44,31 +76,130 @@ type Position struct {
 	Y int `json:"y"`
 }
 
+// Cosmetics are purely visual choices a player has unlocked in their local
+// profile. Values are catalog IDs; clients ignore IDs they don't know.
+type Cosmetics struct {
+	NameColor string `json:"name_color,omitempty"`
+	Banner    string `json:"banner,omitempty"`
+	Glyph     string `json:"glyph,omitempty"`
+}
+
 // Player represents a connected player.
 type Player struct {
-	ID        string   `json:"id"`
-	Name      string   `json:"name"`
-	Pos       Position `json:"pos"`
-	Alive     bool     `json:"alive"`
-	BombMax   int      `json:"bomb_max"`   // Max simultaneous bombs
-	BombRange int      `json:"bomb_range"` // Explosion range in tiles
-	BombsUsed int      `json:"bombs_used"` // Currently active bombs
-	Color     int      `json:"color"`      // Player color index (0-3)
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Pos       Position  `json:"pos"`
+	Alive     bool      `json:"alive"`
+	BombMax   int       `json:"bomb_max"`   // Max simultaneous bombs
+	BombRange int       `json:"bomb_range"` // Explosion range in tiles
+	BombsUsed int       `json:"bombs_used"` // Currently active bombs
+	Color     int       `json:"color"`      // Player color index, which also picks their spawn
+	Cosmetics Cosmetics `json:"cosmetics"`
+	Stamina   int       `json:"stamina"`             // 0..MaxStamina, drained while sprinting
+	Sprinting bool      `json:"sprinting"`           // Moves cover two tiles while set
+	Ammo      int       `json:"ammo"`                // Bombs left to place in ammo mode (unused otherwise)
+	Kills     int       `json:"kills"`               // Other players killed by this player's bombs this match
+	KilledBy  string    `json:"killed_by,omitempty"` // Owner of the bomb that killed this player; "" for enemies
+	DiedAt    uint64    `json:"died_at,omitempty"`   // Tick this player died on
+	MoveSpeed int       `json:"move_speed"`          // Tiles per second
+	CanKick   bool      `json:"can_kick,omitempty"`  // Walking into a bomb kicks it
+	FullFire  bool      `json:"full_fire,omitempty"` // The next bomb placed is a full-fire bomb
+	LineBomb  bool      `json:"line_bomb,omitempty"` // Can lay a row of bombs with ActionLineBomb
+	Mines     int       `json:"mines,omitempty"`     // How many of the next bombs placed are mines
+	Facing    Direction `json:"facing"`              // Direction of the player's last move
+	Sliding   bool      `json:"sliding,omitempty"`   // On ice: moves on in Facing every tick, ignoring move input
+
+	// OutOfSight is set on players the receiving client can't see in fog of
+	// war; their Pos is cleared. See GameState.FogView.
+	OutOfSight bool `json:"out_of_sight,omitempty"`
+
+	// InvulnerableUntil is when fire immunity ends. It is reset to zero once
+	// it passes, so clients can tell a shielded player without comparing
+	// clocks with the server.
+	InvulnerableUntil time.Time `json:"invulnerable_until"`
+
+	// InvisibleUntil is when invisibility ends, and like InvulnerableUntil
+	// is reset to zero once it passes. Opponents' views leave an invisible
+	// player out; see GameState.ViewFor.
+	InvisibleUntil time.Time `json:"invisible_until"`
+
+	// StunnedUntil is when a stun mode stun wears off, and like
+	// InvulnerableUntil is reset to zero once it passes. A stunned player
+	// can't move or place bombs.
+	StunnedUntil time.Time `json:"stunned_until"`
+
+	Effects []StatusEffect `json:"effects,omitempty"` // Active curses; expired ones are removed each tick
+
+	Lives     int       `json:"lives"`      // Lives left, counting the current one
+	HP        int       `json:"hp"`         // Hearts left in this life; see GameConfig.Hearts
+	RespawnAt time.Time `json:"respawn_at"` // When a dead player with lives left respawns; zero otherwise
+
+	RoundScore     int `json:"round_score"`       // Rounds won this match
+	TeamID         int `json:"team_id,omitempty"` // 1..TeamCount in team mode; 0 otherwise
+	WallsDestroyed int `json:"walls_destroyed"`   // Soft walls destroyed by this player's bombs this match
+	HillPoints     int `json:"hill_points"`       // Ticks spent holding the hill this round in king-of-the-hill mode
+
+	// Handicap overrides this player's starting stats; set by the host in
+	// the lobby. See Engine.SetHandicap.
+	Handicap Handicap `json:"handicap"`
+
+	// Disconnected is set while the player's connection is down mid-match.
+	// They stand idle, keeping their place, until their client rejoins or the
+	// server gives up on it.
+	Disconnected bool `json:"disconnected,omitempty"`
+
+	// RTT is the round trip to the player's client, as the server last
+	// measured it. Zero for bots and until it is first measured.
+	RTT time.Duration `json:"rtt,omitempty"`
+
+	// LastSeq is the Seq of the player's last action the engine is done
+	// with, so a client predicting its own moves knows which it still has to
+	// replay on top of the state. A move waiting for move credit isn't done
+	// with until it is made.
+	LastSeq uint32 `json:"last_seq,omitempty"`
+
+	moveCredit  int       // Refills by MoveSpeed each tick; a move costs Config.TickRate
+	pendingMove Direction // Latest move requested while moveCredit was short
+	pendingSeq  uint64    // Engine.actionSeq of pendingMove, which goes first
+	hasPending  bool
+	pendingAck  uint32 // Action.Seq of pendingMove
+	handledSeq  uint32 // Action.Seq of the last action drained
+}
+
+// Handicap overrides a player's starting stats. Zero fields keep the
+// config's StartBombs, StartRange and StartSpeed.
+type Handicap struct {
+	Bombs int `json:"bombs,omitempty"`
+	Range int `json:"range,omitempty"`
+	Speed int `json:"speed,omitempty"` // Tiles per second
 }
 
 // Bomb represents an active bomb on the board.
 type Bomb struct {
-	OwnerID   string    `json:"owner_id"`
-	Pos       Position  `json:"pos"`
-	Range     int       `json:"range"`
-	PlacedAt  time.Time `json:"placed_at"`
-	ExpiresAt time.Time `json:"expires_at"`
+	OwnerID     string    `json:"owner_id"`
+	Pos         Position  `json:"pos"`
+	Range       int       `json:"range"`
+	PlacedAt    time.Time `json:"placed_at"`
+	ExpiresTick uint64    `json:"expires_tick"`        // Tick its fuse runs out on; it goes off on the next
+	Velocity    Position  `json:"velocity"`            // Tiles per slide step while kicked; zero at rest
+	FullFire    bool      `json:"full_fire,omitempty"` // Burns through soft walls and barrels up to a hard wall
+	OwnerOn     bool      `json:"owner_on,omitempty"`  // The owner hasn't stepped off since placing it, so it doesn't block them
+	Mine        bool      `json:"mine,omitempty"`      // Has no fuse; goes off when an opponent steps on it once armed
+	HiddenAt    uint64    `json:"hidden_at,omitempty"` // Mines: tick from which it is armed and hidden from opponents
+	Chained     bool      `json:"chained,omitempty"`   // Caught in another explosion; goes off after ExpiresTick, mines too
+
+	// FuseLeft is the time until the bomb goes off, as of the last tick, so
+	// clients can animate the fuse without comparing clocks with the server.
+	// 0 for mines waiting for a victim.
+	FuseLeft time.Duration `json:"fuse_left"`
 }
 
 // Fire represents an active fire tile from an explosion.
 type Fire struct {
-	Pos       Position  `json:"pos"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Pos         Position `json:"pos"`
+	ExpiresTick uint64   `json:"expires_tick"`        // Tick it goes out on
+	OwnerID     string   `json:"owner_id,omitempty"`  // Owner of the bomb that started the explosion
+	Permanent   bool     `json:"permanent,omitempty"` // Creeping lava, which never goes out
 }
 
 // Enemy represents an AI-controlled enemy on the board.
@@ -80,12 +211,59 @@ type Enemy struct {
 	MoveTimer int       `json:"move_timer"`
 }
 
+// BossAttack is the attack a boss is winding up.
+type BossAttack int
+
+const (
+	BossIdle      BossAttack = iota
+	BossFireSweep            // Sets a whole row or column on fire
+	BossBombs                // Drops bombs around the boss
+)
+
+// Boss is the large enemy of boss mode. It covers BossSize×BossSize tiles
+// from Pos and only takes damage from fire reaching its weak point.
+type Boss struct {
+	Pos       Position   `json:"pos"` // Top-left tile
+	Alive     bool       `json:"alive"`
+	HP        int        `json:"hp"`
+	MaxHP     int        `json:"max_hp"`
+	WeakPoint Position   `json:"weak_point"`        // Offset from Pos of the damageable tile
+	Attack    BossAttack `json:"attack"`            // Attack being telegraphed
+	Warning   []Position `json:"warning,omitempty"` // Tiles the telegraphed attack will hit
+	Timer     int        `json:"timer"`             // Ticks until the next attack phase
+	MoveTimer int        `json:"move_timer"`
+}
+
+// Hill is the scoring zone of king-of-the-hill mode, Size×Size tiles from Pos.
+type Hill struct {
+	Pos  Position `json:"pos"` // Top-left tile
+	Size int      `json:"size"`
+}
+
+// Zone is the safe zone of a battle royale round, Width×Height tiles from
+// Pos. Everything outside it is on fire.
+type Zone struct {
+	Pos      Position      `json:"pos"` // Top-left tile
+	Width    int           `json:"width"`
+	Height   int           `json:"height"`
+	ShrinkIn time.Duration `json:"shrink_in,omitempty"` // Until the zone next closes in; 0 once it's done shrinking
+}
+
 // PickupType represents the kind of power-up.
 type PickupType int
 
 const (
-	PickupBomb  PickupType = iota // +1 bomb to inventory
-	PickupRange                   // +1 explosion range
+	PickupBomb     PickupType = iota // +1 bomb to inventory
+	PickupRange                      // +1 explosion range
+	PickupAmmo                       // +AmmoPerPickup bombs to the ammo stock (ammo mode only)
+	PickupSpeed                      // +1 move speed
+	PickupKick                       // Lets the player kick bombs
+	PickupShield                     // ShieldDuration of fire immunity
+	PickupSkull                      // A random curse for CurseDuration
+	PickupFullFire                   // Makes the next bomb a full-fire bomb
+	PickupLineBomb                   // Lets the player lay a row of bombs at once
+	PickupMine                       // Turns the next MinesPerPickup bombs into mines
+	PickupCloak                      // InvisibilityDuration out of opponents' sight
 )
 
 // Pickup represents a collectible item on the board.
@@ -94,21 +272,91 @@ type Pickup struct {
 	Type PickupType `json:"type"`
 }
 
+// Default starting stats for a freshly spawned player; see
+// GameConfig.StartBombs and GameConfig.StartRange.
+const (
+	StartBombs = 3
+	StartRange = 2
+	StartSpeed = 6 // Tiles per second
+)
+
+// Balance constants for sprinting, in stamina points per tick.
+const (
+	MaxStamina       = 100
+	SprintDrain      = 4  // 25 ticks (1.25s at 20 ticks/sec) of sprint from full
+	StaminaRegen     = 1  // 5s to refill from empty
+	SprintMinStamina = 25 // Needed to start a sprint
+)
+
 // Balance constants for pickups.
 const (
-	PickupBombDropChance  = 0.25 // 25% chance a destroyed wall drops a bomb
-	PickupRangeDropChance = 0.15 // 15% chance (checked if bomb didn't drop)
-	MaxBombs              = 6    // Hard cap on bomb inventory
-	MaxRange              = 4    // Hard cap on explosion range
+	PickupBombDropChance     = 0.25 // 25% chance a destroyed wall drops a bomb
+	PickupRangeDropChance    = 0.15 // 15% chance it drops range instead
+	PickupSpeedDropChance    = 0.10 // 10% chance it drops speed instead
+	PickupKickDropChance     = 0.05 // 5% chance it drops kick instead
+	PickupShieldDropChance   = 0.05 // 5% chance it drops a shield instead
+	PickupSkullDropChance    = 0.05 // 5% chance it drops a skull instead
+	PickupFullFireDropChance = 0.03 // 3% chance it drops full fire instead
+	PickupLineBombDropChance = 0.03 // 3% chance it drops a line bomb instead
+	PickupMineDropChance     = 0.03 // 3% chance it drops mines instead
+	PickupCloakDropChance    = 0.03 // 3% chance it drops a cloak instead
+	MaxBombs                 = 6    // Default cap on bomb inventory
+	MaxRange                 = 4    // Default cap on explosion range
+	MaxSpeed                 = 10   // Default cap on move speed
+)
+
+// ShieldDuration is how long a shield pickup protects from fire.
+const ShieldDuration = 5 * time.Second
+
+// HitProtection is how long fire can't hurt a player again after taking
+// one of their hearts.
+const HitProtection = time.Second
+
+// Effect is a timed status effect on a player.
+type Effect int
+
+const (
+	EffectReverse    Effect = iota // Move in the opposite direction to the one pressed
+	EffectAutoBomb                 // Drop a bomb whenever one is available
+	EffectShortRange               // Bombs explode with range 1
+)
+
+// StatusEffect is an effect on a player and when it wears off.
+type StatusEffect struct {
+	Kind      Effect    `json:"kind"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Respawn timings when Config.Lives gives players more than one life.
+const (
+	RespawnDelay    = 3 * time.Second
+	SpawnProtection = 2 * time.Second // Fire immunity after respawning
+)
+
+// CurseDuration is how long a skull's curse lasts.
+const CurseDuration = 10 * time.Second
+
+// BarrelRange is the fixed explosion range of a barrel.
+const BarrelRange = 2
+
+// Balance constants for ammo mode.
+const (
+	PickupAmmoDropChance = 0.35 // Checked before the other drops in ammo mode
+	AmmoPerPickup        = 2
+	MaxAmmo              = 20
 )
 
 // GameStatus represents the current game phase.
 type GameStatus int
 
 const (
-	StatusLobby   GameStatus = iota // Waiting for players
-	StatusRunning                   // Game in progress
-	StatusOver                      // Game finished
+	StatusLobby         GameStatus = iota // Waiting for players
+	StatusRunning                         // Game in progress
+	StatusOver                            // Game finished
+	StatusCountdown                       // Counting down to the start; see Engine.BeginCountdown
+	StatusIntermission                    // Between the rounds of a best-of-N match
+	StatusLevelComplete                   // Between the levels of the campaign
+	StatusPaused                          // A running match frozen by Engine.Pause
 )
 
 // GameState is the authoritative state of the game, owned by the server.
@@ -124,20 +372,122 @@ type GameState struct {
 	Height  int                `json:"height"`
 	Status  GameStatus         `json:"status"`
 	Winner  string             `json:"winner,omitempty"`
+	Tick    uint64             `json:"tick"`            // Engine ticks since the server started
+	Round   int                `json:"round,omitempty"` // Current round, from 1; 0 in the lobby
+	Level   int                `json:"level,omitempty"` // Current campaign level, from 1; 0 outside the campaign
+
+	SuddenDeath bool          `json:"sudden_death,omitempty"` // A tiebreaker rematch is running
+	Boss        *Boss         `json:"boss,omitempty"`         // Boss mode only
+	TimeLeft    time.Duration `json:"time_left,omitempty"`    // Until the round's time limit runs out; 0 without one
+	WinningTeam int           `json:"winning_team,omitempty"` // Team mode: the team that won, instead of Winner
+	PvE         bool          `json:"pve,omitempty"`          // PvE mode: the match is won by clearing the monsters
+	OvertimeAt  time.Time     `json:"overtime_at"`            // When this round's overtime starts; zero without one
+	Hill        *Hill         `json:"hill,omitempty"`         // King-of-the-hill mode only
+	Zone        *Zone         `json:"zone,omitempty"`         // Battle royale only
+
+	// Spectators are the connections watching without playing. The server
+	// fills them in for each client; the engine leaves them empty.
+	Spectators []Spectator `json:"spectators,omitempty"`
+
+	// Version counts the engine's changes to the state other than its tick,
+	// so a state that only ticked can be told apart without comparing it
+	// whole. It is kept off the wire.
+	Version uint64 `json:"-"`
 }
 
+// Spectator is a connection watching the game without playing.
+type Spectator struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Tiebreaker decides the winner when the last players die on the same tick.
+type Tiebreaker string
+
+const (
+	TiebreakDraw        Tiebreaker = "draw"         // Nobody wins
+	TiebreakBombOwner   Tiebreaker = "bomb_owner"   // The player whose bomb killed the others wins
+	TiebreakKills       Tiebreaker = "kills"        // The player with the most kills wins
+	TiebreakSuddenDeath Tiebreaker = "sudden_death" // The tied players replay on a fresh board
+)
+
 // GameConfig holds configurable parameters for a game session.
 type GameConfig struct {
 	Width           int           `json:"width"`
 	Height          int           `json:"height"`
 	BombTimer       time.Duration `json:"bomb_timer"`
 	FireDuration    time.Duration `json:"fire_duration"`
-	TickRate        int           `json:"tick_rate"` // Ticks per second
+	ChainDelay      time.Duration `json:"chain_delay"` // How long a bomb caught in an explosion takes to go off; 0 for instantly
+	TickRate        int           `json:"tick_rate"`   // Ticks per second
 	MaxPlayers      int           `json:"max_players"`
+	Seed            int64         `json:"seed"`              // Seeds the game's randomness, so a seed replays the same maps; 0 for a random one
 	SoftWallDensity float64       `json:"soft_wall_density"` // 0.0 to 1.0
+	BarrelDensity   float64       `json:"barrel_density"`    // 0.0 to 1.0, of tiles left empty by soft walls
+	IceDensity      float64       `json:"ice_density"`       // 0.0 to 1.0, of tiles left empty by soft walls and barrels
+	CrackedWalls    float64       `json:"cracked_walls"`     // 0.0 to 1.0, of soft walls made cracked walls instead
+	TrapDensity     float64       `json:"trap_density"`      // 0.0 to 1.0, of soft walls hiding a spike trap
+	Overtime        OvertimeRule  `json:"overtime"`          // What happens once a round runs past LavaAfter
+	Lava            bool          `json:"lava"`              // Shorthand for Overtime "lava"; NewEngine sets Overtime from it
+	LavaAfter       time.Duration `json:"lava_after"`        // How long a round runs before overtime starts
+	FogRadius       int           `json:"fog_radius"`        // Fog of war: players only see this many tiles around them; 0 for none
 	EnemyCount      int           `json:"enemy_count"`
+	LobbyReturn     time.Duration `json:"lobby_return"` // Delay before a finished game returns to the lobby; 0 stays on the results
+	SprintEnabled   bool          `json:"sprint_enabled"`
+	AmmoMode        bool          `json:"ammo_mode"`       // Bombs are consumed from a limited stock
+	StartAmmo       int           `json:"start_ammo"`      // Initial stock in ammo mode
+	Tiebreaker      Tiebreaker    `json:"tiebreaker"`      // How simultaneous last deaths are resolved
+	StartCountdown  time.Duration `json:"start_countdown"` // Countdown before each match; 0 starts immediately
+	BossMode        bool          `json:"boss_mode"`       // Co-op: all players fight a boss together
+	BossHP          int           `json:"boss_hp"`         // Weak point hits needed to kill the boss
+	Lives           int           `json:"lives"`           // Lives per match; players respawn until they run out
+	Hearts          int           `json:"hearts"`          // Fire hits a player survives per life, counting the last
+	Rounds          int           `json:"rounds"`          // Best-of-N rounds per match; 1 plays a single round
+	MatchDuration   time.Duration `json:"match_duration"`  // Time limit per round; 0 for none
+	TimeUp          TimeUpRule    `json:"time_up"`         // How a round that runs out of time is decided
+	Mode            Mode          `json:"mode"`            // The rules rounds are won by; the mode switches below are shorthand for it
+	TeamMode        bool          `json:"team_mode"`       // Players split into TeamCount teams that win together
+	FriendlyFire    bool          `json:"friendly_fire"`   // Teammates' bombs can kill each other in team mode
+	FillWithBots    bool          `json:"fill_with_bots"`  // Empty slots get computer players when the game starts
+	BotDifficulty   string        `json:"bot_difficulty"`  // Difficulty of those bots: easy, medium or hard
+	PvEMode         bool          `json:"pve_mode"`        // Co-op: players win by clearing every monster
+	Campaign        bool          `json:"campaign"`        // Play through Levels, clearing each and reaching its exit
+	HillMode        bool          `json:"hill_mode"`       // King of the hill: score by holding the zone in the middle
+	HillScore       int           `json:"hill_score"`      // Hill points, one per tick held, that win a round
+	StunMode        bool          `json:"stun_mode"`       // Casual: fire stuns and scatters power-ups instead of killing; most stuns wins
+	BattleRoyale    bool          `json:"battle_royale"`   // A safe zone closes in on the players, burning everything outside
+	ZoneShrink      time.Duration `json:"zone_shrink"`     // How often the battle royale zone closes in by a tile on each side
+	StartBombs      int           `json:"start_bombs"`     // Bombs each player spawns with
+	StartRange      int           `json:"start_range"`     // Explosion range each player spawns with
+	MaxBombs        int           `json:"max_bombs"`       // Cap on bombs from pickups
+	MaxRange        int           `json:"max_range"`       // Cap on explosion range from pickups
+	MaxSpeed        int           `json:"max_speed"`       // Cap on move speed from pickups, in tiles per second
+
+	// DropTable replaces the chances of a destroyed wall dropping each
+	// pickup, keyed by PickupNames. Pickups left out never drop. Chances
+	// adding up past 1 are scaled down so every wall drops something. nil
+	// keeps the default chances.
+	DropTable map[string]float64 `json:"drop_table,omitempty"`
 }
 
+// TimeUpRule decides the winner when GameConfig.MatchDuration runs out.
+type TimeUpRule string
+
+const (
+	TimeUpDraw  TimeUpRule = "draw"  // Nobody wins
+	TimeUpKills TimeUpRule = "kills" // The surviving player with the most kills wins
+	TimeUpWalls TimeUpRule = "walls" // The surviving player who destroyed the most walls wins
+)
+
+// OvertimeRule decides what happens once a round runs past
+// GameConfig.LavaAfter, to force an end to a stalemate.
+type OvertimeRule string
+
+const (
+	OvertimeNone     OvertimeRule = "none"      // Nothing happens
+	OvertimeLava     OvertimeRule = "lava"      // Open tiles turn to lava one by one
+	OvertimeBombRain OvertimeRule = "bomb_rain" // Bombs fall on open tiles, faster and faster
+)
+
 // DefaultConfig returns a sensible default game configuration.
 func DefaultConfig() GameConfig {
 	return GameConfig{
@@ -145,20 +495,110 @@ func DefaultConfig() GameConfig {
 		Height:          13,
 		BombTimer:       3 * time.Second,
 		FireDuration:    500 * time.Millisecond,
+		ChainDelay:      150 * time.Millisecond,
 		TickRate:        20,
 		MaxPlayers:      4,
 		SoftWallDensity: 0.4,
 		EnemyCount:      3,
+		LobbyReturn:     5 * time.Second,
+		SprintEnabled:   true,
+		StartAmmo:       5,
+		Tiebreaker:      TiebreakDraw,
+		StartCountdown:  3 * time.Second,
+		BossHP:          6,
+		Lives:           1,
+		Hearts:          1,
+		Rounds:          1,
+		TimeUp:          TimeUpDraw,
+		Mode:            ModeClassic,
+		Overtime:        OvertimeNone,
+		BotDifficulty:   "medium",
+		StartBombs:      StartBombs,
+		StartRange:      StartRange,
+		MaxBombs:        MaxBombs,
+		MaxRange:        MaxRange,
+		MaxSpeed:        MaxSpeed,
+		LavaAfter:       2 * time.Minute,
+		HillScore:       300,
+		ZoneShrink:      15 * time.Second,
 	}
 }
 
-// SpawnPositions returns the corner spawn positions for players.
-// These corners and their adjacent tiles are kept clear of soft walls.
+// LoadConfig reads a JSON config file. Fields missing from the file keep
+// their DefaultConfig values; durations are in nanoseconds.
+func LoadConfig(path string) (GameConfig, error) {
+	config := DefaultConfig()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("read config: %w", err)
+	}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return config, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if _, ok := modes[config.Mode]; !ok {
+		return config, fmt.Errorf("config %s: unknown mode %q", path, config.Mode)
+	}
+	spawns := len(SpawnPositions(config.Width, config.Height))
+	var set struct {
+		MaxPlayers *int `json:"max_players"`
+	}
+	json.Unmarshal(raw, &set)
+	if set.MaxPlayers == nil && normalizeMode(config).BattleRoyale {
+		// A battle royale fills every spawn unless told otherwise
+		config.MaxPlayers = spawns
+	}
+	if config.MaxPlayers > spawns {
+		return config, fmt.Errorf("config %s: max_players is %d, but a %dx%d board has only %d spawns",
+			path, config.MaxPlayers, config.Width, config.Height, spawns)
+	}
+	for name, chance := range config.DropTable {
+		if _, ok := PickupNames[name]; !ok {
+			return config, fmt.Errorf("config %s: unknown pickup %q in drop_table", path, name)
+		}
+		if chance < 0 {
+			return config, fmt.Errorf("config %s: negative drop chance for %s", path, name)
+		}
+	}
+	return config, nil
+}
+
+// Board sizes, in tiles along the shorter side, from which SpawnPositions
+// adds more spawns.
+const (
+	MidSpawnSize     = 17 // 8 spawns
+	QuarterSpawnSize = 25 // 16 spawns
+)
+
+// SpawnPositions returns the spawn positions for players: the corners, then
+// on boards of at least MidSpawnSize the middle of each side, and on boards
+// of at least QuarterSpawnSize the quarter points of each side, for up to 16
+// players. These spawns and their adjacent tiles are kept clear of soft walls.
 func SpawnPositions(width, height int) []Position {
-	return []Position{
+	spawns := []Position{
 		{X: 1, Y: 1},                  // Top-left
 		{X: width - 2, Y: 1},          // Top-right
 		{X: 1, Y: height - 2},         // Bottom-left
 		{X: width - 2, Y: height - 2}, // Bottom-right
 	}
+	// Odd coordinates are never pillars
+	along := func(n, num, den int) int { return n*num/den | 1 }
+	if min(width, height) >= MidSpawnSize {
+		spawns = append(spawns,
+			Position{X: along(width, 1, 2), Y: 1},
+			Position{X: along(width, 1, 2), Y: height - 2},
+			Position{X: 1, Y: along(height, 1, 2)},
+			Position{X: width - 2, Y: along(height, 1, 2)},
+		)
+	}
+	if min(width, height) >= QuarterSpawnSize {
+		for _, q := range []int{1, 3} {
+			spawns = append(spawns,
+				Position{X: along(width, q, 4), Y: 1},
+				Position{X: along(width, q, 4), Y: height - 2},
+				Position{X: 1, Y: along(height, q, 4)},
+				Position{X: width - 2, Y: along(height, q, 4)},
+			)
+		}
+	}
+	return spawns
 }
@@ -0,0 +1,39 @@
+package game
+
+// ViewFor returns the state as the player with the given ID may see it:
+// without the mines hidden from them, and with invisible opponents marked
+// OutOfSight and their position cleared. An empty ID hides every armed mine
+// and invisible player. Only Bombs and Players are copied; everything else
+// is shared with s.
+func (s GameState) ViewFor(playerID string) GameState {
+	bombs := make([]*Bomb, 0, len(s.Bombs))
+	for _, b := range s.Bombs {
+		if !s.hiddenFrom(b, playerID) {
+			bombs = append(bombs, b)
+		}
+	}
+	s.Bombs = bombs
+
+	players := make(map[string]*Player, len(s.Players))
+	for id, p := range s.Players {
+		if s.invisibleTo(p, playerID) {
+			hidden := *p
+			hidden.Pos = Position{}
+			hidden.OutOfSight = true
+			p = &hidden
+		}
+		players[id] = p
+	}
+	s.Players = players
+	return s
+}
+
+// EventFor returns what the player with the given ID may see of ev, which
+// happened in s, or false if it's hidden from them: an invisible opponent
+// picking something up would give away where they are.
+func (s GameState) EventFor(ev Event, playerID string) (Event, bool) {
+	if p, ok := s.Players[ev.PlayerID]; ok && ev.Type == EventItemPickedUp && s.invisibleTo(p, playerID) {
+		return Event{}, false
+	}
+	return ev, true
+}
@@ -0,0 +1,86 @@
+package game
+
+import "time"
+
+// ZoneMinSize is the smallest the battle royale zone shrinks to, in tiles
+// across.
+const ZoneMinSize = 3
+
+// spawnZone opens the battle royale zone over the whole board inside its
+// border walls for a new round.
+// MUST be called while e.mu is held.
+func (e *Engine) spawnZone() {
+	if !e.Config.BattleRoyale {
+		return
+	}
+	e.State.Zone = &Zone{
+		Pos:      Position{X: 1, Y: 1},
+		Width:    e.State.Width - 2,
+		Height:   e.State.Height - 2,
+		ShrinkIn: e.Config.ZoneShrink,
+	}
+	e.zoneShrinksAt = e.now().Add(e.Config.ZoneShrink)
+}
+
+// tickZone counts down to the next time the zone closes in, and then sets
+// the ring of tiles it leaves behind on fire for good, burning away any
+// walls and pickups there. The zone stops shrinking at ZoneMinSize.
+func (e *Engine) tickZone() {
+	z := e.State.Zone
+	if z == nil || e.zoneShrinksAt.IsZero() {
+		return
+	}
+	z.ShrinkIn = max(e.zoneShrinksAt.Sub(e.now()), 0)
+	if z.ShrinkIn > 0 {
+		return
+	}
+
+	old := *z
+	z.Pos = Position{X: z.Pos.X + 1, Y: z.Pos.Y + 1}
+	z.Width -= 2
+	z.Height -= 2
+	for y := old.Pos.Y; y < old.Pos.Y+old.Height; y++ {
+		for x := old.Pos.X; x < old.Pos.X+old.Width; x++ {
+			pos := Position{X: x, Y: y}
+			if z.Contains(pos) || e.State.Board[y][x] == HardWall {
+				continue
+			}
+			e.State.Board[y][x] = Empty
+			e.State.Fires = append(e.State.Fires, Fire{Pos: pos, Permanent: true})
+		}
+	}
+	pickups := e.State.Pickups[:0]
+	for _, pk := range e.State.Pickups {
+		if z.Contains(pk.Pos) {
+			pickups = append(pickups, pk)
+		}
+	}
+	e.State.Pickups = pickups
+	e.damagePlayersInFire()
+	e.damageEnemiesInFire()
+
+	if min(z.Width, z.Height)-2 < ZoneMinSize {
+		e.zoneShrinksAt = time.Time{}
+		z.ShrinkIn = 0
+		return
+	}
+	e.zoneShrinksAt = e.now().Add(e.Config.ZoneShrink)
+	z.ShrinkIn = e.Config.ZoneShrink
+}
+
+// Contains reports whether pos is inside the zone.
+func (z *Zone) Contains(pos Position) bool {
+	return pos.X >= z.Pos.X && pos.X < z.Pos.X+z.Width &&
+		pos.Y >= z.Pos.Y && pos.Y < z.Pos.Y+z.Height
+}
+
+// OnEdge reports whether pos is on the ring of the zone that burns the next
+// time it closes in. Nothing is on the edge of a zone that is done
+// shrinking.
+func (z *Zone) OnEdge(pos Position) bool {
+	if z.ShrinkIn == 0 || !z.Contains(pos) {
+		return false
+	}
+	return pos.X == z.Pos.X || pos.X == z.Pos.X+z.Width-1 ||
+		pos.Y == z.Pos.Y || pos.Y == z.Pos.Y+z.Height-1
+}
@@ -0,0 +1,44 @@
+// Package logging sets up where the process logs go. Every package logs
+// through log/slog's default logger with structured fields (player_id, room,
+// msg_type and so on); Setup points it at a writer and a level.
+//
+// The TUI owns the terminal, so a log line on stderr would corrupt its
+// rendering. It runs with Setup pointed at a file, or at io.Discard.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ParseLevel parses a level named on the command line: debug, info, warn or
+// error.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q: use debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+// Setup sends the logs of level and above to w, as text. Anything logged
+// with the standard log package goes there too.
+func Setup(w io.Writer, level slog.Level) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})))
+}
+
+// Open returns where to write logs for the --log-file path: the file,
+// appended to, or fallback for "".
+func Open(path string, fallback io.Writer) (io.Writer, error) {
+	if strings.TrimSpace(path) == "" {
+		return fallback, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open log file: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected an unknown level to fail")
+	}
+	level, err := ParseLevel("warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	Setup(&buf, level)
+	slog.Info("Player joined", "player_id", "p1")
+	slog.Warn("Invalid message", "player_id", "p1", "msg_type", "action")
+
+	out := buf.String()
+	if strings.Contains(out, "Player joined") {
+		t.Error("expected info to be left out at warn")
+	}
+	if !strings.Contains(out, `msg="Invalid message" player_id=p1 msg_type=action`) {
+		t.Errorf("expected the warning's fields, got %q", out)
+	}
+
+	buf.Reset()
+	Setup(&buf, slog.LevelInfo)
+	log.Print("from the log package")
+	if !strings.Contains(buf.String(), "from the log package") {
+		t.Errorf("expected the log package's output too, got %q", buf.String())
+	}
+}
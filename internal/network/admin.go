@@ -0,0 +1,178 @@
+package network
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/crash"
+)
+
+// AdminPlayer is one of a room's connections, as listed by the admin API.
+type AdminPlayer struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Addr      string `json:"addr,omitempty"` // Empty for bots and players whose connection is down
+	Spectator bool   `json:"spectator,omitempty"`
+	Bot       bool   `json:"bot,omitempty"`
+	RTTMillis int64  `json:"rtt_ms,omitempty"`
+}
+
+// AdminStats is the admin API's summary of a whole RoomServer.
+type AdminStats struct {
+	Rooms         int   `json:"rooms"`
+	Clients       int   `json:"clients"`
+	UptimeSeconds int64 `json:"uptime_seconds"`
+	SentPerSec    int64 `json:"sent_per_sec"` // Bytes, over every room
+	RecvPerSec    int64 `json:"recv_per_sec"`
+	SentTotal     int64 `json:"sent_total"`
+	RecvTotal     int64 `json:"recv_total"`
+}
+
+// EnableAdmin serves an HTTP admin API on addr for the server's operator,
+// which takes requests bearing token in an "Authorization: Bearer" header:
+//
+//	GET  /rooms                 the rooms, as RoomSummary
+//	GET  /rooms/{room}/players  a room's players and spectators, as AdminPlayer
+//	POST /rooms/{room}/kick     kick the player of a KickMsg body
+//	POST /rooms/{room}/stop     close a room, hanging up on everyone in it
+//	GET  /stats                 AdminStats
+//
+// Must be called before Start.
+func (r *RoomServer) EnableAdmin(addr, token string) error {
+	if token == "" {
+		return errors.New("the admin API needs a token")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen admin: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rooms", r.adminRooms)
+	mux.HandleFunc("GET /rooms/{room}/players", r.adminPlayers)
+	mux.HandleFunc("POST /rooms/{room}/kick", r.adminKick)
+	mux.HandleFunc("POST /rooms/{room}/stop", r.adminStop)
+	mux.HandleFunc("GET /stats", r.adminStats)
+	r.admin = &http.Server{Handler: authorized(token, mux), ReadHeaderTimeout: 10 * time.Second}
+	r.adminListener = ln
+	slog.Info("Serving admin API", "url", fmt.Sprintf("http://%s", ln.Addr()))
+	crash.Go(func() { r.admin.Serve(ln) })
+	return nil
+}
+
+// authorized passes on requests bearing token, turning the rest away.
+func authorized(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bomberman"`)
+			writeJSON(w, http.StatusUnauthorized, ErrorMsg{Message: "missing or wrong token"})
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// writeJSON answers an admin request with v.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// adminRoom returns the request's room, answering it if there's none.
+func (r *RoomServer) adminRoom(w http.ResponseWriter, req *http.Request) (*Server, bool) {
+	id := req.PathValue("room")
+	r.mu.Lock()
+	room, ok := r.rooms[id]
+	r.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, ErrorMsg{Message: fmt.Sprintf("room %q not found", id)})
+	}
+	return room, ok
+}
+
+func (r *RoomServer) adminRooms(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, r.list())
+}
+
+func (r *RoomServer) adminPlayers(w http.ResponseWriter, req *http.Request) {
+	if room, ok := r.adminRoom(w, req); ok {
+		writeJSON(w, http.StatusOK, room.adminPlayers())
+	}
+}
+
+func (r *RoomServer) adminKick(w http.ResponseWriter, req *http.Request) {
+	room, ok := r.adminRoom(w, req)
+	if !ok {
+		return
+	}
+	var msg KickMsg
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, 4096)).Decode(&msg); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorMsg{Message: fmt.Sprintf("expected a KickMsg: %v", err)})
+		return
+	}
+	if err := room.expel(msg, false, "the server's operator"); err != nil {
+		writeJSON(w, http.StatusNotFound, ErrorMsg{Message: err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *RoomServer) adminStop(w http.ResponseWriter, req *http.Request) {
+	room, ok := r.adminRoom(w, req)
+	if !ok {
+		return
+	}
+	room.broadcastSystem("", SystemMsg{Kind: SystemKick, Text: "The server's operator closed this room"})
+	r.manager.Close(req.PathValue("room"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *RoomServer) adminStats(w http.ResponseWriter, req *http.Request) {
+	stats := AdminStats{UptimeSeconds: int64(time.Since(r.started).Seconds())}
+	r.mu.Lock()
+	for _, room := range r.rooms {
+		b := room.Bandwidth()
+		room.mu.RLock()
+		stats.Clients += len(room.clients)
+		room.mu.RUnlock()
+		stats.Rooms++
+		stats.SentPerSec += b.SentPerSec
+		stats.RecvPerSec += b.RecvPerSec
+		stats.SentTotal += b.SentTotal
+		stats.RecvTotal += b.RecvTotal
+	}
+	r.mu.Unlock()
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// adminPlayers lists the room's players, bots and spectators, sorted by ID.
+func (s *Server) adminPlayers() []AdminPlayer {
+	state := s.engine.GetStateCopy()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	players := make([]AdminPlayer, 0, len(state.Players))
+	for id, p := range state.Players {
+		ap := AdminPlayer{ID: id, Name: p.Name, RTTMillis: p.RTT.Milliseconds()}
+		_, ap.Bot = s.bots[id]
+		if cc, ok := s.clients[id]; ok {
+			ap.Addr = cc.conn.RemoteAddr().String()
+		}
+		players = append(players, ap)
+	}
+	for id, cc := range s.clients {
+		if cc.spectator {
+			players = append(players, AdminPlayer{ID: id, Name: cc.name, Addr: cc.conn.RemoteAddr().String(), Spectator: true})
+		}
+	}
+	slices.SortFunc(players, func(a, b AdminPlayer) int { return strings.Compare(a.ID, b.ID) })
+	return players
+}
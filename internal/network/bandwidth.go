@@ -0,0 +1,74 @@
+package network
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BandwidthStats is a snapshot of connection traffic.
+type BandwidthStats struct {
+	SentPerSec int64 // Bytes sent over the last sampling window
+	RecvPerSec int64 // Bytes received over the last sampling window
+	SentTotal  int64
+	RecvTotal  int64
+}
+
+// bandwidthMeter counts bytes in both directions and derives per-second rates.
+// Rates are recomputed lazily, at most once per second, when Stats is called.
+type bandwidthMeter struct {
+	sent atomic.Int64
+	recv atomic.Int64
+
+	mu         sync.Mutex
+	lastSample time.Time
+	lastSent   int64
+	lastRecv   int64
+	rateSent   int64
+	rateRecv   int64
+}
+
+func newBandwidthMeter() *bandwidthMeter {
+	return &bandwidthMeter{lastSample: time.Now()}
+}
+
+// Stats returns the current totals and the most recent per-second rates.
+func (m *bandwidthMeter) Stats() BandwidthStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sent, recv := m.sent.Load(), m.recv.Load()
+	if elapsed := time.Since(m.lastSample); elapsed >= time.Second {
+		secs := elapsed.Seconds()
+		m.rateSent = int64(float64(sent-m.lastSent) / secs)
+		m.rateRecv = int64(float64(recv-m.lastRecv) / secs)
+		m.lastSent, m.lastRecv = sent, recv
+		m.lastSample = time.Now()
+	}
+
+	return BandwidthStats{
+		SentPerSec: m.rateSent,
+		RecvPerSec: m.rateRecv,
+		SentTotal:  sent,
+		RecvTotal:  recv,
+	}
+}
+
+// meteredConn wraps a net.Conn and records traffic on a shared meter.
+type meteredConn struct {
+	net.Conn
+	meter *bandwidthMeter
+}
+
+func (c *meteredConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.meter.recv.Add(int64(n))
+	return n, err
+}
+
+func (c *meteredConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.meter.sent.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,85 @@
+package network
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// chatBurst and chatInterval rate-limit each player's chat, so nobody
+	// can drown the others out.
+	chatBurst    = 4
+	chatInterval = 2 * time.Second
+
+	// MaxChatLen is the longest chat message relayed, in characters; longer
+	// ones are cut short.
+	MaxChatLen = 120
+)
+
+// errChatTooFast is sent back to a player who chats faster than the limit.
+var errChatTooFast = errors.New("you're chatting too fast; wait a moment")
+
+// ChatMsg is a chat line. Clients send only the text; the server relays it
+// to everyone, the sender included, with who sent it.
+type ChatMsg struct {
+	Text      string `json:"text"`
+	PlayerID  string `json:"player_id,omitempty"` // Sender, set by the server
+	Name      string `json:"name,omitempty"`      // Sender's name, set by the server
+	Color     int    `json:"color"`               // Sender's Player.Color, set by the server
+	Spectator bool   `json:"spectator,omitempty"` // The sender is watching, not playing
+}
+
+// relayChat cleans up a chat message from cc and sends it to every client.
+func (s *Server) relayChat(cc *clientConn, msg ChatMsg) error {
+	text := cleanChat(msg.Text)
+	if text == "" {
+		return nil
+	}
+	if !cc.chat.Allow() {
+		return errChatTooFast
+	}
+	if s.filter != nil {
+		filtered, err := s.filter.FilterChat(text)
+		if err != nil {
+			return err
+		}
+		text = filtered
+	}
+	out := ChatMsg{Text: text, PlayerID: cc.playerID, Name: cc.name, Spectator: cc.spectator}
+	if !cc.spectator {
+		name, color, ok := s.engine.PlayerInfo(cc.playerID)
+		if !ok {
+			return nil
+		}
+		out.Name, out.Color = name, color
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, to := range s.clients {
+		to.send(MsgChat, out)
+	}
+	return nil
+}
+
+// cleanChat trims text, drops control and format characters, which could
+// garble other players' terminals or reorder the line, as sanitizeName does,
+// and cuts it to MaxChatLen.
+func cleanChat(text string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range strings.TrimSpace(text) {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) || r == utf8.RuneError {
+			continue
+		}
+		if n == MaxChatLen {
+			break
+		}
+		b.WriteRune(r)
+		n++
+	}
+	return strings.TrimSpace(b.String())
+}
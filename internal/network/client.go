@@ -1,11 +1,14 @@
 package network
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/amalg/go-bomberman/internal/crash"
 	"github.com/amalg/go-bomberman/internal/game"
 )
 
@@ -13,64 +16,208 @@ import (
 // and receive state updates.
 type Client struct {
 	conn     net.Conn
+	enc      Encoding    // What the client writes, as picked by the server
+	compress Compression // How full states come compressed, as picked by the server
+	addr     string
+	token    string // Session token, for rejoining if the connection drops
 	playerID string
+	watching bool // Joined as a spectator
 	config   game.GameConfig
+	motd     string // From the welcome
+	rules    string
 	stateCh  chan game.GameState
+	systemCh chan SystemMsg
+	eventCh  chan game.Event
+	chatCh   chan ChatMsg
+	reconnCh chan bool  // See ReconnectChan
+	pushMu   sync.Mutex // Lets udpLoop push states alongside receiveLoop
+	shut     bool       // stateCh is closed; guarded by pushMu
+	udp      net.Conn   // States and moves over UDP, if the server granted it; nil otherwise
+	udpKey   string
+	meter    *bandwidthMeter
+	startsAt time.Time       // Local time the match starts, from the last countdown
+	seq      uint32          // Seq of the last action sent
+	base     *game.GameState // The last state received, which deltas change; only touched by receiveLoop
 	done     chan struct{}
+	stopped  chan struct{} // Closed once receiveLoop returns
 	mu       sync.Mutex
 }
 
-// NewClient creates a new client and connects to the server.
-func NewClient(addr, name string) (*Client, error) {
-	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+// leaveTimeout bounds how long Leave waits for the server to hang up.
+const leaveTimeout = time.Second
+
+// errRejected wraps the server's reason for turning a join or rejoin away.
+var errRejected = errors.New("server error")
+
+// NewClient creates a new client, connects to the server and joins with join.
+// If the connection drops mid-match, the client rejoins by itself.
+func NewClient(addr string, join JoinMsg) (*Client, error) {
+	join = offer(join)
+	return openClient(addr, join.Spectate, MsgJoin, join)
+}
+
+// offer fills in what the client can take to join.
+func offer(join JoinMsg) JoinMsg {
+	join.Deltas = true
+	join.Version = ProtocolVersion
+	join.Encodings = []Encoding{EncodingMsgpack}
+	join.Compress = []Compression{CompressionSnappy, CompressionGzip}
+	return join
+}
+
+// openClient connects to addr, joins with payload, a join sent as msgType,
+// and starts receiving.
+func openClient(addr string, spectate bool, msgType MsgType, payload any) (*Client, error) {
+	meter := newBandwidthMeter()
+	conn, welcome, err := dialSession(addr, meter, msgType, payload)
 	if err != nil {
-		return nil, fmt.Errorf("connect to %s: %w", addr, err)
+		return nil, err
 	}
 
 	c := &Client{
-		conn:    conn,
-		stateCh: make(chan game.GameState, 10),
-		done:    make(chan struct{}),
+		conn:     conn,
+		enc:      welcome.Encoding,
+		compress: welcome.Compress,
+		addr:     addr,
+		token:    welcome.Token,
+		playerID: welcome.PlayerID,
+		watching: spectate,
+		config:   welcome.Config,
+		motd:     welcome.MOTD,
+		rules:    welcome.Rules,
+		meter:    meter,
+		stateCh:  make(chan game.GameState, 10),
+		systemCh: make(chan SystemMsg, 16),
+		eventCh:  make(chan game.Event, 64),
+		chatCh:   make(chan ChatMsg, 16),
+		reconnCh: make(chan bool, 4),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	if welcome.UDPKey != "" {
+		c.openUDP(welcome.UDPKey)
+	}
+
+	// Start receiving state updates
+	crash.Go(c.receiveLoop)
+
+	return c, nil
+}
+
+// dialSession connects to addr and opens a session with a join or a rejoin,
+// returning the connection and the server's welcome.
+func dialSession(addr string, meter *bandwidthMeter, msgType MsgType, payload any) (net.Conn, WelcomeMsg, error) {
+	var welcome WelcomeMsg
+	conn, err := dial(addr)
+	if err != nil {
+		return nil, welcome, fmt.Errorf("connect to %s: %w", addr, err)
 	}
+	conn = &meteredConn{Conn: conn, meter: meter}
 
-	// Send join message
-	if err := Encode(conn, MsgJoin, JoinMsg{Name: name}); err != nil {
+	if err := Encode(conn, msgType, payload); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("send join: %w", err)
+		return nil, welcome, fmt.Errorf("send %s: %w", msgType, err)
 	}
 
 	// Read welcome message
 	env, err := Decode(conn)
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("read welcome: %w", err)
+		return nil, welcome, fmt.Errorf("read welcome: %w", err)
 	}
 
 	if env.Type == MsgError {
 		var errMsg ErrorMsg
 		DecodePayload(env, &errMsg)
 		conn.Close()
-		return nil, fmt.Errorf("server error: %s", errMsg.Message)
+		return nil, welcome, fmt.Errorf("%w: %s", errRejected, errMsg.Message)
 	}
 
 	if env.Type != MsgWelcome {
 		conn.Close()
-		return nil, fmt.Errorf("expected welcome, got %s", env.Type)
+		return nil, welcome, fmt.Errorf("expected welcome, got %s", env.Type)
 	}
 
-	var welcome WelcomeMsg
 	if err := DecodePayload(env, &welcome); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("decode welcome: %w", err)
+		return nil, welcome, fmt.Errorf("decode welcome: %w", err)
 	}
 
-	c.playerID = welcome.PlayerID
-	c.config = welcome.Config
+	if welcome.Version != 0 && welcome.Version < MinProtocolVersion {
+		conn.Close()
+		return nil, welcome, fmt.Errorf("server speaks protocol version %d, but this game needs %d or newer; update the host",
+			welcome.Version, MinProtocolVersion)
+	}
+	return conn, welcome, nil
+}
 
-	// Start receiving state updates
-	go c.receiveLoop()
+// reconnect dials the server again after the connection dropped and
+// reclaims the player with the session token, backing off between tries
+// until ReconnectGrace runs out, the server turns it away or the client is
+// closed. Only called by receiveLoop.
+func (c *Client) reconnect() bool {
+	if c.token == "" {
+		// The server predates sessions
+		return false
+	}
+	c.pushReconnecting(true)
+	c.mu.Lock()
+	udp := c.udpKey != ""
+	c.mu.Unlock()
+	rejoin := RejoinMsg{
+		Token:     c.token,
+		Deltas:    true,
+		Encodings: []Encoding{EncodingMsgpack},
+		Version:   ProtocolVersion,
+		UDP:       udp,
+		Compress:  []Compression{CompressionSnappy, CompressionGzip},
+	}
+	giveUp := time.Now().Add(ReconnectGrace)
+	for wait := reconnectRetry; time.Now().Before(giveUp); wait = min(2*wait, reconnectRetryMax) {
+		select {
+		case <-c.done:
+			return false
+		case <-time.After(min(wait, time.Until(giveUp))):
+		}
+		conn, welcome, err := dialSession(c.addr, c.meter, MsgRejoin, rejoin)
+		if errors.Is(err, errRejected) || errors.Is(err, syscall.ECONNREFUSED) {
+			// The session is gone, or the whole server is
+			return false
+		}
+		if err != nil {
+			continue
+		}
 
-	return c, nil
+		c.mu.Lock()
+		select {
+		case <-c.done:
+			c.mu.Unlock()
+			conn.Close()
+			return false
+		default:
+		}
+		c.conn.Close()
+		c.conn, c.enc, c.compress = conn, welcome.Encoding, welcome.Compress
+		c.closeUDP()
+		if welcome.UDPKey != "" {
+			c.openUDP(welcome.UDPKey)
+		}
+		c.mu.Unlock()
+		c.base = nil
+		c.pushReconnecting(false)
+		return true
+	}
+	return false
+}
+
+// pushReconnecting hands a change of connection to ReconnectChan, dropping
+// it if nobody is reading.
+func (c *Client) pushReconnecting(reconnecting bool) {
+	select {
+	case c.reconnCh <- reconnecting:
+	default:
+	}
 }
 
 // PlayerID returns the client's assigned player ID.
@@ -78,48 +225,232 @@ func (c *Client) PlayerID() string {
 	return c.playerID
 }
 
+// Spectating reports whether the client joined to watch rather than play.
+func (c *Client) Spectating() bool {
+	return c.watching
+}
+
 // Config returns the game configuration received from the server.
 func (c *Client) Config() game.GameConfig {
 	return c.config
 }
 
+// MOTD returns the host's message of the day, or "" if there's none.
+func (c *Client) MOTD() string {
+	return c.motd
+}
+
+// Rules returns the host's house rules, or "" if there are none.
+func (c *Client) Rules() string {
+	return c.rules
+}
+
 // StateChan returns a channel that yields game state updates.
 func (c *Client) StateChan() <-chan game.GameState {
 	return c.stateCh
 }
 
-// SendAction sends a player action to the server.
-func (c *Client) SendAction(actionType game.ActionType, dir game.Direction) error {
+// SystemChan returns a channel that yields server notices (MOTD, announcements).
+func (c *Client) SystemChan() <-chan SystemMsg {
+	return c.systemCh
+}
+
+// EventChan returns a channel that yields game events, such as kills.
+func (c *Client) EventChan() <-chan game.Event {
+	return c.eventCh
+}
+
+// ChatChan returns a channel that yields chat lines, the player's own
+// included.
+func (c *Client) ChatChan() <-chan ChatMsg {
+	return c.chatCh
+}
+
+// ReconnectChan returns a channel that yields true when the connection drops
+// and the client starts rejoining by itself, and false once it's back. If it
+// gives up, StateChan closes.
+func (c *Client) ReconnectChan() <-chan bool {
+	return c.reconnCh
+}
+
+// Bandwidth returns this client's traffic statistics.
+func (c *Client) Bandwidth() BandwidthStats {
+	return c.meter.Stats()
+}
+
+// pushState hands a received state to StateChan. The state handed on has
+// players of its own, so changing them leaves the base of the next delta as
+// it was.
+func (c *Client) pushState(state game.GameState) {
+	state.Players = clonePlayers(state.Players)
+	c.pushMu.Lock()
+	defer c.pushMu.Unlock()
+	if c.shut {
+		return
+	}
+	// Non-blocking send to state channel
+	select {
+	case c.stateCh <- state:
+	default:
+		// Drop old state if consumer is slow — latest state matters most
+		select {
+		case <-c.stateCh:
+		default:
+		}
+		c.stateCh <- state
+	}
+}
+
+// SendAction sends a player action to the server. It returns the action's
+// sequence number, which the player's Player.LastSeq reaches once the server
+// is done with it.
+func (c *Client) SendAction(actionType game.ActionType, dir game.Direction) (uint32, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	return Encode(c.conn, MsgAction, ActionMsg{
+	c.seq++
+	if actionType == game.ActionMove && c.udp != nil {
+		// A lost move just isn't made, and prediction puts the player back
+		b, err := encodeMessage(c.enc, MsgUDPMove, UDPMoveMsg{Key: c.udpKey, Direction: dir, Seq: c.seq})
+		if err != nil {
+			return c.seq, err
+		}
+		_, err = c.udp.Write(b)
+		return c.seq, err
+	}
+	return c.seq, EncodeWith(c.conn, c.enc, MsgAction, ActionMsg{
 		ActionType: actionType,
 		Direction:  dir,
+		Seq:        c.seq,
 	})
 }
 
+// SendChat sends a chat line to everyone in the game.
+func (c *Client) SendChat(text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return EncodeWith(c.conn, c.enc, MsgChat, ChatMsg{Text: text})
+}
+
+// SendKick asks the server to remove a player. Only the host's requests are
+// honoured.
+func (c *Client) SendKick(playerID, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return EncodeWith(c.conn, c.enc, MsgKick, KickMsg{PlayerID: playerID, Reason: reason})
+}
+
+// SendBan asks the server to remove a player and turn their address away
+// from then on. Only the host's requests are honoured.
+func (c *Client) SendBan(playerID, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return EncodeWith(c.conn, c.enc, MsgBan, KickMsg{PlayerID: playerID, Reason: reason})
+}
+
+// StartsAt returns when the current countdown ends, in local time.
+// It is zero until the server has timed the countdown.
+func (c *Client) StartsAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.startsAt
+}
+
 // SendStart requests the server to start the game.
 func (c *Client) SendStart() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	return Encode(c.conn, MsgStart, struct{}{})
+	return EncodeWith(c.conn, c.enc, MsgStart, struct{}{})
+}
+
+// SendSwitchTeam asks to move to the other team in the lobby.
+func (c *Client) SendSwitchTeam() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return EncodeWith(c.conn, c.enc, MsgSwitchTeam, struct{}{})
+}
+
+// SendHandicap asks to set a player's handicap in the lobby. Only the host
+// may.
+func (c *Client) SendHandicap(playerID string, h game.Handicap) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return EncodeWith(c.conn, c.enc, MsgSetHandicap, HandicapMsg{PlayerID: playerID, Handicap: h})
+}
+
+// SendPause asks the server to pause or resume the match. Only the host's
+// requests are honoured.
+func (c *Client) SendPause(paused bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return EncodeWith(c.conn, c.enc, MsgPause, PauseMsg{Paused: paused})
 }
 
 // Close disconnects from the server.
 func (c *Client) Close() {
+	c.stop()
+	c.mu.Lock()
+	c.conn.Close()
+	c.closeUDP()
+	c.mu.Unlock()
+}
+
+// Leave tells the server the player is quitting, so their place is freed and
+// everyone is told at once, and then closes the client. Close just hangs up,
+// and mid-match the server holds the player's place in case they rejoin.
+func (c *Client) Leave() {
+	c.stop()
+	c.mu.Lock()
+	c.conn.SetWriteDeadline(time.Now().Add(leaveTimeout))
+	err := EncodeWith(c.conn, c.enc, MsgLeave, struct{}{})
+	c.mu.Unlock()
+	if err == nil {
+		// Hanging up with states unread could reset the connection before
+		// the server reads the leave, so keep reading until the server does
+		select {
+		case <-c.stopped:
+		case <-time.After(leaveTimeout):
+		}
+	}
+	c.Close()
+}
+
+// stop stops the client from reading on, or rejoining, once its connection
+// closes.
+func (c *Client) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	select {
 	case <-c.done:
 	default:
 		close(c.done)
 	}
-	c.conn.Close()
 }
 
 func (c *Client) receiveLoop() {
-	defer close(c.stateCh)
+	defer close(c.stopped)
+	defer func() {
+		c.mu.Lock()
+		c.closeUDP()
+		c.mu.Unlock()
+		c.pushMu.Lock()
+		c.shut = true
+		close(c.stateCh)
+		c.pushMu.Unlock()
+	}()
+	defer close(c.systemCh)
+	defer close(c.eventCh)
+	defer close(c.chatCh)
+	defer close(c.reconnCh)
 
+	heartbeat := false // Whether the server sends heartbeats, so has died if they stop
 	for {
 		select {
 		case <-c.done:
@@ -127,28 +458,94 @@ func (c *Client) receiveLoop() {
 		default:
 		}
 
+		if heartbeat {
+			c.conn.SetReadDeadline(heartbeatDeadline())
+		}
 		env, err := Decode(c.conn)
 		if err != nil {
+			if c.reconnect() {
+				heartbeat = false
+				continue
+			}
 			return
 		}
 
 		switch env.Type {
-		case MsgState:
-			var stateMsg StateMsg
-			if err := DecodePayload(env, &stateMsg); err != nil {
+		case MsgState, MsgCompressed:
+			state, err := decodeState(env, c.compress)
+			if err != nil {
+				continue
+			}
+			c.base = &state
+			c.pushState(state)
+		case MsgStateDelta:
+			var delta StateDeltaMsg
+			if err := DecodePayload(env, &delta); err != nil {
+				continue
+			}
+			if c.base == nil || c.base.Tick != delta.Base {
+				// We don't have the state it changes; ask for a whole one
+				c.mu.Lock()
+				EncodeWith(c.conn, c.enc, MsgResync, struct{}{})
+				c.mu.Unlock()
+				continue
+			}
+			state := applyDelta(*c.base, delta)
+			c.base = &state
+			c.pushState(state)
+		case MsgPing:
+			heartbeat = true
+			var ping PingMsg
+			if err := DecodePayload(env, &ping); err != nil {
+				continue
+			}
+			c.mu.Lock()
+			EncodeWith(c.conn, c.enc, MsgPong, PongMsg{SentAt: ping.SentAt, Version: ProtocolVersion})
+			c.mu.Unlock()
+		case MsgSystem:
+			var sysMsg SystemMsg
+			if err := DecodePayload(env, &sysMsg); err != nil {
+				continue
+			}
+			select {
+			case c.systemCh <- sysMsg:
+			default:
+				// Notices are best-effort; drop if nobody is reading
+			}
+		case MsgEvent:
+			var eventMsg EventMsg
+			if err := DecodePayload(env, &eventMsg); err != nil {
 				continue
 			}
-			// Non-blocking send to state channel
 			select {
-			case c.stateCh <- stateMsg.State:
+			case c.eventCh <- eventMsg.Event:
 			default:
-				// Drop old state if consumer is slow — latest state matters most
-				select {
-				case <-c.stateCh:
-				default:
-				}
-				c.stateCh <- stateMsg.State
+				// Events are best-effort too
+			}
+		case MsgChat:
+			var chatMsg ChatMsg
+			if err := DecodePayload(env, &chatMsg); err != nil {
+				continue
+			}
+			select {
+			case c.chatCh <- chatMsg:
+			default:
+				// Chat is best-effort like notices
+			}
+		case MsgCountdown:
+			var countdown CountdownMsg
+			if err := DecodePayload(env, &countdown); err != nil {
+				continue
+			}
+			c.mu.Lock()
+			if countdown.StartsIn == 0 {
+				// Acknowledge at once so the server can measure our round trip
+				c.startsAt = time.Time{}
+				EncodeWith(c.conn, c.enc, MsgReadyForStart, struct{}{})
+			} else {
+				c.startsAt = time.Now().Add(countdown.StartsIn)
 			}
+			c.mu.Unlock()
 		case MsgError:
 			var errMsg ErrorMsg
 			DecodePayload(env, &errMsg)
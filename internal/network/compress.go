@@ -0,0 +1,150 @@
+package network
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/amalg/go-bomberman/internal/game"
+	"github.com/golang/snappy"
+)
+
+// Compression is how full states are compressed. A client lists the
+// compressions it reads in JoinMsg.Compress; if the server was set one with
+// SetCompression that the client reads, it names it in WelcomeMsg.Compress
+// and sends full states as MsgCompressed from
+// then on. Deltas are small already and stay as they are.
+type Compression string
+
+const (
+	CompressionGzip   Compression = "gzip"   // Smallest, at about twice the cost of sending with snappy
+	CompressionSnappy Compression = "snappy" // Cheap, for somewhat less saving than gzip
+)
+
+// maxDecompressed bounds a decompressed state, so a bad message can't make a
+// client allocate without end.
+const maxDecompressed = 16 << 20
+
+// gzipWriters reuses gzip writers, each of which allocates a megabyte.
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// CompressedStateMsg is a StateMsg, encoded as the session's envelopes are
+// and then compressed.
+type CompressedStateMsg struct {
+	Data []byte `json:"data"` // Compressed StateMsg payload; base64 in JSON
+}
+
+// ParseCompression checks a compression named on the command line; "none"
+// and "" stand for no compression.
+func ParseCompression(name string) (Compression, error) {
+	switch c := Compression(name); c {
+	case "", "none":
+		return "", nil
+	case CompressionGzip, CompressionSnappy:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown compression %q: use gzip, snappy or none", name)
+}
+
+// SetCompression compresses full states with c for clients that read it;
+// "" leaves them uncompressed. Must be called before Start.
+func (s *Server) SetCompression(c Compression) {
+	s.compress = c
+}
+
+// pickCompression returns the server's compression if the client offered
+// it, or "" for none.
+func pickCompression(server Compression, offered []Compression) Compression {
+	if server != "" && slices.Contains(offered, server) {
+		return server
+	}
+	return ""
+}
+
+// stateMessage returns a full state as the message to send it in: a
+// StateMsg, or a CompressedStateMsg when the session compresses states.
+func stateMessage(enc Encoding, c Compression, state game.GameState) (MsgType, any, error) {
+	msg := StateMsg{State: state}
+	if c == "" {
+		return MsgState, msg, nil
+	}
+	var payload []byte
+	var err error
+	if enc == EncodingMsgpack {
+		payload, err = marshalMsgpack(msg)
+	} else {
+		payload, err = json.Marshal(msg)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("marshal state: %w", err)
+	}
+	data, err := compress(c, payload)
+	if err != nil {
+		return "", nil, err
+	}
+	return MsgCompressed, CompressedStateMsg{Data: data}, nil
+}
+
+// decodeState reads the state in a MsgState or MsgCompressed envelope.
+func decodeState(env *Envelope, c Compression) (game.GameState, error) {
+	var msg StateMsg
+	if env.Type == MsgCompressed {
+		var compressed CompressedStateMsg
+		if err := DecodePayload(env, &compressed); err != nil {
+			return msg.State, err
+		}
+		payload, err := decompress(c, compressed.Data)
+		if err != nil {
+			return msg.State, err
+		}
+		env = &Envelope{Type: MsgState, Payload: payload, enc: env.enc}
+	}
+	err := DecodePayload(env, &msg)
+	return msg.State, err
+}
+
+func compress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		zw := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(zw)
+		zw.Reset(&buf)
+		zw.Write(data)
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", c)
+}
+
+func decompress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case CompressionSnappy:
+		if n, err := snappy.DecodedLen(data); err != nil || n > maxDecompressed {
+			return nil, fmt.Errorf("snappy: bad or oversized data")
+		}
+		return snappy.Decode(nil, data)
+	case CompressionGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		out, err := io.ReadAll(io.LimitReader(zr, maxDecompressed+1))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		if len(out) > maxDecompressed {
+			return nil, fmt.Errorf("gzip: over %d bytes decompressed", maxDecompressed)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("state compressed with %q, which wasn't agreed", c)
+}
@@ -0,0 +1,105 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// KeyframeEvery is how many states a client that takes deltas is sent
+// between full ones, so it resyncs even if it never notices going astray.
+const KeyframeEvery = 40
+
+// StateDeltaMsg is a state sent as the changes from the one sent before it,
+// to clients that joined with JoinMsg.Deltas. The board and the players are
+// sent only where they changed; everything else is sent whole, as it is small
+// and mostly changes every tick anyway.
+type StateDeltaMsg struct {
+	Base  uint64         `json:"base"`            // Tick of the state this changes; the client resyncs if it doesn't have it
+	State game.GameState `json:"state"`           // The new state, without its board and with only the players who changed
+	Cells []CellChange   `json:"cells,omitempty"` // Board tiles that changed
+	Left  []string       `json:"left,omitempty"`  // Players gone since Base
+}
+
+// CellChange is a board tile that changed.
+type CellChange struct {
+	X    int           `json:"x"`
+	Y    int           `json:"y"`
+	Tile game.TileType `json:"tile"`
+}
+
+// diffState returns next as changes from base. It returns false if the
+// boards differ in size, so next has to be sent whole.
+func diffState(base, next game.GameState) (StateDeltaMsg, bool) {
+	if base.Width != next.Width || base.Height != next.Height ||
+		len(base.Board) != len(next.Board) {
+		return StateDeltaMsg{}, false
+	}
+	d := StateDeltaMsg{Base: base.Tick, State: next}
+	d.State.Board = nil
+	for y, row := range next.Board {
+		if len(base.Board[y]) != len(row) {
+			return StateDeltaMsg{}, false
+		}
+		for x, tile := range row {
+			if base.Board[y][x] != tile {
+				d.Cells = append(d.Cells, CellChange{X: x, Y: y, Tile: tile})
+			}
+		}
+	}
+	d.State.Players = make(map[string]*game.Player)
+	for id, p := range next.Players {
+		if old, ok := base.Players[id]; !ok || !sameOnWire(old, p) {
+			d.State.Players[id] = p
+		}
+	}
+	for id := range base.Players {
+		if _, ok := next.Players[id]; !ok {
+			d.Left = append(d.Left, id)
+		}
+	}
+	return d, true
+}
+
+// sameOnWire reports whether a and b are sent the same, ignoring what the
+// engine keeps off the wire.
+func sameOnWire(a, b *game.Player) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// applyDelta returns the state d makes of base. base is left as it was.
+func applyDelta(base game.GameState, d StateDeltaMsg) game.GameState {
+	next := d.State
+	next.Board = make([][]game.TileType, len(base.Board))
+	for y, row := range base.Board {
+		next.Board[y] = append([]game.TileType(nil), row...)
+	}
+	for _, c := range d.Cells {
+		if c.Y >= 0 && c.Y < len(next.Board) && c.X >= 0 && c.X < len(next.Board[c.Y]) {
+			next.Board[c.Y][c.X] = c.Tile
+		}
+	}
+	next.Players = clonePlayers(base.Players)
+	for _, id := range d.Left {
+		delete(next.Players, id)
+	}
+	for id, p := range d.State.Players {
+		next.Players[id] = p
+	}
+	return next
+}
+
+// clonePlayers copies players, so a state handed on can be changed, as by
+// client-side prediction, without changing the one deltas apply to.
+func clonePlayers(players map[string]*game.Player) map[string]*game.Player {
+	clone := make(map[string]*game.Player, len(players))
+	for id, p := range players {
+		cp := *p
+		cp.Effects = append([]game.StatusEffect(nil), p.Effects...)
+		clone[id] = &cp
+	}
+	return clone
+}
@@ -0,0 +1,93 @@
+package network
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoding is how a connection's envelopes are written. Every connection
+// starts in JSON: a client lists the encodings it reads in JoinMsg.Encodings
+// and the server picks one in WelcomeMsg.Encoding, which both sides write
+// from then on. Decode tells the encodings apart by their first byte, so
+// either side reads both whatever was picked.
+type Encoding string
+
+const (
+	EncodingJSON    Encoding = "json"
+	EncodingMsgpack Encoding = "msgpack" // MessagePack, with the same field names as JSON
+)
+
+// serverEncodings are the encodings the server writes, most preferred first.
+var serverEncodings = []Encoding{EncodingMsgpack, EncodingJSON}
+
+// pickEncoding returns the server's preferred encoding among those a client
+// offered, or JSON if it offered none the server writes.
+func pickEncoding(offered []Encoding) Encoding {
+	for _, enc := range serverEncodings {
+		if slices.Contains(offered, enc) {
+			return enc
+		}
+	}
+	return EncodingJSON
+}
+
+// msgpackEnvelope is Envelope as written in MessagePack.
+type msgpackEnvelope struct {
+	Type    MsgType            `json:"type"`
+	Payload msgpack.RawMessage `json:"payload"`
+}
+
+// isMsgpack reports whether body is a MessagePack envelope rather than JSON,
+// going by its first byte: a JSON envelope starts with '{' and a MessagePack
+// one with a map header.
+func isMsgpack(body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+	b := body[0]
+	return b&0xf0 == 0x80 || b == 0xde || b == 0xdf
+}
+
+// marshalMsgpack encodes v in MessagePack, naming fields by their JSON tags.
+func marshalMsgpack(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalMsgpack decodes data written by marshalMsgpack into v.
+func unmarshalMsgpack(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// encodeMsgpackBody returns a MessagePack envelope body for Encode.
+func encodeMsgpackBody(msgType MsgType, payload any) ([]byte, error) {
+	payloadBytes, err := marshalMsgpack(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+	body, err := marshalMsgpack(msgpackEnvelope{Type: msgType, Payload: payloadBytes})
+	if err != nil {
+		return nil, fmt.Errorf("marshal envelope: %w", err)
+	}
+	return body, nil
+}
+
+// decodeMsgpackBody parses a MessagePack envelope body for Decode.
+func decodeMsgpackBody(body []byte) (*Envelope, error) {
+	var env msgpackEnvelope
+	if err := unmarshalMsgpack(body, &env); err != nil {
+		return nil, fmt.Errorf("unmarshal envelope: %w", err)
+	}
+	return &Envelope{Type: env.Type, Payload: []byte(env.Payload), enc: EncodingMsgpack}, nil
+}
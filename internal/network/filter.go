@@ -0,0 +1,127 @@
+package network
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Filter moderates player-supplied text on the server before it is accepted
+// or relayed to other clients. Hosts can plug in their own implementation.
+type Filter interface {
+	// FilterName validates a player name at join time.
+	// Returning an error rejects the join with that message.
+	FilterName(name string) (string, error)
+
+	// FilterChat cleans a chat message before it is relayed.
+	// Returning an error drops the message.
+	FilterChat(text string) (string, error)
+}
+
+// defaultBlockedWords is the built-in word list used by WordFilter.
+// It is intentionally short; hosts can extend it with a word file.
+var defaultBlockedWords = []string{
+	"asshole", "bastard", "bitch", "cock", "cunt", "dick",
+	"fuck", "piss", "shit", "slut", "twat", "wanker", "whore",
+}
+
+// WordFilter is a simple word-list based Filter.
+// Names containing a blocked word are rejected; blocked words in chat are masked.
+type WordFilter struct {
+	words []string
+}
+
+// NewWordFilter creates a filter from the built-in word list plus any extra words.
+func NewWordFilter(extra ...string) *WordFilter {
+	f := &WordFilter{}
+	for _, w := range defaultBlockedWords {
+		f.add(w)
+	}
+	for _, w := range extra {
+		f.add(w)
+	}
+	return f
+}
+
+// LoadWordFilter creates a filter from the built-in list plus the words in the
+// given file (one per line, blank lines and lines starting with # ignored).
+func LoadWordFilter(path string) (*WordFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open word list: %w", err)
+	}
+	defer file.Close()
+
+	var extra []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		extra = append(extra, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read word list: %w", err)
+	}
+	return NewWordFilter(extra...), nil
+}
+
+func (f *WordFilter) add(word string) {
+	w := lettersOnly(word)
+	if w != "" {
+		f.words = append(f.words, w)
+	}
+}
+
+// FilterName rejects names that contain a blocked word anywhere,
+// ignoring case and any non-letter characters used to disguise it.
+func (f *WordFilter) FilterName(name string) (string, error) {
+	folded := lettersOnly(name)
+	for _, w := range f.words {
+		if strings.Contains(folded, w) {
+			return "", fmt.Errorf("name %q is not allowed on this server", name)
+		}
+	}
+	return name, nil
+}
+
+// FilterChat masks every word that starts with a blocked word.
+// Punctuation and spacing around masked words are preserved.
+func (f *WordFilter) FilterChat(text string) (string, error) {
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		if !unicode.IsLetter(runes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && unicode.IsLetter(runes[end]) {
+			end++
+		}
+		word := strings.ToLower(string(runes[start:end]))
+		for _, w := range f.words {
+			if strings.HasPrefix(word, w) {
+				for i := start; i < end; i++ {
+					runes[i] = '*'
+				}
+				break
+			}
+		}
+		start = end
+	}
+	return string(runes), nil
+}
+
+// lettersOnly lowercases s and drops everything that isn't a letter.
+func lettersOnly(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package network
+
+import (
+	"testing"
+)
+
+func TestWordFilterName(t *testing.T) {
+	f := NewWordFilter("griefer")
+
+	if _, err := f.FilterName("Alice"); err != nil {
+		t.Errorf("clean name should be accepted: %v", err)
+	}
+
+	// Built-in word, disguised with case and punctuation
+	if _, err := f.FilterName("xX_S.h.I.t_Xx"); err == nil {
+		t.Error("name containing a blocked word should be rejected")
+	}
+
+	// Host-supplied extra word
+	if _, err := f.FilterName("TheGriefer"); err == nil {
+		t.Error("name containing an extra blocked word should be rejected")
+	}
+}
+
+func TestWordFilterChat(t *testing.T) {
+	f := NewWordFilter()
+
+	got, err := f.FilterChat("well SHIT, nice bomb")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "well ****, nice bomb" {
+		t.Errorf("expected blocked word to be masked, got %q", got)
+	}
+}
@@ -0,0 +1,86 @@
+package network
+
+import (
+	"time"
+)
+
+const (
+	// HeartbeatInterval is how often the server pings each client. Clients
+	// answer with a pong, which the server times for the player's RTT.
+	HeartbeatInterval = time.Second
+
+	// MissedHeartbeats is how many heartbeats in a row may pass without a
+	// word from the other side before the connection is dropped as dead.
+	// Only peers that have answered or sent a ping are held to it, so
+	// clients and servers from before heartbeats aren't dropped for silence.
+	MissedHeartbeats = 3
+
+	// DefaultIdleTimeout is how long a client may send nothing at all before
+	// the server drops it, unless set otherwise with SetIdleTimeout.
+	DefaultIdleTimeout = 2 * time.Minute
+)
+
+// heartbeatDeadline returns the read deadline for a peer that keeps up with
+// heartbeats.
+func heartbeatDeadline() time.Time {
+	return time.Now().Add(MissedHeartbeats * HeartbeatInterval)
+}
+
+// SetIdleTimeout drops clients that send nothing, not even heartbeats, for d,
+// and connections that don't join within d, so abandoned ones don't hold
+// player slots; 0 never drops them. Keep d above HeartbeatInterval, or
+// clients that only answer heartbeats are dropped too. Must be called before
+// Start.
+func (s *Server) SetIdleTimeout(d time.Duration) {
+	s.idleTimeout = d
+}
+
+// readDeadline returns the deadline for the next message from a client: the
+// idle timeout's, or a heartbeat's if the client keeps up with them and that
+// comes sooner. It is zero, for none, if neither applies.
+func (s *Server) readDeadline(heartbeat bool) time.Time {
+	var deadline time.Time
+	if s.idleTimeout > 0 {
+		deadline = time.Now().Add(s.idleTimeout)
+	}
+	if hb := heartbeatDeadline(); heartbeat && (deadline.IsZero() || hb.Before(deadline)) {
+		deadline = hb
+	}
+	return deadline
+}
+
+// heartbeatLoop pings every client each HeartbeatInterval until the server
+// stops.
+func (s *Server) heartbeatLoop() {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.pingClients()
+		}
+	}
+}
+
+// pingClients sends every client a ping.
+func (s *Server) pingClients() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, cc := range s.clients {
+		cc.send(MsgPing, PingMsg{SentAt: time.Now().UnixNano()})
+	}
+}
+
+// handlePong records the round trip a client's pong took.
+func (s *Server) handlePong(cc *clientConn, env *Envelope) {
+	var pong PongMsg
+	if err := DecodePayload(env, &pong); err != nil {
+		s.log.Warn("Invalid message", "player_id", cc.playerID, "msg_type", env.Type, "err", err)
+		return
+	}
+	if rtt := time.Since(time.Unix(0, pong.SentAt)); rtt >= 0 {
+		s.engine.SetRTT(cc.playerID, rtt)
+	}
+}
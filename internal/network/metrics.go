@@ -0,0 +1,160 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/crash"
+)
+
+// encodeBuckets are the upper bounds of the state encoding histogram's
+// buckets. Encoding a state for one client normally takes well under a
+// millisecond; the top buckets catch large boards and compression.
+var encodeBuckets = [...]time.Duration{
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+}
+
+// histogram counts durations into encodeBuckets. It is safe for concurrent
+// use.
+type histogram struct {
+	counts [len(encodeBuckets) + 1]atomic.Uint64 // The last counts what's over every bucket
+	sum    atomic.Int64                          // Nanoseconds
+}
+
+// observe records one duration.
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(encodeBuckets) && d > encodeBuckets[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+// snapshot returns what the histogram has recorded so far.
+func (h *histogram) snapshot() histogramSnapshot {
+	var snap histogramSnapshot
+	for i := range h.counts {
+		snap.counts[i] = h.counts[i].Load()
+	}
+	snap.sum = time.Duration(h.sum.Load())
+	return snap
+}
+
+// histogramSnapshot is a histogram's counts at one moment, which can be
+// added together across rooms.
+type histogramSnapshot struct {
+	counts [len(encodeBuckets) + 1]uint64
+	sum    time.Duration
+}
+
+func (h *histogramSnapshot) add(o histogramSnapshot) {
+	for i := range h.counts {
+		h.counts[i] += o.counts[i]
+	}
+	h.sum += o.sum
+}
+
+// roomTotals are the counters of one room, or of several added together.
+type roomTotals struct {
+	ticks   uint64
+	dropped uint64 // Actions dropped because the engine's queue was full
+	sent    int64  // Bytes
+	recv    int64
+	encode  histogramSnapshot
+}
+
+func (t *roomTotals) add(o roomTotals) {
+	t.ticks += o.ticks
+	t.dropped += o.dropped
+	t.sent += o.sent
+	t.recv += o.recv
+	t.encode.add(o.encode)
+}
+
+// totals returns the room's counters so far.
+func (s *Server) totals() roomTotals {
+	m := s.engine.Metrics()
+	return roomTotals{
+		ticks:   m.Ticks,
+		dropped: m.DroppedActions,
+		sent:    s.meter.sent.Load(),
+		recv:    s.meter.recv.Load(),
+		encode:  s.encodeTime.snapshot(),
+	}
+}
+
+// EnableMetrics serves the server's metrics on addr at /metrics, in the
+// Prometheus text format. Counters cover closed rooms as well as open ones,
+// so they only ever grow. Must be called before Start.
+func (r *RoomServer) EnableMetrics(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.writeMetrics(w)
+	})
+	r.metrics = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	r.metricsListener = ln
+	slog.Info("Serving metrics", "url", fmt.Sprintf("http://%s/metrics", ln.Addr()))
+	crash.Go(func() { r.metrics.Serve(ln) })
+	return nil
+}
+
+// writeMetrics writes every metric in the Prometheus text format.
+func (r *RoomServer) writeMetrics(w io.Writer) {
+	r.mu.Lock()
+	t := r.retired
+	rooms, clients := len(r.rooms), 0
+	for _, room := range r.rooms {
+		t.add(room.totals())
+		room.mu.RLock()
+		clients += len(room.clients)
+		room.mu.RUnlock()
+	}
+	r.mu.Unlock()
+
+	metric(w, "bomberman_rooms", "gauge", "Rooms open.", strconv.Itoa(rooms))
+	metric(w, "bomberman_clients", "gauge", "Clients connected, players and spectators.", strconv.Itoa(clients))
+	metric(w, "bomberman_ticks_total", "counter", "Game ticks processed across every room.", strconv.FormatUint(t.ticks, 10))
+	metric(w, "bomberman_sent_bytes_total", "counter", "Bytes sent to clients.", strconv.FormatInt(t.sent, 10))
+	metric(w, "bomberman_received_bytes_total", "counter", "Bytes received from clients.", strconv.FormatInt(t.recv, 10))
+	metric(w, "bomberman_actions_dropped_total", "counter", "Actions dropped because a room's queue was full.", strconv.FormatUint(t.dropped, 10))
+
+	const name = "bomberman_state_encode_seconds"
+	fmt.Fprintf(w, "# HELP %s Time taken to encode a state for one client.\n# TYPE %s histogram\n", name, name)
+	var count uint64
+	for i, bound := range encodeBuckets {
+		count += t.encode.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, seconds(bound), count)
+	}
+	count += t.encode.counts[len(encodeBuckets)]
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, count)
+	fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", name, seconds(t.encode.sum), name, count)
+}
+
+// metric writes a metric with a single value.
+func metric(w io.Writer, name, kind, help, value string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, kind, name, value)
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
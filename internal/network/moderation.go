@@ -0,0 +1,155 @@
+package network
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+// KickMsg is sent by the host to remove a player, with MsgKick, or to
+// remove them and turn their address away from then on, with MsgBan.
+type KickMsg struct {
+	PlayerID string `json:"player_id"`
+	Reason   string `json:"reason,omitempty"` // Shown to the player; a default is used if empty
+}
+
+// moderate kicks or bans a player on behalf of fromID, who must be the host.
+// Bots can be kicked too; banning one just kicks it.
+func (s *Server) moderate(fromID string, msg KickMsg, ban bool) error {
+	verb := "kick"
+	if ban {
+		verb = "ban"
+	}
+	if fromID != s.hostID() {
+		return fmt.Errorf("only the host can %s players", verb)
+	}
+	if msg.PlayerID == fromID {
+		return fmt.Errorf("you can't %s yourself", verb)
+	}
+	return s.expel(msg, ban, "the host")
+}
+
+// expel kicks or bans a player, telling everyone who did it: by.
+func (s *Server) expel(msg KickMsg, ban bool, by string) error {
+	verb, past := "kick", "kicked"
+	if ban {
+		verb, past = "ban", "banned"
+	}
+	s.mu.Lock()
+	_, isBot := s.bots[msg.PlayerID]
+	cc, ok := s.clients[msg.PlayerID]
+	token, away := s.awayLocked(msg.PlayerID)
+	if !ok && !isBot && !away {
+		s.mu.Unlock()
+		return fmt.Errorf("no player %s to %s", msg.PlayerID, verb)
+	}
+	if ban && ok {
+		s.banned[remoteHost(cc.conn)] = true
+	}
+	if away {
+		// Their place is no longer held for a rejoin
+		sess := s.sessions[token]
+		sess.expiry.Stop()
+		delete(s.sessions, token)
+		if ban {
+			s.banned[sess.addr] = true
+		}
+	}
+	s.mu.Unlock()
+
+	name := msg.PlayerID
+	if playerName, _, found := s.engine.PlayerInfo(msg.PlayerID); found {
+		name = playerName
+	} else if ok && cc.name != "" {
+		name = cc.name
+	}
+	if isBot {
+		s.RemoveBot(msg.PlayerID)
+	} else if away {
+		s.removePlayer(msg.PlayerID)
+		s.passHost(msg.PlayerID)
+	} else {
+		reason := cleanChat(msg.Reason)
+		if reason == "" {
+			reason = "no reason given"
+		}
+		cc.send(MsgSystem, SystemMsg{Kind: SystemKick, Text: fmt.Sprintf("You were %s by %s: %s", past, by, reason)})
+		s.removeClient(msg.PlayerID)
+	}
+	s.log.Info("Player "+past, "player_id", msg.PlayerID, "name", name, "by", by)
+	s.broadcastSystem("", SystemMsg{Kind: SystemAnnounce, Text: fmt.Sprintf("%s was %s by %s", name, past, by)})
+	return nil
+}
+
+// turnAwayBanned tells conn it is banned, reporting whether it comes from a
+// banned address.
+func (s *Server) turnAwayBanned(conn net.Conn) bool {
+	if !s.isBanned(conn) {
+		return false
+	}
+	s.log.Info("Turned away banned address", "addr", conn.RemoteAddr())
+	Encode(conn, MsgError, ErrorMsg{Message: "you are banned from this room"})
+	return true
+}
+
+// isBanned reports whether conn comes from a banned address.
+func (s *Server) isBanned(conn net.Conn) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.banned[remoteHost(conn)]
+}
+
+// remoteHost returns the address conn comes from, without its port.
+func remoteHost(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// passHost makes another player the host if the one with goneID, who has
+// just been removed, was it, and tells everyone. Players still connected
+// come first, then those whose place is held for a rejoin, each in the order
+// they joined. With nobody left, the next player to join becomes the host.
+func (s *Server) passHost(goneID string) {
+	s.mu.Lock()
+	if s.host != goneID {
+		s.mu.Unlock()
+		return
+	}
+	var connected, held []string
+	for _, sess := range s.sessions {
+		if _, ok := s.clients[sess.playerID]; ok {
+			connected = append(connected, sess.playerID)
+		} else {
+			held = append(held, sess.playerID)
+		}
+	}
+	s.host = ""
+	for _, ids := range [][]string{connected, held} {
+		if len(ids) > 0 {
+			s.host = slices.MinFunc(ids, joinedBefore)
+			break
+		}
+	}
+	host := s.host
+	s.mu.Unlock()
+
+	if host == "" {
+		return
+	}
+	s.log.Info("Host passed on", "player_id", host)
+	s.broadcastSystem("", SystemMsg{
+		Kind: SystemAnnounce,
+		Text: fmt.Sprintf("%s is now the host", s.engine.PlayerName(host)),
+	})
+}
+
+// joinedBefore orders player IDs by when they joined: they are numbered by
+// the time, so a shorter one came first.
+func joinedBefore(a, b string) int {
+	return cmp.Or(cmp.Compare(len(a), len(b)), strings.Compare(a, b))
+}
@@ -0,0 +1,262 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/crash"
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+const (
+	// MulticastGroup is the LAN multicast group used for spectator streams.
+	// 239.255.0.0/16 is the organization-local scope, so routers won't forward it.
+	MulticastGroup = "239.255.42.99:9997"
+
+	// maxDatagram is the largest state we attempt to send in one UDP packet.
+	maxDatagram = 65000
+
+	// multicastEncoding and multicastCompression are how datagrams are
+	// multicast: msgpack envelopes, gzipped whole, to keep each one in as few
+	// IP fragments as can be, as a lost fragment loses the whole state.
+	multicastEncoding    = EncodingMsgpack
+	multicastCompression = CompressionGzip
+
+	// multicastKeyframeEvery is how many states are multicast between full
+	// ones. Those in between are deltas from the last keyframe rather than
+	// from the state before, so a lost datagram costs spectators that one
+	// state, not every state up to the next keyframe.
+	multicastKeyframeEvery = 20
+
+	// multicastSilence is how long the host a spectator follows has to go
+	// quiet before it follows another, when it wasn't told which to watch.
+	multicastSilence = 3 * time.Second
+)
+
+// multicastStreamer sends every state to a multicast group so any number of
+// LAN spectators can watch without a TCP connection each: a keyframe every
+// multicastKeyframeEvery states, and deltas from it in between.
+type multicastStreamer struct {
+	conn     *net.UDPConn
+	warnOnce sync.Once
+	key      *game.GameState // The last keyframe sent; nil until the first
+	sinceKey int             // Deltas sent since key
+}
+
+func newMulticastStreamer(group string) (*multicastStreamer, error) {
+	addr, err := net.ResolveUDPAddr("udp4", group)
+	if err != nil {
+		return nil, fmt.Errorf("resolve multicast group: %w", err)
+	}
+	conn, err := net.DialUDP("udp4", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial multicast group: %w", err)
+	}
+	return &multicastStreamer{conn: conn}, nil
+}
+
+// send writes one state as a single datagram: a delta from the last
+// keyframe, or a compressed keyframe when one is due.
+func (m *multicastStreamer) send(state game.GameState) {
+	msg, key, err := m.encode(state)
+	if err != nil {
+		return
+	}
+	if len(msg) > maxDatagram {
+		m.warnOnce.Do(func() {
+			slog.Warn("State too large for multicast; spectators will miss frames", "bytes", len(msg))
+		})
+		return
+	}
+	if key {
+		m.key, m.sinceKey = &state, 0
+	} else {
+		m.sinceKey++
+	}
+	m.conn.Write(msg)
+}
+
+// encode returns state as the datagram to send, and whether it's a keyframe.
+func (m *multicastStreamer) encode(state game.GameState) ([]byte, bool, error) {
+	var msg []byte
+	var err error
+	key := true
+	if m.key != nil && m.sinceKey+1 < multicastKeyframeEvery {
+		if delta, ok := diffState(*m.key, state); ok {
+			msg, err = encodeMessage(multicastEncoding, MsgStateDelta, delta)
+			key = false
+		}
+	}
+	if key {
+		msg, err = encodeMessage(multicastEncoding, MsgState, StateMsg{State: state})
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	datagram, err := compress(multicastCompression, msg)
+	return datagram, key, err
+}
+
+func (m *multicastStreamer) close() {
+	m.conn.Close()
+}
+
+// MulticastSpectator receives a host's multicast state stream. It follows
+// one host, so several hosts multicasting on the same LAN don't interleave
+// their frames: the one it was told to watch, or else the first it hears,
+// until that one goes quiet for multicastSilence.
+type MulticastSpectator struct {
+	conn    *net.UDPConn
+	host    net.IP // The host to watch; nil to follow whichever is streaming
+	stateCh chan game.GameState
+	done    chan struct{}
+}
+
+// NewMulticastSpectator joins the multicast group and starts receiving states
+// from host, an IP address, or from whichever host is streaming if host is
+// empty.
+func NewMulticastSpectator(group, host string) (*MulticastSpectator, error) {
+	addr, err := net.ResolveUDPAddr("udp4", group)
+	if err != nil {
+		return nil, fmt.Errorf("resolve multicast group: %w", err)
+	}
+	var hostIP net.IP
+	if host != "" {
+		if hostIP = net.ParseIP(host); hostIP == nil {
+			return nil, fmt.Errorf("watch %q: not an IP address", host)
+		}
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("join multicast group %s: %w", group, err)
+	}
+	conn.SetReadBuffer(maxDatagram * 4)
+
+	s := &MulticastSpectator{
+		conn:    conn,
+		host:    hostIP,
+		stateCh: make(chan game.GameState, 10),
+		done:    make(chan struct{}),
+	}
+	crash.Go(s.receiveLoop)
+	return s, nil
+}
+
+// StateChan returns a channel that yields game state updates.
+func (s *MulticastSpectator) StateChan() <-chan game.GameState {
+	return s.stateCh
+}
+
+// Close leaves the multicast group.
+func (s *MulticastSpectator) Close() {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	s.conn.Close()
+}
+
+func (s *MulticastSpectator) receiveLoop() {
+	defer close(s.stateCh)
+
+	var (
+		from   *net.UDPAddr // The stream followed
+		heard  time.Time    // When it was last heard
+		stream multicastStream
+	)
+	buf := make([]byte, maxDatagram+1024)
+	for {
+		n, src, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+			default:
+				if !errors.Is(err, net.ErrClosed) {
+					slog.Warn("Multicast receive failed; no longer spectating", "err", err)
+				}
+			}
+			return
+		}
+
+		switch {
+		case s.host != nil && !src.IP.Equal(s.host):
+			continue
+		case from == nil || time.Since(heard) > multicastSilence:
+			// A host that restarts streams from a new port, so a new stream
+			// is followed once the old one has gone quiet
+			if from == nil || !sameAddr(from, src) {
+				from, stream = src, multicastStream{}
+			}
+		case !sameAddr(from, src):
+			continue
+		}
+		heard = time.Now()
+
+		state, ok := stream.decode(buf[:n])
+		if !ok {
+			continue
+		}
+
+		// Latest state matters most; drop the oldest if the viewer is slow
+		select {
+		case s.stateCh <- state:
+		default:
+			select {
+			case <-s.stateCh:
+			default:
+			}
+			s.stateCh <- state
+		}
+	}
+}
+
+// multicastStream decodes the datagrams of one host's stream.
+type multicastStream struct {
+	key *game.GameState // The last keyframe, which the deltas build on
+}
+
+// decode returns the state in a datagram, or false if it holds none or is a
+// delta from a keyframe the stream doesn't have.
+func (st *multicastStream) decode(datagram []byte) (game.GameState, bool) {
+	msg, err := decompress(multicastCompression, datagram)
+	if err != nil {
+		return game.GameState{}, false
+	}
+	env, err := Decode(bytes.NewReader(msg))
+	if err != nil {
+		return game.GameState{}, false
+	}
+	switch env.Type {
+	case MsgState:
+		var stateMsg StateMsg
+		if DecodePayload(env, &stateMsg) != nil {
+			return game.GameState{}, false
+		}
+		state := stateMsg.State
+		key := state
+		st.key = &key
+		// Handed on with players of its own, as the client does, so the
+		// keyframe stays as it came
+		state.Players = clonePlayers(state.Players)
+		return state, true
+	case MsgStateDelta:
+		var delta StateDeltaMsg
+		if DecodePayload(env, &delta) != nil || st.key == nil || delta.Base != st.key.Tick {
+			// Joined mid-stream or lost the keyframe: wait for the next
+			return game.GameState{}, false
+		}
+		return applyDelta(*st.key, delta), true
+	}
+	return game.GameState{}, false
+}
+
+// sameAddr reports whether a and b are the same UDP address.
+func sameAddr(a, b *net.UDPAddr) bool {
+	return a.IP.Equal(b.IP) && a.Port == b.Port
+}
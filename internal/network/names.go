@@ -0,0 +1,84 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxNameLen bounds a player's name, in characters.
+const MaxNameLen = 16
+
+// ErrEmptyName rejects a join whose name is empty, or nothing but characters
+// sanitizeName strips.
+var ErrEmptyName = errors.New("enter a name to join")
+
+// sanitizeName cleans a name a client sent, which is drawn into every other
+// player's terminal. It strips ANSI escape sequences, control characters and
+// invisible formatting characters (zero-width spaces and joiners,
+// bidirectional overrides), turns other spacing into plain spaces and trims
+// the ends. Names that are then empty or longer than MaxNameLen are rejected.
+func sanitizeName(name string) (string, error) {
+	name = strings.ToValidUTF8(name, "")
+	var b strings.Builder
+	for i := 0; i < len(name); {
+		if n := escapeLen(name[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(name[i:])
+		i += size
+		switch {
+		case unicode.IsSpace(r):
+			b.WriteByte(' ')
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+		default:
+			b.WriteRune(r)
+		}
+	}
+	name = strings.Join(strings.Fields(b.String()), " ")
+	switch {
+	case name == "":
+		return "", ErrEmptyName
+	case utf8.RuneCountInString(name) > MaxNameLen:
+		return "", fmt.Errorf("names are at most %d characters", MaxNameLen)
+	}
+	return name, nil
+}
+
+// escapeLen returns the length of the terminal escape sequence s starts
+// with, or 0 if it doesn't start with one: a CSI sequence such as a color
+// change, an OSC one such as a window title, or ESC and one character.
+func escapeLen(s string) int {
+	switch {
+	case strings.HasPrefix(s, "\x1b["), strings.HasPrefix(s, "\u009b"):
+		// Parameters and intermediates up to a final byte in @ to ~
+		start := 2
+		if s[0] != '\x1b' {
+			start = len("\u009b")
+		}
+		for i := start; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(s)
+	case strings.HasPrefix(s, "\x1b]"):
+		// Up to BEL or ESC \
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	case strings.HasPrefix(s, "\x1b") && len(s) > 1:
+		_, size := utf8.DecodeRuneInString(s[1:])
+		return 1 + size
+	}
+	return 0
+}
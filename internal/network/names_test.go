@@ -0,0 +1,35 @@
+package network
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	for _, tc := range []struct {
+		name, want string
+	}{
+		{"Alice", "Alice"},
+		{"  Bob  the\tGreat ", "Bob the Great"},
+		{"\x1b[31mRed\x1b[0m", "Red"},
+		{"\x1b]0;pwned\aEve", "Eve"},
+		{"\x1b]8;;http://evil\x1b\\Mallory", "Mallory"},
+		{"Zero\u200bWidth\u202e", "ZeroWidth"},
+		{"Bell\a\r\nRing", "Bell Ring"},
+		{"Zoë 🙂", "Zoë 🙂"},
+	} {
+		got, err := sanitizeName(tc.name)
+		if err != nil || got != tc.want {
+			t.Errorf("sanitizeName(%q) = %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+
+	for _, name := range []string{"", "   ", "\x1b[2J\u200b"} {
+		if _, err := sanitizeName(name); !errors.Is(err, ErrEmptyName) {
+			t.Errorf("sanitizeName(%q) = %v; want ErrEmptyName", name, err)
+		}
+	}
+	if _, err := sanitizeName("ThisNameIsFarTooLong"); err == nil {
+		t.Error("expected a name over MaxNameLen to be rejected")
+	}
+}
@@ -0,0 +1,76 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// maxProbePings bounds how many pings one probe connection is answered.
+	maxProbePings = 32
+	// probeTimeout is how long either side waits for the next probe message.
+	probeTimeout = 5 * time.Second
+)
+
+// ErrProbeUnsupported is returned by Probe when the server predates probes.
+var ErrProbeUnsupported = errors.New("server does not answer probes")
+
+// ProbeResult is what Probe learned about a server.
+type ProbeResult struct {
+	Version int             // Server's ProtocolVersion
+	RTTs    []time.Duration // Round trip of each ping
+}
+
+// Probe pings the server on conn count times without joining the game.
+func Probe(conn net.Conn, count int) (ProbeResult, error) {
+	var result ProbeResult
+	for i := 0; i < count; i++ {
+		conn.SetDeadline(time.Now().Add(probeTimeout))
+		sent := time.Now()
+		if err := Encode(conn, MsgPing, PingMsg{SentAt: sent.UnixNano()}); err != nil {
+			return result, fmt.Errorf("send ping: %w", err)
+		}
+		env, err := Decode(conn)
+		if err != nil {
+			return result, fmt.Errorf("read pong: %w", err)
+		}
+		if env.Type == MsgError {
+			// Servers without probe support insist on a join first
+			return result, ErrProbeUnsupported
+		}
+		if env.Type != MsgPong {
+			return result, fmt.Errorf("expected pong, got %s", env.Type)
+		}
+		var pong PongMsg
+		if err := DecodePayload(env, &pong); err != nil {
+			return result, fmt.Errorf("decode pong: %w", err)
+		}
+		result.Version = pong.Version
+		result.RTTs = append(result.RTTs, time.Since(sent))
+	}
+	conn.SetDeadline(time.Time{})
+	return result, nil
+}
+
+// answerProbe replies to the pings of a connection that opened with one
+// instead of a join, until it sends something else or goes quiet.
+func (s *Server) answerProbe(conn net.Conn, env *Envelope) {
+	for i := 0; i < maxProbePings && env.Type == MsgPing; i++ {
+		var ping PingMsg
+		if err := DecodePayload(env, &ping); err != nil {
+			return
+		}
+		if err := Encode(conn, MsgPong, PongMsg{SentAt: ping.SentAt, Version: ProtocolVersion}); err != nil {
+			return
+		}
+
+		conn.SetReadDeadline(time.Now().Add(probeTimeout))
+		next, err := Decode(conn)
+		if err != nil {
+			return
+		}
+		env = next
+	}
+}
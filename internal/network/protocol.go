@@ -1,43 +1,116 @@
 package network
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/amalg/go-bomberman/internal/game"
 )
 
+// ProtocolVersion is bumped whenever the wire format changes incompatibly.
+// Version 2 sends bomb and fire expiry as ticks (expires_tick) rather than
+// times (expires_at).
+const ProtocolVersion = 2
+
+// MinProtocolVersion is the oldest version the server still speaks, to
+// clients that join with it. Clients that join with an older one are turned
+// away. Raise it when support for a version is dropped.
+const MinProtocolVersion = 2
+
 // MsgType identifies the type of network message.
 type MsgType string
 
 const (
-	MsgJoin    MsgType = "join"
-	MsgWelcome MsgType = "welcome"
-	MsgAction  MsgType = "action"
-	MsgState   MsgType = "state"
-	MsgError   MsgType = "error"
-	MsgStart   MsgType = "start"
+	MsgJoin          MsgType = "join"
+	MsgWelcome       MsgType = "welcome"
+	MsgAction        MsgType = "action"
+	MsgState         MsgType = "state"
+	MsgError         MsgType = "error"
+	MsgStart         MsgType = "start"
+	MsgSystem        MsgType = "system"
+	MsgCountdown     MsgType = "countdown"
+	MsgReadyForStart MsgType = "ready_for_start"
+	MsgPing          MsgType = "ping"
+	MsgPong          MsgType = "pong"
+	MsgSwitchTeam    MsgType = "switch_team"
+	MsgSetHandicap   MsgType = "set_handicap"
+	MsgEvent         MsgType = "event"
+	MsgPause         MsgType = "pause"
+	MsgStateDelta    MsgType = "state_delta"
+	MsgResync        MsgType = "resync"
+	MsgRejoin        MsgType = "rejoin"
+	MsgChat          MsgType = "chat"
+	MsgKick          MsgType = "kick"
+	MsgBan           MsgType = "ban"
+	MsgUDPHello      MsgType = "udp_hello"
+	MsgUDPMove       MsgType = "udp_move"
+	MsgCompressed    MsgType = "compressed_state"
+	MsgListRooms     MsgType = "list_rooms"
+	MsgCreateRoom    MsgType = "create_room"
+	MsgJoinRoom      MsgType = "join_room"
+	MsgRooms         MsgType = "rooms"
+	MsgLeave         MsgType = "leave"
 )
 
 // Envelope wraps all messages with a type discriminator for deserialization.
 type Envelope struct {
 	Type    MsgType         `json:"type"`
 	Payload json.RawMessage `json:"payload"`
+
+	enc Encoding // How Payload is encoded; empty for JSON
 }
 
 // --- Client → Server Messages ---
 
 // JoinMsg is sent by a client to join the game.
 type JoinMsg struct {
-	Name string `json:"name"`
+	Name      string         `json:"name"`
+	Cosmetics game.Cosmetics `json:"cosmetics,omitempty"`
+	Deltas    bool           `json:"deltas,omitempty"`    // The client applies StateDeltaMsg, so send those between full states
+	Encodings []Encoding     `json:"encodings,omitempty"` // Encodings the client reads besides JSON; see Encoding
+	Version   int            `json:"version,omitempty"`   // Client's ProtocolVersion; 0 for clients from before it was sent, which speak version 1
+	Spectate  bool           `json:"spectate,omitempty"`  // Watch without playing, even if the game is full or under way
+	UDP       bool           `json:"udp,omitempty"`       // Also take states and send moves over UDP, if the server can; see WelcomeMsg.UDPKey
+	Compress  []Compression  `json:"compress,omitempty"`  // Compressions the client reads; see Compression
+}
+
+// RejoinMsg opens a connection instead of a JoinMsg to reclaim the player
+// of a session whose connection dropped. The other fields are as in JoinMsg.
+type RejoinMsg struct {
+	Token     string        `json:"token"` // WelcomeMsg.Token of the session
+	Deltas    bool          `json:"deltas,omitempty"`
+	Encodings []Encoding    `json:"encodings,omitempty"`
+	Version   int           `json:"version,omitempty"`
+	UDP       bool          `json:"udp,omitempty"`
+	Compress  []Compression `json:"compress,omitempty"`
 }
 
 // ActionMsg is sent by a client to perform an action.
 type ActionMsg struct {
 	ActionType game.ActionType `json:"action_type"`
 	Direction  game.Direction  `json:"direction,omitempty"`
+	Seq        uint32          `json:"seq,omitempty"` // Numbers the client's actions from 1; see game.Player.LastSeq
+}
+
+// HandicapMsg is sent by the host to set a player's handicap in the lobby.
+type HandicapMsg struct {
+	PlayerID string        `json:"player_id"`
+	Handicap game.Handicap `json:"handicap"`
+}
+
+// PauseMsg is sent by the host to pause or resume the match.
+type PauseMsg struct {
+	Paused bool `json:"paused"`
+}
+
+// PingMsg asks the server for a MsgPong. A connection may open with pings
+// instead of a join to probe the server without taking a player slot.
+type PingMsg struct {
+	SentAt int64 `json:"sent_at"` // Sender's clock in Unix nanoseconds, echoed back
 }
 
 // --- Server → Client Messages ---
@@ -46,6 +119,14 @@ type ActionMsg struct {
 type WelcomeMsg struct {
 	PlayerID string          `json:"player_id"`
 	Config   game.GameConfig `json:"config"`
+	Encoding Encoding        `json:"encoding,omitempty"` // What both sides write after this, picked from JoinMsg.Encodings; empty for JSON
+	Version  int             `json:"version,omitempty"`  // Protocol version the session speaks: the client's, or the server's if that is older
+	Token    string          `json:"token,omitempty"`    // Reclaims the player with a RejoinMsg if the connection drops; keep it secret
+	HostID   string          `json:"host_id,omitempty"`  // The player who may start, pause and moderate the game
+	Compress Compression     `json:"compress,omitempty"` // How full states are compressed, picked from JoinMsg.Compress; empty for none
+	UDPKey   string          `json:"udp_key,omitempty"`  // Set if JoinMsg.UDP was granted: send it in UDPHelloMsg and UDPMoveMsg to the same port over UDP
+	MOTD     string          `json:"motd,omitempty"`     // The host's message of the day, also sent as a SystemMsg for older clients
+	Rules    string          `json:"rules,omitempty"`    // The host's house rules, such as the mode and what's frowned on
 }
 
 // StateMsg is the full game state broadcast to all clients.
@@ -53,27 +134,76 @@ type StateMsg struct {
 	State game.GameState `json:"state"`
 }
 
+// EventMsg forwards an engine event, such as a kill for the kill feed.
+type EventMsg struct {
+	Event game.Event `json:"event"`
+}
+
+// SystemKind distinguishes the kinds of server notices.
+type SystemKind string
+
+const (
+	SystemMOTD     SystemKind = "motd"     // Host's message of the day, sent once on join
+	SystemAnnounce SystemKind = "announce" // Lobby announcements such as "Alice joined (3/4)"
+	SystemKick     SystemKind = "kick"     // Why the host removed you, just before the connection closes
+)
+
+// SystemMsg is a server-generated notice shown to players.
+type SystemMsg struct {
+	Kind SystemKind `json:"kind"`
+	Text string     `json:"text"`
+}
+
+// CountdownMsg announces the start countdown. A zero StartsIn asks the
+// client to reply with MsgReadyForStart straight away; the server then sends
+// each client the time left until the match starts, minus that client's
+// measured round trip, so everyone's first move reaches the server at the
+// same moment.
+type CountdownMsg struct {
+	StartsIn time.Duration `json:"starts_in"`
+}
+
+// PongMsg answers a PingMsg.
+type PongMsg struct {
+	SentAt  int64 `json:"sent_at"` // Copied from the ping
+	Version int   `json:"version"` // Server's ProtocolVersion
+}
+
+// negotiateVersion returns the protocol version to speak with a client that
+// joined speaking version client, or an error saying why it can't join.
+func negotiateVersion(client int) (int, error) {
+	if client == 0 {
+		client = 1
+	}
+	if client < MinProtocolVersion {
+		return 0, fmt.Errorf("your game speaks protocol version %d, but this server needs %d or newer; update your game",
+			client, MinProtocolVersion)
+	}
+	return min(client, ProtocolVersion), nil
+}
+
 // ErrorMsg notifies a client of an error.
 type ErrorMsg struct {
 	Message string `json:"message"`
 }
 
-// Encode serializes a message and writes it to the writer.
+// Encode serializes a message as JSON and writes it to the writer.
 // Format: [4-byte big-endian length][JSON body]
 func Encode(w io.Writer, msgType MsgType, payload interface{}) error {
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("marshal payload: %w", err)
-	}
+	return EncodeWith(w, EncodingJSON, msgType, payload)
+}
 
-	env := Envelope{
-		Type:    msgType,
-		Payload: json.RawMessage(payloadBytes),
+// EncodeWith is Encode in the given encoding.
+func EncodeWith(w io.Writer, enc Encoding, msgType MsgType, payload interface{}) error {
+	var body []byte
+	var err error
+	if enc == EncodingMsgpack {
+		body, err = encodeMsgpackBody(msgType, payload)
+	} else {
+		body, err = encodeJSONBody(msgType, payload)
 	}
-
-	body, err := json.Marshal(env)
 	if err != nil {
-		return fmt.Errorf("marshal envelope: %w", err)
+		return err
 	}
 
 	// Write 4-byte length header
@@ -90,7 +220,37 @@ func Encode(w io.Writer, msgType MsgType, payload interface{}) error {
 	return nil
 }
 
-// Decode reads a length-prefixed JSON message from the reader.
+// encodeMessage returns one message as EncodeWith writes it, to be sent
+// whole: as a datagram, or from a client's send queue.
+func encodeMessage(enc Encoding, msgType MsgType, payload any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := EncodeWith(&buf, enc, msgType, payload); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeJSONBody returns a JSON envelope body for Encode.
+func encodeJSONBody(msgType MsgType, payload interface{}) ([]byte, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	env := Envelope{
+		Type:    msgType,
+		Payload: json.RawMessage(payloadBytes),
+	}
+
+	body, err := json.Marshal(env)
+	if err != nil {
+		return nil, fmt.Errorf("marshal envelope: %w", err)
+	}
+	return body, nil
+}
+
+// Decode reads a length-prefixed message from the reader, in whichever
+// encoding it was written.
 func Decode(r io.Reader) (*Envelope, error) {
 	// Read 4-byte length header
 	var length uint32
@@ -109,6 +269,10 @@ func Decode(r io.Reader) (*Envelope, error) {
 		return nil, fmt.Errorf("read body: %w", err)
 	}
 
+	if isMsgpack(body) {
+		return decodeMsgpackBody(body)
+	}
+
 	var env Envelope
 	if err := json.Unmarshal(body, &env); err != nil {
 		return nil, fmt.Errorf("unmarshal envelope: %w", err)
@@ -119,5 +283,8 @@ func Decode(r io.Reader) (*Envelope, error) {
 
 // DecodePayload unmarshals the payload from an envelope into the target struct.
 func DecodePayload(env *Envelope, target interface{}) error {
+	if env.enc == EncodingMsgpack {
+		return unmarshalMsgpack(env.Payload, target)
+	}
 	return json.Unmarshal(env.Payload, target)
 }
@@ -0,0 +1,45 @@
+package network
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket: it allows bursts of up to burst events and
+// refills one token every interval.
+type rateLimiter struct {
+	mu       sync.Mutex
+	tokens   float64
+	burst    float64
+	interval time.Duration
+	last     time.Time
+}
+
+// newRateLimiter creates a full bucket.
+func newRateLimiter(burst int, interval time.Duration) *rateLimiter {
+	return &rateLimiter{
+		tokens:   float64(burst),
+		burst:    float64(burst),
+		interval: interval,
+		last:     time.Now(),
+	}
+}
+
+// Allow reports whether an event may happen now, consuming a token if so.
+func (r *rateLimiter) Allow() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.tokens += float64(now.Sub(r.last)) / float64(r.interval)
+	if r.tokens > r.burst {
+		r.tokens = r.burst
+	}
+	r.last = now
+
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
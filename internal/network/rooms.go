@@ -0,0 +1,392 @@
+package network
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/amalg/go-bomberman/internal/crash"
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// MaxRooms bounds how many rooms a RoomServer hosts at once.
+const MaxRooms = 64
+
+// maxRoomID bounds the length of a room's ID.
+const maxRoomID = 32
+
+// JoinRoomMsg opens a connection to a multi-room server instead of a
+// JoinMsg, to join one of its rooms. Sent as MsgCreateRoom, it creates the
+// room first.
+type JoinRoomMsg struct {
+	Room string  `json:"room"`
+	Join JoinMsg `json:"join"`
+}
+
+// RoomListMsg answers MsgListRooms with a multi-room server's rooms.
+type RoomListMsg struct {
+	Rooms []RoomSummary `json:"rooms"`
+}
+
+// RoomSummary describes one room of a multi-room server.
+type RoomSummary struct {
+	ID         string          `json:"id"`
+	Players    int             `json:"players"`
+	MaxPlayers int             `json:"max_players"`
+	Status     game.GameStatus `json:"status"`
+	Mode       game.Mode       `json:"mode,omitempty"`
+}
+
+// RoomServer hosts many rooms on one port, each a Server around its own
+// engine, which a game.Manager runs. A connection opens by listing the rooms
+// or by creating or joining one, and is then handed to that room's Server,
+// which serves it as a single-room server would. A rejoin finds its room by
+// its token. Rooms close once their last player leaves, bots aside.
+type RoomServer struct {
+	addr     string
+	config   game.GameConfig // Settings of every room
+	manager  *game.Manager
+	setup    []func(*Server)
+	listener net.Listener
+	tls      *tls.Config // Set by EnableTLS; nil for plaintext
+	rooms    map[string]*Server
+	started  time.Time
+	mu       sync.Mutex
+	done     chan struct{}
+
+	// Set by EnableAdmin; nil without the admin API
+	admin         *http.Server
+	adminListener net.Listener
+
+	// Set by EnableMetrics; nil without metrics
+	metrics         *http.Server
+	metricsListener net.Listener
+
+	// retired adds up the counters of rooms that have closed. Guarded by mu.
+	retired roomTotals
+}
+
+// NewRoomServer creates a server for rooms playing config's game.
+func NewRoomServer(addr string, config game.GameConfig) *RoomServer {
+	r := &RoomServer{
+		addr:    addr,
+		config:  config,
+		manager: game.NewManager(),
+		rooms:   make(map[string]*Server),
+		done:    make(chan struct{}),
+	}
+	r.manager.OnClose(r.closed)
+	return r
+}
+
+// Configure has fn set up each room's Server as it's created, with SetMOTD,
+// SetRules and the like. Must be called before Start.
+func (r *RoomServer) Configure(fn func(*Server)) {
+	r.setup = append(r.setup, fn)
+}
+
+// Start begins listening for connections.
+func (r *RoomServer) Start() error {
+	var err error
+	r.listener, err = net.Listen("tcp", r.addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	if r.tls != nil {
+		r.listener = tls.NewListener(r.listener, r.tls)
+	}
+	r.started = time.Now()
+	slog.Info("Hosting rooms", "addr", r.addr)
+	printLocalIPs(r.addr)
+	crash.Go(r.acceptLoop)
+	return nil
+}
+
+// Stop closes every room and shuts the server down.
+func (r *RoomServer) Stop() {
+	close(r.done)
+	if r.listener != nil {
+		r.listener.Close()
+	}
+	if r.admin != nil {
+		r.admin.Close()
+	}
+	if r.metrics != nil {
+		r.metrics.Close()
+	}
+	r.manager.CloseAll()
+}
+
+func (r *RoomServer) acceptLoop() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			select {
+			case <-r.done:
+				return
+			default:
+				slog.Error("Accept failed", "err", err)
+				continue
+			}
+		}
+		crash.Go(func() { r.handleConn(conn) })
+	}
+}
+
+// handleConn answers a connection's pings and room lists until it creates,
+// joins or rejoins a room, and then hands it to the room.
+func (r *RoomServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		conn.SetReadDeadline(time.Now().Add(DefaultIdleTimeout))
+		env, err := Decode(conn)
+		if err != nil {
+			return
+		}
+		switch env.Type {
+		case MsgPing:
+			var ping PingMsg
+			if DecodePayload(env, &ping) != nil ||
+				Encode(conn, MsgPong, PongMsg{SentAt: ping.SentAt, Version: ProtocolVersion}) != nil {
+				return
+			}
+		case MsgListRooms:
+			if Encode(conn, MsgRooms, RoomListMsg{Rooms: r.list()}) != nil {
+				return
+			}
+		case MsgCreateRoom, MsgJoinRoom:
+			var msg JoinRoomMsg
+			if err := DecodePayload(env, &msg); err != nil {
+				slog.Warn("Invalid message", "msg_type", env.Type, "err", err)
+				return
+			}
+			room, err := r.open(msg.Room, env.Type == MsgCreateRoom)
+			if err != nil {
+				Encode(conn, MsgError, ErrorMsg{Message: err.Error()})
+				return
+			}
+			room.handleJoin(&meteredConn{Conn: conn, meter: room.meter}, msg.Join)
+			if room.engine.PlayerCount() == 0 {
+				// Nobody got to play, as when the join was turned away
+				r.manager.Close(msg.Room)
+			}
+			return
+		case MsgRejoin:
+			var msg RejoinMsg
+			if err := DecodePayload(env, &msg); err != nil {
+				slog.Warn("Invalid message", "msg_type", env.Type, "err", err)
+				return
+			}
+			room := r.roomWithSession(msg.Token)
+			if room == nil {
+				Encode(conn, MsgError, ErrorMsg{Message: "your session has expired; join again"})
+				return
+			}
+			room.handleSession(&meteredConn{Conn: conn, meter: room.meter}, env)
+			return
+		default:
+			Encode(conn, MsgError, ErrorMsg{Message: "expected list_rooms, create_room or join_room"})
+			return
+		}
+	}
+}
+
+// open returns room id, creating it if create is set.
+func (r *RoomServer) open(id string, create bool) (*Server, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !create {
+		room, ok := r.rooms[id]
+		if !ok {
+			return nil, fmt.Errorf("room %q not found", id)
+		}
+		return room, nil
+	}
+	if err := checkRoomID(id); err != nil {
+		return nil, err
+	}
+	if len(r.rooms) >= MaxRooms {
+		return nil, errors.New("this server can't host any more rooms")
+	}
+
+	engine := game.NewEngine(r.config)
+	room := NewServerWithEngine(r.addr, engine)
+	room.manager, room.room = r.manager, id
+	room.tls = r.tls
+	room.log = room.log.With("room", id)
+	for _, fn := range r.setup {
+		fn(room)
+	}
+	if err := r.manager.Add(id, engine); err != nil {
+		return nil, err
+	}
+	r.rooms[id] = room
+	crash.Go(room.heartbeatLoop)
+	slog.Info("Room created", "room", id)
+	return room, nil
+}
+
+// checkRoomID reports whether id may name a room.
+func checkRoomID(id string) error {
+	switch {
+	case strings.TrimSpace(id) == "":
+		return errors.New("name the room")
+	case len(id) > maxRoomID:
+		return fmt.Errorf("room names are at most %d characters", maxRoomID)
+	case strings.IndexFunc(id, func(c rune) bool { return !unicode.IsPrint(c) }) >= 0:
+		return errors.New("room names can't have control characters")
+	}
+	return nil
+}
+
+// closed lets go of a room the manager closed, hanging up on its clients.
+func (r *RoomServer) closed(id string) {
+	r.mu.Lock()
+	room := r.rooms[id]
+	delete(r.rooms, id)
+	if room != nil {
+		r.retired.add(room.totals())
+	}
+	r.mu.Unlock()
+	if room != nil {
+		room.closeRoom()
+		slog.Info("Room closed", "room", id)
+	}
+}
+
+// list returns a summary of every room, sorted by ID.
+func (r *RoomServer) list() []RoomSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rooms := make([]RoomSummary, 0, len(r.rooms))
+	for id, room := range r.rooms {
+		rooms = append(rooms, RoomSummary{
+			ID:         id,
+			Players:    room.engine.PlayerCount(),
+			MaxPlayers: room.engine.Config.MaxPlayers,
+			Status:     room.engine.GetStateCopy().Status,
+			Mode:       room.engine.Config.Mode,
+		})
+	}
+	slices.SortFunc(rooms, func(a, b RoomSummary) int { return strings.Compare(a.ID, b.ID) })
+	return rooms
+}
+
+// roomWithSession returns the room holding the session with token, or nil.
+func (r *RoomServer) roomWithSession(token string) *Server {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, room := range r.rooms {
+		room.mu.RLock()
+		_, ok := room.sessions[token]
+		room.mu.RUnlock()
+		if ok {
+			return room
+		}
+	}
+	return nil
+}
+
+// addPlayer adds a player to the game, through the manager in a room of a
+// RoomServer.
+func (s *Server) addPlayer(playerID, name string) error {
+	if s.manager != nil {
+		return s.manager.Join(s.room, playerID, name)
+	}
+	return s.engine.AddPlayer(playerID, name)
+}
+
+// removePlayer removes a player from the game, through the manager in a room
+// of a RoomServer, which closes the room if they were the last. Bots don't
+// keep a room open: it closes once no human is playing or watching.
+func (s *Server) removePlayer(playerID string) {
+	if s.manager == nil {
+		s.engine.RemovePlayer(playerID)
+		return
+	}
+	s.manager.Leave(s.room, playerID)
+	s.mu.RLock()
+	humans := s.humansLocked()
+	s.mu.RUnlock()
+	if humans == 0 {
+		s.manager.Close(s.room)
+	}
+}
+
+// humansLocked returns how many people are in the room: players, including
+// those whose place is held for a rejoin, and spectators.
+// MUST be called while s.mu is held.
+func (s *Server) humansLocked() int {
+	n := len(s.sessions)
+	for _, cc := range s.clients {
+		if cc.spectator {
+			n++
+		}
+	}
+	return n
+}
+
+// closeRoom hangs up on the clients of a room the manager closed, which has
+// stopped its engine already.
+func (s *Server) closeRoom() {
+	close(s.done)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, c := range s.clients {
+		c.close()
+	}
+}
+
+// ErrSingleRoom is returned by ListRooms for a server that hosts a single
+// room, which is joined with NewClient.
+var ErrSingleRoom = errors.New("server hosts a single room")
+
+// ListRooms returns the rooms of the multi-room server at addr.
+func ListRooms(addr string) ([]RoomSummary, error) {
+	conn, err := dial(addr)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(probeTimeout))
+
+	if err := Encode(conn, MsgListRooms, struct{}{}); err != nil {
+		return nil, fmt.Errorf("send list_rooms: %w", err)
+	}
+	env, err := Decode(conn)
+	if err != nil {
+		return nil, fmt.Errorf("read rooms: %w", err)
+	}
+	switch env.Type {
+	case MsgRooms:
+	case MsgError:
+		// Single-room servers insist on a join first
+		return nil, ErrSingleRoom
+	default:
+		return nil, fmt.Errorf("expected rooms, got %s", env.Type)
+	}
+	var list RoomListMsg
+	if err := DecodePayload(env, &list); err != nil {
+		return nil, fmt.Errorf("decode rooms: %w", err)
+	}
+	return list.Rooms, nil
+}
+
+// JoinRoom is NewClient for room of the multi-room server at addr, which it
+// creates first if create is set.
+func JoinRoom(addr, room string, create bool, join JoinMsg) (*Client, error) {
+	msgType := MsgJoinRoom
+	if create {
+		msgType = MsgCreateRoom
+	}
+	join = offer(join)
+	return openClient(addr, join.Spectate, msgType, JoinRoomMsg{Room: room, Join: join})
+}
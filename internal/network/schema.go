@@ -0,0 +1,141 @@
+package network
+
+import "github.com/amalg/go-bomberman/internal/game"
+
+//go:generate go run ../../cmd/protogen -out ../../protocol
+
+// Message directions for MessageSpec.Dir.
+const (
+	ToServer = "client" // Sent by clients
+	ToClient = "server" // Sent by the server
+	Both     = "both"   // Sent by either side
+)
+
+// MessageSpec describes one message type for the protocol schema generator
+// (cmd/protogen). Every MsgType must have an entry in Messages.
+type MessageSpec struct {
+	Type    MsgType
+	Dir     string
+	Payload any // Zero value of the payload type; nil for an empty object
+	Doc     string
+}
+
+// Messages lists every message on the wire. Keep it in step with the MsgType
+// constants and run `go generate ./internal/network` after changing either,
+// so the schema and bot bindings in protocol/ stay in sync.
+var Messages = []MessageSpec{
+	{MsgJoin, ToServer, JoinMsg{}, "First message on a connection: join the game."},
+	{MsgRejoin, ToServer, RejoinMsg{}, "First message on a connection instead of a join: reclaim a player whose connection dropped."},
+	{MsgAction, ToServer, ActionMsg{}, "Move, place a bomb or toggle sprint."},
+	{MsgStart, ToServer, nil, "Start the match from the lobby."},
+	{MsgReadyForStart, ToServer, nil, "Reply to a countdown with starts_in 0."},
+	{MsgSwitchTeam, ToServer, nil, "Move to the other team; lobby and team mode only."},
+	{MsgSetHandicap, ToServer, HandicapMsg{}, "Override a player's starting stats; host and lobby only."},
+	{MsgPause, ToServer, PauseMsg{}, "Pause or resume the match; host only."},
+	{MsgKick, ToServer, KickMsg{}, "Remove a player from the game; host only."},
+	{MsgBan, ToServer, KickMsg{}, "Remove a player and turn their address away from then on; host only."},
+	{MsgListRooms, ToServer, nil, "May open a connection to a multi-room server instead of a join: list its rooms. May be repeated."},
+	{MsgCreateRoom, ToServer, JoinRoomMsg{}, "First message on a connection to a multi-room server instead of a join: create a room and join it."},
+	{MsgJoinRoom, ToServer, JoinRoomMsg{}, "First message on a connection to a multi-room server instead of a join: join one of its rooms."},
+	{MsgUDPHello, ToServer, UDPHelloMsg{}, "Over UDP: send states here instead of over TCP; repeat every second."},
+	{MsgUDPMove, ToServer, UDPMoveMsg{}, "Over UDP: a move, dropped if one with a higher seq came first."},
+	{MsgResync, ToServer, nil, "Ask for a full state, after a state_delta whose base the client doesn't have."},
+	{MsgLeave, ToServer, nil, "Quit for good: the player is removed at once, even mid-match, and the server hangs up."},
+	{MsgWelcome, ToClient, WelcomeMsg{}, "Reply to a join with the player's ID and the game config."},
+	{MsgState, ToClient, StateMsg{}, "Full game state, sent every tick, or every KeyframeEvery ticks to clients taking deltas."},
+	{MsgCompressed, ToClient, CompressedStateMsg{}, "A full state, compressed, to clients whose welcome names a compression."},
+	{MsgStateDelta, ToClient, StateDeltaMsg{}, "Game state as the changes from the last one sent; only to clients that joined with deltas."},
+	{MsgRooms, ToClient, RoomListMsg{}, "Reply to list_rooms."},
+	{MsgSystem, ToClient, SystemMsg{}, "Server notice shown to players."},
+	{MsgEvent, ToClient, EventMsg{}, "Something happened in the game, sent after the tick's state."},
+	{MsgCountdown, ToClient, CountdownMsg{}, "Start countdown; see CountdownMsg."},
+	{MsgError, ToClient, ErrorMsg{}, "The request failed; a rejected join closes the connection."},
+	{MsgPing, Both, PingMsg{}, "Ask for a pong; may open a connection instead of a join. The server pings every second; a client that answers is dropped if it goes quiet."},
+	{MsgPong, Both, PongMsg{}, "Reply to a ping."},
+	{MsgChat, Both, ChatMsg{}, "Chat line; clients send the text and the server relays it to everyone with the sender filled in."},
+}
+
+// EnumValue is one named value of an enum type sent on the wire.
+type EnumValue struct {
+	Name  string // snake_case; generators convert it to each language's style
+	Value any
+}
+
+// EnumSpec lists the values of an enum type that appears in a message.
+type EnumSpec struct {
+	Type   any // Zero value of the enum type
+	Values []EnumValue
+}
+
+// Enums lists the named values of every enum type in Messages.
+var Enums = []EnumSpec{
+	{MsgType(""), []EnumValue{
+		{"join", MsgJoin}, {"welcome", MsgWelcome}, {"action", MsgAction},
+		{"state", MsgState}, {"error", MsgError}, {"start", MsgStart},
+		{"system", MsgSystem}, {"countdown", MsgCountdown},
+		{"ready_for_start", MsgReadyForStart}, {"ping", MsgPing}, {"pong", MsgPong},
+		{"switch_team", MsgSwitchTeam}, {"set_handicap", MsgSetHandicap}, {"event", MsgEvent},
+		{"pause", MsgPause}, {"state_delta", MsgStateDelta}, {"resync", MsgResync},
+		{"rejoin", MsgRejoin}, {"chat", MsgChat}, {"kick", MsgKick}, {"ban", MsgBan},
+		{"udp_hello", MsgUDPHello}, {"udp_move", MsgUDPMove}, {"compressed_state", MsgCompressed},
+		{"list_rooms", MsgListRooms}, {"create_room", MsgCreateRoom}, {"join_room", MsgJoinRoom}, {"rooms", MsgRooms},
+		{"leave", MsgLeave},
+	}},
+	{game.EventType(""), []EnumValue{
+		{"player_killed", game.EventPlayerKilled}, {"bomb_exploded", game.EventBombExploded},
+		{"wall_destroyed", game.EventWallDestroyed}, {"item_picked_up", game.EventItemPickedUp},
+		{"game_over", game.EventGameOver},
+	}},
+	{SystemKind(""), []EnumValue{{"motd", SystemMOTD}, {"announce", SystemAnnounce}, {"kick", SystemKick}}},
+	{Encoding(""), []EnumValue{{"json", EncodingJSON}, {"msgpack", EncodingMsgpack}}},
+	{Compression(""), []EnumValue{{"gzip", CompressionGzip}, {"snappy", CompressionSnappy}}},
+	{game.TileType(0), []EnumValue{
+		{"empty", game.Empty}, {"hard_wall", game.HardWall},
+		{"soft_wall", game.SoftWall}, {"barrel", game.Barrel}, {"exit_door", game.ExitDoor},
+		{"ice", game.Ice}, {"cracked_wall", game.CrackedWall}, {"cracked_wall_hit", game.CrackedWallHit},
+		{"fog", game.Fog}, {"trap", game.Trap},
+	}},
+	{game.Direction(0), []EnumValue{
+		{"up", game.DirUp}, {"down", game.DirDown},
+		{"left", game.DirLeft}, {"right", game.DirRight},
+	}},
+	{game.ActionType(0), []EnumValue{
+		{"move", game.ActionMove}, {"place_bomb", game.ActionPlaceBomb}, {"sprint", game.ActionSprint},
+		{"line_bomb", game.ActionLineBomb},
+	}},
+	{game.BossAttack(0), []EnumValue{
+		{"idle", game.BossIdle}, {"fire_sweep", game.BossFireSweep}, {"bombs", game.BossBombs},
+	}},
+	{game.PickupType(0), []EnumValue{
+		{"bomb", game.PickupBomb}, {"range", game.PickupRange}, {"ammo", game.PickupAmmo},
+		{"speed", game.PickupSpeed}, {"kick", game.PickupKick},
+		{"shield", game.PickupShield}, {"skull", game.PickupSkull},
+		{"full_fire", game.PickupFullFire}, {"line_bomb", game.PickupLineBomb},
+		{"mine", game.PickupMine}, {"cloak", game.PickupCloak},
+	}},
+	{game.Effect(0), []EnumValue{
+		{"reverse", game.EffectReverse}, {"auto_bomb", game.EffectAutoBomb},
+		{"short_range", game.EffectShortRange},
+	}},
+	{game.GameStatus(0), []EnumValue{
+		{"lobby", game.StatusLobby}, {"running", game.StatusRunning},
+		{"over", game.StatusOver}, {"countdown", game.StatusCountdown},
+		{"intermission", game.StatusIntermission}, {"level_complete", game.StatusLevelComplete},
+		{"paused", game.StatusPaused},
+	}},
+	{game.TimeUpRule(""), []EnumValue{
+		{"draw", game.TimeUpDraw}, {"kills", game.TimeUpKills}, {"walls", game.TimeUpWalls},
+	}},
+	{game.OvertimeRule(""), []EnumValue{
+		{"none", game.OvertimeNone}, {"lava", game.OvertimeLava}, {"bomb_rain", game.OvertimeBombRain},
+	}},
+	{game.Mode(""), []EnumValue{
+		{"classic", game.ModeClassic}, {"teams", game.ModeTeams}, {"koth", game.ModeKOTH},
+		{"battle_royale", game.ModeBattleRoyale}, {"boss", game.ModeBoss}, {"pve", game.ModePvE},
+		{"campaign", game.ModeCampaign}, {"stun", game.ModeStun},
+	}},
+	{game.Tiebreaker(""), []EnumValue{
+		{"draw", game.TiebreakDraw}, {"bomb_owner", game.TiebreakBombOwner},
+		{"kills", game.TiebreakKills}, {"sudden_death", game.TiebreakSuddenDeath},
+	}},
+}
@@ -0,0 +1,150 @@
+package network
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/crash"
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// sendQueueLen is how many messages may wait to be written to a client. A
+// client that falls this far behind is hung up on.
+const sendQueueLen = 256
+
+// writeTimeout bounds writing one message to a client, so a stalled
+// connection is let go of instead of holding up its writer for good.
+const writeTimeout = 5 * time.Second
+
+// startWriter gives the client a send queue and starts writing it out. It
+// must be called before anything is sent to the client.
+func (s *Server) startWriter(cc *clientConn) {
+	cc.out = make(chan []byte, sendQueueLen)
+	cc.quit = make(chan struct{})
+	crash.Go(func() { s.writeLoop(cc) })
+}
+
+// writeLoop writes the client's queue to its connection until the client is
+// closed, or a write fails, and then closes the connection.
+func (s *Server) writeLoop(cc *clientConn) {
+	defer cc.conn.Close()
+	for {
+		select {
+		case msg := <-cc.out:
+			if !s.write(cc, msg) {
+				return
+			}
+		case <-cc.quit:
+			// Finish what's queued, such as why the client was kicked
+			for {
+				select {
+				case msg := <-cc.out:
+					if !s.write(cc, msg) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write writes one queued message to the client, reporting whether it could.
+// A nil message stands for the client's pending state.
+func (s *Server) write(cc *clientConn, msg []byte) bool {
+	if msg == nil {
+		if msg = s.nextState(cc); msg == nil {
+			return true
+		}
+	}
+	cc.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := cc.conn.Write(msg); err != nil {
+		s.log.Info("Failed to write to client", "player_id", cc.playerID, "err", err)
+		return false
+	}
+	return true
+}
+
+// send queues a message for the client, encoded as the client reads them.
+func (cc *clientConn) send(msgType MsgType, payload any) {
+	msg, err := encodeMessage(cc.enc, msgType, payload)
+	if err != nil {
+		slog.Error("Failed to encode message", "player_id", cc.playerID, "msg_type", msgType, "err", err)
+		return
+	}
+	cc.queue(msg)
+}
+
+// queue adds an encoded message to the client's send queue without waiting.
+// If the queue is full the client isn't keeping up, so its connection is
+// closed there and then, which drops it as any lost connection is.
+func (cc *clientConn) queue(msg []byte) {
+	select {
+	case cc.out <- msg:
+		return
+	default:
+	}
+	select {
+	case <-cc.quit:
+		// Already closing
+	default:
+		slog.Warn("Client isn't keeping up; closing its connection", "player_id", cc.playerID)
+		cc.close()
+		cc.conn.Close()
+	}
+}
+
+// sendState queues state for the client. A state still waiting in the queue
+// is replaced rather than followed, so a slow client skips the states it
+// would only have fallen further behind with.
+func (cc *clientConn) sendState(state game.GameState) {
+	if cc.pending.Swap(&state) == nil {
+		cc.queue(nil)
+	}
+}
+
+// nextState takes the client's pending state and returns its view, as the
+// changes from the last one written if the client takes deltas. It returns
+// nil if there's nothing to write, because the state went over UDP.
+func (s *Server) nextState(cc *clientConn) []byte {
+	state := cc.pending.Swap(nil)
+	if state == nil {
+		return nil
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	view := s.viewFor(*state, cc.playerID)
+	if cc.udpAddr != nil && s.sendStateUDP(cc, view) {
+		cc.base = nil // The next state over TCP has to be whole
+		return nil
+	}
+	start := time.Now()
+	var msg []byte
+	var err error
+	if delta, ok := s.deltaFor(cc, view); ok {
+		msg, err = encodeMessage(cc.enc, MsgStateDelta, delta)
+		cc.sinceKey++
+	} else {
+		var msgType MsgType
+		var payload any
+		if msgType, payload, err = stateMessage(cc.enc, cc.compress, view); err == nil {
+			msg, err = encodeMessage(cc.enc, msgType, payload)
+		}
+		cc.sinceKey = 0
+	}
+	s.encodeTime.observe(time.Since(start))
+	if err != nil {
+		s.log.Error("Failed to encode state", "player_id", cc.playerID, "err", err)
+		cc.base = nil
+		return nil
+	}
+	cc.base = &view
+	return msg
+}
+
+// close closes the client's connection once what's queued for it is written.
+func (cc *clientConn) close() {
+	cc.closeOnce.Do(func() { close(cc.quit) })
+}
@@ -1,51 +1,214 @@
 package network
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/amalg/go-bomberman/internal/ai"
+	"github.com/amalg/go-bomberman/internal/crash"
 	"github.com/amalg/go-bomberman/internal/game"
 )
 
+const (
+	// announceBurst and announceInterval rate-limit join announcements so a
+	// client reconnecting in a loop can't flood everyone's lobby.
+	announceBurst    = 3
+	announceInterval = 5 * time.Second
+)
+
+// keepaliveInterval is how often a state is broadcast even if nothing changed.
+const keepaliveInterval = time.Second
+
 // Server hosts the game and manages client connections.
 type Server struct {
-	engine   *game.Engine
-	addr     string
-	listener net.Listener
-	clients  map[string]*clientConn
-	mu       sync.RWMutex
-	done     chan struct{}
+	engine    *game.Engine
+	addr      string
+	listener  net.Listener
+	clients   map[string]*clientConn
+	sessions  map[string]*session // By token
+	banned    map[string]bool     // Addresses the host banned, without port
+	filter    Filter              // Optional moderation of names and chat; nil disables
+	motd      string              // Message of the day sent to each client on join
+	rules     string              // House rules sent in each client's welcome
+	announce  *rateLimiter
+	meter     *bandwidthMeter // Aggregate traffic across all clients
+	observers []func(game.GameState)
+	multicast *multicastStreamer // Optional LAN spectator stream
+	websocket net.Listener       // Optional WebSocket port, alongside listener
+	udp       net.PacketConn     // UDP channel on the listener's port; nil if it couldn't be opened
+	tls       *tls.Config        // Set by EnableTLS; nil for plaintext
+	compress  Compression        // Set by SetCompression; "" sends states uncompressed
+	startSync *startSync         // Countdown acknowledgements being collected; nil otherwise
+	bots      map[string]*ai.Bot // Computer players, driven from the tick callback
+	botSeq    int
+	fillers   map[string]bool // Bots added by FillWithBots, removed back in the lobby
+	status    game.GameStatus // Status at the last tick
+	host      string          // The player who may start and moderate: the first to join, then see passHost
+	mu        sync.RWMutex
+	done      chan struct{}
+
+	// spectators is who is watching, sent with every state. It is replaced
+	// whole while mu is held, so views can read it without taking mu.
+	spectators atomic.Pointer[[]game.Spectator]
+
+	// lastVersion, lastSpectators and lastSent are the last state broadcast's
+	// Version, who was watching it and when it went, so unchanged ones can
+	// be skipped. Only the tick callback touches them.
+	lastVersion    uint64
+	lastSpectators *[]game.Spectator
+	lastSent       time.Time
+
+	// tickState is the state of the tick whose events are being broadcast,
+	// for telling what each client may see of them. The tick callback sets
+	// it just before the engine publishes the tick's events.
+	tickState atomic.Pointer[game.GameState]
+
+	// idleTimeout is how long a client may send nothing before it's dropped;
+	// 0 never drops it. Set by SetIdleTimeout.
+	idleTimeout time.Duration
+
+	// manager and room are set for a room of a RoomServer. Its players join
+	// and leave through the manager, which closes the room once it's empty.
+	manager *game.Manager
+	room    string
+
+	// log is what the server logs to: slog's default logger when created,
+	// with the room for a room of a RoomServer.
+	log *slog.Logger
+
+	// encodeTime is how long encoding each state for a client takes.
+	encodeTime histogram
 }
 
 // clientConn represents a connected client.
 type clientConn struct {
 	conn     net.Conn
 	playerID string
+	token    string       // Session token, for rejoining
+	chat     *rateLimiter // The player's chat allowance
 	mu       sync.Mutex
+
+	name      string // Spectators only; players' names are in the state
+	spectator bool   // Watches without playing
+
+	udpKey  string        // Identifies the client's datagrams; empty without UDP
+	udpAddr net.Addr      // Where the client takes states over UDP, from its hello; nil sends them over TCP
+	maxSeq  atomic.Uint32 // Highest action Seq taken, so moves arriving late over UDP are dropped
+
+	enc      Encoding        // What the client is sent, from the join handshake
+	deltas   bool            // Send StateDeltaMsg between full states
+	compress Compression     // How full states are compressed, from the join handshake
+	base     *game.GameState // The last state sent, which the next delta changes; nil to send a full one
+	sinceKey int             // States sent since the last full one
+
+	out       chan []byte                    // Encoded messages waiting to be written; nil stands for pending
+	pending   atomic.Pointer[game.GameState] // The latest state not yet written
+	quit      chan struct{}                  // Closed to finish writing out and close the connection
+	closeOnce sync.Once
 }
 
 // NewServer creates a new game server.
 func NewServer(addr string, config game.GameConfig) *Server {
-	engine := game.NewEngine(config)
+	return NewServerWithEngine(addr, game.NewEngine(config))
+}
 
+// NewServerWithEngine creates a game server around an existing engine,
+// such as one loaded with game.LoadSave.
+func NewServerWithEngine(addr string, engine *game.Engine) *Server {
 	s := &Server{
-		engine:  engine,
-		addr:    addr,
-		clients: make(map[string]*clientConn),
-		done:    make(chan struct{}),
+		engine:   engine,
+		addr:     addr,
+		clients:  make(map[string]*clientConn),
+		sessions: make(map[string]*session),
+		banned:   make(map[string]bool),
+		bots:     make(map[string]*ai.Bot),
+		fillers:  make(map[string]bool),
+		announce: newRateLimiter(announceBurst, announceInterval),
+		meter:    newBandwidthMeter(),
+		done:     make(chan struct{}),
+
+		idleTimeout: DefaultIdleTimeout,
+		log:         slog.Default(),
 	}
 
 	// Set up the broadcast callback — receives a pre-copied state from the engine
 	engine.OnTick(func(state game.GameState) {
-		s.broadcastState(state)
+		if state.Status == game.StatusLobby && s.status != game.StatusLobby {
+			s.removeFillers()
+		}
+		s.status = state.Status
+		s.tickState.Store(&state)
+		s.driveBots(state)
+		if s.changed(state) {
+			s.broadcastState(state)
+			if s.multicast != nil {
+				// Anyone on the LAN can read the multicast feed
+				s.multicast.send(state.ViewFor(""))
+			}
+		}
+		for _, fn := range s.observers {
+			fn(state)
+		}
 	})
+	engine.OnEvent(s.broadcastEvent)
 
 	return s
 }
 
+// SetFilter installs a moderation filter for player names and chat.
+// Must be called before Start.
+func (s *Server) SetFilter(f Filter) {
+	s.filter = f
+}
+
+// SetMOTD sets the message of the day sent to every client when they join.
+// Must be called before Start.
+func (s *Server) SetMOTD(motd string) {
+	s.motd = motd
+}
+
+// SetRules sets the house rules sent to every client in its welcome, for
+// players to read in the lobby. Must be called before Start.
+func (s *Server) SetRules(rules string) {
+	s.rules = rules
+}
+
+// OnState registers a callback invoked with every tick's state after it has
+// been broadcast, e.g. for statistics. Callbacks must not modify the state.
+// Must be called before Start.
+func (s *Server) OnState(fn func(game.GameState)) {
+	s.observers = append(s.observers, fn)
+}
+
+// EnableMulticast streams every state to a LAN multicast group for spectators.
+// Must be called before Start.
+func (s *Server) EnableMulticast(group string) error {
+	m, err := newMulticastStreamer(group)
+	if err != nil {
+		return err
+	}
+	s.multicast = m
+	return nil
+}
+
+// EnableWebSocket also takes clients over WebSocket, at WebSocketPath on an
+// HTTP port at addr, for browsers. They speak the same protocol as TCP
+// clients. Must be called before Start.
+func (s *Server) EnableWebSocket(addr string) error {
+	l, err := listenWebSocket(addr, s.tls)
+	if err != nil {
+		return fmt.Errorf("listen websocket: %w", err)
+	}
+	s.websocket = l
+	return nil
+}
+
 // Engine returns the underlying game engine.
 func (s *Server) Engine() *game.Engine {
 	return s.engine
@@ -59,16 +222,27 @@ func (s *Server) Start() error {
 		return fmt.Errorf("listen: %w", err)
 	}
 
-	log.Printf("[SERVER] Listening on %s", s.addr)
+	s.log.Info("Listening", "addr", s.addr)
 
 	// Print local IPs for convenience
 	printLocalIPs(s.addr)
 
+	if s.tls != nil {
+		s.listener = tls.NewListener(s.listener, s.tls)
+	} else {
+		s.listenUDP()
+	}
+
 	// Start game engine in background
-	go s.engine.Run()
+	crash.Go(s.engine.Run)
 
 	// Accept connections
-	go s.acceptLoop()
+	crash.Go(func() { s.acceptLoop(s.listener) })
+	if s.websocket != nil {
+		s.log.Info("Taking WebSocket clients", "url", fmt.Sprintf("ws://%s%s", s.websocket.Addr(), WebSocketPath))
+		crash.Go(func() { s.acceptLoop(s.websocket) })
+	}
+	crash.Go(s.heartbeatLoop)
 
 	return nil
 }
@@ -80,31 +254,111 @@ func (s *Server) Stop() {
 	if s.listener != nil {
 		s.listener.Close()
 	}
+	if s.websocket != nil {
+		s.websocket.Close()
+	}
+	if s.udp != nil {
+		s.udp.Close()
+	}
+	if s.multicast != nil {
+		s.multicast.close()
+	}
 	s.mu.RLock()
 	for _, c := range s.clients {
-		c.conn.Close()
+		c.close()
 	}
 	s.mu.RUnlock()
 }
 
-// StartGame starts the game from lobby to running.
+// Bandwidth returns aggregate traffic statistics across all clients.
+func (s *Server) Bandwidth() BandwidthStats {
+	return s.meter.Stats()
+}
+
+// StartGame starts the game from lobby to running. With a start countdown
+// configured, every client first acknowledges the countdown so it can be
+// shown in sync regardless of ping.
 func (s *Server) StartGame() error {
-	return s.engine.StartGame()
+	if s.engine.GetStateCopy().Status != game.StatusLobby {
+		return fmt.Errorf("game already in progress")
+	}
+	added := s.fillWithBots()
+	if s.engine.Config.StartCountdown <= 0 {
+		if err := s.engine.StartGame(); err != nil {
+			s.dropFillers(added)
+			return err
+		}
+		return nil
+	}
+	if err := s.engine.BeginCountdown(); err != nil {
+		s.dropFillers(added)
+		return err
+	}
+	s.beginStartSync()
+	return nil
+}
+
+// fillWithBots adds bots to the empty slots when Config.FillWithBots is
+// set, so a lone player still gets a full match. It returns the IDs of the
+// bots it added.
+func (s *Server) fillWithBots() []string {
+	if !s.engine.Config.FillWithBots {
+		return nil
+	}
+	var added []string
+	for s.engine.PlayerCount() < s.engine.Config.MaxPlayers {
+		id, err := s.AddBot(ai.Difficulty(s.engine.Config.BotDifficulty))
+		if err != nil {
+			break // The game is already under way
+		}
+		s.mu.Lock()
+		s.fillers[id] = true
+		s.mu.Unlock()
+		added = append(added, id)
+	}
+	return added
 }
 
-func (s *Server) acceptLoop() {
+// removeFillers takes out the bots added by fillWithBots, freeing their
+// slots for people joining the lobby.
+func (s *Server) removeFillers() {
+	s.mu.RLock()
+	ids := make([]string, 0, len(s.fillers))
+	for id := range s.fillers {
+		ids = append(ids, id)
+	}
+	s.mu.RUnlock()
+	s.dropFillers(ids)
+}
+
+// dropFillers takes out the given bots added by fillWithBots.
+func (s *Server) dropFillers(ids []string) {
+	s.mu.Lock()
+	for _, id := range ids {
+		delete(s.fillers, id)
+	}
+	s.mu.Unlock()
+
+	for _, id := range ids {
+		s.RemoveBot(id)
+	}
+}
+
+// acceptLoop serves the clients l takes, over TCP or WebSocket.
+func (s *Server) acceptLoop(l net.Listener) {
 	for {
-		conn, err := s.listener.Accept()
+		conn, err := l.Accept()
 		if err != nil {
 			select {
 			case <-s.done:
 				return
 			default:
-				log.Printf("[SERVER] Accept error: %v", err)
+				s.log.Error("Accept failed", "err", err)
 				continue
 			}
 		}
-		go s.handleClient(conn)
+		mc := &meteredConn{Conn: conn, meter: s.meter}
+		crash.Go(func() { s.handleClient(mc) })
 	}
 }
 
@@ -112,21 +366,81 @@ func (s *Server) handleClient(conn net.Conn) {
 	defer conn.Close()
 
 	// Read join message
+	conn.SetReadDeadline(s.readDeadline(false))
 	env, err := Decode(conn)
 	if err != nil {
-		log.Printf("[SERVER] Failed to read join message: %v", err)
+		s.log.Info("Failed to read join message", "addr", conn.RemoteAddr(), "err", err)
+		return
+	}
+	s.handleSession(conn, env)
+}
+
+// handleSession serves a connection that opened with env.
+func (s *Server) handleSession(conn net.Conn, env *Envelope) {
+	if env.Type == MsgPing {
+		s.answerProbe(conn, env)
+		return
+	}
+
+	if env.Type == MsgRejoin {
+		if s.turnAwayBanned(conn) {
+			return
+		}
+		if cc, ok := s.rejoin(conn, env); ok {
+			s.serveClient(cc)
+		}
 		return
 	}
 
 	if env.Type != MsgJoin {
-		log.Printf("[SERVER] Expected join message, got %s", env.Type)
+		s.log.Warn("Expected join message", "addr", conn.RemoteAddr(), "msg_type", env.Type)
 		Encode(conn, MsgError, ErrorMsg{Message: "expected join message"})
 		return
 	}
 
 	var joinMsg JoinMsg
 	if err := DecodePayload(env, &joinMsg); err != nil {
-		log.Printf("[SERVER] Failed to decode join message: %v", err)
+		s.log.Warn("Invalid message", "addr", conn.RemoteAddr(), "msg_type", env.Type, "err", err)
+		return
+	}
+	s.handleJoin(conn, joinMsg)
+}
+
+// handleJoin serves a connection that opened with joinMsg.
+func (s *Server) handleJoin(conn net.Conn, joinMsg JoinMsg) {
+	if s.turnAwayBanned(conn) {
+		return
+	}
+
+	name, err := sanitizeName(joinMsg.Name)
+	if err != nil {
+		s.log.Info("Rejected join", "addr", conn.RemoteAddr(), "err", err)
+		Encode(conn, MsgError, ErrorMsg{Message: err.Error()})
+		return
+	}
+	joinMsg.Name = name
+
+	if s.filter != nil {
+		name, err := s.filter.FilterName(joinMsg.Name)
+		if err != nil {
+			s.log.Info("Rejected join", "addr", conn.RemoteAddr(), "err", err)
+			Encode(conn, MsgError, ErrorMsg{Message: err.Error()})
+			return
+		}
+		joinMsg.Name = name
+	}
+
+	version, err := negotiateVersion(joinMsg.Version)
+	if err != nil {
+		s.log.Info("Rejected join", "addr", conn.RemoteAddr(), "err", err)
+		Encode(conn, MsgError, ErrorMsg{Message: err.Error()})
+		return
+	}
+
+	if joinMsg.Spectate {
+		if cc, ok := s.joinSpectator(conn, joinMsg, version); ok {
+			s.serveClient(cc)
+		}
 		return
 	}
 
@@ -134,38 +448,60 @@ func (s *Server) handleClient(conn net.Conn) {
 	playerID := fmt.Sprintf("p%d", time.Now().UnixNano())
 
 	// Add player to engine
-	if err := s.engine.AddPlayer(playerID, joinMsg.Name); err != nil {
+	if err := s.addPlayer(playerID, joinMsg.Name); err != nil {
 		Encode(conn, MsgError, ErrorMsg{Message: err.Error()})
 		return
 	}
+	joinMsg.Name = s.engine.PlayerName(playerID) // Made unique if taken
+
+	s.engine.SetCosmetics(playerID, sanitizeCosmetics(joinMsg.Cosmetics))
 
 	// Register client
 	cc := &clientConn{
 		conn:     conn,
 		playerID: playerID,
+		token:    newToken(),
+		chat:     newRateLimiter(chatBurst, chatInterval),
+		enc:      pickEncoding(joinMsg.Encodings),
+		deltas:   joinMsg.Deltas,
+		compress: pickCompression(s.compress, joinMsg.Compress),
+	}
+	if joinMsg.UDP && s.udp != nil {
+		cc.udpKey = newToken()
 	}
+	s.startWriter(cc)
 	s.mu.Lock()
+	if s.host == "" {
+		s.host = playerID
+	}
+	// Welcome the client before anything else can be queued for it
+	s.welcomeLocked(cc, version)
 	s.clients[playerID] = cc
+	s.sessions[cc.token] = &session{playerID: playerID}
 	s.mu.Unlock()
 
-	log.Printf("[SERVER] Player joined: %s (%s)", joinMsg.Name, playerID)
+	s.log.Info("Player joined", "player_id", playerID, "name", joinMsg.Name)
 
-	// Send welcome message
-	welcome := WelcomeMsg{
-		PlayerID: playerID,
-		Config:   s.engine.Config,
+	// Send initial state
+	cc.sendState(s.engine.GetStateCopy())
+
+	if s.motd != "" {
+		cc.send(MsgSystem, SystemMsg{Kind: SystemMOTD, Text: s.motd})
 	}
-	if err := Encode(conn, MsgWelcome, welcome); err != nil {
-		log.Printf("[SERVER] Failed to send welcome: %v", err)
-		s.removeClient(playerID)
-		return
+	if s.announce.Allow() {
+		s.broadcastSystem(playerID, SystemMsg{
+			Kind: SystemAnnounce,
+			Text: fmt.Sprintf("%s joined (%d/%d)", joinMsg.Name, s.engine.PlayerCount(), s.engine.Config.MaxPlayers),
+		})
 	}
 
-	// Send initial state
-	initialState := s.engine.GetStateCopy()
-	s.sendStateTo(cc, initialState)
+	s.serveClient(cc)
+}
 
-	// Read actions loop
+// serveClient reads a joined client's messages until its connection drops.
+func (s *Server) serveClient(cc *clientConn) {
+	conn, playerID := cc.conn, cc.playerID
+	heartbeat := false // Whether the client keeps up with heartbeats, so may be dropped for missing them
 	for {
 		select {
 		case <-s.done:
@@ -173,32 +509,215 @@ func (s *Server) handleClient(conn net.Conn) {
 		default:
 		}
 
+		conn.SetReadDeadline(s.readDeadline(heartbeat))
 		env, err := Decode(conn)
 		if err != nil {
-			log.Printf("[SERVER] Player %s disconnected: %v", playerID, err)
-			s.removeClient(playerID)
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				s.log.Info("Player timed out", "player_id", playerID)
+			} else {
+				s.log.Info("Player disconnected", "player_id", playerID, "err", err)
+			}
+			s.dropClient(cc)
 			return
 		}
+		if cc.spectator && !spectatorMsgs[env.Type] {
+			continue
+		}
 
 		switch env.Type {
 		case MsgAction:
 			var actionMsg ActionMsg
 			if err := DecodePayload(env, &actionMsg); err != nil {
-				log.Printf("[SERVER] Invalid action from %s: %v", playerID, err)
+				s.log.Warn("Invalid message", "player_id", playerID, "msg_type", env.Type, "err", err)
 				continue
 			}
+			cc.raiseSeq(actionMsg.Seq)
 			s.engine.EnqueueAction(game.Action{
 				PlayerID: playerID,
 				Type:     actionMsg.ActionType,
 				Dir:      actionMsg.Direction,
+				Seq:      actionMsg.Seq,
 			})
+		case MsgPong:
+			heartbeat = true
+			s.handlePong(cc, env)
+		case MsgPing:
+			heartbeat = true
+			var ping PingMsg
+			if err := DecodePayload(env, &ping); err != nil {
+				s.log.Warn("Invalid message", "player_id", playerID, "msg_type", env.Type, "err", err)
+				continue
+			}
+			cc.send(MsgPong, PongMsg{SentAt: ping.SentAt, Version: ProtocolVersion})
+		case MsgChat:
+			var chatMsg ChatMsg
+			if err := DecodePayload(env, &chatMsg); err != nil {
+				s.log.Warn("Invalid message", "player_id", playerID, "msg_type", env.Type, "err", err)
+				continue
+			}
+			if err := s.relayChat(cc, chatMsg); err != nil {
+				cc.send(MsgError, ErrorMsg{Message: err.Error()})
+			}
+		case MsgKick, MsgBan:
+			var kMsg KickMsg
+			if err := DecodePayload(env, &kMsg); err != nil {
+				s.log.Warn("Invalid message", "player_id", playerID, "msg_type", env.Type, "err", err)
+				continue
+			}
+			if err := s.moderate(playerID, kMsg, env.Type == MsgBan); err != nil {
+				cc.send(MsgError, ErrorMsg{Message: err.Error()})
+			}
+		case MsgLeave:
+			s.leave(cc)
+			return
+		case MsgResync:
+			cc.mu.Lock()
+			cc.base = nil
+			cc.mu.Unlock()
 		case MsgStart:
 			// Host requests game start
-			if err := s.engine.StartGame(); err != nil {
-				Encode(conn, MsgError, ErrorMsg{Message: err.Error()})
+			if playerID != s.hostID() {
+				cc.send(MsgError, ErrorMsg{Message: "only the host can start the game"})
+				continue
+			}
+			if err := s.StartGame(); err != nil {
+				cc.send(MsgError, ErrorMsg{Message: err.Error()})
+			}
+		case MsgReadyForStart:
+			s.markReady(playerID, true)
+		case MsgSwitchTeam:
+			if err := s.engine.SwitchTeam(playerID); err != nil {
+				cc.send(MsgError, ErrorMsg{Message: err.Error()})
+			}
+		case MsgSetHandicap:
+			var hMsg HandicapMsg
+			if err := DecodePayload(env, &hMsg); err != nil {
+				s.log.Warn("Invalid message", "player_id", playerID, "msg_type", env.Type, "err", err)
+				continue
+			}
+			if err := s.setHandicap(playerID, hMsg); err != nil {
+				cc.send(MsgError, ErrorMsg{Message: err.Error()})
+			}
+		case MsgPause:
+			var pMsg PauseMsg
+			if err := DecodePayload(env, &pMsg); err != nil {
+				s.log.Warn("Invalid message", "player_id", playerID, "msg_type", env.Type, "err", err)
+				continue
+			}
+			if err := s.setPaused(playerID, pMsg.Paused); err != nil {
+				cc.send(MsgError, ErrorMsg{Message: err.Error()})
 			}
 		default:
-			log.Printf("[SERVER] Unknown message type from %s: %s", playerID, env.Type)
+			s.log.Warn("Unknown message type", "player_id", playerID, "msg_type", env.Type)
+		}
+	}
+}
+
+// setHandicap applies a handicap sent by fromID, which must be the host.
+func (s *Server) setHandicap(fromID string, msg HandicapMsg) error {
+	s.mu.RLock()
+	host := s.host
+	s.mu.RUnlock()
+	if fromID != host {
+		return fmt.Errorf("only the host can set handicaps")
+	}
+	return s.engine.SetHandicap(msg.PlayerID, msg.Handicap)
+}
+
+// welcomeLocked queues the client's welcome. It goes in JSON whatever the
+// client's encoding, which the client learns from it.
+// MUST be called while s.mu is held.
+func (s *Server) welcomeLocked(cc *clientConn, version int) {
+	msg, err := encodeMessage(EncodingJSON, MsgWelcome, WelcomeMsg{
+		PlayerID: cc.playerID,
+		Config:   s.engine.Config,
+		Encoding: cc.enc,
+		Version:  version,
+		Token:    cc.token,
+		HostID:   s.host,
+		Compress: cc.compress,
+		UDPKey:   cc.udpKey,
+		MOTD:     s.motd,
+		Rules:    s.rules,
+	})
+	if err != nil {
+		s.log.Error("Failed to encode welcome", "player_id", cc.playerID, "err", err)
+		cc.close()
+		return
+	}
+	cc.queue(msg)
+}
+
+// hostID returns the host's player ID.
+func (s *Server) hostID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.host
+}
+
+// setPaused pauses or resumes the match for the player with fromID, who
+// must be the host.
+func (s *Server) setPaused(fromID string, paused bool) error {
+	s.mu.RLock()
+	host := s.host
+	s.mu.RUnlock()
+	if fromID != host {
+		return fmt.Errorf("only the host can pause")
+	}
+	if paused {
+		return s.engine.Pause()
+	}
+	return s.engine.Resume()
+}
+
+// AddBot adds a computer player of the given difficulty to the lobby and
+// returns its player ID.
+func (s *Server) AddBot(d ai.Difficulty) (string, error) {
+	s.mu.Lock()
+	s.botSeq++
+	seq := s.botSeq
+	s.mu.Unlock()
+
+	id := fmt.Sprintf("bot%d", seq)
+	bot := ai.NewBot(id, d, s.engine.Config)
+	name := fmt.Sprintf("Bot %d (%s)", seq, bot.Difficulty)
+	if err := s.engine.AddPlayer(id, name); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.bots[id] = bot
+	s.mu.Unlock()
+
+	s.log.Info("Bot added", "player_id", id, "name", name)
+	s.broadcastSystem("", SystemMsg{
+		Kind: SystemAnnounce,
+		Text: fmt.Sprintf("%s joined (%d/%d)", name, s.engine.PlayerCount(), s.engine.Config.MaxPlayers),
+	})
+	return id, nil
+}
+
+// RemoveBot takes a computer player out of the game.
+func (s *Server) RemoveBot(id string) {
+	s.mu.Lock()
+	_, ok := s.bots[id]
+	delete(s.bots, id)
+	s.mu.Unlock()
+	if ok {
+		s.removePlayer(id)
+	}
+}
+
+// driveBots lets every bot react to the latest state, queueing their
+// actions like those of human players.
+func (s *Server) driveBots(state game.GameState) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for id, b := range s.bots {
+		view := s.viewFor(state, id)
+		if a, ok := b.Observe(&view); ok {
+			s.engine.EnqueueAction(a)
 		}
 	}
 }
@@ -206,12 +725,18 @@ func (s *Server) handleClient(conn net.Conn) {
 func (s *Server) removeClient(playerID string) {
 	s.mu.Lock()
 	if cc, ok := s.clients[playerID]; ok {
-		cc.conn.Close()
+		cc.close()
 		delete(s.clients, playerID)
+		delete(s.sessions, cc.token)
+		if cc.spectator {
+			s.updateSpectatorsLocked()
+		}
 	}
 	s.mu.Unlock()
-	s.engine.RemovePlayer(playerID)
-	log.Printf("[SERVER] Player removed: %s", playerID)
+	s.markReady(playerID, false)
+	s.removePlayer(playerID)
+	s.log.Info("Player removed", "player_id", playerID)
+	s.passHost(playerID)
 }
 
 func (s *Server) broadcastState(state game.GameState) {
@@ -219,21 +744,101 @@ func (s *Server) broadcastState(state game.GameState) {
 	defer s.mu.RUnlock()
 
 	for _, cc := range s.clients {
-		s.sendStateTo(cc, state)
+		cc.sendState(state)
 	}
 }
 
-func (s *Server) sendStateTo(cc *clientConn, state game.GameState) {
-	cc.mu.Lock()
-	defer cc.mu.Unlock()
+// changed reports whether state is worth broadcasting: whether the engine
+// changed anything but its tick, or the spectators changed, since the last
+// one broadcast, or keepaliveInterval has passed since, for clients that
+// missed it. In the lobby or while paused the engine ticks on with nothing
+// changing. A running match changes nearly every tick, so its states aren't
+// checked.
+func (s *Server) changed(state game.GameState) bool {
+	spectators := s.spectators.Load()
+	if state.Status != game.StatusRunning && state.Version == s.lastVersion && spectators == s.lastSpectators &&
+		time.Since(s.lastSent) < keepaliveInterval {
+		return false
+	}
+	s.lastVersion, s.lastSpectators, s.lastSent = state.Version, spectators, time.Now()
+	return true
+}
+
+// broadcastEvent forwards an engine event to every client that may see it,
+// by the same rules as the state it is sent. In fog of war only kills and
+// the end of the match are sent, without where they happened, so events
+// can't see through the fog.
+func (s *Server) broadcastEvent(ev game.Event) {
+	if s.engine.Config.FogRadius > 0 {
+		if ev.Type != game.EventPlayerKilled && ev.Type != game.EventGameOver {
+			return
+		}
+		ev.Pos = game.Position{}
+	}
 
-	msg := StateMsg{State: state}
-	if err := Encode(cc.conn, MsgState, msg); err != nil {
-		log.Printf("[SERVER] Failed to send state to %s: %v", cc.playerID, err)
+	state := s.tickState.Load()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, cc := range s.clients {
+		seen := ev
+		if state != nil {
+			var ok bool
+			if seen, ok = state.EventFor(ev, cc.playerID); !ok {
+				continue
+			}
+		}
+		cc.send(MsgEvent, EventMsg{Event: seen})
+	}
+}
+
+// viewFor returns what the player with the given ID gets to see of state:
+// no mines hidden from them and, in fog of war, only what's near them.
+func (s *Server) viewFor(state game.GameState, playerID string) game.GameState {
+	view := state.ViewFor(playerID)
+	if r := s.engine.Config.FogRadius; r > 0 {
+		view = view.FogView(playerID, r)
+	}
+	view.Spectators = s.spectatorList()
+	return view
+}
+
+// deltaFor returns view as a delta for the client, or false if it should be
+// sent whole.
+// MUST be called while cc.mu is held.
+func (s *Server) deltaFor(cc *clientConn, view game.GameState) (StateDeltaMsg, bool) {
+	if !cc.deltas || cc.base == nil || cc.sinceKey+1 >= KeyframeEvery {
+		return StateDeltaMsg{}, false
+	}
+	return diffState(*cc.base, view)
+}
+
+// broadcastSystem sends a system notice to every client except the given player.
+func (s *Server) broadcastSystem(exceptID string, msg SystemMsg) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for id, cc := range s.clients {
+		if id != exceptID {
+			cc.send(MsgSystem, msg)
+		}
+	}
+}
+
+// maxCosmeticID bounds cosmetic IDs relayed to other clients.
+const maxCosmeticID = 32
+
+// sanitizeCosmetics drops oversized cosmetic IDs. Unlocks are tracked in each
+// player's local profile, so the server can't verify them; they're cosmetic only.
+func sanitizeCosmetics(c game.Cosmetics) game.Cosmetics {
+	for _, id := range []*string{&c.NameColor, &c.Banner, &c.Glyph} {
+		if len(*id) > maxCosmeticID {
+			*id = ""
+		}
 	}
+	return c
 }
 
-// printLocalIPs prints all local network interfaces for players to connect to.
+// printLocalIPs logs all local network interfaces for players to connect to.
 func printLocalIPs(addr string) {
 	_, port, _ := net.SplitHostPort(addr)
 
@@ -242,12 +847,13 @@ func printLocalIPs(addr string) {
 		return
 	}
 
-	log.Println("[SERVER] Players can connect using:")
+	var local []string
 	for _, a := range addrs {
 		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
 			if ipnet.IP.To4() != nil {
-				log.Printf("[SERVER]   %s:%s", ipnet.IP.String(), port)
+				local = append(local, net.JoinHostPort(ipnet.IP.String(), port))
 			}
 		}
 	}
+	slog.Info("Players can connect using", "addrs", local)
 }
@@ -0,0 +1,1182 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+func TestFillWithBots(t *testing.T) {
+	config := game.DefaultConfig()
+	config.StartCountdown = 0
+	config.FillWithBots = true
+	s := NewServer("127.0.0.1:0", config)
+	s.engine.AddPlayer("p1", "Alice")
+
+	if err := s.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.engine.PlayerCount(); n != config.MaxPlayers {
+		t.Fatalf("expected bots to fill the game, got %d players", n)
+	}
+	if len(s.bots) != config.MaxPlayers-1 {
+		t.Fatalf("expected %d bots, got %d", config.MaxPlayers-1, len(s.bots))
+	}
+
+	// Starting again mid-match fails without taking the bots out
+	if err := s.StartGame(); err == nil {
+		t.Fatal("expected no second start")
+	}
+	if n := s.engine.PlayerCount(); n != config.MaxPlayers {
+		t.Fatalf("expected the bots to stay in the match, got %d players", n)
+	}
+
+	// Back in the lobby the bots leave and the human stays
+	s.removeFillers()
+	if n := s.engine.PlayerCount(); n != 1 || len(s.bots) != 0 {
+		t.Fatalf("expected only Alice left, got %d players and %d bots", n, len(s.bots))
+	}
+}
+
+func TestSetHandicapHostOnly(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	s.engine.AddPlayer("p1", "Alice")
+	s.engine.AddPlayer("p2", "Bob")
+	s.host = "p1"
+
+	weak := HandicapMsg{PlayerID: "p1", Handicap: game.Handicap{Bombs: 1, Range: 1}}
+	if err := s.setHandicap("p2", weak); err == nil {
+		t.Fatal("expected only the host to set handicaps")
+	}
+	if err := s.setHandicap("p1", weak); err != nil {
+		t.Fatal(err)
+	}
+	if p := s.engine.GetStateCopy().Players["p1"]; p.BombMax != 1 || p.BombRange != 1 {
+		t.Errorf("expected Alice to start with 1 bomb of range 1, got %d and %d", p.BombMax, p.BombRange)
+	}
+}
+
+func TestPauseHostOnly(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	s.engine.AddPlayer("p1", "Alice")
+	s.engine.AddPlayer("p2", "Bob")
+	s.host = "p1"
+	s.engine.StartGame()
+
+	if err := s.setPaused("p2", true); err == nil {
+		t.Fatal("expected only the host to pause")
+	}
+	if err := s.setPaused("p1", true); err != nil {
+		t.Fatal(err)
+	}
+	if status := s.engine.GetStateCopy().Status; status != game.StatusPaused {
+		t.Fatalf("expected the match paused, got status %d", status)
+	}
+	if err := s.setPaused("p1", false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStateDeltas(t *testing.T) {
+	config := game.DefaultConfig()
+	config.EnemyCount = 0
+	engine := game.NewEngine(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.AddPlayer("p3", "Carol")
+	engine.StartGame()
+	base := engine.GetStateCopy()
+
+	engine.EnqueueAction(game.Action{PlayerID: "p1", Type: game.ActionPlaceBomb})
+	engine.RemovePlayer("p3")
+	engine.Step(1)
+	next := engine.GetStateCopy()
+	next.Board[1][3] = game.Barrel
+
+	d, ok := diffState(base, next)
+	if !ok {
+		t.Fatal("expected a delta between boards of the same size")
+	}
+	if len(d.Cells) != 1 || len(d.Left) != 1 || d.Left[0] != "p3" {
+		t.Errorf("expected one cell changed and Carol gone, got %+v and %v", d.Cells, d.Left)
+	}
+	if _, ok := d.State.Players["p2"]; ok {
+		t.Error("expected Bob, who didn't change, to be left out")
+	}
+
+	// Sent and applied, the delta makes the same state as sending it whole
+	raw, _ := json.Marshal(d)
+	var sent StateDeltaMsg
+	if err := json.Unmarshal(raw, &sent); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(next)
+	got, _ := json.Marshal(applyDelta(base, sent))
+	if string(got) != string(want) {
+		t.Errorf("applied delta differs:\n got %s\nwant %s", got, want)
+	}
+
+	// Every KeyframeEvery states goes whole
+	s := NewServerWithEngine("127.0.0.1:0", engine)
+	cc := &clientConn{playerID: "p1", deltas: true, base: &base, sinceKey: KeyframeEvery - 2}
+	if _, ok := s.deltaFor(cc, next); !ok {
+		t.Error("expected a delta before the keyframe")
+	}
+	cc.sinceKey++
+	if _, ok := s.deltaFor(cc, next); ok {
+		t.Error("expected a full state for the keyframe")
+	}
+}
+
+func TestMsgpackEncoding(t *testing.T) {
+	if enc := pickEncoding([]Encoding{EncodingMsgpack}); enc != EncodingMsgpack {
+		t.Errorf("expected msgpack to be picked when offered, got %q", enc)
+	}
+	if enc := pickEncoding(nil); enc != EncodingJSON {
+		t.Errorf("expected JSON for a client that offers nothing, got %q", enc)
+	}
+
+	engine := game.NewEngine(game.DefaultConfig())
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.StartGame()
+	engine.Step(1)
+	state := engine.GetStateCopy()
+
+	var asJSON, asMsgpack bytes.Buffer
+	if err := Encode(&asJSON, MsgState, StateMsg{State: state}); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncodeWith(&asMsgpack, EncodingMsgpack, MsgState, StateMsg{State: state}); err != nil {
+		t.Fatal(err)
+	}
+	if asMsgpack.Len() >= asJSON.Len() {
+		t.Errorf("expected msgpack to be smaller than JSON, got %d and %d bytes", asMsgpack.Len(), asJSON.Len())
+	}
+
+	// Decode tells the encodings apart, and both decode to the same state
+	for _, buf := range []*bytes.Buffer{&asJSON, &asMsgpack} {
+		env, err := Decode(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if env.Type != MsgState {
+			t.Fatalf("expected a state, got %s", env.Type)
+		}
+		var msg StateMsg
+		if err := DecodePayload(env, &msg); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := json.Marshal(state)
+		got, _ := json.Marshal(msg.State)
+		if string(got) != string(want) {
+			t.Errorf("%q state differs:\n got %s\nwant %s", env.enc, got, want)
+		}
+	}
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	if v, err := negotiateVersion(0); MinProtocolVersion <= 1 && (err != nil || v != 1) {
+		t.Errorf("expected a client that sends no version to speak 1, got %d, %v", v, err)
+	} else if MinProtocolVersion > 1 && err == nil {
+		t.Error("expected a client that sends no version to be taken for version 1 and turned away")
+	}
+	if v, err := negotiateVersion(ProtocolVersion + 1); err != nil || v != ProtocolVersion {
+		t.Errorf("expected a newer client to be spoken to in version %d, got %d, %v", ProtocolVersion, v, err)
+	}
+	if MinProtocolVersion > 1 {
+		if _, err := negotiateVersion(MinProtocolVersion - 1); err == nil {
+			t.Error("expected a client older than MinProtocolVersion to be turned away")
+		}
+	}
+}
+
+func TestHeartbeatRTT(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	c, err := NewClient(s.listener.Addr().String(), JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// The client answers the ping, and the server times the answer
+	s.pingClients()
+	waitFor(t, "the player's RTT to be measured", func() bool {
+		p := s.engine.GetStateCopy().Players[c.PlayerID()]
+		return p != nil && p.RTT > 0
+	})
+}
+
+func TestRejoin(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	c, err := NewClient(s.listener.Addr().String(), JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	id := c.PlayerID()
+	s.engine.AddPlayer("p2", "Bob")
+	if err := s.engine.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection drops mid-match and the client rejoins as Alice
+	s.mu.RLock()
+	first := s.clients[id]
+	s.mu.RUnlock()
+	c.mu.Lock()
+	c.conn.Close()
+	c.mu.Unlock()
+	waitFor(t, "the client to rejoin", func() bool {
+		s.mu.RLock()
+		cc := s.clients[id]
+		s.mu.RUnlock()
+		p := s.engine.GetStateCopy().Players[id]
+		return cc != nil && cc != first && p != nil && !p.Disconnected
+	})
+	if c.PlayerID() != id {
+		t.Errorf("expected to rejoin as %s, got %s", id, c.PlayerID())
+	}
+	for _, want := range []bool{true, false} {
+		select {
+		case got := <-c.ReconnectChan():
+			if got != want {
+				t.Errorf("expected reconnecting to be %v, got %v", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected reconnecting to be %v", want)
+		}
+	}
+
+	// Gone for good, Alice is held until the grace period runs out
+	c.Close()
+	waitFor(t, "Alice to be marked disconnected", func() bool {
+		p := s.engine.GetStateCopy().Players[id]
+		return p != nil && p.Disconnected
+	})
+	s.mu.RLock()
+	token := first.token
+	sess := s.sessions[token]
+	s.mu.RUnlock()
+	s.expireSession(token, sess)
+	if _, ok := s.engine.GetStateCopy().Players[id]; ok {
+		t.Error("expected Alice to be removed once the grace period ran out")
+	}
+}
+
+func TestLeave(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	alice, err := NewClient(s.listener.Addr().String(), JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	bob, err := NewClient(s.listener.Addr().String(), JoinMsg{Name: "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.engine.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mid-match a dropped player would be held; one who leaves is gone at once
+	id := bob.PlayerID()
+	bob.Leave()
+	waitFor(t, "Bob to be removed", func() bool {
+		_, ok := s.engine.GetStateCopy().Players[id]
+		return !ok
+	})
+	waitFor(t, "Alice to hear Bob left", func() bool {
+		select {
+		case msg := <-alice.SystemChan():
+			return msg.Kind == SystemAnnounce && msg.Text == "Bob left"
+		default:
+			return false
+		}
+	})
+}
+
+func TestStartSyncFairToLaggyPlayers(t *testing.T) {
+	config := game.DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	config.StartCountdown = time.Second
+	s := NewServer("127.0.0.1:0", config)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	addr := s.listener.Addr().String()
+	alice, err := NewClient(addr, JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	bob, err := NewClient(lagProxy(t, addr, 150*time.Millisecond), JoinMsg{Name: "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bob.Close()
+	if err := s.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each presses a key the moment their countdown says go: Bob's move,
+	// 150ms away, reaches the server on the same tick as Alice's
+	for c, dir := range map[*Client]game.Direction{alice: game.DirRight, bob: game.DirLeft} {
+		go func() {
+			for c.StartsAt().IsZero() {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(time.Until(c.StartsAt()))
+			c.SendAction(game.ActionMove, dir)
+		}()
+	}
+	spawns := game.SpawnPositions(config.Width, config.Height)
+	movedOn := make(map[string]uint64)
+	deadline := time.Now().Add(3 * time.Second)
+	for len(movedOn) < 2 && time.Now().Before(deadline) {
+		state := s.engine.GetStateCopy()
+		for id, p := range state.Players {
+			if _, ok := movedOn[id]; !ok && p.Pos != spawns[p.Color] {
+				movedOn[id] = state.Tick
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	a, b := movedOn[alice.PlayerID()], movedOn[bob.PlayerID()]
+	if a == 0 || b == 0 {
+		t.Fatalf("expected both first moves to be made, got ticks %d and %d", a, b)
+	}
+	if max(a, b)-min(a, b) > 1 {
+		t.Errorf("expected both first moves on the same tick, Alice's on %d and Bob's on %d", a, b)
+	}
+}
+
+// lagProxy forwards connections to addr, holding everything sent either way
+// for delay, and returns its own address.
+func lagProxy(t *testing.T, addr string, delay time.Duration) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	lag := func(dst, src net.Conn) {
+		type chunk struct {
+			at   time.Time
+			data []byte
+		}
+		chunks := make(chan chunk, 1024)
+		go func() {
+			defer dst.Close()
+			for c := range chunks {
+				time.Sleep(time.Until(c.at))
+				if _, err := dst.Write(c.data); err != nil {
+					return
+				}
+			}
+		}()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := src.Read(buf)
+			if n > 0 {
+				chunks <- chunk{at: time.Now().Add(delay), data: bytes.Clone(buf[:n])}
+			}
+			if err != nil {
+				close(chunks)
+				return
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			up, err := net.Dial("tcp", addr)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			go lag(up, conn)
+			go lag(conn, up)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// waitFor polls cond until it holds, failing the test after two seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestChatRelay(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	s.SetFilter(NewWordFilter())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	alice, err := NewClient(s.listener.Addr().String(), JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	bob, err := NewClient(s.listener.Addr().String(), JoinMsg{Name: "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bob.Close()
+
+	alice.SendChat("  good \x07luck,\u009b\u202e\u200b shit\x1b  ")
+	select {
+	case msg := <-bob.ChatChan():
+		if msg.Name != "Alice" || msg.PlayerID != alice.PlayerID() {
+			t.Errorf("expected the line to come from Alice, got %+v", msg)
+		}
+		if msg.Text != "good luck, ****" {
+			t.Errorf("expected the line trimmed, filtered and without control characters, got %q", msg.Text)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Bob to get Alice's chat")
+	}
+
+	// Chatting past the limit is refused
+	cc := &clientConn{playerID: alice.PlayerID(), chat: newRateLimiter(chatBurst, chatInterval)}
+	for range chatBurst {
+		if err := s.relayChat(cc, ChatMsg{Text: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.relayChat(cc, ChatMsg{Text: "hi"}); err != errChatTooFast {
+		t.Errorf("expected chatting too fast to be refused, got %v", err)
+	}
+}
+
+func TestKickAndBan(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	addr := s.listener.Addr().String()
+	alice, err := NewClient(addr, JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	bob, err := NewClient(addr, JoinMsg{Name: "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bob.Close()
+
+	if err := s.moderate(bob.PlayerID(), KickMsg{PlayerID: alice.PlayerID()}, false); err == nil {
+		t.Fatal("expected only the host to kick")
+	}
+	if err := s.moderate(alice.PlayerID(), KickMsg{PlayerID: alice.PlayerID()}, false); err == nil {
+		t.Fatal("expected the host not to kick themselves")
+	}
+
+	// Banned, Bob is told why, removed and turned away when he comes back
+	if err := s.moderate(alice.PlayerID(), KickMsg{PlayerID: bob.PlayerID(), Reason: "spam"}, true); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "Bob to be told he was banned", func() bool {
+		select {
+		case msg := <-bob.SystemChan():
+			return msg.Kind == SystemKick
+		default:
+			return false
+		}
+	})
+	if _, ok := s.engine.GetStateCopy().Players[bob.PlayerID()]; ok {
+		t.Error("expected Bob to be removed")
+	}
+	if _, err := NewClient(addr, JoinMsg{Name: "Bob"}); err == nil {
+		t.Error("expected Bob's address to be turned away")
+	}
+}
+
+func TestHostPassesOn(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	addr := s.listener.Addr().String()
+	alice, err := NewClient(addr, JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	bob, err := NewClient(addr, JoinMsg{Name: "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bob.Close()
+
+	alice.Leave()
+	waitFor(t, "Bob to become host", func() bool {
+		return s.hostID() == bob.PlayerID()
+	})
+	waitFor(t, "Bob to be told", func() bool {
+		select {
+		case msg := <-bob.SystemChan():
+			return msg.Text == "Bob is now the host"
+		default:
+			return false
+		}
+	})
+
+	// A player away on a dropped connection can still be banned
+	s.engine.AddPlayer("p3", "Carol")
+	s.mu.Lock()
+	s.sessions["carol"] = &session{playerID: "p3", expiry: time.AfterFunc(time.Hour, func() {}), addr: "192.0.2.7"}
+	s.mu.Unlock()
+	if err := s.moderate(bob.PlayerID(), KickMsg{PlayerID: "p3"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.engine.GetStateCopy().Players["p3"]; ok {
+		t.Error("expected Carol to be removed")
+	}
+	s.mu.RLock()
+	_, held := s.sessions["carol"]
+	banned := s.banned["192.0.2.7"]
+	s.mu.RUnlock()
+	if held || !banned {
+		t.Errorf("expected Carol's place dropped and address banned, got held %v banned %v", held, banned)
+	}
+}
+
+func TestSpectator(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	addr := s.listener.Addr().String()
+	alice, err := NewClient(addr, JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	watcher, err := NewClient(addr, JoinMsg{Name: "Watcher", Spectate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !watcher.Spectating() {
+		t.Error("expected the client to know it is spectating")
+	}
+	if _, ok := s.engine.GetStateCopy().Players[watcher.PlayerID()]; ok {
+		t.Error("expected a spectator not to get a player")
+	}
+	if s.hostID() != alice.PlayerID() {
+		t.Errorf("expected Alice to stay host, got %q", s.hostID())
+	}
+	waitFor(t, "the spectator to be listed in its own state", func() bool {
+		select {
+		case state := <-watcher.StateChan():
+			return len(state.Spectators) == 1 && state.Spectators[0].Name == "Watcher"
+		default:
+			return false
+		}
+	})
+
+	// Actions from a spectator are ignored
+	watcher.SendStart()
+	time.Sleep(100 * time.Millisecond)
+	if status := s.engine.GetStateCopy().Status; status != game.StatusLobby {
+		t.Errorf("expected a spectator not to start the match, got status %v", status)
+	}
+
+	watcher.Close()
+	waitFor(t, "the spectator to leave the list", func() bool {
+		return len(s.spectatorList()) == 0
+	})
+}
+
+func TestWebSocket(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	if err := s.EnableWebSocket("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.websocket.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", WebSocketPath, key)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %s", resp.Status)
+	}
+	if got, want := resp.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("expected accept %q, got %q", want, got)
+	}
+
+	// One envelope per message, without the length header
+	join := fmt.Sprintf(`{"type":"join","payload":{"name":"Browser","version":%d}}`, ProtocolVersion)
+	if err := writeWSFrame(conn, wsText, []byte(join), true); err != nil {
+		t.Fatal(err)
+	}
+	_, opcode, payload, err := readWSFrame(br, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env Envelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		t.Fatalf("expected a JSON envelope, got %q: %v", payload, err)
+	}
+	if opcode != wsText || env.Type != MsgWelcome {
+		t.Fatalf("expected a welcome text message, got opcode %d type %s", opcode, env.Type)
+	}
+	var welcome WelcomeMsg
+	if err := DecodePayload(&env, &welcome); err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := s.engine.GetStateCopy().Players[welcome.PlayerID]; !ok || p.Name != "Browser" {
+		t.Errorf("expected the browser to join as a player, got %+v", p)
+	}
+
+	// An unmasked frame from a client closes the connection
+	if err := writeWSFrame(conn, wsText, []byte(`{"type":"ping","payload":{}}`), false); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the browser to be dropped", func() bool {
+		return s.engine.PlayerCount() == 0
+	})
+}
+
+func TestUDP(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	c, err := NewClient(s.listener.Addr().String(), JoinMsg{Name: "Alice", UDP: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.udpKey == "" {
+		t.Fatal("expected the server to grant UDP")
+	}
+
+	s.mu.RLock()
+	cc := s.clients[c.PlayerID()]
+	s.mu.RUnlock()
+	waitFor(t, "the server to hear the client's hello", func() bool {
+		cc.mu.Lock()
+		defer cc.mu.Unlock()
+		return cc.udpAddr != nil
+	})
+
+	// Moves go over UDP, and one arriving after a newer one is dropped
+	seq, err := c.SendAction(game.ActionMove, game.DirRight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the move to arrive over UDP", func() bool {
+		return cc.maxSeq.Load() == seq
+	})
+	if cc.raiseSeq(seq) {
+		t.Error("expected a move no newer than the last to be dropped")
+	}
+	if !cc.raiseSeq(seq + 1) {
+		t.Error("expected a newer move to be taken")
+	}
+}
+
+// writeTestCert writes a self-signed certificate and its key to dir.
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "bomberman.test"},
+		DNSNames:     []string{"bomberman.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+func TestTLS(t *testing.T) {
+	certFile, keyFile := writeTestCert(t, t.TempDir())
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	if err := s.EnableTLS(certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	addr := s.listener.Addr().String()
+
+	c, err := NewClient(TLSScheme+addr, JoinMsg{Name: "Alice", UDP: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.udpKey != "" {
+		t.Error("expected no UDP alongside TLS")
+	}
+	if _, err := NewClient(addr, JoinMsg{Name: "Plain"}); err == nil {
+		t.Error("expected a plaintext client to be turned away")
+	}
+}
+
+func TestStalledClient(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	alice, err := NewClient(s.listener.Addr().String(), JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+
+	// Nothing reads Bob's end of the pipe, so every write to him blocks
+	conn, bobEnd := net.Pipe()
+	defer bobEnd.Close()
+	bob := &clientConn{conn: conn, playerID: "bob"}
+	s.startWriter(bob)
+	s.mu.Lock()
+	s.clients[bob.playerID] = bob
+	s.mu.Unlock()
+
+	// Alice keeps getting what's broadcast while Bob's queue fills up
+	state := s.engine.GetStateCopy()
+	for i := range uint64(sendQueueLen + 1) {
+		s.broadcastState(state)
+		s.broadcastEvent(game.Event{Type: game.EventGameOver, Tick: i})
+		waitFor(t, "Alice to get the event", func() bool {
+			for {
+				select {
+				case ev := <-alice.EventChan():
+					if ev.Tick == i {
+						return true
+					}
+				default:
+					return false
+				}
+			}
+		})
+	}
+
+	// Bob fell a queue behind, so his connection was closed
+	bobEnd.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 4096)
+	for {
+		if _, err := bobEnd.Read(buf); err != nil {
+			if err != io.EOF {
+				t.Errorf("expected Bob's connection closed, got %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestSkipUnchangedStates(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	s.engine.AddPlayer("p1", "Alice")
+	state := s.engine.GetStateCopy()
+	if !s.changed(state) {
+		t.Fatal("expected the first state to be broadcast")
+	}
+	state.Tick++
+	if s.changed(state) {
+		t.Error("expected a lobby state that only ticked to be skipped")
+	}
+	s.engine.AddPlayer("p2", "Bob")
+	state = s.engine.GetStateCopy()
+	if !s.changed(state) {
+		t.Error("expected a player joining to be broadcast")
+	}
+	s.engine.SetRTT("p2", 30*time.Millisecond)
+	state = s.engine.GetStateCopy()
+	if !s.changed(state) {
+		t.Error("expected a new ping to be broadcast")
+	}
+	s.spectators.Store(&[]game.Spectator{{ID: "s1", Name: "Carol"}})
+	if !s.changed(state) {
+		t.Error("expected a spectator arriving to be broadcast")
+	}
+	if s.changed(state) {
+		t.Error("expected an unchanged state to be skipped")
+	}
+	s.lastSent = time.Now().Add(-keepaliveInterval)
+	if !s.changed(state) {
+		t.Error("expected an unchanged state to be broadcast after keepaliveInterval")
+	}
+	state.Status = game.StatusRunning
+	if !s.changed(state) || !s.changed(state) {
+		t.Error("expected every state of a running match to be broadcast")
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	s.SetIdleTimeout(3 * HeartbeatInterval / 2)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	addr := s.listener.Addr().String()
+
+	// Answering heartbeats keeps a client in
+	alice, err := NewClient(addr, JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+
+	// One that joins and goes quiet is dropped, as is one that never joins
+	quiet, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer quiet.Close()
+	if err := Encode(quiet, MsgJoin, JoinMsg{Name: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	silent, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+
+	for _, conn := range []net.Conn{quiet, silent} {
+		conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+		if _, err := io.Copy(io.Discard, conn); err != nil {
+			t.Errorf("expected the server to close the connection, got %v", err)
+		}
+	}
+	waitFor(t, "Bob to be removed", func() bool {
+		return s.engine.PlayerCount() == 1
+	})
+	if _, ok := s.engine.GetStateCopy().Players[alice.PlayerID()]; !ok {
+		t.Error("expected Alice, who answers heartbeats, to stay")
+	}
+}
+
+func TestRoomServer(t *testing.T) {
+	r := NewRoomServer("127.0.0.1:0", game.DefaultConfig())
+	r.Configure(func(s *Server) {
+		s.SetMOTD("welcome to the arena")
+		s.SetRules("no spawn camping")
+	})
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	addr := r.listener.Addr().String()
+
+	rooms, err := ListRooms(addr)
+	if err != nil || len(rooms) != 0 {
+		t.Fatalf("expected no rooms, got %v, %v", rooms, err)
+	}
+	alice, err := JoinRoom(addr, "arena", true, JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	if alice.MOTD() != "welcome to the arena" || alice.Rules() != "no spawn camping" {
+		t.Errorf("expected the room's MOTD and rules in the welcome, got %q and %q", alice.MOTD(), alice.Rules())
+	}
+	if _, err := JoinRoom(addr, "arena", true, JoinMsg{Name: "Carol"}); err == nil {
+		t.Error("expected creating a room that exists to fail")
+	}
+	if _, err := JoinRoom(addr, "nowhere", false, JoinMsg{Name: "Carol"}); err == nil {
+		t.Error("expected joining a room that doesn't exist to fail")
+	}
+	bob, err := JoinRoom(addr, "arena", false, JoinMsg{Name: "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bob.Close()
+	select {
+	case msg := <-bob.SystemChan():
+		if msg.Kind != SystemMOTD || msg.Text != "welcome to the arena" {
+			t.Errorf("expected the room's MOTD, got %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("expected the room's MOTD")
+	}
+
+	rooms, err = ListRooms(addr)
+	if err != nil || len(rooms) != 1 || rooms[0].ID != "arena" || rooms[0].Players != 2 {
+		t.Fatalf("expected arena with 2 players, got %+v, %v", rooms, err)
+	}
+
+	// The room closes once its last player leaves
+	alice.Close()
+	bob.Close()
+	waitFor(t, "the room to close", func() bool {
+		rooms, err := ListRooms(addr)
+		return err == nil && len(rooms) == 0
+	})
+
+	s := NewServer("127.0.0.1:0", game.DefaultConfig())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	if _, err := ListRooms(s.listener.Addr().String()); !errors.Is(err, ErrSingleRoom) {
+		t.Errorf("expected a single-room server to say so, got %v", err)
+	}
+}
+
+func TestRoomClosesWithOnlyBots(t *testing.T) {
+	config := game.DefaultConfig()
+	config.StartCountdown = 0
+	config.FillWithBots = true
+	r := NewRoomServer("127.0.0.1:0", config)
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	addr := r.listener.Addr().String()
+
+	alice, err := JoinRoom(addr, "arena", true, JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	if err := alice.SendStart(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "bots to fill the room", func() bool {
+		rooms, err := ListRooms(addr)
+		return err == nil && len(rooms) == 1 && rooms[0].Players == config.MaxPlayers
+	})
+
+	// The bots play on without anyone, so the room closes when Alice leaves
+	alice.Leave()
+	waitFor(t, "the room to close", func() bool {
+		rooms, err := ListRooms(addr)
+		return err == nil && len(rooms) == 0
+	})
+}
+
+func TestAdminAPI(t *testing.T) {
+	r := NewRoomServer("127.0.0.1:0", game.DefaultConfig())
+	if err := r.EnableAdmin("127.0.0.1:0", ""); err == nil {
+		t.Error("expected the admin API to need a token")
+	}
+	if err := r.EnableAdmin("127.0.0.1:0", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	addr := r.listener.Addr().String()
+	base := "http://" + r.adminListener.Addr().String()
+
+	call := func(method, path, token, body string, out any) int {
+		t.Helper()
+		req, err := http.NewRequest(method, base+path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if out != nil && resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	if code := call("GET", "/rooms", "", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", code)
+	}
+	if code := call("GET", "/rooms", "wrong", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with the wrong token, got %d", code)
+	}
+
+	alice, err := JoinRoom(addr, "arena", true, JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	bob, err := JoinRoom(addr, "arena", false, JoinMsg{Name: "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bob.Close()
+
+	var rooms []RoomSummary
+	if code := call("GET", "/rooms", "s3cret", "", &rooms); code != http.StatusOK || len(rooms) != 1 || rooms[0].Players != 2 {
+		t.Fatalf("expected arena with 2 players, got %d %+v", code, rooms)
+	}
+	var players []AdminPlayer
+	if code := call("GET", "/rooms/arena/players", "s3cret", "", &players); code != http.StatusOK || len(players) != 2 {
+		t.Fatalf("expected 2 players, got %d %+v", code, players)
+	}
+	for _, p := range players {
+		if p.Addr == "" {
+			t.Errorf("expected %s's address", p.Name)
+		}
+	}
+	if code := call("GET", "/rooms/nowhere/players", "s3cret", "", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing room, got %d", code)
+	}
+
+	kick := fmt.Sprintf(`{"player_id": %q, "reason": "cheating"}`, bob.PlayerID())
+	if code := call("POST", "/rooms/arena/kick", "s3cret", kick, nil); code != http.StatusNoContent {
+		t.Fatalf("expected 204 for a kick, got %d", code)
+	}
+	waitFor(t, "Bob to hear of the kick", func() bool {
+		select {
+		case msg := <-bob.SystemChan():
+			return msg.Kind == SystemKick
+		default:
+			return false
+		}
+	})
+
+	var stats AdminStats
+	if code := call("GET", "/stats", "s3cret", "", &stats); code != http.StatusOK || stats.Rooms != 1 || stats.SentTotal == 0 {
+		t.Errorf("expected stats for one room, got %d %+v", code, stats)
+	}
+
+	if code := call("POST", "/rooms/arena/stop", "s3cret", "", nil); code != http.StatusNoContent {
+		t.Fatalf("expected 204 for a stop, got %d", code)
+	}
+	if code := call("GET", "/rooms", "s3cret", "", &rooms); code != http.StatusOK || len(rooms) != 0 {
+		t.Errorf("expected no rooms once stopped, got %d %+v", code, rooms)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	r := NewRoomServer("127.0.0.1:0", game.DefaultConfig())
+	if err := r.EnableMetrics("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	addr := r.listener.Addr().String()
+
+	scrape := func() map[string]string {
+		t.Helper()
+		resp, err := http.Get("http://" + r.metricsListener.Addr().String() + "/metrics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		metrics := make(map[string]string)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if name, value, ok := strings.Cut(line, " "); ok && !strings.HasPrefix(line, "#") {
+				metrics[name] = value
+			}
+		}
+		return metrics
+	}
+
+	alice, err := JoinRoom(addr, "arena", true, JoinMsg{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	waitFor(t, "a state to be encoded", func() bool {
+		return scrape()["bomberman_state_encode_seconds_count"] != "0"
+	})
+	m := scrape()
+	if m["bomberman_rooms"] != "1" || m["bomberman_clients"] != "1" {
+		t.Errorf("expected 1 room and 1 client, got %s and %s", m["bomberman_rooms"], m["bomberman_clients"])
+	}
+	if m[`bomberman_state_encode_seconds_bucket{le="+Inf"}`] != m["bomberman_state_encode_seconds_count"] {
+		t.Error("expected the +Inf bucket to count every encoding")
+	}
+	sent, _ := strconv.Atoi(m["bomberman_sent_bytes_total"])
+
+	// Counters keep what closed rooms counted
+	alice.Close()
+	waitFor(t, "the room to close", func() bool { return scrape()["bomberman_rooms"] == "0" })
+	if after, _ := strconv.Atoi(scrape()["bomberman_sent_bytes_total"]); sent == 0 || after < sent {
+		t.Errorf("expected sent bytes to keep growing after the room closed, got %d then %d", sent, after)
+	}
+}
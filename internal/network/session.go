@@ -0,0 +1,179 @@
+package network
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/crash"
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// ReconnectGrace is how long a player whose connection dropped mid-match is
+// kept, standing idle, for their client to rejoin with its session token.
+const ReconnectGrace = 30 * time.Second
+
+// reconnectRetry is how long a client whose connection dropped waits before
+// its first try to rejoin. Each failed try doubles the wait, up to
+// reconnectRetryMax, so a server that's down isn't hammered.
+const (
+	reconnectRetry    = 250 * time.Millisecond
+	reconnectRetryMax = 4 * time.Second
+)
+
+// session is a joined player's claim on their character, held by whoever
+// knows its token.
+type session struct {
+	playerID string
+	expiry   *time.Timer // Runs while the player is disconnected; nil otherwise
+	addr     string      // Where the disconnected player was, without port, for banning them while away
+}
+
+// newToken returns a fresh session token.
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// dropClient handles a client's connection going down. Mid-match the player
+// is kept for ReconnectGrace in case the client rejoins; otherwise they are
+// removed at once.
+func (s *Server) dropClient(cc *clientConn) {
+	stopping := false
+	select {
+	case <-s.done:
+		stopping = true
+	default:
+	}
+	lobby := s.engine.GetStateCopy().Status == game.StatusLobby
+
+	s.mu.Lock()
+	if s.clients[cc.playerID] != cc {
+		// Already replaced by a rejoin
+		s.mu.Unlock()
+		return
+	}
+	sess := s.sessions[cc.token]
+	if sess == nil || lobby || stopping {
+		s.mu.Unlock()
+		s.removeClient(cc.playerID)
+		return
+	}
+	delete(s.clients, cc.playerID)
+	sess.addr = remoteHost(cc.conn)
+	sess.expiry = time.AfterFunc(ReconnectGrace, func() {
+		defer crash.Recover()
+		s.expireSession(cc.token, sess)
+	})
+	s.mu.Unlock()
+
+	cc.close()
+	s.markReady(cc.playerID, false)
+	s.engine.SetDisconnected(cc.playerID, true)
+	s.log.Info("Player disconnected; holding their place", "player_id", cc.playerID, "grace", ReconnectGrace)
+}
+
+// leave removes a client that quit on purpose. Unlike a dropped connection,
+// its player's place isn't held for a rejoin.
+func (s *Server) leave(cc *clientConn) {
+	name := cc.name
+	if !cc.spectator {
+		name = s.engine.PlayerName(cc.playerID)
+	}
+	s.mu.RLock()
+	current := s.clients[cc.playerID] == cc
+	s.mu.RUnlock()
+	if !current {
+		return
+	}
+	s.removeClient(cc.playerID)
+	s.log.Info("Player left", "player_id", cc.playerID)
+	if s.announce.Allow() {
+		s.broadcastSystem("", SystemMsg{Kind: SystemAnnounce, Text: fmt.Sprintf("%s left", name)})
+	}
+}
+
+// expireSession removes a disconnected player whose client didn't rejoin in
+// time.
+func (s *Server) expireSession(token string, sess *session) {
+	s.mu.Lock()
+	if s.sessions[token] != sess || sess.expiry == nil {
+		// Rejoined just in time
+		s.mu.Unlock()
+		return
+	}
+	delete(s.sessions, token)
+	s.mu.Unlock()
+
+	s.removePlayer(sess.playerID)
+	s.log.Info("Player removed; didn't reconnect", "player_id", sess.playerID)
+	s.passHost(sess.playerID)
+}
+
+// awayLocked returns the token of the session held for the disconnected
+// player with the given ID, or false if they aren't away.
+// MUST be called while s.mu is held.
+func (s *Server) awayLocked(playerID string) (string, bool) {
+	for token, sess := range s.sessions {
+		if sess.playerID == playerID && sess.expiry != nil {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// rejoin hands a reconnecting client back its player, answering it as a
+// join would. It returns false if the client was turned away.
+func (s *Server) rejoin(conn net.Conn, env *Envelope) (*clientConn, bool) {
+	var msg RejoinMsg
+	if err := DecodePayload(env, &msg); err != nil {
+		s.log.Warn("Invalid message", "msg_type", env.Type, "err", err)
+		return nil, false
+	}
+	version, err := negotiateVersion(msg.Version)
+	if err != nil {
+		s.log.Info("Rejected rejoin", "addr", conn.RemoteAddr(), "err", err)
+		Encode(conn, MsgError, ErrorMsg{Message: err.Error()})
+		return nil, false
+	}
+
+	s.mu.Lock()
+	sess := s.sessions[msg.Token]
+	if sess == nil || !s.engine.SetDisconnected(sess.playerID, false) {
+		s.mu.Unlock()
+		Encode(conn, MsgError, ErrorMsg{Message: "your session has expired; join again"})
+		return nil, false
+	}
+	if sess.expiry != nil {
+		sess.expiry.Stop()
+		sess.expiry = nil
+	}
+	cc := &clientConn{
+		conn:     conn,
+		playerID: sess.playerID,
+		token:    msg.Token,
+		chat:     newRateLimiter(chatBurst, chatInterval),
+		enc:      pickEncoding(msg.Encodings),
+		deltas:   msg.Deltas,
+		compress: pickCompression(s.compress, msg.Compress),
+	}
+	if msg.UDP && s.udp != nil {
+		cc.udpKey = newToken()
+	}
+	s.startWriter(cc)
+	s.welcomeLocked(cc, version)
+	old := s.clients[cc.playerID]
+	s.clients[cc.playerID] = cc
+	s.mu.Unlock()
+	if old != nil {
+		// The old connection is dead but hasn't noticed yet
+		old.close()
+	}
+
+	s.log.Info("Player rejoined", "player_id", cc.playerID)
+	cc.sendState(s.engine.GetStateCopy())
+	return cc, true
+}
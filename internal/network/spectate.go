@@ -0,0 +1,78 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// MaxSpectators bounds how many connections may watch a game at once.
+const MaxSpectators = 16
+
+// spectatorMsgs are the messages a spectator may send; anything else, such
+// as actions, is ignored.
+var spectatorMsgs = map[MsgType]bool{
+	MsgPing:   true,
+	MsgPong:   true,
+	MsgResync: true,
+	MsgChat:   true,
+	MsgLeave:  true,
+}
+
+// joinSpectator lets a client that joined with JoinMsg.Spectate watch the
+// game, answering it as a player's join would. It returns false if the
+// client was turned away.
+func (s *Server) joinSpectator(conn net.Conn, join JoinMsg, version int) (*clientConn, bool) {
+	cc := &clientConn{
+		conn:      conn,
+		playerID:  fmt.Sprintf("s%d", time.Now().UnixNano()),
+		name:      join.Name,
+		spectator: true,
+		chat:      newRateLimiter(chatBurst, chatInterval),
+		enc:       pickEncoding(join.Encodings),
+		deltas:    join.Deltas,
+		compress:  pickCompression(s.compress, join.Compress),
+	}
+	s.mu.Lock()
+	if len(s.spectatorList()) >= MaxSpectators {
+		s.mu.Unlock()
+		Encode(conn, MsgError, ErrorMsg{Message: "too many spectators"})
+		return nil, false
+	}
+	s.startWriter(cc)
+	s.welcomeLocked(cc, version)
+	s.clients[cc.playerID] = cc
+	s.updateSpectatorsLocked()
+	s.mu.Unlock()
+
+	s.log.Info("Spectator joined", "player_id", cc.playerID, "name", join.Name)
+	cc.sendState(s.engine.GetStateCopy())
+	return cc, true
+}
+
+// updateSpectatorsLocked refreshes the spectator list sent with each state.
+// MUST be called while s.mu is held.
+func (s *Server) updateSpectatorsLocked() {
+	var list []game.Spectator
+	for _, cc := range s.clients {
+		if cc.spectator {
+			list = append(list, game.Spectator{ID: cc.playerID, Name: cc.name})
+		}
+	}
+	slices.SortFunc(list, func(a, b game.Spectator) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	s.spectators.Store(&list)
+}
+
+// spectatorList returns who is watching, in the order they joined.
+func (s *Server) spectatorList() []game.Spectator {
+	if list := s.spectators.Load(); list != nil {
+		return *list
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package network
+
+import (
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/crash"
+)
+
+// readyTimeout bounds how long the countdown waits for acknowledgements, so
+// one unresponsive client can't hold up the match.
+const readyTimeout = 2 * time.Second
+
+// startSync collects MsgReadyForStart acknowledgements for one countdown and
+// the round trip each took.
+type startSync struct {
+	sentAt  time.Time
+	pending map[string]bool
+	rtts    map[string]time.Duration
+	timer   *time.Timer
+}
+
+// beginStartSync asks every client to acknowledge the countdown.
+func (s *Server) beginStartSync() {
+	s.mu.Lock()
+	sync := &startSync{
+		sentAt:  time.Now(),
+		pending: make(map[string]bool, len(s.clients)),
+		rtts:    make(map[string]time.Duration, len(s.clients)),
+	}
+	for id, cc := range s.clients {
+		if !cc.spectator {
+			sync.pending[id] = true
+		}
+	}
+	sync.timer = time.AfterFunc(readyTimeout, func() {
+		defer crash.Recover()
+		s.finishStartSync(sync)
+	})
+	s.startSync = sync
+	s.mu.Unlock()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, cc := range s.clients {
+		cc.send(MsgCountdown, CountdownMsg{})
+	}
+}
+
+// markReady records that a client acknowledged the countdown, or stopped
+// being waited for because it left. The countdown runs once nobody is pending.
+func (s *Server) markReady(playerID string, acked bool) {
+	s.mu.Lock()
+	sync := s.startSync
+	if sync == nil || !sync.pending[playerID] {
+		s.mu.Unlock()
+		return
+	}
+	delete(sync.pending, playerID)
+	if acked {
+		sync.rtts[playerID] = time.Since(sync.sentAt)
+	}
+	done := len(sync.pending) == 0
+	s.mu.Unlock()
+
+	if done {
+		s.finishStartSync(sync)
+	}
+}
+
+// finishStartSync runs the engine's countdown and tells each client how long
+// until the start, compensated for its latency: a whole round trip early, so
+// a first move sent on the client's "go" reaches the server as the match
+// starts, however far away the player is.
+func (s *Server) finishStartSync(sync *startSync) {
+	s.mu.Lock()
+	if s.startSync != sync {
+		// Already finished by the last acknowledgement or the timeout
+		s.mu.Unlock()
+		return
+	}
+	s.startSync = nil
+	sync.timer.Stop()
+	s.mu.Unlock()
+
+	if len(sync.pending) > 0 {
+		s.log.Warn("Starting without every countdown acknowledgement", "missing", len(sync.pending))
+	}
+	s.engine.RunCountdown()
+
+	countdown := s.engine.Config.StartCountdown
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for id, cc := range s.clients {
+		// The message takes half a round trip to reach the client, and its
+		// first move half of one to come back. A zero StartsIn would ask for
+		// another acknowledgement, so a round trip longer than the countdown
+		// starts the client at once instead.
+		cc.send(MsgCountdown, CountdownMsg{StartsIn: max(countdown-sync.rtts[id], time.Millisecond)})
+	}
+}
@@ -0,0 +1,106 @@
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// TLSScheme prefixes the address of a room that needs TLS, as in
+// tls://host:port.
+const TLSScheme = "tls://"
+
+// dialTimeout bounds how long a client waits to connect.
+const dialTimeout = 5 * time.Second
+
+// TLSRoots are the certificate authorities clients trust for tls:// rooms;
+// nil trusts the system's. Set it with LoadTLSRoots to trust a host's
+// self-signed certificate.
+var TLSRoots *x509.CertPool
+
+// LoadTLSRoots trusts the PEM certificates in path for tls:// rooms, in
+// place of the system's.
+func LoadTLSRoots(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read certificates: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no PEM certificates in %s", path)
+	}
+	TLSRoots = pool
+	return nil
+}
+
+// EnableTLS makes clients connect over TLS, on the game port and the
+// WebSocket one, with the certificate and key in the given PEM files. UDP
+// isn't offered, as it would go around TLS. Must be called before Start and
+// EnableWebSocket.
+func (s *Server) EnableTLS(certFile, keyFile string) error {
+	config, err := loadTLS(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	s.tls = config
+	return nil
+}
+
+// EnableTLS makes clients connect to every room over TLS, with the
+// certificate and key in the given PEM files. Must be called before Start.
+func (r *RoomServer) EnableTLS(certFile, keyFile string) error {
+	config, err := loadTLS(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	r.tls = config
+	return nil
+}
+
+// loadTLS returns the server side of TLS with the certificate and key in
+// the given PEM files.
+func loadTLS(certFile, keyFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// TLS reports whether clients must connect over TLS.
+func (s *Server) TLS() bool {
+	return s.tls != nil
+}
+
+// dial connects to a room's address, over TLS if it starts with TLSScheme.
+// A loopback address never leaves the machine, so its certificate isn't
+// checked; that's how a host's own client connects.
+func dial(addr string) (net.Conn, error) {
+	hostPort, secure := strings.CutPrefix(addr, TLSScheme)
+	if !secure {
+		return net.DialTimeout("tcp", addr, dialTimeout)
+	}
+	host, _, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(host)
+	config := &tls.Config{
+		ServerName:         host,
+		RootCAs:            TLSRoots,
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: host == "localhost" || (ip != nil && ip.IsLoopback()),
+	}
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: dialTimeout}, Config: config}
+	conn, err := dialer.Dial("tcp", hostPort)
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return nil, fmt.Errorf("%w (trust a self-signed host with --tls-ca)", err)
+	}
+	return conn, err
+}
@@ -0,0 +1,191 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/crash"
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// UDPHelloMsg tells the server where to send a client its states over UDP.
+// Clients send it every HeartbeatInterval, which also keeps NAT mappings
+// open.
+type UDPHelloMsg struct {
+	Key string `json:"key"` // WelcomeMsg.UDPKey
+}
+
+// UDPMoveMsg is a move sent over UDP. Moves that arrive after one with a
+// higher Seq are dropped, so only the latest counts.
+type UDPMoveMsg struct {
+	Key       string         `json:"key"` // WelcomeMsg.UDPKey
+	Direction game.Direction `json:"direction"`
+	Seq       uint32         `json:"seq"` // Numbers the client's actions, shared with ActionMsg.Seq
+}
+
+// listenUDP opens the UDP channel on the TCP listener's port. Without it,
+// clients asking for UDP just aren't granted it.
+func (s *Server) listenUDP() {
+	conn, err := net.ListenPacket("udp", s.listener.Addr().String())
+	if err != nil {
+		s.log.Warn("UDP unavailable, using TCP only", "err", err)
+		return
+	}
+	s.udp = conn
+	crash.Go(s.udpLoop)
+}
+
+// udpLoop handles datagrams from clients until the server stops.
+func (s *Server) udpLoop() {
+	buf := make([]byte, maxDatagram)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		s.meter.recv.Add(int64(n))
+		env, err := Decode(bytes.NewReader(buf[:n]))
+		if err != nil {
+			continue
+		}
+		switch env.Type {
+		case MsgUDPHello:
+			var hello UDPHelloMsg
+			if DecodePayload(env, &hello) != nil {
+				continue
+			}
+			if cc := s.udpClient(hello.Key); cc != nil {
+				cc.mu.Lock()
+				cc.udpAddr = addr
+				cc.mu.Unlock()
+			}
+		case MsgUDPMove:
+			var move UDPMoveMsg
+			if DecodePayload(env, &move) != nil {
+				continue
+			}
+			if cc := s.udpClient(move.Key); cc != nil && cc.raiseSeq(move.Seq) {
+				s.engine.EnqueueAction(game.Action{
+					PlayerID: cc.playerID,
+					Type:     game.ActionMove,
+					Dir:      move.Direction,
+					Seq:      move.Seq,
+				})
+			}
+		}
+	}
+}
+
+// udpClient returns the client with the given UDP key, or nil.
+func (s *Server) udpClient(key string) *clientConn {
+	if key == "" {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, cc := range s.clients {
+		if cc.udpKey == key {
+			return cc
+		}
+	}
+	return nil
+}
+
+// sendStateUDP sends the client view over UDP, reporting whether it did.
+// MUST be called while cc.mu is held.
+func (s *Server) sendStateUDP(cc *clientConn, view game.GameState) bool {
+	start := time.Now()
+	msgType, msg, err := stateMessage(cc.enc, cc.compress, view)
+	if err != nil {
+		return false
+	}
+	b, err := encodeMessage(cc.enc, msgType, msg)
+	s.encodeTime.observe(time.Since(start))
+	if err != nil || len(b) > maxDatagram {
+		// Too big for one datagram
+		return false
+	}
+	n, err := s.udp.WriteTo(b, cc.udpAddr)
+	if err != nil {
+		s.log.Info("Failed to send UDP state", "player_id", cc.playerID, "err", err)
+		return false
+	}
+	s.meter.sent.Add(int64(n))
+	return true
+}
+
+// raiseSeq records that an action numbered seq was taken, reporting whether
+// it is newer than every one before it.
+func (cc *clientConn) raiseSeq(seq uint32) bool {
+	for {
+		last := cc.maxSeq.Load()
+		if seq <= last {
+			return false
+		}
+		if cc.maxSeq.CompareAndSwap(last, seq) {
+			return true
+		}
+	}
+}
+
+// openUDP starts taking states and sending moves over UDP with the key the
+// server granted. Until the first state arrives, states keep coming over TCP.
+// MUST be called while c.mu is held.
+func (c *Client) openUDP(key string) {
+	conn, err := net.Dial("udp", c.addr)
+	if err != nil {
+		return
+	}
+	udp, enc, compress := &meteredConn{Conn: conn, meter: c.meter}, c.enc, c.compress
+	c.udp, c.udpKey = udp, key
+	crash.Go(func() { c.udpLoop(udp, enc, compress, key) })
+}
+
+// closeUDP stops using UDP. MUST be called while c.mu is held.
+func (c *Client) closeUDP() {
+	if c.udp != nil {
+		c.udp.Close()
+		c.udp, c.udpKey = nil, ""
+	}
+}
+
+// udpLoop says hello every HeartbeatInterval and hands on the states that
+// arrive over conn, dropping any older than one already handed on.
+func (c *Client) udpLoop(conn net.Conn, enc Encoding, compress Compression, key string) {
+	hello, err := encodeMessage(enc, MsgUDPHello, UDPHelloMsg{Key: key})
+	if err != nil {
+		return
+	}
+	buf := make([]byte, maxDatagram)
+	var lastTick uint64
+	nextHello := time.Now()
+	for {
+		if !time.Now().Before(nextHello) {
+			conn.Write(hello)
+			nextHello = time.Now().Add(HeartbeatInterval)
+		}
+		conn.SetReadDeadline(nextHello)
+		n, err := conn.Read(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		env, err := Decode(bytes.NewReader(buf[:n]))
+		if err != nil || (env.Type != MsgState && env.Type != MsgCompressed) {
+			continue
+		}
+		state, err := decodeState(env, compress)
+		if err != nil || state.Tick <= lastTick {
+			continue
+		}
+		lastTick = state.Tick
+		c.pushState(state)
+	}
+}
@@ -0,0 +1,323 @@
+package network
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/crash"
+)
+
+// WebSocketPath is where the server takes WebSocket connections.
+const WebSocketPath = "/ws"
+
+// wsGUID is appended to the client's key to make Sec-WebSocket-Accept
+// (RFC 6455, section 1.3).
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket frame opcodes.
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// maxWSMessage bounds a message from a WebSocket client, as Decode does for
+// TCP ones.
+const maxWSMessage = 1 << 20
+
+// wsListener takes WebSocket connections on an HTTP port. It is a
+// net.Listener like the TCP one, so the server treats both alike past Accept.
+type wsListener struct {
+	net.Listener // The HTTP port
+	conns        chan net.Conn
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+// listenWebSocket starts taking WebSocket connections at WebSocketPath on
+// addr, over TLS if config isn't nil.
+func listenWebSocket(addr string, config *tls.Config) (*wsListener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		ln = tls.NewListener(ln, config)
+	}
+	l := &wsListener{
+		Listener: ln,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(WebSocketPath, l.upgrade)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	crash.Go(func() { srv.Serve(ln) })
+	return l, nil
+}
+
+// Accept waits for the next WebSocket connection.
+func (l *wsListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops taking connections.
+func (l *wsListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+// upgrade completes the WebSocket handshake and hands the connection to
+// Accept. Any origin may connect, as with the TCP port.
+func (l *wsListener) upgrade(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" ||
+		!headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "can't take over the connection", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		slog.Error("WebSocket hijack failed", "err", err)
+		return
+	}
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", wsAccept(key))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+
+	ws := &wsConn{Conn: conn, br: rw.Reader}
+	select {
+	case l.conns <- ws:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+// headerHas reports whether the comma-separated header h lists token, in
+// any case.
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wsAccept returns the Sec-WebSocket-Accept answering key.
+func wsAccept(key string) string {
+	sum := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// wsConn carries the protocol over a WebSocket, one envelope per message
+// and without the length header, which the framing makes redundant. It
+// reads and writes the length-prefixed stream Encode and Decode use, adding
+// and removing the header on the way. JSON envelopes go out as text messages
+// and MessagePack ones as binary.
+type wsConn struct {
+	net.Conn
+	br   *bufio.Reader
+	rbuf []byte // What's left of the last message read, with its length header
+
+	wmu  sync.Mutex
+	wbuf []byte // Bytes written that don't make a whole envelope yet
+}
+
+func (c *wsConn) Read(p []byte) (int, error) {
+	if len(c.rbuf) == 0 {
+		msg, err := c.readMessage()
+		if err != nil {
+			return 0, err
+		}
+		c.rbuf = binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(msg)), uint32(len(msg)))
+		c.rbuf = append(c.rbuf, msg...)
+	}
+	n := copy(p, c.rbuf)
+	c.rbuf = c.rbuf[n:]
+	return n, nil
+}
+
+func (c *wsConn) Write(p []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.wbuf = append(c.wbuf, p...)
+	for len(c.wbuf) >= 4 {
+		n := int(binary.BigEndian.Uint32(c.wbuf))
+		if len(c.wbuf) < 4+n {
+			break
+		}
+		body := c.wbuf[4 : 4+n]
+		opcode := byte(wsText)
+		if isMsgpack(body) {
+			opcode = wsBinary
+		}
+		if err := writeWSFrame(c.Conn, opcode, body, false); err != nil {
+			return 0, err
+		}
+		c.wbuf = c.wbuf[4+n:]
+	}
+	return len(p), nil
+}
+
+// Close sends a close frame, if nothing is being written, and closes the
+// connection.
+func (c *wsConn) Close() error {
+	if c.wmu.TryLock() {
+		writeWSFrame(c.Conn, wsClose, []byte{0x03, 0xe8}, false) // 1000: normal closure
+		c.wmu.Unlock()
+	}
+	return c.Conn.Close()
+}
+
+// readMessage reads the next data message, answering pings on the way. It
+// returns io.EOF once the client closes the connection.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, opcode, payload, err := readWSFrame(c.br, true)
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case wsPing:
+			c.wmu.Lock()
+			err := writeWSFrame(c.Conn, wsPong, payload, false)
+			c.wmu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			return nil, io.EOF
+		}
+		if len(msg)+len(payload) > maxWSMessage {
+			return nil, fmt.Errorf("message too large: over %d bytes", maxWSMessage)
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// readWSFrame reads one frame, unmasking its payload. Frames from clients
+// must be masked and frames from servers must not be, as RFC 6455 requires;
+// fromClient says which end sent it.
+func readWSFrame(r io.Reader, fromClient bool) (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0f
+	masked := head[1]&0x80 != 0
+	if masked != fromClient {
+		return false, 0, nil, errors.New("WebSocket frame masked the wrong way")
+	}
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxWSMessage {
+		return false, 0, nil, fmt.Errorf("frame too large: %d bytes", length)
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	if opcode != wsContinuation && opcode != wsText && opcode != wsBinary &&
+		opcode != wsClose && opcode != wsPing && opcode != wsPong {
+		return false, 0, nil, errors.New("unknown WebSocket opcode")
+	}
+	return fin, opcode, payload, nil
+}
+
+// writeWSFrame writes payload as one final frame. Clients must mask what
+// they send; servers must not.
+func writeWSFrame(w io.Writer, opcode byte, payload []byte, mask bool) error {
+	frame := []byte{0x80 | opcode, 0}
+	switch n := len(payload); {
+	case n < 126:
+		frame[1] = byte(n)
+	case n <= 0xffff:
+		frame[1] = 126
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame[1] = 127
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if !mask {
+		_, err := w.Write(append(frame, payload...))
+		return err
+	}
+	frame[1] |= 0x80
+	var key [4]byte
+	rand.Read(key[:])
+	frame = append(frame, key[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^key[i%4])
+	}
+	_, err := w.Write(frame)
+	return err
+}
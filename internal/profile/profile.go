@@ -0,0 +1,133 @@
+// Package profile stores a player's local progression: games played, wins,
+// and the cosmetics those unlock.
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// CosmeticKind groups unlocks by what they change.
+type CosmeticKind int
+
+const (
+	KindNameColor CosmeticKind = iota // Color of the player's name in the HUD
+	KindBanner                        // Banner shown when the player wins
+	KindGlyph                         // Characters drawn for the player on the board
+)
+
+// Unlock is a cosmetic and the milestone that unlocks it.
+type Unlock struct {
+	ID    string
+	Kind  CosmeticKind
+	Name  string
+	Wins  int // Wins required
+	Games int // Games played required
+}
+
+// Catalog lists every cosmetic. IDs are sent over the wire, so never rename one.
+var Catalog = []Unlock{
+	{ID: "gold", Kind: KindNameColor, Name: "Gold name", Wins: 3},
+	{ID: "crimson", Kind: KindNameColor, Name: "Crimson name", Games: 10},
+	{ID: "ice", Kind: KindNameColor, Name: "Ice name", Wins: 10},
+	{ID: "crown", Kind: KindBanner, Name: "Crown banner", Wins: 1},
+	{ID: "inferno", Kind: KindBanner, Name: "Inferno banner", Wins: 5},
+	{ID: "smile", Kind: KindGlyph, Name: "Smiley glyph", Games: 5},
+	{ID: "star", Kind: KindGlyph, Name: "Star glyph", Games: 20},
+}
+
+// Profile is the local player's progression, persisted as JSON.
+type Profile struct {
+	GamesPlayed int            `json:"games_played"`
+	Wins        int            `json:"wins"`
+	Selected    game.Cosmetics `json:"selected"`
+
+	path string
+}
+
+// DefaultPath returns the profile location in the user's config directory.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "bomberman-profile.json"
+	}
+	return filepath.Join(dir, "bomberman", "profile.json")
+}
+
+// Load reads the profile at path, starting fresh if it doesn't exist.
+func Load(path string) (*Profile, error) {
+	p := &Profile{path: path}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read profile: %w", err)
+	}
+	if err := json.Unmarshal(raw, p); err != nil {
+		return nil, fmt.Errorf("parse profile %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Save writes the profile back to disk.
+func (p *Profile) Save() error {
+	raw, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode profile: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
+		return fmt.Errorf("create profile dir: %w", err)
+	}
+	return os.WriteFile(p.path, raw, 0o644)
+}
+
+// RecordGame counts a finished game.
+func (p *Profile) RecordGame(won bool) {
+	p.GamesPlayed++
+	if won {
+		p.Wins++
+	}
+}
+
+// Unlocked reports whether the profile has reached u's milestone.
+func (p *Profile) Unlocked(u Unlock) bool {
+	return p.Wins >= u.Wins && p.GamesPlayed >= u.Games
+}
+
+// IsSelected reports whether u is currently equipped.
+func (p *Profile) IsSelected(u Unlock) bool {
+	return *p.slot(u.Kind) == u.ID
+}
+
+// Toggle equips u, or unequips it if already selected.
+// Locked cosmetics can't be equipped.
+func (p *Profile) Toggle(u Unlock) error {
+	if !p.Unlocked(u) {
+		return fmt.Errorf("%s is locked", u.Name)
+	}
+	slot := p.slot(u.Kind)
+	if *slot == u.ID {
+		*slot = ""
+	} else {
+		*slot = u.ID
+	}
+	return nil
+}
+
+func (p *Profile) slot(kind CosmeticKind) *string {
+	switch kind {
+	case KindNameColor:
+		return &p.Selected.NameColor
+	case KindBanner:
+		return &p.Selected.Banner
+	default:
+		return &p.Selected.Glyph
+	}
+}
@@ -0,0 +1,109 @@
+package protogen
+
+import "encoding/json"
+
+// JSONSchema renders m as a JSON Schema (draft 2020-12) validating one
+// decoded envelope.
+func JSONSchema(m *Model) ([]byte, error) {
+	defs := make(map[string]any)
+	var messages []any
+	for _, msg := range m.Messages {
+		payload := map[string]any{"type": "object"}
+		if msg.Payload != "" {
+			payload = map[string]any{"$ref": "#/$defs/" + msg.Payload}
+		}
+		name := pascal(msg.Type) + "Message"
+		defs[name] = map[string]any{
+			"description": msg.Doc,
+			"x-sent-by":   msg.Dir,
+			"type":        "object",
+			"properties": map[string]any{
+				"type":    map[string]any{"const": msg.Type},
+				"payload": payload,
+			},
+			"required": []string{"type", "payload"},
+		}
+		messages = append(messages, map[string]any{"$ref": "#/$defs/" + name})
+	}
+
+	for _, t := range m.Types {
+		if t.IsEnum() {
+			values := make([]any, len(t.Enum))
+			names := make([]string, len(t.Enum))
+			for i, v := range t.Enum {
+				values[i], names[i] = v.Value, v.Name
+			}
+			defs[t.Name] = map[string]any{
+				"type":         schemaType(t.Base),
+				"enum":         values,
+				"x-enum-names": names,
+			}
+			continue
+		}
+		props := make(map[string]any)
+		required := []string{}
+		for _, f := range t.Fields {
+			props[f.Name] = refSchema(f.Type)
+			if !f.Optional {
+				required = append(required, f.Name)
+			}
+		}
+		defs[t.Name] = map[string]any{
+			"type":       "object",
+			"properties": props,
+			"required":   required,
+		}
+	}
+
+	schema := map[string]any{
+		"$schema":            "https://json-schema.org/draft/2020-12/schema",
+		"title":              "Bomberman wire protocol",
+		"description":        "Each message is a 4-byte big-endian length followed by that many bytes (at most 1 MiB) of a JSON envelope matching this schema.",
+		"x-protocol-version": m.Version,
+		"oneOf":              messages,
+		"$defs":              defs,
+	}
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func schemaType(k Kind) string {
+	switch k {
+	case KindString, KindTime:
+		return "string"
+	case KindInteger, KindDuration:
+		return "integer"
+	case KindNumber:
+		return "number"
+	case KindBoolean:
+		return "boolean"
+	case KindArray:
+		return "array"
+	}
+	return "object"
+}
+
+func refSchema(r TypeRef) map[string]any {
+	var s map[string]any
+	switch r.Kind {
+	case KindRef:
+		return map[string]any{"$ref": "#/$defs/" + r.Name}
+	case KindAny:
+		return map[string]any{}
+	case KindTime:
+		s = map[string]any{"format": "date-time"}
+	case KindDuration:
+		s = map[string]any{"description": "nanoseconds"}
+	case KindArray:
+		s = map[string]any{"items": refSchema(*r.Elem)}
+	case KindMap:
+		s = map[string]any{"additionalProperties": refSchema(*r.Elem)}
+	default:
+		s = map[string]any{}
+	}
+	s["type"] = schemaType(r.Kind)
+	return s
+}
@@ -0,0 +1,292 @@
+// Package protogen describes the wire protocol as a language-neutral model,
+// built by reflection from network.Messages, and renders it as a JSON Schema
+// and as typed bindings for bot authors. cmd/protogen writes the output to
+// protocol/.
+package protogen
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/network"
+)
+
+// Kind is the shape of a value on the wire.
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindInteger
+	KindNumber
+	KindBoolean
+	KindTime     // RFC 3339 string
+	KindDuration // Integer nanoseconds
+	KindArray
+	KindMap // Object with string keys
+	KindRef // Named type in Model.Types
+	KindAny
+)
+
+// TypeRef is the type of a field.
+type TypeRef struct {
+	Kind Kind
+	Name string   // KindRef
+	Elem *TypeRef // KindArray and KindMap
+}
+
+// Field is one property of an object type.
+type Field struct {
+	Name     string // JSON name
+	Type     TypeRef
+	Optional bool // omitempty: left out when zero
+}
+
+// Type is a named object or enum type.
+type Type struct {
+	Name   string
+	Fields []Field
+	Enum   []network.EnumValue
+	Base   Kind // KindString or KindInteger for enums
+}
+
+// IsEnum reports whether t is an enum rather than an object.
+func (t *Type) IsEnum() bool { return t.Enum != nil }
+
+// Message is one message type.
+type Message struct {
+	Type    string
+	Dir     string // network.ToServer, network.ToClient or network.Both
+	Payload string // Name of the payload type; "" for an empty object
+	Doc     string
+}
+
+// Model is the whole protocol.
+type Model struct {
+	Version  int
+	Messages []Message
+	Types    []*Type // Enums first, then objects, each sorted by name
+}
+
+// Build describes network.Messages.
+func Build() (*Model, error) {
+	b := &builder{
+		enums: make(map[reflect.Type][]network.EnumValue),
+		types: make(map[string]*Type),
+		goTyp: make(map[string]reflect.Type),
+	}
+	for _, spec := range network.Enums {
+		t := reflect.TypeOf(spec.Type)
+		for _, v := range spec.Values {
+			if reflect.TypeOf(v.Value) != t {
+				return nil, fmt.Errorf("enum %s: value %q is a %T", t, v.Name, v.Value)
+			}
+		}
+		b.enums[t] = spec.Values
+	}
+
+	m := &Model{Version: network.ProtocolVersion}
+	for _, spec := range network.Messages {
+		msg := Message{Type: string(spec.Type), Dir: spec.Dir, Doc: spec.Doc}
+		if spec.Payload != nil {
+			ref, err := b.ref(reflect.TypeOf(spec.Payload))
+			if err != nil {
+				return nil, fmt.Errorf("message %s: %w", spec.Type, err)
+			}
+			if ref.Kind != KindRef {
+				return nil, fmt.Errorf("message %s: payload must be a named struct", spec.Type)
+			}
+			msg.Payload = ref.Name
+		}
+		m.Messages = append(m.Messages, msg)
+	}
+	// MsgType itself never appears in a payload but bindings need it
+	if _, err := b.ref(reflect.TypeOf(network.MsgType(""))); err != nil {
+		return nil, err
+	}
+
+	for _, t := range b.types {
+		m.Types = append(m.Types, t)
+	}
+	sort.Slice(m.Types, func(i, j int) bool {
+		a, c := m.Types[i], m.Types[j]
+		if a.IsEnum() != c.IsEnum() {
+			return a.IsEnum()
+		}
+		return a.Name < c.Name
+	})
+	return m, nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	rawType      = reflect.TypeOf(json.RawMessage(nil))
+)
+
+type builder struct {
+	enums map[reflect.Type][]network.EnumValue
+	types map[string]*Type
+	goTyp map[string]reflect.Type // Catches two Go types with the same name
+}
+
+func (b *builder) ref(t reflect.Type) (TypeRef, error) {
+	switch t {
+	case timeType:
+		return TypeRef{Kind: KindTime}, nil
+	case durationType:
+		return TypeRef{Kind: KindDuration}, nil
+	case rawType:
+		return TypeRef{Kind: KindAny}, nil
+	}
+	if values, ok := b.enums[t]; ok {
+		return b.named(t, func(def *Type) error {
+			def.Enum = values
+			def.Base = KindInteger
+			if t.Kind() == reflect.String {
+				def.Base = KindString
+			}
+			return nil
+		})
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.ref(t.Elem())
+	case reflect.Interface:
+		return TypeRef{Kind: KindAny}, nil
+	case reflect.Struct:
+		return b.named(t, func(def *Type) error {
+			fields, err := b.fields(t)
+			def.Fields = fields
+			return err
+		})
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if t.Kind() == reflect.Map && t.Key().Kind() != reflect.String {
+			return TypeRef{}, fmt.Errorf("%s: map keys must be strings", t)
+		}
+		elem, err := b.ref(t.Elem())
+		if err != nil {
+			return TypeRef{}, err
+		}
+		kind := KindArray
+		if t.Kind() == reflect.Map {
+			kind = KindMap
+		}
+		return TypeRef{Kind: kind, Elem: &elem}, nil
+	}
+
+	if t.Name() != "" && strings.HasPrefix(t.PkgPath(), "github.com/amalg/go-bomberman/") {
+		return TypeRef{}, fmt.Errorf("%s has no entry in network.Enums", t)
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return TypeRef{Kind: KindString}, nil
+	case reflect.Bool:
+		return TypeRef{Kind: KindBoolean}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeRef{Kind: KindInteger}, nil
+	case reflect.Float32, reflect.Float64:
+		return TypeRef{Kind: KindNumber}, nil
+	}
+	return TypeRef{}, fmt.Errorf("%s: unsupported kind %s", t, t.Kind())
+}
+
+// named registers the named type t, filling it in with fill the first time.
+func (b *builder) named(t reflect.Type, fill func(*Type) error) (TypeRef, error) {
+	ref := TypeRef{Kind: KindRef, Name: t.Name()}
+	if ref.Name == "" {
+		return TypeRef{}, fmt.Errorf("anonymous %s: give it a name", t)
+	}
+	if prev, ok := b.goTyp[ref.Name]; ok {
+		if prev != t {
+			return TypeRef{}, fmt.Errorf("%s and %s share a name", prev, t)
+		}
+		return ref, nil
+	}
+	def := &Type{Name: ref.Name}
+	b.goTyp[ref.Name] = t
+	b.types[ref.Name] = def // Before filling, for recursive types
+	if err := fill(def); err != nil {
+		return TypeRef{}, err
+	}
+	return ref, nil
+}
+
+// fields lists the JSON properties of struct t, flattening embedded structs
+// the way encoding/json does.
+func (b *builder) fields(t reflect.Type) ([]Field, error) {
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			embedded, err := b.fields(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, embedded...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		ref, err := b.ref(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+		}
+		fields = append(fields, Field{
+			Name:     name,
+			Type:     ref,
+			Optional: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+	return fields, nil
+}
+
+// literal renders an enum value as a JSON string or integer literal, which
+// Python and TypeScript both accept.
+func literal(v any) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.String {
+		return strconv.Quote(rv.String())
+	}
+	return fmt.Sprint(rv.Convert(reflect.TypeOf(int64(0))).Int())
+}
+
+// pascal converts a snake_case name to PascalCase.
+func pascal(s string) string {
+	parts := strings.Split(s, "_")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// Generate renders every output file, keyed by its path under protocol/.
+func Generate() (map[string][]byte, error) {
+	m, err := Build()
+	if err != nil {
+		return nil, err
+	}
+	schema, err := JSONSchema(m)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		"schema.json":                  schema,
+		"python/bomberman_protocol.py": Python(m),
+		"typescript/protocol.ts":       TypeScript(m),
+	}, nil
+}
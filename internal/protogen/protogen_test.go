@@ -0,0 +1,82 @@
+package protogen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amalg/go-bomberman/internal/network"
+)
+
+// TestGeneratedUpToDate fails when protocol/ is stale; fix it with
+// `go generate ./internal/network`.
+func TestGeneratedUpToDate(t *testing.T) {
+	files, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join("..", "..", "protocol", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("protocol/%s is out of date: run go generate ./internal/network", name)
+		}
+	}
+}
+
+// TestEveryMessageListed checks each MsgType constant in protocol.go has an
+// entry in network.Messages and network.Enums.
+func TestEveryMessageListed(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), filepath.Join("..", "network", "protocol.go"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := make(map[string]bool)
+	for _, spec := range network.Messages {
+		listed[string(spec.Type)] = true
+	}
+	m, err := Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enumNames := make(map[string]bool)
+	for _, typ := range m.Types {
+		if typ.Name == "MsgType" {
+			for _, v := range typ.Enum {
+				enumNames[string(v.Value.(network.MsgType))] = true
+			}
+		}
+	}
+
+	found := 0
+	ast.Inspect(f, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		if typ, ok := spec.Type.(*ast.Ident); !ok || typ.Name != "MsgType" {
+			return true
+		}
+		for _, v := range spec.Values {
+			value := v.(*ast.BasicLit).Value
+			value = value[1 : len(value)-1]
+			found++
+			if !listed[value] {
+				t.Errorf("message %q is missing from network.Messages", value)
+			}
+			if !enumNames[value] {
+				t.Errorf("message %q is missing from the MsgType entry of network.Enums", value)
+			}
+		}
+		return true
+	})
+	if found == 0 {
+		t.Fatal("no MsgType constants found in protocol.go")
+	}
+}
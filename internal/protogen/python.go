@@ -0,0 +1,123 @@
+package protogen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/amalg/go-bomberman/internal/network"
+)
+
+// Python renders m as a Python 3.11 module of enums, TypedDicts and framing
+// helpers.
+func Python(m *Model) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, `# Code generated by protogen from internal/network. DO NOT EDIT.
+"""Typed bindings for the bomberman wire protocol, version %d.
+
+Payloads are plain dicts typed with TypedDict; use encode() to frame a message
+for sending and decode() to read one from a socket file (sock.makefile("rb")).
+"""
+
+from __future__ import annotations
+
+import json
+import struct
+from enum import IntEnum, StrEnum
+from typing import Any, BinaryIO, NotRequired, TypedDict
+
+PROTOCOL_VERSION = %d
+MAX_MESSAGE_SIZE = 1 << 20
+`, m.Version, m.Version)
+
+	for _, t := range m.Types {
+		b.WriteString("\n\n")
+		if t.IsEnum() {
+			base := "IntEnum"
+			if t.Base == KindString {
+				base = "StrEnum"
+			}
+			fmt.Fprintf(&b, "class %s(%s):\n", t.Name, base)
+			for _, v := range t.Enum {
+				fmt.Fprintf(&b, "    %s = %s\n", strings.ToUpper(v.Name), literal(v.Value))
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "class %s(TypedDict):\n", t.Name)
+		if len(t.Fields) == 0 {
+			b.WriteString("    pass\n")
+		}
+		for _, f := range t.Fields {
+			typ := pyType(f.Type)
+			if f.Optional {
+				typ = "NotRequired[" + typ + "]"
+			}
+			fmt.Fprintf(&b, "    %s: %s\n", f.Name, typ)
+		}
+	}
+
+	b.WriteString("\n\nclass Envelope(TypedDict):\n    type: MsgType\n    payload: Any\n")
+	for _, dir := range []string{network.ToServer, network.ToClient} {
+		fmt.Fprintf(&b, "\n\n# Payload type of each message sent by the %s; None is an empty object.\n", dir)
+		fmt.Fprintf(&b, "%s_MESSAGES: dict[MsgType, type | None] = {\n", strings.ToUpper(dir))
+		for _, msg := range m.Messages {
+			if msg.Dir != dir && msg.Dir != network.Both {
+				continue
+			}
+			payload := msg.Payload
+			if payload == "" {
+				payload = "None"
+			}
+			fmt.Fprintf(&b, "    MsgType.%s: %s,  # %s\n", strings.ToUpper(msg.Type), payload, msg.Doc)
+		}
+		b.WriteString("}\n")
+	}
+
+	b.WriteString(`
+
+def encode(msg_type: MsgType, payload: Any = None) -> bytes:
+    """Frames a message: a 4-byte big-endian length, then the JSON envelope."""
+    body = json.dumps({"type": msg_type, "payload": {} if payload is None else payload}).encode()
+    return struct.pack(">I", len(body)) + body
+
+
+def decode(stream: BinaryIO) -> Envelope:
+    """Reads one framed message from stream; raises EOFError when it closes."""
+    (length,) = struct.unpack(">I", _read_exactly(stream, 4))
+    if length > MAX_MESSAGE_SIZE:
+        raise ValueError(f"message too large: {length} bytes")
+    env = json.loads(_read_exactly(stream, length))
+    env["type"] = MsgType(env["type"])
+    return env
+
+
+def _read_exactly(stream: BinaryIO, n: int) -> bytes:
+    data = stream.read(n)
+    if data is None or len(data) < n:
+        raise EOFError("connection closed")
+    return data
+`)
+	return []byte(b.String())
+}
+
+func pyType(r TypeRef) string {
+	var t string
+	switch r.Kind {
+	case KindString, KindTime:
+		t = "str"
+	case KindInteger, KindDuration:
+		t = "int"
+	case KindNumber:
+		t = "float"
+	case KindBoolean:
+		t = "bool"
+	case KindArray:
+		t = "list[" + pyType(*r.Elem) + "]"
+	case KindMap:
+		t = "dict[str, " + pyType(*r.Elem) + "]"
+	case KindRef:
+		t = r.Name
+	default:
+		t = "Any"
+	}
+	return t
+}
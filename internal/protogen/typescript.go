@@ -0,0 +1,122 @@
+package protogen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/amalg/go-bomberman/internal/network"
+)
+
+// TypeScript renders m as a TypeScript module of enums, interfaces, message
+// unions and framing helpers.
+func TypeScript(m *Model) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, `// Code generated by protogen from internal/network. DO NOT EDIT.
+//
+// Typed bindings for the bomberman wire protocol, version %d. Send messages
+// with encode() and feed received bytes to a Decoder.
+
+export const PROTOCOL_VERSION = %d;
+export const MAX_MESSAGE_SIZE = 1 << 20;
+
+/** Payload of messages that carry no data. */
+export type Empty = Record<string, never>;
+`, m.Version, m.Version)
+
+	for _, t := range m.Types {
+		b.WriteString("\n")
+		if t.IsEnum() {
+			fmt.Fprintf(&b, "export enum %s {\n", t.Name)
+			for _, v := range t.Enum {
+				fmt.Fprintf(&b, "  %s = %s,\n", pascal(v.Name), literal(v.Value))
+			}
+			b.WriteString("}\n")
+			continue
+		}
+		fmt.Fprintf(&b, "export interface %s {\n", t.Name)
+		for _, f := range t.Fields {
+			opt := ""
+			if f.Optional {
+				opt = "?"
+			}
+			fmt.Fprintf(&b, "  %s%s: %s;\n", f.Name, opt, tsType(f.Type))
+		}
+		b.WriteString("}\n")
+	}
+
+	for _, dir := range []string{network.ToServer, network.ToClient} {
+		union := map[string]string{network.ToServer: "ClientMessage", network.ToClient: "ServerMessage"}[dir]
+		fmt.Fprintf(&b, "\n/** Messages sent by the %s. */\nexport type %s =\n", dir, union)
+		for _, msg := range m.Messages {
+			if msg.Dir != dir && msg.Dir != network.Both {
+				continue
+			}
+			payload := msg.Payload
+			if payload == "" {
+				payload = "Empty"
+			}
+			fmt.Fprintf(&b, "  | { type: MsgType.%s; payload: %s } // %s\n", pascal(msg.Type), payload, msg.Doc)
+		}
+		b.WriteString(";\n")
+	}
+
+	b.WriteString(`
+/** Frames a message: a 4-byte big-endian length, then the JSON envelope. */
+export function encode(msg: ClientMessage): Uint8Array {
+  const body = new TextEncoder().encode(JSON.stringify(msg));
+  const out = new Uint8Array(4 + body.length);
+  new DataView(out.buffer).setUint32(0, body.length);
+  out.set(body, 4);
+  return out;
+}
+
+/** Reassembles framed messages from a byte stream. */
+export class Decoder {
+  private buf = new Uint8Array(0);
+
+  /** Adds received bytes and returns the messages they complete. */
+  push(chunk: Uint8Array): ServerMessage[] {
+    const joined = new Uint8Array(this.buf.length + chunk.length);
+    joined.set(this.buf);
+    joined.set(chunk, this.buf.length);
+    this.buf = joined;
+
+    const out: ServerMessage[] = [];
+    while (this.buf.length >= 4) {
+      const length = new DataView(this.buf.buffer, this.buf.byteOffset).getUint32(0);
+      if (length > MAX_MESSAGE_SIZE) {
+        throw new Error("message too large: " + length + " bytes");
+      }
+      if (this.buf.length < 4 + length) {
+        break;
+      }
+      out.push(JSON.parse(new TextDecoder().decode(this.buf.subarray(4, 4 + length))));
+      this.buf = this.buf.subarray(4 + length);
+    }
+    return out;
+  }
+}
+`)
+	return []byte(b.String())
+}
+
+func tsType(r TypeRef) string {
+	var t string
+	switch r.Kind {
+	case KindString, KindTime:
+		t = "string"
+	case KindInteger, KindNumber, KindDuration:
+		t = "number"
+	case KindBoolean:
+		t = "boolean"
+	case KindArray:
+		t = tsType(*r.Elem) + "[]"
+	case KindMap:
+		t = "Record<string, " + tsType(*r.Elem) + ">"
+	case KindRef:
+		t = r.Name
+	default:
+		t = "unknown"
+	}
+	return t
+}
@@ -0,0 +1,331 @@
+// Package script runs a host's Lua script as custom game rules, so new game
+// modes can be tried without recompiling.
+//
+// A script defines any of these global callbacks, each called while a match
+// is running:
+//
+//	function onTick(state) end         -- every tick, after the built-in rules
+//	function onWallDestroyed(ev) end   -- ev.x, ev.y, ev.by
+//	function onPlayerDeath(ev) end     -- ev.player, ev.by, ev.x, ev.y
+//
+// and changes the game through the game table: game.kill(id), game.win(id),
+// game.draw(), game.tile(x, y), game.set_tile(x, y, tile), game.drop(x, y,
+// pickup) and game.state(). Tiles and pickups are named as on the wire, such
+// as "soft_wall" and "full_fire".
+//
+// Scripts are sandboxed: they only get Lua's base, string, table and math
+// libraries, without any way to reach files, the network or other programs,
+// and every callback is cut off after CallTimeout. math.random draws from the
+// game's randomness, so a seed replays scripted games too, and so only works
+// in callbacks; there is no math.randomseed. A script that fails is
+// logged and switched off; the match carries on without it.
+package script
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+const (
+	// CallTimeout is how long a callback may run before it is stopped.
+	CallTimeout = 10 * time.Millisecond
+
+	// LoadTimeout is how long the script's top level may run when loaded.
+	LoadTimeout = time.Second
+)
+
+// callbacks maps events to the global functions that handle them.
+var callbacks = map[game.EventType]string{
+	game.EventWallDestroyed: "onWallDestroyed",
+	game.EventPlayerKilled:  "onPlayerDeath",
+}
+
+// tileNames are the tiles scripts can name, the same as on the wire.
+var tileNames = map[string]game.TileType{
+	"empty":            game.Empty,
+	"hard_wall":        game.HardWall,
+	"soft_wall":        game.SoftWall,
+	"barrel":           game.Barrel,
+	"exit_door":        game.ExitDoor,
+	"ice":              game.Ice,
+	"cracked_wall":     game.CrackedWall,
+	"cracked_wall_hit": game.CrackedWallHit,
+	"trap":             game.Trap,
+}
+
+// Script is a loaded script. It implements game.Hooks.
+type Script struct {
+	path  string
+	L     *lua.LState
+	rules game.Rules // The engine's, while a callback runs
+	err   error      // Why the script was switched off; nil while it runs
+}
+
+var _ game.Hooks = (*Script)(nil)
+
+// Load reads and runs the script at path, ready to install with
+// Engine.SetHooks.
+func Load(path string) (*Script, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read script: %w", err)
+	}
+
+	s := &Script{path: path, L: newSandbox()}
+	s.L.SetGlobal("game", s.L.SetFuncs(s.L.NewTable(), map[string]lua.LGFunction{
+		"kill":     s.kill,
+		"win":      s.win,
+		"draw":     s.draw,
+		"tile":     s.tile,
+		"set_tile": s.setTile,
+		"drop":     s.drop,
+		"state":    s.state,
+	}))
+	math := s.L.GetGlobal(lua.MathLibName).(*lua.LTable)
+	math.RawSetString("random", s.L.NewFunction(s.random))
+	math.RawSetString("randomseed", lua.LNil)
+
+	fn, err := s.L.Load(strings.NewReader(string(src)), filepath.Base(path))
+	if err != nil {
+		s.L.Close()
+		return nil, fmt.Errorf("load script: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), LoadTimeout)
+	defer cancel()
+	s.L.SetContext(ctx)
+	defer s.L.RemoveContext()
+	if err := s.L.CallByParam(lua.P{Fn: fn, Protect: true}); err != nil {
+		s.L.Close()
+		return nil, fmt.Errorf("run script: %w", err)
+	}
+	return s, nil
+}
+
+// newSandbox returns a Lua state with only the libraries that can't reach
+// outside the game.
+func newSandbox() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true, CallStackSize: 200, RegistryMaxSize: 1 << 16})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "require", "module"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	L.SetGlobal("print", L.NewFunction(func(L *lua.LState) int {
+		parts := make([]string, L.GetTop())
+		for i := range parts {
+			parts[i] = L.ToStringMeta(L.Get(i + 1)).String()
+		}
+		slog.Info("Script printed", "text", strings.Join(parts, "\t"))
+		return 0
+	}))
+	return L
+}
+
+// Close frees the script's Lua state.
+func (s *Script) Close() {
+	s.L.Close()
+}
+
+// Err returns why the script was switched off, or nil while it runs.
+func (s *Script) Err() error {
+	return s.err
+}
+
+// Event calls the callback for ev, if the script defines one.
+func (s *Script) Event(r game.Rules, ev game.Event) {
+	name, ok := callbacks[ev.Type]
+	if !ok {
+		return
+	}
+	t := s.L.NewTable()
+	t.RawSetString("tick", lua.LNumber(ev.Tick))
+	t.RawSetString("x", lua.LNumber(ev.Pos.X))
+	t.RawSetString("y", lua.LNumber(ev.Pos.Y))
+	if ev.PlayerID != "" {
+		t.RawSetString("player", lua.LString(ev.PlayerID))
+	}
+	if ev.ByID != "" {
+		t.RawSetString("by", lua.LString(ev.ByID))
+	}
+	s.call(r, name, t)
+}
+
+// Tick calls onTick, if the script defines it.
+func (s *Script) Tick(r game.Rules) {
+	if s.err != nil || s.L.GetGlobal("onTick") == lua.LNil {
+		return
+	}
+	s.call(r, "onTick", stateTable(s.L, r.State()))
+}
+
+// call calls the global function name with arg, switching the script off if
+// it fails.
+func (s *Script) call(r game.Rules, name string, arg lua.LValue) {
+	if s.err != nil {
+		return
+	}
+	fn := s.L.GetGlobal(name)
+	if fn == lua.LNil {
+		return
+	}
+
+	s.rules = r
+	ctx, cancel := context.WithTimeout(context.Background(), CallTimeout)
+	defer cancel()
+	s.L.SetContext(ctx)
+	err := s.L.CallByParam(lua.P{Fn: fn, Protect: true}, arg)
+	s.L.RemoveContext()
+	s.rules = game.Rules{}
+	if err != nil {
+		s.err = fmt.Errorf("%s: %w", name, err)
+		slog.Error("Script switched off", "script", s.path, "err", s.err)
+	}
+}
+
+// stateTable returns the parts of state scripts can see, as a Lua table.
+func stateTable(L *lua.LState, state *game.GameState) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString("tick", lua.LNumber(state.Tick))
+	t.RawSetString("round", lua.LNumber(state.Round))
+	t.RawSetString("width", lua.LNumber(state.Width))
+	t.RawSetString("height", lua.LNumber(state.Height))
+	players := L.NewTable()
+	for id, p := range state.Players {
+		pt := L.NewTable()
+		pt.RawSetString("name", lua.LString(p.Name))
+		pt.RawSetString("x", lua.LNumber(p.Pos.X))
+		pt.RawSetString("y", lua.LNumber(p.Pos.Y))
+		pt.RawSetString("alive", lua.LBool(p.Alive))
+		pt.RawSetString("kills", lua.LNumber(p.Kills))
+		pt.RawSetString("lives", lua.LNumber(p.Lives))
+		pt.RawSetString("hp", lua.LNumber(p.HP))
+		pt.RawSetString("team", lua.LNumber(p.TeamID))
+		players.RawSetString(id, pt)
+	}
+	t.RawSetString("players", players)
+	return t
+}
+
+// running raises a Lua error unless a callback is running, as the game can
+// only be changed from one.
+func (s *Script) running() {
+	if s.rules == (game.Rules{}) {
+		s.L.RaiseError("the game can only be changed from a callback")
+	}
+}
+
+// check raises err as a Lua error.
+func (s *Script) check(err error) {
+	if err != nil {
+		s.L.RaiseError("%s", err.Error())
+	}
+}
+
+func (s *Script) kill(L *lua.LState) int {
+	s.running()
+	s.check(s.rules.Kill(L.CheckString(1)))
+	return 0
+}
+
+func (s *Script) win(L *lua.LState) int {
+	s.running()
+	s.check(s.rules.Win(L.CheckString(1)))
+	return 0
+}
+
+func (s *Script) draw(L *lua.LState) int {
+	s.running()
+	s.rules.Draw()
+	return 0
+}
+
+func (s *Script) tile(L *lua.LState) int {
+	s.running()
+	x, y := L.CheckInt(1), L.CheckInt(2)
+	state := s.rules.State()
+	if x < 0 || y < 0 || x >= state.Width || y >= state.Height {
+		L.ArgError(1, "off the board")
+	}
+	for name, tile := range tileNames {
+		if tile == state.Board[y][x] {
+			L.Push(lua.LString(name))
+			return 1
+		}
+	}
+	L.Push(lua.LNil)
+	return 1
+}
+
+func (s *Script) setTile(L *lua.LState) int {
+	s.running()
+	x, y, name := L.CheckInt(1), L.CheckInt(2), L.CheckString(3)
+	tile, ok := tileNames[name]
+	if !ok {
+		L.ArgError(3, fmt.Sprintf("unknown tile %q", name))
+	}
+	s.check(s.rules.SetTile(game.Position{X: x, Y: y}, tile))
+	return 0
+}
+
+func (s *Script) drop(L *lua.LState) int {
+	s.running()
+	x, y, name := L.CheckInt(1), L.CheckInt(2), L.CheckString(3)
+	typ, ok := game.PickupNames[name]
+	if !ok {
+		L.ArgError(3, fmt.Sprintf("unknown pickup %q", name))
+	}
+	s.check(s.rules.DropPickup(game.Position{X: x, Y: y}, typ))
+	return 0
+}
+
+// random is math.random, drawing from the game's randomness: a number in
+// [0, 1), or with m an integer in [1, m], or with m and n one in [m, n].
+func (s *Script) random(L *lua.LState) int {
+	if s.rules == (game.Rules{}) {
+		L.RaiseError("math.random draws from the game's randomness, so only works in a callback")
+	}
+	rng := s.rules.Rand()
+	switch L.GetTop() {
+	case 0:
+		L.Push(lua.LNumber(rng.Float64()))
+	case 1:
+		m := L.CheckInt(1)
+		if m < 1 {
+			L.ArgError(1, "interval is empty")
+		}
+		L.Push(lua.LNumber(1 + rng.Intn(m)))
+	default:
+		m, n := L.CheckInt(1), L.CheckInt(2)
+		if m > n {
+			L.ArgError(2, "interval is empty")
+		}
+		L.Push(lua.LNumber(m + rng.Intn(n-m+1)))
+	}
+	return 1
+}
+
+func (s *Script) state(L *lua.LState) int {
+	s.running()
+	L.Push(stateTable(L, s.rules.State()))
+	return 1
+}
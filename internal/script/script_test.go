@@ -0,0 +1,156 @@
+package script
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// load writes src to a script file and loads it.
+func load(t *testing.T, src string) (*Script, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.lua")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+// newEngine returns a running three-player game with s installed.
+func newEngine(t *testing.T, s *Script) *game.Engine {
+	t.Helper()
+	config := game.DefaultConfig()
+	config.Seed = 1
+	config.EnemyCount = 0
+	engine := game.NewEngine(config)
+	engine.SetHooks(s)
+	for _, id := range []string{"p1", "p2", "p3"} {
+		if err := engine.AddPlayer(id, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := engine.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestScriptRules(t *testing.T) {
+	s, err := load(t, `
+		function onTick(state)
+			if state.tick == 3 then
+				game.set_tile(3, 1, "barrel")
+				game.kill("p3")
+			end
+		end
+
+		function onPlayerDeath(ev)
+			game.drop(ev.x, ev.y, "kick")
+			game.win("p1")
+		end
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	engine := newEngine(t, s)
+	spawn := engine.State.Players["p3"].Pos
+
+	engine.Step(3)
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	state := engine.GetStateCopy()
+	if state.Board[1][3] != game.Barrel {
+		t.Error("expected the script to place a barrel")
+	}
+	if state.Players["p3"].Alive {
+		t.Error("expected the script to kill p3")
+	}
+	if len(state.Pickups) != 1 || state.Pickups[0].Pos != spawn || state.Pickups[0].Type != game.PickupKick {
+		t.Errorf("expected a kick where p3 died, got %+v", state.Pickups)
+	}
+	if state.Status != game.StatusOver || state.Winner != "p1" {
+		t.Errorf("expected the script to make p1 win, got %v won by %q", state.Status, state.Winner)
+	}
+}
+
+func TestScriptSwitchedOffWhenStuck(t *testing.T) {
+	s, err := load(t, `function onTick() while true do end end`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	engine := newEngine(t, s)
+
+	engine.Step(2)
+	if s.Err() == nil {
+		t.Fatal("expected a script stuck in a loop to be switched off")
+	}
+	if engine.State.Tick != 2 {
+		t.Errorf("expected the game to carry on without the script, at tick %d", engine.State.Tick)
+	}
+}
+
+func TestScriptSandbox(t *testing.T) {
+	s, err := load(t, `assert(os == nil and io == nil and require == nil and dofile == nil and load == nil)`)
+	if err != nil {
+		t.Fatalf("expected no way out of the sandbox: %v", err)
+	}
+	s.Close()
+
+	if _, err := load(t, `game.kill("p1")`); err == nil || !strings.Contains(err.Error(), "callback") {
+		t.Errorf("expected changing the game outside a callback to fail, got %v", err)
+	}
+}
+
+func TestScriptRandomFollowsSeed(t *testing.T) {
+	src := `
+		rolls = {}
+		function onTick(state)
+			if state.tick <= 5 then
+				table.insert(rolls, math.random(1, 100))
+				game.set_tile(math.random(7) * 2 - 1, 1, "barrel")
+			end
+		end
+	`
+	// The same seed rolls the same, and its board follows
+	var got []string
+	for range 2 {
+		s, err := load(t, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		engine := newEngine(t, s)
+		engine.Step(5)
+		if err := s.Err(); err != nil {
+			t.Fatal(err)
+		}
+		rolls := s.L.GetGlobal("rolls").(*lua.LTable)
+		var b strings.Builder
+		for i := 1; i <= rolls.Len(); i++ {
+			b.WriteString(rolls.RawGetInt(i).String() + " ")
+		}
+		for _, row := range engine.GetStateCopy().Board {
+			fmt.Fprint(&b, row)
+		}
+		got = append(got, b.String())
+		s.Close()
+	}
+	if got[0] != got[1] {
+		t.Errorf("expected a seed to replay a script's rolls:\n%s\n%s", got[0], got[1])
+	}
+
+	if _, err := load(t, `assert(math.randomseed == nil)`); err != nil {
+		t.Errorf("expected no math.randomseed: %v", err)
+	}
+	if _, err := load(t, `math.random()`); err == nil || !strings.Contains(err.Error(), "callback") {
+		t.Errorf("expected math.random outside a callback to fail, got %v", err)
+	}
+}
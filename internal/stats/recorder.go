@@ -0,0 +1,87 @@
+package stats
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// bombKey identifies one bomb across state snapshots.
+type bombKey struct {
+	owner    string
+	pos      game.Position
+	placedAt time.Time
+}
+
+// Recorder derives statistics from successive game states on the host and
+// writes them to a Store. Feed it every state the engine produces.
+type Recorder struct {
+	store *Store
+	alive map[string]bool
+	bombs map[bombKey]bool
+	prev  game.GameStatus
+}
+
+// NewRecorder creates a recorder writing to store.
+func NewRecorder(store *Store) *Recorder {
+	return &Recorder{
+		store: store,
+		alive: make(map[string]bool),
+		bombs: make(map[bombKey]bool),
+	}
+}
+
+// Observe compares state with the previous one and records new deaths and
+// bomb placements. The store is saved whenever a game or round finishes.
+func (r *Recorder) Observe(state game.GameState) {
+	if state.Status == game.StatusPaused {
+		return // Nothing changes until the match resumes
+	}
+	defer func() { r.prev = state.Status }()
+
+	if state.Status != game.StatusRunning {
+		ended := state.Status == game.StatusOver || state.Status == game.StatusIntermission
+		if ended && r.prev == game.StatusRunning {
+			r.recordDeaths(state)
+			r.store.RecordGame(state.Width, state.Height)
+			if err := r.store.Save(); err != nil {
+				slog.Error("Failed to save stats", "err", err)
+			}
+		}
+		r.reset()
+		return
+	}
+
+	if r.prev != game.StatusRunning {
+		// New game: everyone starts alive
+		for id := range state.Players {
+			r.alive[id] = true
+		}
+	}
+	r.recordDeaths(state)
+
+	current := make(map[bombKey]bool, len(state.Bombs))
+	for _, b := range state.Bombs {
+		k := bombKey{owner: b.OwnerID, pos: b.Pos, placedAt: b.PlacedAt}
+		current[k] = true
+		if !r.bombs[k] {
+			r.store.RecordBomb(state.Width, state.Height, b.Pos)
+		}
+	}
+	r.bombs = current
+}
+
+func (r *Recorder) recordDeaths(state game.GameState) {
+	for id, p := range state.Players {
+		if r.alive[id] && !p.Alive {
+			r.store.RecordDeath(state.Width, state.Height, p.Pos)
+		}
+		r.alive[id] = p.Alive
+	}
+}
+
+func (r *Recorder) reset() {
+	r.alive = make(map[string]bool)
+	r.bombs = make(map[bombKey]bool)
+}
@@ -0,0 +1,87 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SeasonsDir returns the directory seasons archived from the stats file at
+// path are kept in.
+func SeasonsDir(path string) string {
+	return filepath.Join(filepath.Dir(path), "seasons")
+}
+
+// SeasonPath returns the file of the season called name archived from the
+// stats file at path.
+func SeasonPath(path, name string) string {
+	return filepath.Join(SeasonsDir(path), name+".json")
+}
+
+// ArchiveSeason moves the stats file at path into the seasons directory
+// under name, so recording starts fresh, and returns where it went. Hosts
+// only load stats when a room opens, so archive while not hosting.
+func ArchiveSeason(path, name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid season name %q", name)
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("no statistics recorded since the last season")
+	}
+	dest := SeasonPath(path, name)
+	if _, err := os.Stat(dest); err == nil {
+		return "", fmt.Errorf("season %q already exists", name)
+	}
+	if err := os.MkdirAll(SeasonsDir(path), 0o755); err != nil {
+		return "", fmt.Errorf("create seasons dir: %w", err)
+	}
+	if err := os.Rename(path, dest); err != nil {
+		return "", fmt.Errorf("archive season: %w", err)
+	}
+	return dest, nil
+}
+
+// Seasons lists the seasons archived from the stats file at path, most
+// recent first.
+func Seasons(path string) ([]string, error) {
+	entries, err := os.ReadDir(SeasonsDir(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list seasons: %w", err)
+	}
+
+	type season struct {
+		name string
+		mod  int64
+	}
+	var seasons []season
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		seasons = append(seasons, season{name, info.ModTime().UnixNano()})
+	}
+	// Archiving keeps the file's time of last write, the season's last game
+	sort.Slice(seasons, func(i, j int) bool {
+		if seasons[i].mod != seasons[j].mod {
+			return seasons[i].mod > seasons[j].mod
+		}
+		return seasons[i].name < seasons[j].name
+	})
+
+	names := make([]string, len(seasons))
+	for i, s := range seasons {
+		names[i] = s.name
+	}
+	return names, nil
+}
@@ -0,0 +1,188 @@
+// Package stats persists gameplay statistics recorded by hosts across sessions.
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// Heatmap counts events per board tile.
+type Heatmap struct {
+	Width  int   `json:"width"`
+	Height int   `json:"height"`
+	Cells  []int `json:"cells"` // Row-major, Width*Height entries
+}
+
+// NewHeatmap creates an empty heatmap for a board of the given size.
+func NewHeatmap(width, height int) *Heatmap {
+	return &Heatmap{Width: width, Height: height, Cells: make([]int, width*height)}
+}
+
+// Add records one event at pos. Out-of-bounds positions are ignored.
+func (h *Heatmap) Add(pos game.Position) {
+	if pos.X < 0 || pos.X >= h.Width || pos.Y < 0 || pos.Y >= h.Height {
+		return
+	}
+	h.Cells[pos.Y*h.Width+pos.X]++
+}
+
+// At returns the event count at (x, y).
+func (h *Heatmap) At(x, y int) int {
+	return h.Cells[y*h.Width+x]
+}
+
+// Max returns the highest count on the heatmap.
+func (h *Heatmap) Max() int {
+	max := 0
+	for _, c := range h.Cells {
+		if c > max {
+			max = c
+		}
+	}
+	return max
+}
+
+// MapStats aggregates events for one map.
+type MapStats struct {
+	Games  int      `json:"games"`
+	Deaths *Heatmap `json:"deaths"`
+	Bombs  *Heatmap `json:"bombs"`
+}
+
+// MapKey identifies a map for statistics. Boards are generated from the same
+// layout rules, so the dimensions identify the map.
+func MapKey(width, height int) string {
+	return fmt.Sprintf("classic-%dx%d", width, height)
+}
+
+// storeData is the on-disk format.
+type storeData struct {
+	Maps map[string]*MapStats `json:"maps"`
+}
+
+// Store is a JSON file of statistics. It is safe for concurrent use.
+type Store struct {
+	path string
+	mu   sync.Mutex
+	data storeData
+}
+
+// DefaultPath returns the stats file location in the user's config directory.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "bomberman-stats.json"
+	}
+	return filepath.Join(dir, "bomberman", "stats.json")
+}
+
+// Open loads the store at path, starting empty if the file doesn't exist.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, data: storeData{Maps: make(map[string]*MapStats)}}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read stats: %w", err)
+	}
+	if err := json.Unmarshal(raw, &s.data); err != nil {
+		return nil, fmt.Errorf("parse stats %s: %w", path, err)
+	}
+	if s.data.Maps == nil {
+		s.data.Maps = make(map[string]*MapStats)
+	}
+	return s, nil
+}
+
+// Save writes the store to disk, creating the parent directory if needed.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	raw, err := json.MarshalIndent(s.data, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encode stats: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("create stats dir: %w", err)
+	}
+	// Write to a temp file and rename so a crash can't truncate the stats
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return fmt.Errorf("write stats: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// mapStatsLocked returns the stats for a map, creating them if needed.
+// MUST be called while s.mu is held.
+func (s *Store) mapStatsLocked(width, height int) *MapStats {
+	key := MapKey(width, height)
+	ms, ok := s.data.Maps[key]
+	if !ok {
+		ms = &MapStats{
+			Deaths: NewHeatmap(width, height),
+			Bombs:  NewHeatmap(width, height),
+		}
+		s.data.Maps[key] = ms
+	}
+	return ms
+}
+
+// RecordDeath adds a player death at pos on a width×height map.
+func (s *Store) RecordDeath(width, height int, pos game.Position) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mapStatsLocked(width, height).Deaths.Add(pos)
+}
+
+// RecordBomb adds a bomb placement at pos on a width×height map.
+func (s *Store) RecordBomb(width, height int, pos game.Position) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mapStatsLocked(width, height).Bombs.Add(pos)
+}
+
+// RecordGame counts a finished game on a width×height map.
+func (s *Store) RecordGame(width, height int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mapStatsLocked(width, height).Games++
+}
+
+// MapKeys returns the keys of all maps with statistics, sorted.
+func (s *Store) MapKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.data.Maps))
+	for k := range s.data.Maps {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Map returns a copy of the statistics for a map key.
+func (s *Store) Map(key string) (MapStats, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms, ok := s.data.Maps[key]
+	if !ok {
+		return MapStats{}, false
+	}
+	deaths, bombs := *ms.Deaths, *ms.Bombs
+	deaths.Cells = append([]int(nil), ms.Deaths.Cells...)
+	bombs.Cells = append([]int(nil), ms.Bombs.Cells...)
+	return MapStats{Games: ms.Games, Deaths: &deaths, Bombs: &bombs}, true
+}
@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+func TestRecorderDeathsAndBombs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	rec := NewRecorder(store)
+
+	placed := time.Now()
+	state := game.GameState{
+		Width: 15, Height: 13, Status: game.StatusRunning,
+		Players: map[string]*game.Player{
+			"p1": {ID: "p1", Alive: true, Pos: game.Position{X: 1, Y: 1}},
+			"p2": {ID: "p2", Alive: true, Pos: game.Position{X: 3, Y: 1}},
+		},
+		Bombs: []*game.Bomb{{OwnerID: "p1", Pos: game.Position{X: 1, Y: 1}, PlacedAt: placed}},
+	}
+	rec.Observe(state)
+	rec.Observe(state) // Same bomb seen again must not be double counted
+
+	state.Players["p2"].Alive = false
+	state.Status = game.StatusOver
+	rec.Observe(state)
+
+	// Game over saves the store; reload it from disk
+	reloaded, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	ms, ok := reloaded.Map(MapKey(15, 13))
+	if !ok {
+		t.Fatal("expected stats for the map to be saved")
+	}
+	if ms.Games != 1 {
+		t.Errorf("expected 1 game, got %d", ms.Games)
+	}
+	if got := ms.Bombs.At(1, 1); got != 1 {
+		t.Errorf("expected 1 bomb at (1,1), got %d", got)
+	}
+	if got := ms.Deaths.At(3, 1); got != 1 {
+		t.Errorf("expected 1 death at (3,1), got %d", got)
+	}
+	if got := ms.Deaths.At(1, 1); got != 0 {
+		t.Errorf("survivor should not be recorded as a death, got %d", got)
+	}
+}
+
+func TestArchiveSeason(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	if _, err := ArchiveSeason(path, "spring"); err == nil {
+		t.Fatal("expected an error archiving with nothing recorded")
+	}
+
+	store, _ := Open(path)
+	store.RecordGame(15, 13)
+	store.Save()
+
+	if _, err := ArchiveSeason(path, "../escape"); err == nil {
+		t.Fatal("expected season names with path separators to be rejected")
+	}
+	if _, err := ArchiveSeason(path, "spring"); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+
+	// Recording starts fresh
+	fresh, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if len(fresh.MapKeys()) != 0 {
+		t.Error("expected the current season to start empty")
+	}
+	fresh.RecordGame(15, 13)
+	fresh.Save()
+	if _, err := ArchiveSeason(path, "spring"); err == nil {
+		t.Error("expected an error reusing a season name")
+	}
+
+	seasons, err := Seasons(path)
+	if err != nil || len(seasons) != 1 || seasons[0] != "spring" {
+		t.Fatalf("expected [spring], got %v (%v)", seasons, err)
+	}
+	old, err := Open(SeasonPath(path, "spring"))
+	if err != nil {
+		t.Fatalf("open season: %v", err)
+	}
+	if ms, ok := old.Map(MapKey(15, 13)); !ok || ms.Games != 1 {
+		t.Errorf("expected the archived season to keep its game, got %+v", ms)
+	}
+}
@@ -0,0 +1,465 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// cellKind is what a board cell displays, in draw priority order.
+type cellKind int
+
+const (
+	cellEmpty cellKind = iota
+	cellHardWall
+	cellSoftWall
+	cellBarrel
+	cellExit
+	cellIce
+	cellTrap
+	cellCrackedWall
+	cellCrackedWallHit
+	cellFog
+	cellHill
+	cellZoneEdge // Tile the battle royale zone burns next
+	cellGhost    // Where the ghost of a best run is
+	cellPickupBomb
+	cellPickupRange
+	cellPickupAmmo
+	cellPickupSpeed
+	cellPickupKick
+	cellPickupShield
+	cellPickupSkull
+	cellPickupFullFire
+	cellPickupLineBomb
+	cellPickupMine
+	cellPickupCloak
+	cellBomb
+	cellMine
+	cellFire
+	cellLava
+	cellWarning // Tile a boss attack is about to hit
+	cellEnemy
+	cellBoss
+	cellBossWeak
+	cellPlayer
+	cellSelf
+)
+
+// cellKey identifies a cell's appearance. Cells with equal keys render to
+// the same styled string, so glyphs can be cached by key.
+type cellKey struct {
+	kind  cellKind
+	color int    // Player color index for cellPlayer/cellSelf
+	team  int    // Team of a cellPlayer/cellSelf in team mode, which colors it instead
+	glyph string // Cosmetic glyph ID for cellPlayer/cellSelf
+
+	fuse fuseStage // Bomb's fuse, for cellBomb
+
+	shielded bool // Player is immune to fire
+	cursed   bool // Player carries a curse
+	faint    bool // Player is invisible to opponents
+	stunned  bool // Player is stunned in stun mode
+}
+
+// cachedRow remembers the keys a row was last rendered from.
+type cachedRow struct {
+	keys []cellKey
+	text string
+}
+
+// BoardRenderer renders the game board, caching styled glyphs per cell key
+// and reusing row strings for rows whose cells haven't changed since the
+// previous frame. Restyling every cell with lipgloss each frame is the main
+// rendering cost on large boards.
+type BoardRenderer struct {
+	glyphs map[cellKey]string
+	rows   []cachedRow
+	ghosts []game.Player // Players of a raced run, shown faintly
+}
+
+// NewBoardRenderer creates a renderer with empty caches.
+func NewBoardRenderer() *BoardRenderer {
+	return &BoardRenderer{glyphs: make(map[cellKey]string)}
+}
+
+// SetGhosts sets the players of a raced run to show under the live game, or
+// clears them with nil.
+func (r *BoardRenderer) SetGhosts(ghosts []game.Player) {
+	r.ghosts = ghosts
+}
+
+// RenderBoard renders the part of the board inside vp as seen by player myID.
+// A zero Viewport renders the whole board.
+func (r *BoardRenderer) RenderBoard(state *game.GameState, myID string, vp Viewport) string {
+	if state == nil || len(state.Board) == 0 {
+		return "Waiting for game state..."
+	}
+	if vp.Width == 0 || vp.Height == 0 {
+		vp = Viewport{Width: state.Width, Height: state.Height}
+	}
+
+	cells := indexCells(state)
+	for _, p := range r.ghosts {
+		cells.ghosts[p.Pos] = p.Color
+	}
+
+	if len(r.rows) != vp.Height {
+		r.rows = make([]cachedRow, vp.Height)
+	}
+
+	rows := make([]string, vp.Height)
+	keys := make([]cellKey, vp.Width)
+	for i := 0; i < vp.Height; i++ {
+		y := vp.Y + i
+		for j := 0; j < vp.Width; j++ {
+			x := vp.X + j
+			pos := game.Position{X: x, Y: y}
+			keys[j] = cells.classify(state.Board[y][x], pos, myID)
+		}
+
+		cached := &r.rows[i]
+		if !sameKeys(cached.keys, keys) {
+			var b strings.Builder
+			for _, k := range keys {
+				b.WriteString(r.glyph(k))
+			}
+			cached.keys = append(cached.keys[:0], keys...)
+			cached.text = b.String()
+		}
+		rows[i] = cached.text
+	}
+	return strings.Join(rows, "\n")
+}
+
+// cellIndex maps board positions to the entities on them.
+type cellIndex struct {
+	fires   map[game.Position]bool
+	lava    map[game.Position]bool
+	bombs   map[game.Position]time.Duration // Fuse left
+	mines   map[game.Position]bool
+	players map[game.Position]*game.Player // Alive players only
+	enemies map[game.Position]bool         // Alive enemies only
+	pickups map[game.Position]game.PickupType
+	warning map[game.Position]bool
+	ghosts  map[game.Position]int // Ghost players' colors
+	boss    *game.Boss            // Alive boss only
+	hill    *game.Hill
+	zone    *game.Zone
+}
+
+// indexCells builds the cell index for one state.
+func indexCells(state *game.GameState) cellIndex {
+	ix := cellIndex{
+		fires:   make(map[game.Position]bool),
+		lava:    make(map[game.Position]bool),
+		bombs:   make(map[game.Position]time.Duration),
+		mines:   make(map[game.Position]bool),
+		players: make(map[game.Position]*game.Player),
+		enemies: make(map[game.Position]bool),
+		pickups: make(map[game.Position]game.PickupType),
+		warning: make(map[game.Position]bool),
+		ghosts:  make(map[game.Position]int),
+		hill:    state.Hill,
+		zone:    state.Zone,
+	}
+	for _, f := range state.Fires {
+		ix.fires[f.Pos] = true
+		if f.Permanent {
+			ix.lava[f.Pos] = true
+		}
+	}
+	for _, b := range state.Bombs {
+		if b.Mine {
+			ix.mines[b.Pos] = true
+		} else {
+			ix.bombs[b.Pos] = b.FuseLeft
+		}
+	}
+	for _, p := range state.Players {
+		if p.Alive && !p.OutOfSight {
+			ix.players[p.Pos] = p
+		}
+	}
+	for _, en := range state.Enemies {
+		if en.Alive {
+			ix.enemies[en.Pos] = true
+		}
+	}
+	for _, pk := range state.Pickups {
+		ix.pickups[pk.Pos] = pk.Type
+	}
+	if b := state.Boss; b != nil && b.Alive {
+		ix.boss = b
+		for _, pos := range b.Warning {
+			ix.warning[pos] = true
+		}
+	}
+	return ix
+}
+
+// classify decides what a cell shows. Entities are layered over tiles:
+// players, then enemies, the boss, fire, attack warnings, bombs, pickups, the
+// ghost of a raced run, and finally the tile itself, with open tiles on the
+// hill or the edge of the battle royale zone highlighted.
+func (ix cellIndex) classify(tile game.TileType, pos game.Position, myID string) cellKey {
+	if p, ok := ix.players[pos]; ok {
+		kind := cellPlayer
+		if p.ID == myID {
+			kind = cellSelf
+		}
+		return cellKey{kind: kind, color: p.Color, team: p.TeamID, glyph: p.Cosmetics.Glyph, shielded: !p.InvulnerableUntil.IsZero(), cursed: len(p.Effects) > 0, faint: !p.InvisibleUntil.IsZero(), stunned: !p.StunnedUntil.IsZero()}
+	}
+	if ix.enemies[pos] {
+		return cellKey{kind: cellEnemy}
+	}
+	if b := ix.boss; b != nil && pos.X >= b.Pos.X && pos.X < b.Pos.X+game.BossSize &&
+		pos.Y >= b.Pos.Y && pos.Y < b.Pos.Y+game.BossSize {
+		if pos.X-b.Pos.X == b.WeakPoint.X && pos.Y-b.Pos.Y == b.WeakPoint.Y {
+			return cellKey{kind: cellBossWeak}
+		}
+		return cellKey{kind: cellBoss}
+	}
+	if ix.lava[pos] {
+		return cellKey{kind: cellLava}
+	}
+	if ix.fires[pos] {
+		return cellKey{kind: cellFire}
+	}
+	if ix.warning[pos] {
+		return cellKey{kind: cellWarning}
+	}
+	if left, ok := ix.bombs[pos]; ok {
+		return cellKey{kind: cellBomb, fuse: fuseAt(left)}
+	}
+	if ix.mines[pos] {
+		return cellKey{kind: cellMine}
+	}
+	if pkType, ok := ix.pickups[pos]; ok {
+		switch pkType {
+		case game.PickupBomb:
+			return cellKey{kind: cellPickupBomb}
+		case game.PickupRange:
+			return cellKey{kind: cellPickupRange}
+		case game.PickupAmmo:
+			return cellKey{kind: cellPickupAmmo}
+		case game.PickupSpeed:
+			return cellKey{kind: cellPickupSpeed}
+		case game.PickupKick:
+			return cellKey{kind: cellPickupKick}
+		case game.PickupShield:
+			return cellKey{kind: cellPickupShield}
+		case game.PickupSkull:
+			return cellKey{kind: cellPickupSkull}
+		case game.PickupFullFire:
+			return cellKey{kind: cellPickupFullFire}
+		case game.PickupLineBomb:
+			return cellKey{kind: cellPickupLineBomb}
+		case game.PickupMine:
+			return cellKey{kind: cellPickupMine}
+		case game.PickupCloak:
+			return cellKey{kind: cellPickupCloak}
+		}
+	}
+	if color, ok := ix.ghosts[pos]; ok {
+		return cellKey{kind: cellGhost, color: color}
+	}
+	switch tile {
+	case game.HardWall:
+		return cellKey{kind: cellHardWall}
+	case game.SoftWall:
+		return cellKey{kind: cellSoftWall}
+	case game.Barrel:
+		return cellKey{kind: cellBarrel}
+	case game.ExitDoor:
+		return cellKey{kind: cellExit}
+	case game.Ice:
+		return cellKey{kind: cellIce}
+	case game.Trap:
+		return cellKey{kind: cellTrap}
+	case game.CrackedWall:
+		return cellKey{kind: cellCrackedWall}
+	case game.CrackedWallHit:
+		return cellKey{kind: cellCrackedWallHit}
+	case game.Fog:
+		return cellKey{kind: cellFog}
+	}
+	if ix.hill != nil && ix.hill.Contains(pos) {
+		return cellKey{kind: cellHill}
+	}
+	if ix.zone != nil && ix.zone.OnEdge(pos) {
+		return cellKey{kind: cellZoneEdge}
+	}
+	return cellKey{kind: cellEmpty}
+}
+
+// glyph returns the styled two-column string for a cell key, rendering it
+// once and caching it for later frames.
+func (r *BoardRenderer) glyph(k cellKey) string {
+	if g, ok := r.glyphs[k]; ok {
+		return g
+	}
+
+	var g string
+	switch k.kind {
+	case cellSelf, cellPlayer:
+		color := playerColors[k.color%len(playerColors)]
+		if k.team > 0 {
+			color = teamColors[(k.team-1)%len(teamColors)]
+		}
+		style := lipgloss.NewStyle().Background(lipgloss.Color("#1a1a2e")).Bold(true).Foreground(color)
+		if k.shielded {
+			style = style.Blink(true)
+		}
+		if k.cursed {
+			style = style.Underline(true)
+		}
+		if k.faint {
+			style = style.Faint(true)
+		}
+		custom, hasGlyph := cosmeticGlyphs[k.glyph]
+		if k.stunned {
+			custom, hasGlyph = "zz", true
+		}
+		switch {
+		case k.kind == cellSelf && hasGlyph:
+			g = style.Background(color).Foreground(lipgloss.Color("#1a1a2e")).Render(custom)
+		case k.kind == cellSelf:
+			g = style.Background(color).Render("██")
+		case hasGlyph:
+			g = style.Render(custom)
+		case k.color >= 9:
+			// Two digits fill the cell on their own
+			g = style.Render(fmt.Sprintf("%d", k.color+1))
+		default:
+			g = style.Render(fmt.Sprintf("P%d", k.color+1))
+		}
+	case cellGhost:
+		// Faint and without the player's background, so it reads as
+		// behind the live game
+		color := playerColors[k.color%len(playerColors)]
+		label := fmt.Sprintf("P%d", k.color+1)
+		if k.color >= 9 {
+			label = fmt.Sprintf("%d", k.color+1)
+		}
+		g = lipgloss.NewStyle().Foreground(color).Faint(true).Render(label)
+	case cellEnemy:
+		g = enemyStyle.Render("EE")
+	case cellBoss:
+		g = bossStyle.Render("██")
+	case cellBossWeak:
+		g = bossWeakStyle.Render("<>")
+	case cellFire:
+		g = fireStyle.Render("░░")
+	case cellLava:
+		g = lavaStyle.Render("≈≈")
+	case cellWarning:
+		g = warningStyle.Render("!!")
+	case cellBomb:
+		switch k.fuse {
+		case fuseLit:
+			g = bombLitStyle.Render("()")
+		case fuseFlash:
+			g = bombFlashStyle.Render("{}")
+		default:
+			g = bombStyle.Render("()")
+		}
+	case cellMine:
+		g = bombStyle.Render("^^")
+	case cellPickupBomb:
+		g = pickupBombStyle.Render("+B")
+	case cellPickupRange:
+		g = pickupRangeStyle.Render("+R")
+	case cellPickupAmmo:
+		g = pickupAmmoStyle.Render("+A")
+	case cellPickupSpeed:
+		g = pickupSpeedStyle.Render("+S")
+	case cellPickupKick:
+		g = pickupKickStyle.Render("+K")
+	case cellPickupShield:
+		g = pickupShieldStyle.Render("+H")
+	case cellPickupSkull:
+		g = pickupSkullStyle.Render("+?")
+	case cellPickupFullFire:
+		g = pickupFullFireStyle.Render("+F")
+	case cellPickupLineBomb:
+		g = pickupLineBombStyle.Render("+L")
+	case cellPickupMine:
+		g = pickupMineStyle.Render("+M")
+	case cellPickupCloak:
+		g = pickupCloakStyle.Render("+I")
+	case cellHardWall:
+		g = hardWallStyle.Render("██")
+	case cellSoftWall:
+		g = softWallStyle.Render("▒▒")
+	case cellBarrel:
+		g = barrelStyle.Render("▓▓")
+	case cellExit:
+		g = exitStyle.Render("[]")
+	case cellIce:
+		g = iceStyle.Render("··")
+	case cellTrap:
+		g = trapStyle.Render("▲▲")
+	case cellCrackedWall:
+		g = crackedWallStyle.Render("▓▓")
+	case cellCrackedWallHit:
+		g = crackedWallStyle.Render("▚▞")
+	case cellFog:
+		g = fogStyle.Render("  ")
+	case cellHill:
+		g = hillStyle.Render("  ")
+	case cellZoneEdge:
+		g = zoneEdgeStyle.Render("  ")
+	default:
+		g = emptyStyle.Render("  ")
+	}
+
+	r.glyphs[k] = g
+	return g
+}
+
+// fuseStage is how a bomb's fuse is drawn. Flashing between stages
+// speeds up as the fuse runs out.
+type fuseStage int
+
+const (
+	fuseSteady fuseStage = iota
+	fuseLit
+	fuseFlash
+)
+
+// fuseAt returns the stage of a bomb with the given fuse left: steady until
+// its last second, then alternating with lit every quarter second, and with
+// flash every tenth of a second for the last half second.
+func fuseAt(left time.Duration) fuseStage {
+	switch {
+	case left > time.Second:
+		return fuseSteady
+	case left > time.Second/2:
+		if left/(time.Second/4)%2 == 0 {
+			return fuseLit
+		}
+		return fuseSteady
+	default:
+		if left/(time.Second/10)%2 == 0 {
+			return fuseFlash
+		}
+		return fuseLit
+	}
+}
+
+func sameKeys(a, b []cellKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
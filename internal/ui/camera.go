@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// Viewport is the visible part of the board, in tiles.
+// A zero Viewport means the whole board is visible.
+type Viewport struct {
+	X, Y, Width, Height int
+}
+
+// Camera picks the visible part of boards larger than the terminal.
+//
+// In auto mode it eases toward a target one tile per frame so the view never
+// jumps. Panning switches to manual mode until auto is re-enabled.
+type Camera struct {
+	Auto   bool
+	center game.Position
+	placed bool // Whether center has been initialized
+}
+
+// NewCamera creates a camera in auto mode.
+func NewCamera() *Camera {
+	return &Camera{Auto: true}
+}
+
+// Pan moves the camera manually and disables auto mode.
+func (c *Camera) Pan(dx, dy int) {
+	c.Auto = false
+	c.center.X += dx
+	c.center.Y += dy
+}
+
+// Follow moves the camera one step toward target when in auto mode.
+func (c *Camera) Follow(target game.Position) {
+	if !c.placed {
+		c.center = target
+		c.placed = true
+		return
+	}
+	if !c.Auto {
+		return
+	}
+	c.center.X += sign(target.X - c.center.X)
+	c.center.Y += sign(target.Y - c.center.Y)
+}
+
+// Viewport returns the cols×rows window around the camera, clamped to the
+// board. If the board fits entirely, the zero Viewport is returned.
+func (c *Camera) Viewport(boardW, boardH, cols, rows int) Viewport {
+	if cols >= boardW && rows >= boardH {
+		return Viewport{}
+	}
+	if cols > boardW {
+		cols = boardW
+	}
+	if rows > boardH {
+		rows = boardH
+	}
+	c.center.X = clamp(c.center.X, 0, boardW-1)
+	c.center.Y = clamp(c.center.Y, 0, boardH-1)
+	return Viewport{
+		X:      clamp(c.center.X-cols/2, 0, boardW-cols),
+		Y:      clamp(c.center.Y-rows/2, 0, boardH-rows),
+		Width:  cols,
+		Height: rows,
+	}
+}
+
+// actionFocus returns where the action is: the centroid of bombs and fire,
+// or of the surviving players when nothing is exploding.
+func actionFocus(state *game.GameState) game.Position {
+	var sumX, sumY, n int
+	add := func(p game.Position, weight int) {
+		sumX += p.X * weight
+		sumY += p.Y * weight
+		n += weight
+	}
+
+	for _, f := range state.Fires {
+		add(f.Pos, 2) // Explosions happening now matter more than pending bombs
+	}
+	for _, b := range state.Bombs {
+		add(b.Pos, 1)
+	}
+	if n == 0 {
+		for _, p := range state.Players {
+			if p.Alive && !p.OutOfSight {
+				add(p.Pos, 1)
+			}
+		}
+	}
+	if n == 0 {
+		return game.Position{X: state.Width / 2, Y: state.Height / 2}
+	}
+	return game.Position{X: sumX / n, Y: sumY / n}
+}
+
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func clamp(x, lo, hi int) int {
+	if x < lo {
+		return lo
+	}
+	if x > hi {
+		return hi
+	}
+	return x
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/amalg/go-bomberman/internal/game"
+	"github.com/amalg/go-bomberman/internal/profile"
+)
+
+// Visuals for the cosmetics in profile.Catalog, keyed by ID.
+var (
+	cosmeticNameColors = map[string]lipgloss.Color{
+		"gold":    lipgloss.Color("#ffd700"),
+		"crimson": lipgloss.Color("#dc143c"),
+		"ice":     lipgloss.Color("#a0e8ff"),
+	}
+	cosmeticBanners = map[string]string{
+		"crown":   "👑 %s WINS! 👑",
+		"inferno": "🔥🔥 %s WINS 🔥🔥",
+	}
+	cosmeticGlyphs = map[string]string{
+		"smile": "☺☺",
+		"star":  "★★",
+	}
+)
+
+// nameColor returns the HUD color for a player's name.
+func nameColor(p *game.Player) lipgloss.Color {
+	if c, ok := cosmeticNameColors[p.Cosmetics.NameColor]; ok {
+		return c
+	}
+	return playerColors[p.Color%len(playerColors)]
+}
+
+// winBanner returns the victory line for a winning player.
+func winBanner(p *game.Player) string {
+	if format, ok := cosmeticBanners[p.Cosmetics.Banner]; ok {
+		return fmt.Sprintf(format, p.Name)
+	}
+	return fmt.Sprintf("🏆 %s WINS!", p.Name)
+}
+
+// RenderCosmetics renders the unlock list for the local profile.
+func RenderCosmetics(prof *profile.Profile, cursor int) string {
+	kinds := map[profile.CosmeticKind]string{
+		profile.KindNameColor: "Name color",
+		profile.KindBanner:    "Victory banner",
+		profile.KindGlyph:     "Board glyph",
+	}
+
+	var lines []string
+	for i, u := range profile.Catalog {
+		mark := "🔒"
+		switch {
+		case prof.IsSelected(u):
+			mark = "✅"
+		case prof.Unlocked(u):
+			mark = "  "
+		}
+		line := fmt.Sprintf("%s %-16s %-15s %s", mark, u.Name, kinds[u.Kind], requirement(u))
+		if i == cursor {
+			lines = append(lines, roomSelectedStyle.Render("▸ "+line))
+		} else if prof.Unlocked(u) {
+			lines = append(lines, roomStyle.Render("  "+line))
+		} else {
+			lines = append(lines, roomEmptyStyle.Render("  "+line))
+		}
+	}
+
+	summary := inputLabelStyle.Render(fmt.Sprintf("Games played: %d  •  Wins: %d", prof.GamesPlayed, prof.Wins))
+	content := strings.Join([]string{
+		titleStyle.Render("🎨 Cosmetics"), "",
+		summary, "",
+		strings.Join(lines, "\n"), "",
+		helpStyle.Render("↑↓ Navigate  •  Enter Equip/Unequip  •  Esc Back"),
+	}, "\n")
+	return menuBoxStyle.Render(content) + "\n"
+}
+
+func requirement(u profile.Unlock) string {
+	var reqs []string
+	if u.Wins > 0 {
+		reqs = append(reqs, fmt.Sprintf("%d wins", u.Wins))
+	}
+	if u.Games > 0 {
+		reqs = append(reqs, fmt.Sprintf("%d games", u.Games))
+	}
+	if len(reqs) == 0 {
+		return "free"
+	}
+	return strings.Join(reqs, ", ")
+}
@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/amalg/go-bomberman/internal/stats"
+)
+
+// HeatmapLayer selects which statistic the heatmap screen shows.
+type HeatmapLayer int
+
+const (
+	HeatmapDeaths HeatmapLayer = iota
+	HeatmapBombs
+	heatmapLayerCount
+)
+
+// heatColors runs from cold to hot; index 0 is used for tiles with no events.
+var heatColors = []lipgloss.Color{
+	lipgloss.Color("#1a1a2e"),
+	lipgloss.Color("#2b3a67"),
+	lipgloss.Color("#4a7a96"),
+	lipgloss.Color("#e0a030"),
+	lipgloss.Color("#ff6600"),
+	lipgloss.Color("#ff2222"),
+}
+
+// RenderHeatmapScreen renders the stored heatmap for the selected map and
+// layer of seasons[season], where "" is the current season.
+func RenderHeatmapScreen(store *stats.Store, mapIdx int, layer HeatmapLayer, seasons []string, season int) string {
+	title := titleStyle.Render("📊 Heatmaps")
+	help := helpStyle.Render("←→ Map  •  Tab Deaths/Bombs  •  Esc Back")
+	if len(seasons) > 1 {
+		name := seasons[season]
+		if name == "" {
+			name = "Current"
+		}
+		title += fmt.Sprintf("  •  Season %s (%d/%d)", lobbyStyle.Render(name), season+1, len(seasons))
+		help = helpStyle.Render("←→ Map  •  ↑↓ Season  •  Tab Deaths/Bombs  •  Esc Back")
+	}
+
+	keys := store.MapKeys()
+	if len(keys) == 0 {
+		body := roomEmptyStyle.Render("  No games recorded yet.\n  Host a game to start collecting statistics.")
+		return menuBoxStyle.Render(strings.Join([]string{title, "", body, "", help}, "\n")) + "\n"
+	}
+	if mapIdx >= len(keys) {
+		mapIdx = len(keys) - 1
+	}
+
+	ms, _ := store.Map(keys[mapIdx])
+	heat, label := ms.Deaths, "Deaths"
+	if layer == HeatmapBombs {
+		heat, label = ms.Bombs, "Bomb placements"
+	}
+
+	header := fmt.Sprintf("%s  (%d/%d)  •  %d games  •  %s",
+		keys[mapIdx], mapIdx+1, len(keys), ms.Games, lobbyStyle.Render(label))
+
+	content := strings.Join([]string{
+		title, "",
+		header, "",
+		renderHeatmap(heat), "",
+		renderHeatLegend(heat.Max()), "",
+		help,
+	}, "\n")
+	return menuBoxStyle.Render(content) + "\n"
+}
+
+// renderHeatmap draws each tile colored by its share of the hottest tile.
+// Hard walls follow the fixed border and pillar layout every board shares.
+func renderHeatmap(h *stats.Heatmap) string {
+	max := h.Max()
+	rows := make([]string, h.Height)
+	for y := 0; y < h.Height; y++ {
+		var b strings.Builder
+		for x := 0; x < h.Width; x++ {
+			border := x == 0 || y == 0 || x == h.Width-1 || y == h.Height-1
+			if border || (x%2 == 0 && y%2 == 0) {
+				b.WriteString(hardWallStyle.Render("██"))
+				continue
+			}
+			b.WriteString(heatCell(h.At(x, y), max))
+		}
+		rows[y] = b.String()
+	}
+	return strings.Join(rows, "\n")
+}
+
+func heatCell(count, max int) string {
+	idx := 0
+	if count > 0 && max > 0 {
+		// Scale 1..max onto the non-empty colors; the hottest tile gets the last one
+		idx = 1 + (count*(len(heatColors)-1)-1)/max
+	}
+	return lipgloss.NewStyle().Background(heatColors[idx]).Render("  ")
+}
+
+func renderHeatLegend(max int) string {
+	var b strings.Builder
+	b.WriteString(helpStyle.Render("0 "))
+	for _, c := range heatColors {
+		b.WriteString(lipgloss.NewStyle().Background(c).Render("  "))
+	}
+	b.WriteString(helpStyle.Render(fmt.Sprintf(" %d", max)))
+	return b.String()
+}
@@ -1,18 +1,26 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
-	"io"
-	"log"
+	"io/fs"
+	"log/slog"
 	"net"
+	"path/filepath"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/amalg/go-bomberman/internal/ai"
 	"github.com/amalg/go-bomberman/internal/discovery"
 	"github.com/amalg/go-bomberman/internal/game"
 	"github.com/amalg/go-bomberman/internal/network"
+	"github.com/amalg/go-bomberman/internal/profile"
+	"github.com/amalg/go-bomberman/internal/script"
+	"github.com/amalg/go-bomberman/internal/stats"
 )
 
 // Screen represents which screen is currently shown.
@@ -23,31 +31,105 @@ const (
 	ScreenCreateRoom
 	ScreenBrowseRooms
 	ScreenGame
+	ScreenHeatmap
+	ScreenCosmetics
 )
 
 // --- Messages ---
 
 type stateUpdateMsg game.GameState
+type systemNoticeMsg network.SystemMsg
+type gameEventMsg game.Event
+type chatLineMsg network.ChatMsg
+type reconnectingMsg bool
 type roomsUpdateMsg []discovery.RoomInfo
+type serverRoomsMsg struct {
+	addr  string
+	rooms []discovery.RoomInfo
+}
 type errMsg struct{ err error }
 type serverReadyMsg struct {
-	server *network.Server
-	client *network.Client
-	bc     *discovery.Broadcaster
+	server    *network.Server
+	client    *network.Client
+	bc        *discovery.Broadcaster
+	ghost     *game.Ghost // Challenge: the best run to race; nil without one
+	ghostPath string      // Challenge: where its best run is kept
 }
 type clientConnectedMsg struct {
 	client *network.Client
 }
+type spectatorReadyMsg struct {
+	spectator *network.MulticastSpectator
+}
 type tickMsg time.Time
+type frameMsg time.Time
 
 func (e errMsg) Error() string { return e.err.Error() }
 
 // --- Model ---
 
+// Options configures a Model from command-line flags.
+type Options struct {
+	PlayerName string
+	Port       int              // TCP game port when hosting
+	WSPort     int              // HTTP port also taking WebSocket clients when hosting; 0 disables
+	UDP        bool             // Take states and send moves over UDP where the room offers it
+	TLSCert    string           // PEM certificate hosted rooms require TLS with; "" for plaintext
+	TLSKey     string           // PEM private key of TLSCert
+	Filter     network.Filter   // Moderation applied when hosting; nil disables
+	MOTD       string           // Message of the day shown to joining players when hosting
+	Rules      string           // House rules shown to joining players in the lobby when hosting
+	FPS        int              // Maximum board redraws per second; 0 uses DefaultFPS
+	Debug      bool             // Enables the F3 state inspection overlay and checks hosted games' invariants
+	StatsPath  string           // Stats file recorded when hosting and shown in Heatmaps; "" disables
+	Multicast  bool             // Stream state to LAN multicast spectators when hosting
+	Profile    *profile.Profile // Local progression and cosmetics; nil disables both
+	Config     game.GameConfig  // Game settings used when hosting; zero value uses game.DefaultConfig
+	SavePath   string           // Where Ctrl+S saves the hosted match
+	ResumePath string           // Save to resume when hosting; "" starts a new match
+	StepMode   bool             // Hosted games only advance when the host steps a tick
+	ScriptPath string           // Lua script of custom rules for hosted games; "" for none
+	GhostDir   string           // Where the best daily challenge and practice runs are kept; "" disables ghosts
+	Seed       int64            // Seed of the map Practice plays, on Config's settings
+
+	// Compress is how full states sent when hosting are compressed; "" for
+	// none.
+	Compress network.Compression
+
+	// IdleTimeout is how long players of a hosted room may send nothing
+	// before they're dropped; 0 never drops them.
+	IdleTimeout time.Duration
+}
+
+// stateSource is anything that streams game states: a player connection or
+// a multicast spectator.
+type stateSource interface {
+	StateChan() <-chan game.GameState
+}
+
+// DefaultFPS is the redraw cap used when Options.FPS is unset.
+const DefaultFPS = 30
+
+// browseInput is what's being typed on the browse screen, besides the
+// player's name.
+type browseInput int
+
+const (
+	inputNone    browseInput = iota
+	inputServer              // Address of a multi-room server to list the rooms of
+	inputNewRoom             // Name of a room to create on that server
+)
+
+// maxNotices is how many recent server announcements the game screen keeps.
+const maxNotices = 4
+
+// maxChat is how many recent chat lines the game screen keeps.
+const maxChat = 6
+
 type Model struct {
 	screen     Screen
 	playerName string
-	port       int
+	opts       Options
 
 	// Main menu
 	menuCursor int
@@ -61,28 +143,85 @@ type Model struct {
 	rooms          []discovery.RoomInfo
 	roomCursor     int
 	browseEditName bool
+	serverAddr     string // Multi-room server whose rooms are listed instead of the LAN's; "" for the LAN
+	browseInput    browseInput
+	browseText     string
+
+	// Heatmaps
+	statsStore     *stats.Store
+	heatmapMap     int // Index into statsStore.MapKeys()
+	heatmapLayer   HeatmapLayer
+	heatmapSeasons []string // Archived seasons, after "" for the current one
+	heatmapSeason  int      // Index into heatmapSeasons
+
+	// Cosmetics
+	cosmeticCursor int
 
 	// Game
-	server   *network.Server
-	client   *network.Client
-	bc       *discovery.Broadcaster
-	state    *game.GameState
-	playerID string
-	isHost   bool
+	server    *network.Server
+	client    *network.Client
+	spectator *network.MulticastSpectator
+	source    stateSource
+	bc        *discovery.Broadcaster
+	state     *game.GameState
+	playerID  string
+	isHost    bool
+	motd      string   // Host's message of the day, shown in the lobby
+	rules     string   // Host's house rules, shown in the lobby
+	notices   []string // Recent server announcements, oldest first
+	showNet   bool     // Bandwidth panel toggle
+	picked    string   // Host: player whose handicap -/+ changes in the lobby and whom X kicks; "" until picked
+	predict   predictor
+
+	// Daily challenge and practice
+	ghost     *game.Ghost // The best run at the challenge, raced alongside; nil without one
+	ghostPath string      // Where the challenge's best run is kept; "" outside a challenge
+
+	// reconnecting is set while the client rejoins after its connection dropped.
+	reconnecting bool
+
+	// Chat
+	chat      []network.ChatMsg // Recent chat lines, oldest first
+	chatting  bool              // Typing a chat line: keys go to chatInput
+	chatInput string
+
+	// Debug overlay (only reachable with Options.Debug)
+	showDebug    bool
+	lastAction   string
+	lastActionAt time.Time
+	lastSeq      uint32
+
+	// Frame limiting: state updates land in pending and are applied at most
+	// once per frame interval, so slow terminals don't fall behind the server.
+	pending        *game.GameState
+	frameScheduled bool
+	lastFrame      time.Time
+	board          *BoardRenderer
+	camera         *Camera
+	width, height  int // Terminal size
 
 	err      error
 	quitting bool
 }
 
-func NewModel(playerName string, port int) Model {
+func NewModel(opts Options) Model {
+	playerName := opts.PlayerName
 	if playerName == "" {
 		playerName = "Player"
 	}
+	if opts.FPS <= 0 {
+		opts.FPS = DefaultFPS
+	}
+	if opts.Config.Width == 0 {
+		opts.Config = game.DefaultConfig()
+	}
 	return Model{
 		screen:     ScreenMainMenu,
 		playerName: playerName,
-		port:       port,
+		opts:       opts,
 		roomName:   "Bomberman",
+		board:      NewBoardRenderer(),
+		camera:     NewCamera(),
 	}
 }
 
@@ -94,46 +233,122 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.err = msg.err
 		return m, nil
 
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
 	case serverReadyMsg:
 		m.server = msg.server
 		m.client = msg.client
 		m.bc = msg.bc
 		m.playerID = msg.client.PlayerID()
+		m.source = msg.client
 		m.isHost = true
+		m.motd, m.rules = msg.client.MOTD(), msg.client.Rules()
+		m.ghost, m.ghostPath = msg.ghost, msg.ghostPath
 		m.screen = ScreenGame
-		return m, waitForState(m.client)
+		if m.server.Engine().Resuming() {
+			m.addNotice("Resuming saved match: players rejoin with their saved names")
+		}
+		if m.ghost != nil {
+			m.addNotice("👻 Racing your best run: " + describeRun(m.ghost.Run()))
+		}
+		return m, tea.Batch(waitForState(m.source), waitForSystem(m.client), waitForEvent(m.client), waitForChat(m.client), waitForReconnect(m.client))
 
 	case clientConnectedMsg:
 		m.client = msg.client
 		m.playerID = msg.client.PlayerID()
+		m.source = msg.client
 		m.isHost = false
+		m.motd, m.rules = msg.client.MOTD(), msg.client.Rules()
 		m.screen = ScreenGame
 		if m.listener != nil {
 			m.listener.Stop()
 			m.listener = nil
 		}
-		return m, waitForState(m.client)
+		return m, tea.Batch(waitForState(m.source), waitForSystem(m.client), waitForEvent(m.client), waitForChat(m.client), waitForReconnect(m.client))
+
+	case spectatorReadyMsg:
+		m.spectator = msg.spectator
+		m.source = msg.spectator
+		m.screen = ScreenGame
+		return m, waitForState(m.source)
 
 	case stateUpdateMsg:
 		state := game.GameState(msg)
-		m.state = &state
-		if m.bc != nil {
-			m.bc.UpdatePlayerCount(len(state.Players))
+		m.pending = &state
+		cmds := []tea.Cmd{waitForState(m.source)}
+		if !m.frameScheduled {
+			m.frameScheduled = true
+			cmds = append(cmds, scheduleFrame(m.lastFrame, time.Second/time.Duration(m.opts.FPS)))
 		}
-		return m, waitForState(m.client)
+		return m, tea.Batch(cmds...)
 
-	case roomsUpdateMsg:
-		m.rooms = []discovery.RoomInfo(msg)
-		if m.screen == ScreenBrowseRooms {
-			return m, tea.Tick(time.Second, func(t time.Time) tea.Msg {
-				return tickMsg(t)
-			})
+	case frameMsg:
+		m.frameScheduled = false
+		m.lastFrame = time.Time(msg)
+		if m.pending != nil {
+			m.recordResult(m.state, m.pending)
+			m.recordRun(m.state, m.pending)
+			m.state = m.pending
+			m.pending = nil
+			m.predict.reconcile(m.state, m.playerID)
+			m.camera.Follow(m.cameraTarget())
+			m.raceGhost()
+			if m.bc != nil {
+				m.bc.UpdatePlayerCount(len(m.state.Players))
+				if m.state.Status == game.StatusLobby {
+					m.bc.UpdateStatus(discovery.RoomLobby)
+				} else {
+					m.bc.UpdateStatus(discovery.RoomInGame)
+				}
+			}
 		}
 		return m, nil
 
+	case systemNoticeMsg:
+		switch msg.Kind {
+		case network.SystemMOTD:
+			m.motd = msg.Text
+		default:
+			m.addNotice(msg.Text)
+		}
+		return m, waitForSystem(m.client)
+
+	case chatLineMsg:
+		chat := append([]network.ChatMsg{}, m.chat...)
+		chat = append(chat, network.ChatMsg(msg))
+		if len(chat) > maxChat {
+			chat = chat[len(chat)-maxChat:]
+		}
+		m.chat = chat
+		return m, waitForChat(m.client)
+
+	case reconnectingMsg:
+		m.reconnecting = bool(msg)
+		return m, waitForReconnect(m.client)
+
+	case gameEventMsg:
+		if text := killFeed(game.Event(msg), m.state); text != "" {
+			m.addNotice(text)
+		}
+		return m, waitForEvent(m.client)
+
+	case roomsUpdateMsg:
+		if m.serverAddr != "" {
+			return m, nil // Listing a server's rooms instead
+		}
+		return m.showRooms(msg)
+
+	case serverRoomsMsg:
+		if msg.addr != m.serverAddr {
+			return m, nil
+		}
+		return m.showRooms(msg.rooms)
+
 	case tickMsg:
-		if m.screen == ScreenBrowseRooms && m.listener != nil && !m.browseEditName {
-			return m, refreshRooms(m.listener)
+		if m.screen == ScreenBrowseRooms && !m.browseEditName && m.browseInput == inputNone {
+			return m, m.refreshBrowse()
 		}
 		return m, nil
 	}
@@ -147,6 +362,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateBrowseRooms(msg)
 	case ScreenGame:
 		return m.updateGame(msg)
+	case ScreenHeatmap:
+		return m.updateHeatmap(msg)
+	case ScreenCosmetics:
+		return m.updateCosmetics(msg)
 	}
 	return m, nil
 }
@@ -163,11 +382,62 @@ func (m Model) View() string {
 	case ScreenCreateRoom:
 		view = RenderCreateRoom(m.roomName, m.playerName, m.createField)
 	case ScreenBrowseRooms:
-		view = RenderBrowseRooms(m.rooms, m.roomCursor, m.playerName, m.browseEditName)
+		label, text := m.browsePrompt()
+		view = RenderBrowseRooms(m.rooms, m.roomCursor, m.serverAddr, label, text)
+	case ScreenHeatmap:
+		view = RenderHeatmapScreen(m.statsStore, m.heatmapMap, m.heatmapLayer, m.heatmapSeasons, m.heatmapSeason)
+	case ScreenCosmetics:
+		view = RenderCosmetics(m.opts.Profile, m.cosmeticCursor)
 	case ScreenGame:
-		board := RenderBoard(m.state, m.playerID)
-		hud := RenderHUD(m.state, m.playerID)
+		board := m.board.RenderBoard(m.state, m.playerID, m.boardViewport())
+		if m.state.Status == game.StatusPaused {
+			board = RenderPaused(lipgloss.Width(board), lipgloss.Height(board), m.server != nil)
+		}
+		if m.showDebug {
+			board = lipgloss.JoinVertical(lipgloss.Left, RenderDebug(m.debugInfo()), board)
+		}
+		if m.opts.StepMode && m.server != nil {
+			board = lipgloss.JoinVertical(lipgloss.Left, RenderStepBar(m.state), board)
+		}
+		var config *game.GameConfig
+		var startsAt time.Time
+		if m.client != nil {
+			c := m.client.Config()
+			config = &c
+			startsAt = m.client.StartsAt()
+		}
+		hud := RenderHUD(m.state, m.playerID, m.picked, config, startsAt)
+		if m.showNet && m.client != nil {
+			var serverStats *network.BandwidthStats
+			if m.server != nil {
+				st := m.server.Bandwidth()
+				serverStats = &st
+			}
+			hud = lipgloss.JoinVertical(lipgloss.Left, hud, RenderNetStats(m.client.Bandwidth(), serverStats))
+		}
 		view = lipgloss.JoinHorizontal(lipgloss.Top, board, "  ", hud)
+		if m.reconnecting {
+			view = warningStyle.Render("⚠ Connection lost. Reconnecting…") + "\n" + view
+		}
+		if m.spectator != nil {
+			view = lobbyStyle.Render("📺 Spectating LAN multicast  •  Q to leave") + "\n" + view
+		} else if m.client != nil && m.client.Spectating() {
+			view = lobbyStyle.Render("📺 Spectating  •  T to chat  •  Q to leave") + "\n" + view
+		}
+		inLobby := m.state != nil && m.state.Status == game.StatusLobby
+		if inLobby && m.server != nil {
+			view += "\n" + helpStyle.Render("Add a bot: [1] easy  [2] medium  [3] hard")
+			view += "\n" + helpStyle.Render("Handicaps: [H] pick a player  [-] weaker  [+] stronger")
+		}
+		if m.server != nil {
+			view += "\n" + helpStyle.Render("Moderation: [H] pick a player  [X] kick  [Shift+X] ban")
+		}
+		if notices := RenderNotices(m.motd, m.rules, m.notices, inLobby); notices != "" {
+			view += "\n" + notices
+		}
+		if chat := RenderChat(m.chat, m.chatInput, m.chatting); chat != "" {
+			view += "\n" + chat
+		}
 	}
 
 	if m.err != nil {
@@ -176,6 +446,64 @@ func (m Model) View() string {
 	return view + "\n"
 }
 
+// showRooms lists rooms on the browse screen and schedules the next refresh.
+func (m Model) showRooms(rooms []discovery.RoomInfo) (tea.Model, tea.Cmd) {
+	// Keep the cursor on the same room as others appear and expire
+	var selected string
+	if m.roomCursor < len(m.rooms) {
+		selected = m.rooms[m.roomCursor].RoomID
+	}
+	m.rooms = rooms
+	for i, r := range m.rooms {
+		if selected != "" && r.RoomID == selected {
+			m.roomCursor = i
+		}
+	}
+	if m.roomCursor >= len(m.rooms) {
+		m.roomCursor = max(len(m.rooms)-1, 0)
+	}
+	if m.screen == ScreenBrowseRooms {
+		return m, tea.Tick(time.Second, func(t time.Time) tea.Msg {
+			return tickMsg(t)
+		})
+	}
+	return m, nil
+}
+
+// refreshBrowse lists the rooms being browsed again: the LAN's, or
+// serverAddr's.
+func (m Model) refreshBrowse() tea.Cmd {
+	if m.serverAddr != "" {
+		return listServerRooms(m.serverAddr)
+	}
+	if m.listener != nil {
+		return refreshRooms(m.listener)
+	}
+	return nil
+}
+
+// browsePrompt returns the label and text of what's being typed on the
+// browse screen, or "" if nothing is.
+func (m Model) browsePrompt() (label, text string) {
+	switch {
+	case m.browseEditName:
+		return "Your Name: ", m.playerName
+	case m.browseInput == inputServer:
+		return "Server Address: ", m.browseText
+	case m.browseInput == inputNewRoom:
+		return "New Room: ", m.browseText
+	}
+	return "", ""
+}
+
+// joinRoom joins a listed room, of the LAN or of serverAddr.
+func (m Model) joinRoom(room discovery.RoomInfo, join network.JoinMsg) tea.Cmd {
+	if m.serverAddr != "" {
+		return joinServerRoom(m.serverAddr, room.RoomID, false, join)
+	}
+	return connectToRoom(room.JoinAddr(), join)
+}
+
 // --- Screen handlers ---
 
 func (m Model) updateMainMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -189,7 +517,7 @@ func (m Model) updateMainMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.menuCursor--
 			}
 		case "down", "j":
-			if m.menuCursor < 2 {
+			if m.menuCursor < 7 {
 				m.menuCursor++
 			}
 		case "enter":
@@ -202,8 +530,49 @@ func (m Model) updateMainMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.screen = ScreenBrowseRooms
 				m.browseEditName = true
 				m.roomCursor = 0
+				m.serverAddr = ""
 				m.err = nil
 			case 2:
+				m.err = nil
+				return m, startSpectating()
+			case 3:
+				m.err = nil
+				now := time.Now()
+				return m, startChallenge(game.DailyConfig(game.DefaultConfig(), now),
+					"daily-"+now.Format(time.DateOnly), m.joinMsg(), m.opts)
+			case 4:
+				m.err = nil
+				config := m.opts.Config
+				config.Seed = m.opts.Seed
+				return m, startChallenge(game.PracticeConfig(config),
+					fmt.Sprintf("practice-%d", m.opts.Seed), m.joinMsg(), m.opts)
+			case 5:
+				if m.opts.StatsPath == "" {
+					m.err = fmt.Errorf("statistics are disabled")
+					return m, nil
+				}
+				seasons, err := stats.Seasons(m.opts.StatsPath)
+				if err != nil {
+					m.err = err
+					return m, nil
+				}
+				m.heatmapSeasons = append([]string{""}, seasons...)
+				m.heatmapSeason = 0
+				if err := m.openHeatmapSeason(); err != nil {
+					m.err = err
+					return m, nil
+				}
+				m.screen = ScreenHeatmap
+				m.err = nil
+			case 6:
+				if m.opts.Profile == nil {
+					m.err = fmt.Errorf("profile is disabled")
+					return m, nil
+				}
+				m.screen = ScreenCosmetics
+				m.cosmeticCursor = 0
+				m.err = nil
+			case 7:
 				m.quitting = true
 				return m, tea.Quit
 			}
@@ -231,7 +600,7 @@ func (m Model) updateCreateRoom(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.playerName == "" {
 				m.playerName = "Host"
 			}
-			return m, startServer(m.roomName, m.playerName, m.port)
+			return m, startServer(m.roomName, m.joinMsg(), m.opts)
 		case "backspace":
 			if m.createField == 0 && len(m.roomName) > 0 {
 				m.roomName = m.roomName[:len(m.roomName)-1]
@@ -243,7 +612,7 @@ func (m Model) updateCreateRoom(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if len(ch) == 1 {
 				if m.createField == 0 {
 					m.roomName += ch
-				} else {
+				} else if len(m.playerName) < network.MaxNameLen {
 					m.playerName += ch
 				}
 			}
@@ -283,22 +652,66 @@ func (m Model) updateBrowseRooms(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			default:
 				ch := keyMsg.String()
-				if len(ch) == 1 {
+				if len(ch) == 1 && len(m.playerName) < network.MaxNameLen {
 					m.playerName += ch
 				}
 			}
 			return m, nil
 		}
 
+		if m.browseInput != inputNone {
+			switch keyMsg.String() {
+			case "esc":
+				m.browseInput = inputNone
+				return m, m.refreshBrowse()
+			case "ctrl+c":
+				m.quitting = true
+				return m, tea.Quit
+			case "enter":
+				input, text := m.browseInput, strings.TrimSpace(m.browseText)
+				m.browseInput = inputNone
+				switch {
+				case text == "":
+					return m, m.refreshBrowse()
+				case input == inputServer:
+					m.serverAddr, m.rooms, m.roomCursor, m.err = text, nil, 0, nil
+					return m, listServerRooms(text)
+				default:
+					return m, joinServerRoom(m.serverAddr, text, true, m.joinMsg())
+				}
+			case "backspace":
+				if len(m.browseText) > 0 {
+					m.browseText = m.browseText[:len(m.browseText)-1]
+				}
+			default:
+				ch := keyMsg.String()
+				if len(ch) == 1 {
+					m.browseText += ch
+				}
+			}
+			return m, nil
+		}
+
 		switch keyMsg.String() {
 		case "esc":
+			m.err = nil
+			if m.serverAddr != "" {
+				// Back to the LAN's rooms
+				m.serverAddr, m.rooms, m.roomCursor = "", nil, 0
+				return m, m.refreshBrowse()
+			}
 			m.screen = ScreenMainMenu
 			if m.listener != nil {
 				m.listener.Stop()
 				m.listener = nil
 			}
-			m.err = nil
 			return m, nil
+		case "a":
+			m.browseInput, m.browseText = inputServer, m.serverAddr
+		case "n":
+			if m.serverAddr != "" {
+				m.browseInput, m.browseText = inputNewRoom, ""
+			}
 		case "ctrl+c":
 			m.quitting = true
 			return m, tea.Quit
@@ -313,14 +726,109 @@ func (m Model) updateBrowseRooms(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "enter":
 			if len(m.rooms) > 0 && m.roomCursor < len(m.rooms) {
 				room := m.rooms[m.roomCursor]
-				return m, connectToRoom(room.GameAddr, m.playerName)
+				if !room.Joinable() {
+					m.err = fmt.Errorf("room %q is %s", room.RoomName, roomStatusLabel(room))
+					return m, nil
+				}
+				return m, m.joinRoom(room, m.joinMsg())
+			}
+		case "v":
+			if len(m.rooms) > 0 && m.roomCursor < len(m.rooms) {
+				join := m.joinMsg()
+				join.Spectate = true
+				return m, m.joinRoom(m.rooms[m.roomCursor], join)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m Model) updateHeatmap(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "esc", "q":
+			m.screen = ScreenMainMenu
+			m.statsStore = nil
+		case "ctrl+c":
+			m.quitting = true
+			return m, tea.Quit
+		case "left", "a":
+			if m.heatmapMap > 0 {
+				m.heatmapMap--
+			}
+		case "right", "d":
+			if m.heatmapMap < len(m.statsStore.MapKeys())-1 {
+				m.heatmapMap++
+			}
+		case "up", "w", "down", "s":
+			season := m.heatmapSeason + 1
+			if keyMsg.String() == "up" || keyMsg.String() == "w" {
+				season = m.heatmapSeason - 1
+			}
+			if season < 0 || season >= len(m.heatmapSeasons) {
+				break
+			}
+			prev := m.heatmapSeason
+			m.heatmapSeason = season
+			if err := m.openHeatmapSeason(); err != nil {
+				m.heatmapSeason = prev
+				m.err = err
 			}
+		case "tab":
+			m.heatmapLayer = (m.heatmapLayer + 1) % heatmapLayerCount
+		}
+	}
+	return m, nil
+}
+
+// openHeatmapSeason loads the stats of the selected season.
+func (m *Model) openHeatmapSeason() error {
+	path := m.opts.StatsPath
+	if name := m.heatmapSeasons[m.heatmapSeason]; name != "" {
+		path = stats.SeasonPath(path, name)
+	}
+	store, err := stats.Open(path)
+	if err != nil {
+		return err
+	}
+	m.statsStore = store
+	m.heatmapMap = 0
+	m.err = nil
+	return nil
+}
+
+func (m Model) updateCosmetics(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "esc", "q":
+			m.screen = ScreenMainMenu
+			m.err = nil
+		case "ctrl+c":
+			m.quitting = true
+			return m, tea.Quit
+		case "up", "k":
+			if m.cosmeticCursor > 0 {
+				m.cosmeticCursor--
+			}
+		case "down", "j":
+			if m.cosmeticCursor < len(profile.Catalog)-1 {
+				m.cosmeticCursor++
+			}
+		case "enter", " ":
+			if err := m.opts.Profile.Toggle(profile.Catalog[m.cosmeticCursor]); err != nil {
+				m.err = err
+				return m, nil
+			}
+			m.err = m.opts.Profile.Save()
 		}
 	}
 	return m, nil
 }
 
 func (m Model) updateGame(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && m.chatting {
+		return m.updateChat(keyMsg)
+	}
 	if keyMsg, ok := msg.(tea.KeyMsg); ok {
 		switch keyMsg.String() {
 		case "q", "ctrl+c", "esc":
@@ -328,15 +836,91 @@ func (m Model) updateGame(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.quitting = true
 			return m, tea.Quit
 		case "up", "w":
-			m.client.SendAction(game.ActionMove, game.DirUp)
+			m.sendAction(game.ActionMove, game.DirUp, "move up")
 		case "down", "s":
-			m.client.SendAction(game.ActionMove, game.DirDown)
+			m.sendAction(game.ActionMove, game.DirDown, "move down")
 		case "left", "a":
-			m.client.SendAction(game.ActionMove, game.DirLeft)
+			m.sendAction(game.ActionMove, game.DirLeft, "move left")
 		case "right", "d":
-			m.client.SendAction(game.ActionMove, game.DirRight)
+			m.sendAction(game.ActionMove, game.DirRight, "move right")
 		case " ":
-			m.client.SendAction(game.ActionPlaceBomb, 0)
+			m.sendAction(game.ActionPlaceBomb, 0, "place bomb")
+		case "e":
+			m.sendAction(game.ActionSprint, 0, "sprint")
+		case "f":
+			m.sendAction(game.ActionLineBomb, 0, "line bomb")
+		case "b":
+			m.showNet = !m.showNet
+		case "i":
+			m.camera.Pan(0, -1)
+		case "k":
+			m.camera.Pan(0, 1)
+		case "j":
+			m.camera.Pan(-1, 0)
+		case "l":
+			m.camera.Pan(1, 0)
+		case "c":
+			m.camera.Auto = !m.camera.Auto
+		case "ctrl+s":
+			m.saveMatch()
+		case ".":
+			if m.opts.StepMode && m.server != nil {
+				m.server.Engine().Step(1)
+			}
+		case "f3":
+			if m.opts.Debug {
+				m.showDebug = !m.showDebug
+			}
+		case "t":
+			if m.client != nil {
+				m.chatting = true
+				m.chatInput = ""
+			}
+		case "g":
+			if m.client != nil {
+				m.client.SendSwitchTeam()
+			}
+		case "p":
+			if m.server != nil && m.client != nil && m.state != nil {
+				switch m.state.Status {
+				case game.StatusRunning:
+					m.client.SendPause(true)
+				case game.StatusPaused:
+					m.client.SendPause(false)
+				}
+			}
+		case "1", "2", "3":
+			// The host fills the lobby with bots
+			if m.server != nil && m.state != nil && m.state.Status == game.StatusLobby {
+				d := ai.Difficulties[keyMsg.String()[0]-'1']
+				if _, err := m.server.AddBot(d); err != nil {
+					m.err = err
+				}
+			}
+		case "h":
+			if m.server != nil && m.state != nil {
+				m.picked = nextPlayer(m.state, m.picked)
+			}
+		case "x", "X":
+			// The host kicks, or with shift bans, the picked player
+			if m.server != nil && m.client != nil && m.picked != "" && m.picked != m.playerID {
+				if keyMsg.String() == "X" {
+					m.client.SendBan(m.picked, "")
+				} else {
+					m.client.SendKick(m.picked, "")
+				}
+				m.picked = ""
+			}
+		case "-", "+", "=":
+			if m.server != nil && m.client != nil && m.state != nil && m.state.Status == game.StatusLobby {
+				if p, ok := m.state.Players[m.picked]; ok {
+					step := 1
+					if keyMsg.String() == "-" {
+						step = -1
+					}
+					m.client.SendHandicap(p.ID, stepHandicap(p.Handicap, step, m.client.Config()))
+				}
+			}
 		case "enter":
 			if m.client != nil {
 				m.client.SendStart()
@@ -346,12 +930,266 @@ func (m Model) updateGame(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// updateChat handles keys while a chat line is being typed.
+func (m Model) updateChat(keyMsg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch keyMsg.Type {
+	case tea.KeyCtrlC:
+		m.cleanup()
+		m.quitting = true
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.chatting = false
+	case tea.KeyEnter:
+		if text := strings.TrimSpace(m.chatInput); text != "" {
+			m.client.SendChat(text)
+		}
+		m.chatting = false
+	case tea.KeyBackspace:
+		if runes := []rune(m.chatInput); len(runes) > 0 {
+			m.chatInput = string(runes[:len(runes)-1])
+		}
+	case tea.KeySpace:
+		m.chatInput += " "
+	case tea.KeyRunes:
+		if utf8.RuneCountInString(m.chatInput) < network.MaxChatLen {
+			m.chatInput += string(keyMsg.Runes)
+		}
+	}
+	return m, nil
+}
+
+// nextPlayer returns the player after id in HUD order, wrapping around.
+func nextPlayer(state *game.GameState, id string) string {
+	players := hudOrder(state)
+	for i, p := range players {
+		if p.ID == id {
+			return players[(i+1)%len(players)].ID
+		}
+	}
+	if len(players) == 0 {
+		return ""
+	}
+	return players[0].ID
+}
+
+// handicaps are the handicaps the host steps through with -/+: one bomb of
+// range one, none, and two extra bombs and range.
+func handicaps(config game.GameConfig) []game.Handicap {
+	return []game.Handicap{
+		{Bombs: 1, Range: 1},
+		{},
+		{Bombs: min(config.StartBombs+2, config.MaxBombs), Range: min(config.StartRange+2, config.MaxRange)},
+	}
+}
+
+// stepHandicap returns the handicap step places after h, staying at the
+// ends. A handicap not in the list counts as none.
+func stepHandicap(h game.Handicap, step int, config game.GameConfig) game.Handicap {
+	list := handicaps(config)
+	at := 1
+	for i, c := range list {
+		if c == h {
+			at = i
+		}
+	}
+	return list[max(0, min(at+step, len(list)-1))]
+}
+
+// cameraTarget is what the camera follows: our own player while alive,
+// otherwise wherever the action is.
+func (m Model) cameraTarget() game.Position {
+	if p, ok := m.state.Players[m.playerID]; ok && p.Alive {
+		return p.Pos
+	}
+	return actionFocus(m.state)
+}
+
+// hudWidth is the approximate terminal width taken by the HUD beside the board.
+const hudWidth = 48
+
+// boardViewport returns the part of the board that fits in the terminal.
+func (m Model) boardViewport() Viewport {
+	if m.state == nil || m.width == 0 || m.height == 0 {
+		return Viewport{}
+	}
+	cols := (m.width - hudWidth) / 2 // Each tile is two columns wide
+	rows := m.height - 2
+	return m.camera.Viewport(m.state.Width, m.state.Height, max(cols, 5), max(rows, 5))
+}
+
+// addNotice appends a notice, keeping only the latest maxNotices.
+func (m *Model) addNotice(text string) {
+	notices := append([]string{}, m.notices...)
+	notices = append(notices, text)
+	if len(notices) > maxNotices {
+		notices = notices[len(notices)-maxNotices:]
+	}
+	m.notices = notices
+}
+
+// killFeed describes a kill for the notices, or returns "" for any other
+// event.
+func killFeed(ev game.Event, state *game.GameState) string {
+	if ev.Type != game.EventPlayerKilled || state == nil {
+		return ""
+	}
+	name := func(id string) string {
+		if p, ok := state.Players[id]; ok {
+			return p.Name
+		}
+		return id
+	}
+	victim := name(ev.PlayerID)
+	switch ev.ByID {
+	case ev.PlayerID:
+		return "💀 " + victim + " blew themselves up"
+	case "":
+		return "💀 A monster got " + victim
+	case game.BossID:
+		return "💀 The boss got " + victim
+	case game.SkyID:
+		return "💀 A falling bomb got " + victim
+	case game.TrapID:
+		return "💀 " + victim + " stepped on a spike trap"
+	}
+	return "💀 " + name(ev.ByID) + " blew up " + victim
+}
+
+// saveMatch writes the hosted match to the save file.
+func (m *Model) saveMatch() {
+	if m.server == nil || m.opts.SavePath == "" {
+		return
+	}
+	if err := m.server.Engine().Save(m.opts.SavePath); err != nil {
+		m.err = fmt.Errorf("save match: %w", err)
+		return
+	}
+	m.addNotice("Match saved to " + m.opts.SavePath)
+}
+
+// joinMsg builds the join request for this player.
+func (m Model) joinMsg() network.JoinMsg {
+	join := network.JoinMsg{Name: m.playerName, UDP: m.opts.UDP}
+	if m.opts.Profile != nil {
+		join.Cosmetics = m.opts.Profile.Selected
+	}
+	return join
+}
+
+// recordResult counts a finished game in the local profile when the state
+// moves from running to over.
+func (m *Model) recordResult(prev, next *game.GameState) {
+	if m.opts.Profile == nil || m.client == nil || prev == nil {
+		return
+	}
+	if prev.Status == game.StatusRunning && next.Status == game.StatusOver {
+		won := next.Winner == m.playerID
+		if me, ok := next.Players[m.playerID]; ok && next.WinningTeam != 0 {
+			won = me.TeamID == next.WinningTeam
+		}
+		m.opts.Profile.RecordGame(won)
+		if err := m.opts.Profile.Save(); err != nil {
+			m.err = err
+		}
+	}
+}
+
+// recordRun keeps a finished challenge run as the ghost to race next time,
+// if it beats the best one so far.
+func (m *Model) recordRun(prev, next *game.GameState) {
+	if m.ghostPath == "" || m.server == nil || prev == nil ||
+		prev.Status == game.StatusOver || next.Status != game.StatusOver {
+		return
+	}
+	run, ok := m.server.Engine().LastRun()
+	if !ok {
+		return
+	}
+	if m.ghost != nil && !run.Beats(m.ghost.Run()) {
+		m.addNotice("🏁 " + describeRun(run) + "; your best stands")
+		return
+	}
+	if err := run.Save(m.ghostPath); err != nil {
+		m.err = fmt.Errorf("save ghost: %w", err)
+		return
+	}
+	m.addNotice("🏁 New best: " + describeRun(run) + ". Your ghost races you next time")
+}
+
+// raceGhost replays the ghost up to where the live match is and shows it on
+// the board, from the start of the match to its end.
+func (m *Model) raceGhost() {
+	if m.ghost == nil || m.server == nil {
+		return
+	}
+	if m.state.Status == game.StatusLobby || m.state.Status == game.StatusCountdown {
+		m.board.SetGhosts(nil)
+		return
+	}
+	if start, ok := m.server.Engine().RunStart(); ok && m.state.Tick >= start {
+		m.ghost.AdvanceTo(m.state.Tick - start)
+	}
+	m.board.SetGhosts(m.ghost.Players())
+}
+
+// describeRun sums a challenge run up for the notices.
+func describeRun(run game.Run) string {
+	took := time.Duration(run.Ticks) * time.Second / time.Duration(max(run.Start.Config.TickRate, 1))
+	if run.Won {
+		return "cleared in " + took.Round(time.Second/10).String()
+	}
+	return "lasted " + took.Round(time.Second/10).String()
+}
+
+// sendAction forwards an action to the server, remembering it for the debug
+// overlay. Moves are predicted, so they show before the server's state does.
+func (m *Model) sendAction(t game.ActionType, dir game.Direction, label string) {
+	if m.client == nil || m.client.Spectating() {
+		return // Spectating
+	}
+	seq, err := m.client.SendAction(t, dir)
+	if err == nil && t == game.ActionMove && m.state != nil {
+		m.predict.move(m.state, m.playerID, seq, dir, time.Now())
+		m.camera.Follow(m.cameraTarget())
+	}
+	m.lastAction = label
+	m.lastActionAt = time.Now()
+	m.lastSeq = seq
+}
+
+// debugInfo collects the data shown in the debug overlay.
+func (m Model) debugInfo() DebugInfo {
+	info := DebugInfo{
+		State:        m.state,
+		LastAction:   m.lastAction,
+		LastActionAt: m.lastActionAt,
+		LastSeq:      m.lastSeq,
+	}
+	if m.state != nil {
+		if p, ok := m.state.Players[m.playerID]; ok {
+			info.Acked = p.LastSeq
+			info.RTT = p.RTT
+		}
+	}
+	if m.client != nil {
+		info.Net = m.client.Bandwidth()
+	}
+	if m.server != nil {
+		metrics := m.server.Engine().Metrics()
+		info.Engine = &metrics
+	}
+	return info
+}
+
 func (m *Model) cleanup() {
 	if m.bc != nil {
 		m.bc.Stop()
 	}
 	if m.client != nil {
-		m.client.Close()
+		m.client.Leave()
+	}
+	if m.spectator != nil {
+		m.spectator.Close()
 	}
 	if m.server != nil {
 		m.server.Stop()
@@ -363,13 +1201,82 @@ func (m *Model) cleanup() {
 
 // --- Commands ---
 
-func waitForState(client *network.Client) tea.Cmd {
+// waitForState delivers the newest available state. If several states have
+// queued up behind a slow render, only the latest is returned.
+func waitForState(source stateSource) tea.Cmd {
 	return func() tea.Msg {
-		state, ok := <-client.StateChan()
+		state, ok := <-source.StateChan()
 		if !ok {
 			return errMsg{err: fmt.Errorf("server connection closed")}
 		}
-		return stateUpdateMsg(state)
+		for {
+			select {
+			case next, ok := <-source.StateChan():
+				if !ok {
+					return stateUpdateMsg(state)
+				}
+				state = next
+			default:
+				return stateUpdateMsg(state)
+			}
+		}
+	}
+}
+
+// scheduleFrame fires a frameMsg once interval has passed since the last frame.
+func scheduleFrame(last time.Time, interval time.Duration) tea.Cmd {
+	delay := interval - time.Since(last)
+	if delay <= 0 {
+		return func() tea.Msg { return frameMsg(time.Now()) }
+	}
+	return tea.Tick(delay, func(t time.Time) tea.Msg { return frameMsg(t) })
+}
+
+// waitForSystem delivers the next server notice. When the client closes the
+// channel it returns nil so the command simply stops re-arming itself.
+func waitForSystem(client *network.Client) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-client.SystemChan()
+		if !ok {
+			return nil
+		}
+		return systemNoticeMsg(msg)
+	}
+}
+
+// waitForChat delivers the next chat line, stopping like waitForSystem when
+// the client closes.
+func waitForChat(client *network.Client) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-client.ChatChan()
+		if !ok {
+			return nil
+		}
+		return chatLineMsg(msg)
+	}
+}
+
+// waitForEvent delivers the next game event, stopping like waitForSystem
+// when the client closes.
+func waitForEvent(client *network.Client) tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := <-client.EventChan()
+		if !ok {
+			return nil
+		}
+		return gameEventMsg(ev)
+	}
+}
+
+// waitForReconnect delivers the next change of the client's connection,
+// stopping like waitForSystem when the client closes.
+func waitForReconnect(client *network.Client) tea.Cmd {
+	return func() tea.Msg {
+		reconnecting, ok := <-client.ReconnectChan()
+		if !ok {
+			return nil
+		}
+		return reconnectingMsg(reconnecting)
 	}
 }
 
@@ -379,14 +1286,105 @@ func refreshRooms(listener *discovery.Listener) tea.Cmd {
 	}
 }
 
-func startServer(roomName, playerName string, port int) tea.Cmd {
+// listServerRooms lists the rooms of the multi-room server at addr, as the
+// browse screen shows rooms found on the LAN.
+func listServerRooms(addr string) tea.Cmd {
 	return func() tea.Msg {
-		log.SetOutput(io.Discard)
+		rooms, err := network.ListRooms(addr)
+		if err != nil {
+			return errMsg{err: fmt.Errorf("list rooms: %w", err)}
+		}
+		infos := make([]discovery.RoomInfo, len(rooms))
+		for i, r := range rooms {
+			status := discovery.RoomInGame
+			if r.Status == game.StatusLobby {
+				status = discovery.RoomLobby
+			}
+			infos[i] = discovery.RoomInfo{
+				RoomID:      r.ID,
+				RoomName:    r.ID,
+				PlayerCount: r.Players,
+				MaxPlayers:  r.MaxPlayers,
+				GameAddr:    addr,
+				Status:      status,
+			}
+		}
+		return serverRoomsMsg{addr: addr, rooms: infos}
+	}
+}
+
+// joinServerRoom joins room of the multi-room server at addr, creating it
+// first if create is set.
+func joinServerRoom(addr, room string, create bool, join network.JoinMsg) tea.Cmd {
+	return func() tea.Msg {
+		client, err := network.JoinRoom(addr, room, create, join)
+		if err != nil {
+			return errMsg{err: fmt.Errorf("join room: %w", err)}
+		}
+		return clientConnectedMsg{client: client}
+	}
+}
 
-		config := game.DefaultConfig()
+func startServer(roomName string, join network.JoinMsg, opts Options) tea.Cmd {
+	port := opts.Port
+	return func() tea.Msg {
+		config := opts.Config
 		addr := fmt.Sprintf("0.0.0.0:%d", port)
 
-		server := network.NewServer(addr, config)
+		var server *network.Server
+		if opts.ResumePath != "" {
+			engine, err := game.LoadSave(opts.ResumePath)
+			if err != nil {
+				return errMsg{err: fmt.Errorf("resume match: %w", err)}
+			}
+			config = engine.Config
+			server = network.NewServerWithEngine(addr, engine)
+		} else {
+			server = network.NewServer(addr, config)
+		}
+		if opts.Filter != nil {
+			server.SetFilter(opts.Filter)
+		}
+		server.SetMOTD(opts.MOTD)
+		server.SetRules(opts.Rules)
+		server.SetCompression(opts.Compress)
+		server.SetIdleTimeout(opts.IdleTimeout)
+		if opts.Multicast {
+			if err := server.EnableMulticast(network.MulticastGroup); err != nil {
+				return errMsg{err: fmt.Errorf("enable multicast: %w", err)}
+			}
+		}
+		if opts.TLSCert != "" {
+			if err := server.EnableTLS(opts.TLSCert, opts.TLSKey); err != nil {
+				return errMsg{err: err}
+			}
+		}
+		if opts.WSPort != 0 {
+			if err := server.EnableWebSocket(fmt.Sprintf("0.0.0.0:%d", opts.WSPort)); err != nil {
+				return errMsg{err: fmt.Errorf("enable websocket: %w", err)}
+			}
+		}
+		if opts.StatsPath != "" {
+			store, err := stats.Open(opts.StatsPath)
+			if err != nil {
+				slog.Warn("Stats recording disabled", "err", err)
+			} else {
+				server.OnState(stats.NewRecorder(store).Observe)
+			}
+		}
+		if opts.StepMode {
+			server.Engine().SetStepMode(true)
+		}
+		if opts.Debug {
+			server.Engine().SetCheckInvariants(true)
+		}
+		if opts.ScriptPath != "" {
+			s, err := script.Load(opts.ScriptPath)
+			if err != nil {
+				return errMsg{err: err}
+			}
+			server.Engine().SetHooks(s)
+		}
 		if err := server.Start(); err != nil {
 			return errMsg{err: fmt.Errorf("start server: %w", err)}
 		}
@@ -394,7 +1392,10 @@ func startServer(roomName, playerName string, port int) tea.Cmd {
 		time.Sleep(200 * time.Millisecond)
 
 		clientAddr := fmt.Sprintf("127.0.0.1:%d", port)
-		client, err := network.NewClient(clientAddr, playerName)
+		if server.TLS() {
+			clientAddr = network.TLSScheme + clientAddr
+		}
+		client, err := network.NewClient(clientAddr, join)
 		if err != nil {
 			server.Stop()
 			return errMsg{err: fmt.Errorf("connect as host: %w", err)}
@@ -403,10 +1404,12 @@ func startServer(roomName, playerName string, port int) tea.Cmd {
 		gameAddr := fmt.Sprintf("%s:%d", getLocalIP(), port)
 		bc := discovery.NewBroadcaster(discovery.RoomInfo{
 			RoomName:    roomName,
-			HostName:    playerName,
+			HostName:    join.Name,
 			PlayerCount: 1,
 			MaxPlayers:  config.MaxPlayers,
 			GameAddr:    gameAddr,
+			Status:      discovery.RoomLobby,
+			TLS:         server.TLS(),
 		})
 		bc.Start()
 
@@ -414,9 +1417,59 @@ func startServer(roomName, playerName string, port int) tea.Cmd {
 	}
 }
 
-func connectToRoom(addr, playerName string) tea.Cmd {
+// startChallenge hosts a daily challenge or practice match for this player
+// alone, on the loopback interface so it isn't offered on the LAN, racing the
+// best run kept under key if there is one.
+func startChallenge(config game.GameConfig, key string, join network.JoinMsg, opts Options) tea.Cmd {
+	port := opts.Port
+	return func() tea.Msg {
+		var ghost *game.Ghost
+		var ghostPath string
+		if opts.GhostDir != "" {
+			ghostPath = filepath.Join(opts.GhostDir, key+".json")
+			run, err := game.LoadRun(ghostPath)
+			switch {
+			case err == nil:
+				if ghost, err = game.NewGhost(run); err != nil {
+					slog.Warn("Ghost dropped", "path", ghostPath, "err", err)
+				}
+			case !errors.Is(err, fs.ErrNotExist):
+				slog.Warn("Ghost dropped", "path", ghostPath, "err", err)
+			}
+		}
+
+		addr := fmt.Sprintf("127.0.0.1:%d", port)
+		server := network.NewServer(addr, config)
+		server.SetIdleTimeout(opts.IdleTimeout)
+		server.Engine().RecordRuns(true)
+		if opts.Debug {
+			server.Engine().SetCheckInvariants(true)
+		}
+		if err := server.Start(); err != nil {
+			return errMsg{err: fmt.Errorf("start server: %w", err)}
+		}
+		client, err := network.NewClient(addr, join)
+		if err != nil {
+			server.Stop()
+			return errMsg{err: fmt.Errorf("connect as host: %w", err)}
+		}
+		return serverReadyMsg{server: server, client: client, ghost: ghost, ghostPath: ghostPath}
+	}
+}
+
+func startSpectating() tea.Cmd {
+	return func() tea.Msg {
+		spectator, err := network.NewMulticastSpectator(network.MulticastGroup, "")
+		if err != nil {
+			return errMsg{err: fmt.Errorf("watch LAN: %w", err)}
+		}
+		return spectatorReadyMsg{spectator: spectator}
+	}
+}
+
+func connectToRoom(addr string, join network.JoinMsg) tea.Cmd {
 	return func() tea.Msg {
-		client, err := network.NewClient(addr, playerName)
+		client, err := network.NewClient(addr, join)
 		if err != nil {
 			return errMsg{err: fmt.Errorf("join room: %w", err)}
 		}
@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// predictor moves the player's own character as soon as they press a key,
+// instead of a round trip later, and reconciles with the server's state as
+// it arrives: moves the server is done with are dropped, as the state shows
+// them already, and the rest are replayed on top of it.
+type predictor struct {
+	moves []predictedMove // Sent but not yet done with by the server, oldest first
+	next  time.Time       // When move speed allows the next predicted move
+}
+
+type predictedMove struct {
+	seq uint32
+	dir game.Direction
+}
+
+// move predicts the move the player just sent as seq, moving them in state
+// if the server will make the move too. Moves sent faster than the player's
+// speed aren't predicted, as the server holds them back.
+func (pr *predictor) move(state *game.GameState, id string, seq uint32, dir game.Direction, now time.Time) {
+	p := state.Players[id]
+	if p == nil || now.Before(pr.next) || !predictStep(state, p, dir) {
+		return
+	}
+	pr.moves = append(pr.moves, predictedMove{seq: seq, dir: dir})
+	pr.next = now.Add(time.Second / time.Duration(max(p.MoveSpeed, 1)))
+}
+
+// reconcile applies the moves the server hasn't caught up with yet to a
+// newly arrived state.
+func (pr *predictor) reconcile(state *game.GameState, id string) {
+	p := state.Players[id]
+	if p == nil || state.Status != game.StatusRunning {
+		pr.moves = nil
+		return
+	}
+	moves := pr.moves[:0]
+	for _, mv := range pr.moves {
+		if mv.seq > p.LastSeq {
+			moves = append(moves, mv)
+			predictStep(state, p, mv.dir)
+		}
+	}
+	pr.moves = moves
+}
+
+// predictStep moves p in dir as the server would: a tile, or two while
+// sprinting, unless something is in the way. Returns false if p didn't move.
+func predictStep(state *game.GameState, p *game.Player, dir game.Direction) bool {
+	if state.Status != game.StatusRunning || !p.Alive || p.Sliding || !p.StunnedUntil.IsZero() {
+		return false
+	}
+	if p.HasEffect(game.EffectReverse) {
+		dir = dir.Opposite()
+	}
+	p.Facing = dir
+	steps := 1
+	if p.Sprinting {
+		steps = 2
+	}
+	moved := false
+	for range steps {
+		next := p.Pos
+		switch dir {
+		case game.DirUp:
+			next.Y--
+		case game.DirDown:
+			next.Y++
+		case game.DirLeft:
+			next.X--
+		case game.DirRight:
+			next.X++
+		}
+		if !walkable(state, p, next) {
+			break
+		}
+		p.Pos = next
+		moved = true
+	}
+	return moved
+}
+
+// walkable reports whether p can step onto pos.
+func walkable(state *game.GameState, p *game.Player, pos game.Position) bool {
+	if pos.X < 0 || pos.Y < 0 || pos.X >= state.Width || pos.Y >= state.Height || state.Board[pos.Y][pos.X].Solid() {
+		return false
+	}
+	for _, b := range state.Bombs {
+		if b.Pos == pos && !b.Mine && !(b.OwnerOn && b.OwnerID == p.ID) {
+			return false
+		}
+	}
+	for _, other := range state.Players {
+		if other.ID != p.ID && other.Alive && !other.OutOfSight && other.Pos == pos {
+			return false
+		}
+	}
+	return true
+}
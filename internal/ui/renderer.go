@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/amalg/go-bomberman/internal/discovery"
 	"github.com/amalg/go-bomberman/internal/game"
+	"github.com/amalg/go-bomberman/internal/network"
 )
 
 // Color palette
@@ -41,26 +43,82 @@ var (
 			Background(lipgloss.Color("#3a3a3a")).Foreground(lipgloss.Color("#555555"))
 	softWallStyle = lipgloss.NewStyle().
 			Background(lipgloss.Color("#8B6914")).Foreground(lipgloss.Color("#A0772B"))
+	crackedWallStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color("#5a4a3a")).Foreground(lipgloss.Color("#8a7a6a"))
+	barrelStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#aa3322")).Foreground(lipgloss.Color("#ff8844"))
+	fogStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#0a0a14"))
+	hillStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#4a3f10"))
+	zoneEdgeStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#3a1a10"))
+	iceStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#1e3a5a")).Foreground(lipgloss.Color("#aaddff"))
+	trapStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#cc3333")).Bold(true)
+	exitStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#00ff88")).Bold(true)
 	emptyStyle = lipgloss.NewStyle().
 			Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#1a1a2e"))
 	bombStyle = lipgloss.NewStyle().
 			Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#ff4444")).Bold(true)
+	bombLitStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#ffcc00")).Bold(true)
+	bombFlashStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#ff4444")).Foreground(lipgloss.Color("#ffffff")).Bold(true)
 	fireStyle = lipgloss.NewStyle().
 			Background(lipgloss.Color("#ff6600")).Foreground(lipgloss.Color("#ffcc00")).Bold(true)
 
+	lavaStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#aa2200")).Foreground(lipgloss.Color("#ff7700")).Bold(true)
+
 	enemyStyle = lipgloss.NewStyle().
 			Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#ff2222")).Bold(true)
 
+	bossStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#5a1a6e")).Foreground(lipgloss.Color("#9a3abe"))
+	bossWeakStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#5a1a6e")).Foreground(lipgloss.Color("#ffff44")).Bold(true)
+	warningStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#3a1010")).Foreground(lipgloss.Color("#ffaa00")).Bold(true)
+
 	pickupBombStyle = lipgloss.NewStyle().
 			Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#00ddff")).Bold(true)
 	pickupRangeStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#ff66ff")).Bold(true)
+				Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#ff66ff")).Bold(true)
+	pickupAmmoStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#ffaa00")).Bold(true)
+	pickupSpeedStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#66ff66")).Bold(true)
+	pickupKickStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#ffffff")).Bold(true)
+	pickupShieldStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#88ccff")).Bold(true)
+	pickupSkullStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#aa66ff")).Bold(true)
+	pickupFullFireStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#ff3300")).Bold(true)
+	pickupLineBombStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#ff8888")).Bold(true)
+	pickupMineStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#cccc66")).Bold(true)
+	pickupCloakStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color("#1a1a2e")).Foreground(lipgloss.Color("#dddddd")).Faint(true)
 
 	playerColors = []lipgloss.Color{
 		lipgloss.Color("#00ff88"),
 		lipgloss.Color("#4488ff"),
 		lipgloss.Color("#ff44ff"),
 		lipgloss.Color("#ffff44"),
+		lipgloss.Color("#ff8844"),
+		lipgloss.Color("#44ffff"),
+		lipgloss.Color("#ff4466"),
+		lipgloss.Color("#aaaaff"),
+	}
+	teamColors = []lipgloss.Color{
+		lipgloss.Color("#ff5555"),
+		lipgloss.Color("#55aaff"),
 	}
 
 	deadPlayerStyle = lipgloss.NewStyle().
@@ -71,6 +129,17 @@ var (
 	winnerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#00ff88")).Bold(true).Blink(true)
 	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff4444"))
 	helpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#555566"))
+	heartStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff4466"))
+	motdStyle   = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("#44aaff")).Padding(0, 1)
+	noticeStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#aaaacc")).Italic(true)
+	chatStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#ffffff")).Bold(true)
+	debugStyle  = lipgloss.NewStyle().
+			Border(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("#ffff44")).
+			Foreground(lipgloss.Color("#ffff88")).Padding(0, 1)
+	pausedStyle = lipgloss.NewStyle().
+			Border(lipgloss.DoubleBorder()).BorderForeground(lipgloss.Color("#44aaff")).
+			Foreground(lipgloss.Color("#44aaff")).Bold(true).Padding(1, 4).Align(lipgloss.Center)
 )
 
 func RenderMainMenu(cursor int) string {
@@ -79,7 +148,7 @@ func RenderMainMenu(cursor int) string {
   ║   💣  B O M B E R M A N  ║
   ╚══════════════════════════╝`)
 
-	items := []string{"🎮 Create Room", "🔍 Join Room", "🚪 Quit"}
+	items := []string{"🎮 Create Room", "🔍 Join Room", "📺 Watch LAN", "🏁 Daily Challenge", "🎯 Practice", "📊 Heatmaps", "🎨 Cosmetics", "🚪 Quit"}
 	var menu []string
 	for i, item := range items {
 		if i == cursor {
@@ -126,17 +195,29 @@ func RenderCreateRoom(roomName, playerName string, editing int) string {
 	return menuBoxStyle.Render(content) + "\n"
 }
 
-func RenderBrowseRooms(rooms []discovery.RoomInfo, cursor int, playerName string, editing bool) string {
+// RenderBrowseRooms renders the rooms found on the LAN, or those of the
+// multi-room server at server if it isn't "". While something is typed,
+// label and text show it instead.
+func RenderBrowseRooms(rooms []discovery.RoomInfo, cursor int, server, label, text string) string {
 	var body string
-	if editing {
-		body = inputLabelStyle.Render("Your Name: ") + inputStyle.Render(playerName+"▌")
+	if label != "" {
+		body = inputLabelStyle.Render(label) + inputStyle.Render(text+"▌")
+	} else if len(rooms) == 0 && server != "" {
+		body = roomEmptyStyle.Render("  No rooms on this server yet.\n  Press N to create one.")
 	} else if len(rooms) == 0 {
 		body = roomEmptyStyle.Render("  Searching for rooms on the network...\n  Make sure someone has created a room.")
 	} else {
 		var lines []string
 		for i, r := range rooms {
-			line := fmt.Sprintf("%s's Room \"%s\"  [%d/%d players]",
-				r.HostName, r.RoomName, r.PlayerCount, r.MaxPlayers)
+			line := fmt.Sprintf("%s's Room \"%s\"  [%d/%d players]  %s",
+				r.HostName, r.RoomName, r.PlayerCount, r.MaxPlayers, roomStatusLabel(r))
+			if server != "" {
+				line = fmt.Sprintf("Room \"%s\"  [%d/%d players]  %s",
+					r.RoomName, r.PlayerCount, r.MaxPlayers, roomStatusLabel(r))
+			}
+			if r.TLS {
+				line += "  🔒"
+			}
 			if i == cursor {
 				lines = append(lines, roomSelectedStyle.Render("▸ "+line))
 			} else {
@@ -146,13 +227,18 @@ func RenderBrowseRooms(rooms []discovery.RoomInfo, cursor int, playerName string
 		body = strings.Join(lines, "\n")
 	}
 
-	helpText := "↑↓ Navigate  •  Enter Join  •  Esc Back"
-	if editing {
-		helpText = "Type your name  •  Enter Confirm  •  Esc Back"
+	title := "🔍 Join Room"
+	helpText := "↑↓ Navigate  •  Enter Join  •  V Watch  •  A Server  •  Esc Back"
+	if server != "" {
+		title = "🔍 Rooms on " + server
+		helpText = "↑↓ Navigate  •  Enter Join  •  V Watch  •  N New Room  •  Esc LAN Rooms"
+	}
+	if label != "" {
+		helpText = "Type, then Enter to confirm  •  Esc Back"
 	}
 
 	content := strings.Join([]string{
-		titleStyle.Render("🔍 Join Room"), "",
+		titleStyle.Render(title), "",
 		body, "",
 		helpStyle.Render(helpText),
 	}, "\n")
@@ -160,90 +246,25 @@ func RenderBrowseRooms(rooms []discovery.RoomInfo, cursor int, playerName string
 	return menuBoxStyle.Render(content) + "\n"
 }
 
-func RenderBoard(state *game.GameState, myID string) string {
-	if state == nil || len(state.Board) == 0 {
-		return "Waiting for game state..."
-	}
-
-	fireSet := make(map[game.Position]bool)
-	for _, f := range state.Fires {
-		fireSet[f.Pos] = true
-	}
-	bombSet := make(map[game.Position]*game.Bomb)
-	for _, b := range state.Bombs {
-		bombSet[b.Pos] = b
-	}
-	playerSet := make(map[game.Position]*game.Player)
-	for _, p := range state.Players {
-		if p.Alive {
-			playerSet[p.Pos] = p
-		}
-	}
-	enemySet := make(map[game.Position]*game.Enemy)
-	for _, en := range state.Enemies {
-		if en.Alive {
-			enemySet[en.Pos] = en
-		}
-	}
-	pickupSet := make(map[game.Position]game.PickupType)
-	for _, pk := range state.Pickups {
-		pickupSet[pk.Pos] = pk.Type
-	}
-
-	var rows []string
-	for y := 0; y < state.Height; y++ {
-		var cells []string
-		for x := 0; x < state.Width; x++ {
-			pos := game.Position{X: x, Y: y}
-			cells = append(cells, renderCell(state.Board[y][x], pos, fireSet, bombSet, playerSet, enemySet, pickupSet, myID))
-		}
-		rows = append(rows, strings.Join(cells, ""))
-	}
-	return strings.Join(rows, "\n")
-}
-
-func renderCell(tile game.TileType, pos game.Position,
-	fireSet map[game.Position]bool, bombSet map[game.Position]*game.Bomb,
-	playerSet map[game.Position]*game.Player, enemySet map[game.Position]*game.Enemy,
-	pickupSet map[game.Position]game.PickupType, myID string) string {
-
-	if p, ok := playerSet[pos]; ok {
-		colorIdx := p.Color % len(playerColors)
-		style := lipgloss.NewStyle().Background(lipgloss.Color("#1a1a2e")).Bold(true).
-			Foreground(playerColors[colorIdx])
-		if p.ID == myID {
-			return style.Background(playerColors[colorIdx]).Render("██")
-		}
-		return style.Render(fmt.Sprintf("P%d", p.Color+1))
-	}
-	if _, ok := enemySet[pos]; ok {
-		return enemyStyle.Render("EE")
-	}
-	if fireSet[pos] {
-		return fireStyle.Render("░░")
-	}
-	if _, ok := bombSet[pos]; ok {
-		return bombStyle.Render("()")
-	}
-	if pkType, ok := pickupSet[pos]; ok {
-		switch pkType {
-		case game.PickupBomb:
-			return pickupBombStyle.Render("+B")
-		case game.PickupRange:
-			return pickupRangeStyle.Render("+R")
-		}
-	}
-	switch tile {
-	case game.HardWall:
-		return hardWallStyle.Render("██")
-	case game.SoftWall:
-		return softWallStyle.Render("▒▒")
+// roomStatusLabel describes whether a discovered room can be joined right now.
+func roomStatusLabel(r discovery.RoomInfo) string {
+	switch {
+	case r.Status == discovery.RoomInGame:
+		return "in game"
+	case r.OpenSeats() == 0:
+		return "full"
+	case r.OpenSeats() == 1:
+		return "1 seat free"
 	default:
-		return emptyStyle.Render("  ")
+		return fmt.Sprintf("%d seats free", r.OpenSeats())
 	}
 }
 
-func RenderHUD(state *game.GameState, myID string) string {
+// RenderHUD renders the status panel. selectedID is the player the host is
+// setting a handicap for in the lobby, or "". config is the host's game
+// config, or nil when it isn't known (spectators). startsAt is when the
+// start countdown ends, or zero when it hasn't been timed yet.
+func RenderHUD(state *game.GameState, myID, selectedID string, config *game.GameConfig, startsAt time.Time) string {
 	if state == nil {
 		return ""
 	}
@@ -254,58 +275,470 @@ func RenderHUD(state *game.GameState, myID string) string {
 	case game.StatusLobby:
 		parts = append(parts, lobbyStyle.Render("⏳ LOBBY — Waiting for players..."))
 		parts = append(parts, "   Press [Enter] to start!")
+		if config != nil && config.TeamMode {
+			parts = append(parts, "   Press [G] to switch team")
+		}
+		if config != nil && config.FillWithBots {
+			parts = append(parts, "   Empty slots are filled with bots")
+		}
+	case game.StatusCountdown:
+		parts = append(parts, lobbyStyle.Render(renderCountdown(startsAt)))
 	case game.StatusRunning:
-		parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("#ff4444")).Render("🔥 GAME IN PROGRESS"))
+		if state.SuddenDeath {
+			parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("#ff4444")).Bold(true).Render("⚔️  SUDDEN DEATH"))
+		} else {
+			parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("#ff4444")).Render("🔥 GAME IN PROGRESS"))
+		}
+	case game.StatusPaused:
+		parts = append(parts, lobbyStyle.Render("⏸  PAUSED"))
+	case game.StatusIntermission:
+		result := "nobody takes the round"
+		if p, ok := state.Players[state.Winner]; ok {
+			result = p.Name + " takes the round"
+		} else if state.WinningTeam != 0 {
+			result = fmt.Sprintf("team %d takes the round", state.WinningTeam)
+		}
+		parts = append(parts, lobbyStyle.Render(fmt.Sprintf("🏁 ROUND %d OVER — %s", state.Round, result)))
+		parts = append(parts, "   Next round starting...")
+	case game.StatusLevelComplete:
+		parts = append(parts, winnerStyle.Render(fmt.Sprintf("🚪 LEVEL %d CLEAR", state.Level)))
+		parts = append(parts, "   Next level starting...")
 	case game.StatusOver:
-		if state.Winner != "" {
+		if state.Level > 0 {
+			if game.CampaignWon(state) {
+				parts = append(parts, winnerStyle.Render("🏆 CAMPAIGN COMPLETE"))
+			} else {
+				parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Render(
+					fmt.Sprintf("💀 GAME OVER ON LEVEL %d", state.Level)))
+			}
+		} else if state.Boss != nil {
+			if !state.Boss.Alive {
+				parts = append(parts, winnerStyle.Render("🏆 BOSS DEFEATED"))
+			} else {
+				parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Render("💀 THE BOSS WINS"))
+			}
+		} else if state.PvE {
+			if !monstersLeft(state) {
+				parts = append(parts, winnerStyle.Render("🏆 MONSTERS CLEARED"))
+			} else {
+				parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Render("💀 THE MONSTERS WIN"))
+			}
+		} else if state.Winner != "" {
 			if p, ok := state.Players[state.Winner]; ok {
-				parts = append(parts, winnerStyle.Render(fmt.Sprintf("🏆 %s WINS!", p.Name)))
+				parts = append(parts, winnerStyle.Render(winBanner(p)))
 			}
+		} else if state.WinningTeam != 0 {
+			parts = append(parts, winnerStyle.Render(fmt.Sprintf("🏆 TEAM %d WINS!", state.WinningTeam)))
 		} else {
 			parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Render("💀 DRAW"))
 		}
 	}
 
-	// Enemy count
-	aliveEnemies := 0
-	for _, en := range state.Enemies {
-		if en.Alive {
-			aliveEnemies++
+	if state.Status == game.StatusRunning && state.TimeLeft > 0 {
+		parts = append(parts, "   "+renderClock(state.TimeLeft))
+	}
+
+	if z := state.Zone; z != nil && state.Status == game.StatusRunning && z.ShrinkIn > 0 {
+		parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("#ff7700")).Render(
+			fmt.Sprintf("   🔥 Zone closes in %s", formatClock(z.ShrinkIn))))
+	}
+
+	if state.Status == game.StatusRunning && config != nil {
+		overtimeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#ff7700")).Bold(true)
+		switch {
+		case config.Overtime == game.OvertimeLava && lavaRising(state):
+			parts = append(parts, overtimeStyle.Render("   🌋 THE LAVA IS RISING"))
+		case config.Overtime == game.OvertimeBombRain && bombsRaining(state):
+			parts = append(parts, overtimeStyle.Render("   ☄️ BOMBS ARE RAINING DOWN"))
 		}
 	}
+
+	if config != nil && config.Rounds > 1 && state.Round > 0 {
+		parts = append(parts, fmt.Sprintf("   Round %d of %d — first to %d", state.Round, config.Rounds, config.Rounds/2+1))
+	}
+
+	if state.Level > 0 {
+		parts = append(parts, fmt.Sprintf("   Level %d of %d: %s", state.Level, len(game.Levels), game.Levels[state.Level-1].Name))
+	}
+
+	if b := state.Boss; b != nil && b.Alive {
+		parts = append(parts, "", renderBossHP(b))
+	}
+
+	if state.Hill != nil && config != nil {
+		parts = append(parts, "", lipgloss.NewStyle().Foreground(lipgloss.Color("#ffcc00")).Render(
+			fmt.Sprintf("👑 Hold the hill alone: first to %d points", config.HillScore)))
+	}
+
+	// Enemy count
 	if len(state.Enemies) > 0 {
 		parts = append(parts, "",
 			lipgloss.NewStyle().Foreground(lipgloss.Color("#ff2222")).Render(
-				fmt.Sprintf("👾 Enemies: %d/%d", aliveEnemies, len(state.Enemies))))
+				fmt.Sprintf("👾 Enemies: %d/%d", aliveEnemies(state), len(state.Enemies))))
 	}
 
 	parts = append(parts, "", lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Render("Players:"))
 
-	// Sort players by color index so the list order is stable across renders.
-	sortedPlayers := make([]*game.Player, 0, len(state.Players))
-	for _, p := range state.Players {
-		sortedPlayers = append(sortedPlayers, p)
-	}
-	sort.Slice(sortedPlayers, func(i, j int) bool {
-		return sortedPlayers[i].Color < sortedPlayers[j].Color
-	})
-
-	for _, p := range sortedPlayers {
-		colorIdx := p.Color % len(playerColors)
-		nameStyle := lipgloss.NewStyle().Foreground(playerColors[colorIdx])
+	team := 0
+	for _, p := range hudOrder(state) {
+		if p.TeamID != team {
+			team = p.TeamID
+			header := lipgloss.NewStyle().Foreground(teamColors[(team-1)%len(teamColors)]).Bold(true)
+			parts = append(parts, header.Render(fmt.Sprintf(" Team %d", team)))
+		}
+		nameStyle := lipgloss.NewStyle().Foreground(nameColor(p))
 		status := "❤️ "
+		if !p.StunnedUntil.IsZero() {
+			status = "💫"
+		}
+		if p.Disconnected {
+			status = "🔌"
+		}
 		if !p.Alive {
 			status = "💀"
+			if !p.RespawnAt.IsZero() {
+				status = "⏳"
+			}
 			nameStyle = deadPlayerStyle
 		}
+		if config != nil && config.Lives > 1 {
+			status += fmt.Sprintf("×%d", p.Lives)
+		}
+		if config != nil && config.Hearts > 1 {
+			status += " " + renderHearts(p.HP, config.Hearts)
+		}
 		marker := "  "
-		if p.ID == myID {
+		if p.ID == selectedID {
+			marker = "» "
+		} else if p.ID == myID {
 			marker = "→ "
 		}
-		parts = append(parts, fmt.Sprintf("%s%s %s [💣×%d 🔥%d]",
-			marker, status, nameStyle.Render(p.Name), p.BombMax-p.BombsUsed, p.BombRange))
+		extras := ""
+		if config != nil && config.Rounds > 1 {
+			extras += fmt.Sprintf(" 🏆%d", p.RoundScore)
+		}
+		if config != nil && config.StunMode {
+			extras += fmt.Sprintf(" ⭐%d", p.Kills)
+		}
+		if p.CanKick {
+			extras += " 🦶"
+		}
+		if !p.InvulnerableUntil.IsZero() {
+			extras += " 🛡️"
+		}
+		if len(p.Effects) > 0 {
+			extras += " 🌀"
+		}
+		if p.FullFire {
+			extras += " 💥"
+		}
+		if p.LineBomb {
+			extras += " 🧨"
+		}
+		if p.Mines > 0 {
+			extras += fmt.Sprintf(" ⚠️%d", p.Mines)
+		}
+		if !p.InvisibleUntil.IsZero() {
+			extras += " 👻"
+		}
+		if state.Hill != nil {
+			extras += fmt.Sprintf(" 👑%d", p.HillPoints)
+		}
+		if p.Handicap != (game.Handicap{}) {
+			extras += " ⚖️"
+		}
+		if p.RTT > 0 {
+			extras += fmt.Sprintf(" 📶%dms", p.RTT.Milliseconds())
+		}
+		if config != nil && config.AmmoMode {
+			parts = append(parts, fmt.Sprintf("%s%s %s [💣×%d 🎒%d 🔥%d 👟%d%s]",
+				marker, status, nameStyle.Render(p.Name), p.BombMax-p.BombsUsed, p.Ammo, p.BombRange, p.MoveSpeed, extras))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s%s %s [💣×%d 🔥%d 👟%d%s]",
+				marker, status, nameStyle.Render(p.Name), p.BombMax-p.BombsUsed, p.BombRange, p.MoveSpeed, extras))
+		}
 	}
 
-	parts = append(parts, "", helpStyle.Render("WASD/Arrows: Move | Space: Bomb | Q: Quit"))
+	if len(state.Spectators) > 0 {
+		parts = append(parts, "", lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Render("Spectators:"))
+		for _, sp := range state.Spectators {
+			marker := "  "
+			if sp.ID == myID {
+				marker = "→ "
+			}
+			parts = append(parts, marker+"📺 "+sp.Name)
+		}
+	}
+
+	if me, ok := state.Players[myID]; ok && config != nil {
+		if config.AmmoMode {
+			parts = append(parts, "", renderAmmo(me))
+		}
+		if config.SprintEnabled {
+			parts = append(parts, "", renderStamina(me))
+		}
+	}
+
+	help := "WASD/Arrows: Move | Space: Bomb | T: Chat | B: Net | Q: Quit"
+	if config != nil && config.SprintEnabled {
+		help = "WASD/Arrows: Move | Space: Bomb | E: Sprint | T: Chat | B: Net | Q: Quit"
+	}
+	parts = append(parts, "", helpStyle.Render(help))
 	return hudBorderStyle.Render(strings.Join(parts, "\n"))
 }
+
+// hudOrder sorts players by team, then color index, so the HUD's player list
+// is stable across renders and lists teammates together.
+func hudOrder(state *game.GameState) []*game.Player {
+	players := make([]*game.Player, 0, len(state.Players))
+	for _, p := range state.Players {
+		players = append(players, p)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		a, b := players[i], players[j]
+		if a.TeamID != b.TeamID {
+			return a.TeamID < b.TeamID
+		}
+		return a.Color < b.Color
+	})
+	return players
+}
+
+// renderCountdown shows the seconds left before the match starts.
+func renderCountdown(startsAt time.Time) string {
+	if startsAt.IsZero() {
+		return "⏱ Get ready..."
+	}
+	left := time.Until(startsAt)
+	if left <= 0 {
+		return "🏁 GO!"
+	}
+	return fmt.Sprintf("⏱ Starting in %d...", int(left.Seconds())+1)
+}
+
+// renderClock shows the time left before the round's time limit, turning
+// red for the last 10 seconds.
+func renderClock(left time.Duration) string {
+	clock := "⏱ " + formatClock(left)
+	if left <= 10*time.Second {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#ff4444")).Bold(true).Render(clock)
+	}
+	return clock
+}
+
+// formatClock formats a time left as m:ss, rounding up so the clock reads
+// 0:00 only once time is up.
+func formatClock(left time.Duration) string {
+	secs := int((left + time.Second - 1) / time.Second)
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+// renderAmmo shows the remaining bomb stock in ammo mode.
+func renderAmmo(p *game.Player) string {
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("#ffaa00")).Bold(true)
+	if p.Ammo == 0 {
+		style = errorStyle
+	}
+	return style.Render(fmt.Sprintf("🎒 Ammo: %d", p.Ammo))
+}
+
+// renderStamina draws a player's stamina as a ten-segment bar.
+func renderStamina(p *game.Player) string {
+	filled := p.Stamina * 10 / game.MaxStamina
+	color := lipgloss.Color("#44aaff")
+	label := "⚡ Stamina "
+	if p.Sprinting {
+		color = lipgloss.Color("#ffcc00")
+		label = "⚡ SPRINT  "
+	}
+	bar := lipgloss.NewStyle().Foreground(color).Render(strings.Repeat("█", filled)) +
+		helpStyle.Render(strings.Repeat("░", 10-filled))
+	return label + bar
+}
+
+// lavaRising reports whether creeping lava has started to spread.
+func lavaRising(state *game.GameState) bool {
+	if state.OvertimeAt.IsZero() {
+		return false
+	}
+	for _, f := range state.Fires {
+		if f.Permanent {
+			return true
+		}
+	}
+	return false
+}
+
+// bombsRaining reports whether bomb rain has started to fall.
+func bombsRaining(state *game.GameState) bool {
+	if state.OvertimeAt.IsZero() {
+		return false
+	}
+	for _, b := range state.Bombs {
+		if b.OwnerID == game.SkyID {
+			return true
+		}
+	}
+	return false
+}
+
+// aliveEnemies counts the enemies still alive.
+func aliveEnemies(state *game.GameState) int {
+	n := 0
+	for _, en := range state.Enemies {
+		if en.Alive {
+			n++
+		}
+	}
+	return n
+}
+
+// monstersLeft reports whether a PvE match still has monsters to clear.
+func monstersLeft(state *game.GameState) bool {
+	return aliveEnemies(state) > 0
+}
+
+// renderBossHP draws the boss's health bar, which turns red once it's enraged.
+func renderBossHP(b *game.Boss) string {
+	filled := b.HP * 10 / b.MaxHP
+	color := lipgloss.Color("#9a3abe")
+	if b.HP*2 <= b.MaxHP {
+		color = lipgloss.Color("#ff4444")
+	}
+	bar := lipgloss.NewStyle().Foreground(color).Render(strings.Repeat("█", filled)) +
+		helpStyle.Render(strings.Repeat("░", 10-filled))
+	return fmt.Sprintf("👹 Boss %s %d/%d", bar, b.HP, b.MaxHP)
+}
+
+// RenderNotices renders the host's MOTD and house rules (lobby only) and
+// recent server announcements.
+func RenderNotices(motd, rules string, notices []string, inLobby bool) string {
+	var parts []string
+	if inLobby && motd != "" {
+		parts = append(parts, motdStyle.Render(lobbyStyle.Render("📜 Message of the day")+"\n"+motd))
+	}
+	if inLobby && rules != "" {
+		parts = append(parts, motdStyle.Render(lobbyStyle.Render("📋 House rules")+"\n"+rules))
+	}
+	for _, n := range notices {
+		parts = append(parts, noticeStyle.Render("» "+n))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// RenderChat renders the recent chat lines, each sender's name in their
+// player color, and the line being typed if typing.
+func RenderChat(lines []network.ChatMsg, input string, typing bool) string {
+	var parts []string
+	for _, l := range lines {
+		name := lipgloss.NewStyle().Foreground(playerColors[l.Color%len(playerColors)]).Bold(true).Render(l.Name)
+		if l.Spectator {
+			name = lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Render("📺 " + l.Name)
+		}
+		parts = append(parts, "💬 "+name+": "+l.Text)
+	}
+	if typing {
+		parts = append(parts, chatStyle.Render("💬 > "+input+"▌")+"  "+helpStyle.Render("Enter Send  •  Esc Cancel"))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// RenderNetStats renders the bandwidth panel. server is nil when not hosting.
+func RenderNetStats(client network.BandwidthStats, server *network.BandwidthStats) string {
+	label := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	lines := []string{
+		label.Render("📶 Network"),
+		fmt.Sprintf("Client ↑ %s/s  ↓ %s/s", formatBytes(client.SentPerSec), formatBytes(client.RecvPerSec)),
+	}
+	if server != nil {
+		lines = append(lines,
+			fmt.Sprintf("Server ↑ %s/s  ↓ %s/s", formatBytes(server.SentPerSec), formatBytes(server.RecvPerSec)),
+			label.Render(fmt.Sprintf("Server total ↑ %s  ↓ %s", formatBytes(server.SentTotal), formatBytes(server.RecvTotal))))
+	}
+	return hudBorderStyle.Render(strings.Join(lines, "\n"))
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// DebugInfo is the raw data shown in the --debug overlay.
+type DebugInfo struct {
+	State        *game.GameState
+	Net          network.BandwidthStats
+	RTT          time.Duration // Zero when unknown
+	Engine       *game.Metrics // The hosted game's engine; nil when not hosting
+	LastAction   string
+	LastActionAt time.Time
+	LastSeq      uint32 // Sequence number of LastAction; see game.Player.LastSeq
+	Acked        uint32 // The last sequence number the server is done with
+}
+
+// RenderPaused covers the board, which is width by height, with the paused
+// banner, so nobody can plan their next move while the clock is stopped.
+func RenderPaused(width, height int, host bool) string {
+	text := "⏸  PAUSED"
+	if host {
+		text += "\n\nPress [P] to resume"
+	}
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, pausedStyle.Render(text))
+}
+
+// RenderStepBar renders the step mode banner with the current tick.
+func RenderStepBar(state *game.GameState) string {
+	var tick uint64
+	if state != nil {
+		tick = state.Tick
+	}
+	return debugStyle.Render(fmt.Sprintf("⏸ STEP MODE  tick %d  •  . Step one tick", tick))
+}
+
+// RenderDebug renders the state inspection overlay used for bug reports.
+func RenderDebug(info DebugInfo) string {
+	lines := []string{"DEBUG (F3)"}
+	if st := info.State; st != nil {
+		lines = append(lines,
+			fmt.Sprintf("tick %d  status %d  board %dx%d", st.Tick, st.Status, st.Width, st.Height),
+			fmt.Sprintf("players %d  bombs %d  fires %d  enemies %d  pickups %d",
+				len(st.Players), len(st.Bombs), len(st.Fires), len(st.Enemies), len(st.Pickups)))
+	} else {
+		lines = append(lines, "no state yet")
+	}
+
+	rtt := "n/a"
+	if info.RTT > 0 {
+		rtt = info.RTT.Round(time.Millisecond).String()
+	}
+	lines = append(lines, fmt.Sprintf("rtt %s  ↑ %s/s  ↓ %s/s", rtt,
+		formatBytes(info.Net.SentPerSec), formatBytes(info.Net.RecvPerSec)))
+
+	if m := info.Engine; m != nil {
+		lines = append(lines, fmt.Sprintf("tick %s  mean %s  max %s  actions %d/tick  dropped %d  throttled %d",
+			m.LastTick.Round(time.Microsecond), m.MeanTick().Round(time.Microsecond),
+			m.MaxTick.Round(time.Microsecond), m.LastActions, m.DroppedActions, m.ThrottledActions))
+	}
+
+	last := "none"
+	if info.LastAction != "" {
+		last = fmt.Sprintf("%s (%s ago, seq %d, acked %d)", info.LastAction,
+			time.Since(info.LastActionAt).Round(time.Millisecond), info.LastSeq, info.Acked)
+	}
+	lines = append(lines, "last action "+last)
+
+	return debugStyle.Render(strings.Join(lines, "\n"))
+}
+
+// renderHearts shows the hearts a player has left out of hearts, as full
+// and empty hearts.
+func renderHearts(hp, hearts int) string {
+	hp = min(max(hp, 0), hearts)
+	return heartStyle.Render(strings.Repeat("♥", hp)) + helpStyle.Render(strings.Repeat("♡", hearts-hp))
+}
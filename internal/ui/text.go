@@ -0,0 +1,253 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/amalg/go-bomberman/internal/game"
+	"github.com/amalg/go-bomberman/internal/network"
+)
+
+// textGlyphs are the plain characters the text client draws each cell with.
+var textGlyphs = map[cellKind]byte{
+	cellEmpty:          '.',
+	cellHardWall:       '#',
+	cellSoftWall:       '+',
+	cellBarrel:         'O',
+	cellExit:           'D',
+	cellIce:            '~',
+	cellTrap:           'T',
+	cellCrackedWall:    '%',
+	cellCrackedWallHit: '&',
+	cellFog:            ' ',
+	cellHill:           ':',
+	cellZoneEdge:       ',',
+	cellPickupBomb:     'b',
+	cellPickupRange:    'r',
+	cellPickupAmmo:     'a',
+	cellPickupSpeed:    's',
+	cellPickupKick:     'k',
+	cellPickupShield:   'h',
+	cellPickupSkull:    '?',
+	cellPickupFullFire: 'f',
+	cellPickupLineBomb: 'l',
+	cellPickupMine:     'm',
+	cellPickupCloak:    'i',
+	cellBomb:           'o',
+	cellMine:           '^',
+	cellFire:           '*',
+	cellLava:           '=',
+	cellWarning:        '!',
+	cellEnemy:          'E',
+	cellBoss:           'X',
+	cellBossWeak:       'W',
+	cellSelf:           '@',
+}
+
+// RenderText renders the board and status as plain ASCII, one character per
+// cell, for terminals and scripts that can't use the TUI. Other players are
+// drawn as their number.
+func RenderText(state *game.GameState, myID string) string {
+	if state == nil || len(state.Board) == 0 {
+		return "Waiting for game state..."
+	}
+
+	cells := indexCells(state)
+	var b strings.Builder
+	for y := 0; y < state.Height; y++ {
+		for x := 0; x < state.Width; x++ {
+			k := cells.classify(state.Board[y][x], game.Position{X: x, Y: y}, myID)
+			if k.kind == cellPlayer {
+				b.WriteByte('1' + byte(k.color%9))
+			} else {
+				b.WriteByte(textGlyphs[k.kind])
+			}
+		}
+		b.WriteByte('\n')
+	}
+
+	b.WriteString(textStatus(state))
+	b.WriteByte('\n')
+
+	players := make([]*game.Player, 0, len(state.Players))
+	for _, p := range state.Players {
+		players = append(players, p)
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].Color < players[j].Color })
+	for _, p := range players {
+		mark := fmt.Sprintf("%d", p.Color+1)
+		if p.ID == myID {
+			mark = "@"
+		}
+		life := "alive"
+		if !p.StunnedUntil.IsZero() {
+			life = "stunned"
+		}
+		if !p.Alive {
+			life = "dead"
+			if !p.RespawnAt.IsZero() {
+				life = "respawning"
+			}
+		}
+		fmt.Fprintf(&b, "%s %s %s bombs %d range %d speed %d", mark, p.Name, life, p.BombMax-p.BombsUsed, p.BombRange, p.MoveSpeed)
+		if p.TeamID != 0 {
+			fmt.Fprintf(&b, " team %d", p.TeamID)
+		}
+		if state.Hill != nil {
+			fmt.Fprintf(&b, " hill %d", p.HillPoints)
+		}
+		if state.Round > 1 || state.Status == game.StatusIntermission {
+			fmt.Fprintf(&b, " rounds %d", p.RoundScore)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func textStatus(state *game.GameState) string {
+	switch state.Status {
+	case game.StatusLobby:
+		return "LOBBY: press Enter to start"
+	case game.StatusCountdown:
+		return "STARTING..."
+	case game.StatusRunning:
+		if state.SuddenDeath {
+			return "SUDDEN DEATH"
+		}
+		status := "RUNNING"
+		if b := state.Boss; b != nil && b.Alive {
+			status = fmt.Sprintf("RUNNING: boss %d/%d HP", b.HP, b.MaxHP)
+		} else if state.Level > 0 {
+			status = fmt.Sprintf("RUNNING: level %d, %d monsters left", state.Level, aliveEnemies(state))
+		} else if state.PvE {
+			status = fmt.Sprintf("RUNNING: %d monsters left", aliveEnemies(state))
+		}
+		if left := state.TimeLeft; left > 0 {
+			status += " (" + formatClock(left) + " left)"
+		}
+		if z := state.Zone; z != nil && z.ShrinkIn > 0 {
+			status += " (zone closes in " + formatClock(z.ShrinkIn) + ")"
+		}
+		return status
+	case game.StatusPaused:
+		return "PAUSED"
+	case game.StatusIntermission:
+		if p, ok := state.Players[state.Winner]; ok {
+			return fmt.Sprintf("ROUND %d OVER: %s takes the round", state.Round, p.Name)
+		}
+		if state.WinningTeam != 0 {
+			return fmt.Sprintf("ROUND %d OVER: team %d takes the round", state.Round, state.WinningTeam)
+		}
+		return fmt.Sprintf("ROUND %d OVER: draw", state.Round)
+	case game.StatusLevelComplete:
+		return fmt.Sprintf("LEVEL %d CLEAR", state.Level)
+	default:
+		if state.Level > 0 {
+			if game.CampaignWon(state) {
+				return "OVER: campaign complete"
+			}
+			return fmt.Sprintf("OVER: game over on level %d", state.Level)
+		}
+		if b := state.Boss; b != nil {
+			if !b.Alive {
+				return "OVER: boss defeated"
+			}
+			return "OVER: the boss wins"
+		}
+		if state.PvE {
+			if !monstersLeft(state) {
+				return "OVER: monsters cleared"
+			}
+			return "OVER: the monsters win"
+		}
+		if p, ok := state.Players[state.Winner]; ok {
+			return "OVER: " + p.Name + " wins"
+		}
+		if state.WinningTeam != 0 {
+			return fmt.Sprintf("OVER: team %d wins", state.WinningTeam)
+		}
+		return "OVER: draw"
+	}
+}
+
+// RunText plays through client with plain-text output and single-key input:
+// w/a/s/d to move, space for a bomb, e to sprint, t to switch team, Enter
+// to start, q to quit.
+// A frame is written whenever the picture changes. With crlf set, lines end
+// in "\r\n" for terminals in raw mode.
+func RunText(client *network.Client, in io.Reader, out io.Writer, crlf bool) error {
+	quit := make(chan struct{})
+	go func() {
+		defer close(quit)
+		r := bufio.NewReader(in)
+		for {
+			key, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			switch key {
+			case 'w':
+				client.SendAction(game.ActionMove, game.DirUp)
+			case 's':
+				client.SendAction(game.ActionMove, game.DirDown)
+			case 'a':
+				client.SendAction(game.ActionMove, game.DirLeft)
+			case 'd':
+				client.SendAction(game.ActionMove, game.DirRight)
+			case ' ':
+				client.SendAction(game.ActionPlaceBomb, 0)
+			case 'e':
+				client.SendAction(game.ActionSprint, 0)
+			case 'f':
+				client.SendAction(game.ActionLineBomb, 0)
+			case 't':
+				client.SendSwitchTeam()
+			case '\r', '\n':
+				client.SendStart()
+			case 'q', 3: // 3 is Ctrl+C in raw mode
+				return
+			}
+		}
+	}()
+
+	var last string
+	reconnecting := client.ReconnectChan()
+	for {
+		select {
+		case <-quit:
+			return nil
+		case lost, ok := <-reconnecting:
+			if !ok {
+				reconnecting = nil
+				continue
+			}
+			if lost {
+				line := "Connection lost. Reconnecting…\n"
+				if crlf {
+					line = strings.ReplaceAll(line, "\n", "\r\n")
+				}
+				io.WriteString(out, line)
+				last = "" // Draw the next frame even if nothing changed
+			}
+		case state, ok := <-client.StateChan():
+			if !ok {
+				return fmt.Errorf("server connection closed")
+			}
+			frame := RenderText(&state, client.PlayerID())
+			if frame == last {
+				continue
+			}
+			last = frame
+			frame += "\n"
+			if crlf {
+				frame = strings.ReplaceAll(frame, "\n", "\r\n")
+			}
+			if _, err := io.WriteString(out, frame); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package bomberman embeds the game engine behind the go-bomberman server, for
+// bots, alternative frontends and simulations that don't want to fork the repo.
+//
+// An Engine is driven either in real time by Run, as the server does, or
+// headless by Step and RunFor, which run the game as fast as the caller
+// likes. Either way players join while the game is in the lobby, Start begins
+// the match, and Do queues the same actions a network client would send:
+//
+//	engine := bomberman.New(bomberman.DefaultConfig())
+//	engine.AddPlayer("p1", "Alice")
+//	engine.AddPlayer("p2", "Bob")
+//	engine.Start()
+//	engine.Do(bomberman.Action{PlayerID: "p1", Type: bomberman.ActionPlaceBomb})
+//	engine.RunFor(5 * time.Second)
+//	state := engine.State()
+//
+// The functions and methods in this package are kept stable across releases,
+// while the engine behind them lives in an internal package and may change
+// freely. The types are another matter: they are the engine's own, the ones
+// the wire protocol carries, so their fields change as the game and the
+// protocol do (see the server's protocol version).
+package bomberman
+
+import (
+	"time"
+
+	"github.com/amalg/go-bomberman/internal/game"
+)
+
+// Engine runs one game. Its methods are safe for concurrent use.
+type Engine struct {
+	e *game.Engine
+}
+
+// New creates an engine in the lobby with the given config.
+func New(config Config) *Engine {
+	return &Engine{e: game.NewEngine(config)}
+}
+
+// Load resumes a match saved by Engine.Save. Players reclaim their saved
+// characters by joining with the same IDs before Start.
+func Load(path string) (*Engine, error) {
+	e, err := game.LoadSave(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{e: e}, nil
+}
+
+// DefaultConfig returns the config the server plays with by default.
+func DefaultConfig() Config {
+	return game.DefaultConfig()
+}
+
+// LoadConfig reads a JSON config file like the server's --config. Fields
+// missing from the file keep their DefaultConfig values.
+func LoadConfig(path string) (Config, error) {
+	return game.LoadConfig(path)
+}
+
+// AddPlayer adds a player to the lobby. It fails once the match has started
+// or the game is full.
+func (g *Engine) AddPlayer(id, name string) error {
+	return g.e.AddPlayer(id, name)
+}
+
+// RemovePlayer removes a player from the game.
+func (g *Engine) RemovePlayer(id string) {
+	g.e.RemovePlayer(id)
+}
+
+// Start moves the game from the lobby straight into the match, without a
+// countdown.
+func (g *Engine) Start() error {
+	return g.e.StartGame()
+}
+
+// Do queues a player's action for the next tick. Actions are dropped if the
+// queue is full.
+func (g *Engine) Do(a Action) {
+	g.e.EnqueueAction(a)
+}
+
+// Step runs n ticks of the game right away, calling the OnTick and OnEvent
+// callbacks after each one. Step must not be used while Run is going.
+func (g *Engine) Step(n int) {
+	g.e.Step(n)
+}
+
+// RunFor runs d of game time right away, rounded down to whole ticks. See
+// Step.
+func (g *Engine) RunFor(d time.Duration) {
+	g.e.RunFor(d)
+}
+
+// Run runs the game in real time at Config.TickRate until Stop is called.
+func (g *Engine) Run() {
+	g.e.Run()
+}
+
+// Stop ends Run. An engine can't be run again once stopped.
+func (g *Engine) Stop() {
+	g.e.Stop()
+}
+
+// Pause freezes a running match; Resume picks it up where it left off.
+func (g *Engine) Pause() error {
+	return g.e.Pause()
+}
+
+// Resume continues a paused match.
+func (g *Engine) Resume() error {
+	return g.e.Resume()
+}
+
+// Save writes the match in progress to path, for Load.
+func (g *Engine) Save(path string) error {
+	return g.e.Save(path)
+}
+
+// Snapshot returns everything the engine holds as JSON, for Restore. Unlike
+// Save it works in any status and restores exactly, randomness included.
+func (g *Engine) Snapshot() ([]byte, error) {
+	return g.e.Snapshot()
+}
+
+// Restore replaces the game with one from Snapshot.
+func (g *Engine) Restore(data []byte) error {
+	return g.e.Restore(data)
+}
+
+// State returns a copy of the game state, which the caller may keep and
+// modify.
+func (g *Engine) State() State {
+	return g.e.GetStateCopy()
+}
+
+// Config returns a copy of the engine's config.
+func (g *Engine) Config() Config {
+	return g.e.GetConfig()
+}
+
+// Metrics returns how long ticks take and how many actions were handled and
+// dropped, for watching a simulation keep up.
+func (g *Engine) Metrics() Metrics {
+	return g.e.Metrics()
+}
+
+// OnTick calls fn after every tick with a copy of the state. fn is called
+// outside the engine's lock, so it may call back into the engine. Must be
+// called before the game is driven.
+func (g *Engine) OnTick(fn func(State)) {
+	g.e.OnTick(fn)
+}
+
+// OnEvent calls fn for everything that happens from now on, in order, after
+// the tick it happened on. Like OnTick, fn may call back into the engine and
+// must be registered before the game is driven.
+func (g *Engine) OnEvent(fn func(Event)) {
+	g.e.OnEvent(fn)
+}
@@ -0,0 +1,65 @@
+package bomberman_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/amalg/go-bomberman/pkg/bomberman"
+)
+
+// A headless match: no network, no terminal, and no waiting on the clock.
+func Example() {
+	config := bomberman.DefaultConfig()
+	config.Seed = 1
+	config.EnemyCount = 0
+	engine := bomberman.New(config)
+	engine.AddPlayer("p1", "Alice")
+	engine.AddPlayer("p2", "Bob")
+	engine.OnEvent(func(ev bomberman.Event) {
+		if ev.Type == bomberman.EventBombExploded {
+			fmt.Printf("%s's bomb went off\n", ev.ByID)
+		}
+	})
+	engine.Start()
+
+	engine.Do(bomberman.Action{PlayerID: "p1", Type: bomberman.ActionPlaceBomb})
+	engine.RunFor(config.BombTimer + time.Second)
+	fmt.Println(engine.State().Players["p1"].Alive)
+	// Output:
+	// p1's bomb went off
+	// false
+}
+
+func TestStateIsACopy(t *testing.T) {
+	engine := bomberman.New(bomberman.DefaultConfig())
+	if err := engine.AddPlayer("p1", "Alice"); err != nil {
+		t.Fatal(err)
+	}
+	state := engine.State()
+	state.Players["p1"].Alive = false
+	if !engine.State().Players["p1"].Alive {
+		t.Error("changing a copy of the state changed the game")
+	}
+}
+
+func TestConfigWhileRestoring(t *testing.T) {
+	engine := bomberman.New(bomberman.DefaultConfig())
+	snap, err := engine.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			engine.Restore(snap)
+		}
+	}()
+	for range 100 {
+		if engine.Config().Width == 0 {
+			t.Error("expected the config whole while restoring")
+		}
+	}
+	<-done
+}
@@ -0,0 +1,102 @@
+package bomberman
+
+import "github.com/amalg/go-bomberman/internal/game"
+
+// The game's types. They are the ones the engine and the wire protocol use,
+// so states and actions pass between this package, the server and its
+// clients unchanged, and their fields aren't covered by the package's
+// stability promise.
+type (
+	Config     = game.GameConfig
+	State      = game.GameState
+	Status     = game.GameStatus
+	Player     = game.Player
+	Position   = game.Position
+	Tile       = game.TileType
+	Direction  = game.Direction
+	Action     = game.Action
+	ActionType = game.ActionType
+	Bomb       = game.Bomb
+	Fire       = game.Fire
+	Enemy      = game.Enemy
+	Boss       = game.Boss
+	Pickup     = game.Pickup
+	PickupType = game.PickupType
+	Event      = game.Event
+	EventType  = game.EventType
+	Metrics    = game.Metrics
+)
+
+// Board tiles.
+const (
+	Empty          = game.Empty
+	HardWall       = game.HardWall
+	SoftWall       = game.SoftWall
+	Barrel         = game.Barrel
+	ExitDoor       = game.ExitDoor
+	Ice            = game.Ice
+	CrackedWall    = game.CrackedWall
+	CrackedWallHit = game.CrackedWallHit
+	Fog            = game.Fog
+	Trap           = game.Trap
+)
+
+// Directions.
+const (
+	Up    = game.DirUp
+	Down  = game.DirDown
+	Left  = game.DirLeft
+	Right = game.DirRight
+)
+
+// Actions.
+const (
+	ActionMove      = game.ActionMove
+	ActionPlaceBomb = game.ActionPlaceBomb
+	ActionSprint    = game.ActionSprint
+	ActionLineBomb  = game.ActionLineBomb
+)
+
+// Game statuses.
+const (
+	StatusLobby         = game.StatusLobby
+	StatusRunning       = game.StatusRunning
+	StatusOver          = game.StatusOver
+	StatusCountdown     = game.StatusCountdown
+	StatusIntermission  = game.StatusIntermission
+	StatusLevelComplete = game.StatusLevelComplete
+	StatusPaused        = game.StatusPaused
+)
+
+// Pickups.
+const (
+	PickupBomb     = game.PickupBomb
+	PickupRange    = game.PickupRange
+	PickupAmmo     = game.PickupAmmo
+	PickupSpeed    = game.PickupSpeed
+	PickupKick     = game.PickupKick
+	PickupShield   = game.PickupShield
+	PickupSkull    = game.PickupSkull
+	PickupFullFire = game.PickupFullFire
+	PickupLineBomb = game.PickupLineBomb
+	PickupMine     = game.PickupMine
+	PickupCloak    = game.PickupCloak
+)
+
+// Events.
+const (
+	EventPlayerKilled  = game.EventPlayerKilled
+	EventBombExploded  = game.EventBombExploded
+	EventWallDestroyed = game.EventWallDestroyed
+	EventItemPickedUp  = game.EventItemPickedUp
+	EventGameOver      = game.EventGameOver
+)
+
+// BossSize is the width and height of the boss in tiles, from Boss.Pos.
+const BossSize = game.BossSize
+
+// SpawnPositions returns where players spawn on a board of the given size, in
+// the order they join.
+func SpawnPositions(width, height int) []Position {
+	return game.SpawnPositions(width, height)
+}
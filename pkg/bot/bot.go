@@ -0,0 +1,43 @@
+// Package bot is a toolkit for computer players: the Bot interface they
+// implement, and a World that answers the questions every bot asks of a
+// state — where it's dangerous, how to get somewhere, and where to run to.
+//
+// The server's own bots are built on it, and so can bots that play over the
+// network or drive a bomberman.Engine directly.
+package bot
+
+import "github.com/amalg/go-bomberman/pkg/bomberman"
+
+// Bot plays as one player.
+type Bot interface {
+	// Observe is called with every state the bot's player sees, after
+	// fog of war and invisibility, and returns what the player does about
+	// it. ok is false when the player does nothing this tick.
+	Observe(state *bomberman.State) (a bomberman.Action, ok bool)
+}
+
+// Func adapts a function to the Bot interface.
+type Func func(state *bomberman.State) (bomberman.Action, bool)
+
+// Observe calls f.
+func (f Func) Observe(state *bomberman.State) (bomberman.Action, bool) {
+	return f(state)
+}
+
+// Dirs lists every direction.
+var Dirs = []bomberman.Direction{bomberman.Up, bomberman.Down, bomberman.Left, bomberman.Right}
+
+// Step returns the tile next to pos in direction d.
+func Step(pos bomberman.Position, d bomberman.Direction) bomberman.Position {
+	switch d {
+	case bomberman.Up:
+		pos.Y--
+	case bomberman.Down:
+		pos.Y++
+	case bomberman.Left:
+		pos.X--
+	case bomberman.Right:
+		pos.X++
+	}
+	return pos
+}
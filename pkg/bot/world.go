@@ -0,0 +1,215 @@
+package bot
+
+import "github.com/amalg/go-bomberman/pkg/bomberman"
+
+// Position is shorthand for bomberman.Position.
+type Position = bomberman.Position
+
+// World is a bot's view of one state: what blocks it, what can hurt it and
+// what it might want. It is built once per state and must not outlive it.
+type World struct {
+	State   *bomberman.State
+	Me      *bomberman.Player
+	Bombs   map[Position]bool
+	Fires   map[Position]bool
+	Enemies map[Position]bool
+	Pickups map[Position]bool
+	Players map[Position]bool // Other players, who block the way like walls
+
+	// Danger holds fire, every bomb's blast zone however long its fuse,
+	// boss warnings, enemies and spike traps.
+	Danger map[Position]bool
+}
+
+// NewWorld builds the world as seen by me in state.
+func NewWorld(state *bomberman.State, me *bomberman.Player) *World {
+	w := &World{
+		State:   state,
+		Me:      me,
+		Bombs:   make(map[Position]bool, len(state.Bombs)),
+		Fires:   make(map[Position]bool, len(state.Fires)),
+		Enemies: make(map[Position]bool, len(state.Enemies)),
+		Pickups: make(map[Position]bool, len(state.Pickups)),
+		Players: make(map[Position]bool, len(state.Players)),
+		Danger:  make(map[Position]bool),
+	}
+	for _, p := range state.Players {
+		if p.Alive && p.ID != me.ID {
+			w.Players[p.Pos] = true
+		}
+	}
+	for _, b := range state.Bombs {
+		w.Bombs[b.Pos] = true
+	}
+	for _, f := range state.Fires {
+		w.Fires[f.Pos] = true
+		w.Danger[f.Pos] = true
+	}
+	for _, en := range state.Enemies {
+		if en.Alive {
+			w.Enemies[en.Pos] = true
+			w.Danger[en.Pos] = true
+		}
+	}
+	for _, pk := range state.Pickups {
+		w.Pickups[pk.Pos] = true
+	}
+	for y, row := range state.Board {
+		for x, tile := range row {
+			if tile == bomberman.Trap {
+				w.Danger[Position{X: x, Y: y}] = true
+			}
+		}
+	}
+	if boss := state.Boss; boss != nil && boss.Alive {
+		for _, pos := range boss.Warning {
+			w.Danger[pos] = true
+		}
+	}
+	for _, b := range state.Bombs {
+		for _, pos := range w.Blast(b.Pos, b.Range, b.FullFire) {
+			w.Danger[pos] = true
+		}
+	}
+	return w
+}
+
+// Blast returns the tiles a bomb at pos with the given range would burn.
+// A full-fire bomb burns on through soft walls and barrels.
+func (w *World) Blast(pos Position, rng int, fullFire bool) []Position {
+	tiles := []Position{pos}
+	for _, d := range Dirs {
+		p := pos
+		for dist := 1; dist <= rng; dist++ {
+			p = Step(p, d)
+			if !w.InBounds(p) {
+				break
+			}
+			tile := w.State.Board[p.Y][p.X]
+			if tile == bomberman.HardWall || w.BossCovers(p) {
+				break
+			}
+			tiles = append(tiles, p)
+			if tile.Solid() && !fullFire {
+				break
+			}
+		}
+	}
+	return tiles
+}
+
+// Safe reports whether pos is out of harm's way.
+func (w *World) Safe(pos Position) bool {
+	return !w.Danger[pos]
+}
+
+// Path searches breadth-first from start for the nearest tile satisfying
+// goal, at most maxSteps away, and returns the way there, one direction per
+// tile. Fire, enemies and anything solid are never entered; other dangerous
+// tiles are only crossed when throughDanger is set, as when fleeing. start
+// itself is never the goal.
+func (w *World) Path(start Position, goal func(Position) bool, throughDanger bool, maxSteps int) ([]bomberman.Direction, bool) {
+	type node struct {
+		pos  Position
+		from int // Index in nodes of the tile before; -1 for start's neighbours
+		dir  bomberman.Direction
+		dist int
+	}
+	seen := map[Position]bool{start: true}
+	var nodes []node
+	for _, d := range w.OpenDirs(start) {
+		next := Step(start, d)
+		seen[next] = true
+		nodes = append(nodes, node{next, -1, d, 1})
+	}
+	for i := 0; i < len(nodes); i++ {
+		n := nodes[i]
+		if !throughDanger && w.Danger[n.pos] {
+			continue
+		}
+		if goal(n.pos) {
+			path := make([]bomberman.Direction, n.dist)
+			for j := i; j >= 0; j = nodes[j].from {
+				path[nodes[j].dist-1] = nodes[j].dir
+			}
+			return path, true
+		}
+		if n.dist >= maxSteps {
+			continue
+		}
+		for _, d := range w.OpenDirs(n.pos) {
+			next := Step(n.pos, d)
+			if !seen[next] {
+				seen[next] = true
+				nodes = append(nodes, node{next, i, d, n.dist + 1})
+			}
+		}
+	}
+	return nil, false
+}
+
+// NearestSafe returns the way to the nearest tile out of danger, at most
+// maxSteps away, crossing danger to get there. The way is empty when start
+// is already safe.
+func (w *World) NearestSafe(start Position, maxSteps int) ([]bomberman.Direction, bool) {
+	if w.Safe(start) {
+		return nil, true
+	}
+	return w.Path(start, w.Safe, true, maxSteps)
+}
+
+// CanEscape reports whether, after placing a bomb with the given range at
+// pos, there is a safe tile within maxSteps. A player with full fire is
+// assumed to place a full-fire bomb.
+func (w *World) CanEscape(pos Position, rng, maxSteps int) bool {
+	danger := make(map[Position]bool, len(w.Danger))
+	for p := range w.Danger {
+		danger[p] = true
+	}
+	fullFire := w.Me.FullFire
+	if fullFire {
+		rng = max(w.State.Width, w.State.Height)
+	}
+	for _, p := range w.Blast(pos, rng, fullFire) {
+		danger[p] = true
+	}
+	_, ok := w.Path(pos, func(p Position) bool { return !danger[p] }, true, maxSteps)
+	return ok
+}
+
+// OpponentAt reports whether an alive player the bot is playing against
+// stands at pos.
+func (w *World) OpponentAt(pos Position) bool {
+	for _, p := range w.State.Players {
+		if p.Alive && p.ID != w.Me.ID && p.Pos == pos && (p.TeamID == 0 || p.TeamID != w.Me.TeamID) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenDirs returns the directions a player at pos can walk in without being
+// blocked, by other players too, or walking into fire or an enemy.
+func (w *World) OpenDirs(pos Position) []bomberman.Direction {
+	var dirs []bomberman.Direction
+	for _, d := range Dirs {
+		p := Step(pos, d)
+		if w.InBounds(p) && !w.State.Board[p.Y][p.X].Solid() && !w.Bombs[p] &&
+			!w.Fires[p] && !w.Enemies[p] && !w.Players[p] && !w.BossCovers(p) {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// InBounds reports whether p is on the board.
+func (w *World) InBounds(p Position) bool {
+	return p.X >= 0 && p.X < w.State.Width && p.Y >= 0 && p.Y < w.State.Height
+}
+
+// BossCovers reports whether the boss stands on p.
+func (w *World) BossCovers(p Position) bool {
+	b := w.State.Boss
+	return b != nil && b.Alive && p.X >= b.Pos.X && p.X < b.Pos.X+bomberman.BossSize &&
+		p.Y >= b.Pos.Y && p.Y < b.Pos.Y+bomberman.BossSize
+}
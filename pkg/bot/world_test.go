@@ -0,0 +1,85 @@
+package bot_test
+
+import (
+	"testing"
+
+	"github.com/amalg/go-bomberman/pkg/bomberman"
+	"github.com/amalg/go-bomberman/pkg/bot"
+)
+
+// newWorld returns the world of a player at pos on an open board.
+func newWorld(t *testing.T, pos bomberman.Position, bombs ...*bomberman.Bomb) *bot.World {
+	t.Helper()
+	config := bomberman.DefaultConfig()
+	config.SoftWallDensity = 0
+	config.EnemyCount = 0
+	engine := bomberman.New(config)
+	if err := engine.AddPlayer("me", "Me"); err != nil {
+		t.Fatal(err)
+	}
+	state := engine.State()
+	me := state.Players["me"]
+	me.Pos = pos
+	state.Bombs = bombs
+	return bot.NewWorld(&state, me)
+}
+
+func TestBlastStopsAtWalls(t *testing.T) {
+	w := newWorld(t, bomberman.Position{X: 1, Y: 1})
+	w.State.Board[1][3] = bomberman.SoftWall
+
+	got := make(map[bomberman.Position]bool)
+	for _, p := range w.Blast(bomberman.Position{X: 1, Y: 1}, 3, false) {
+		got[p] = true
+	}
+	// Hard walls stop it before, soft walls burn and stop it after
+	for _, p := range []bomberman.Position{{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 3, Y: 1}, {X: 1, Y: 2}, {X: 1, Y: 4}} {
+		if !got[p] {
+			t.Errorf("expected %v in the blast", p)
+		}
+	}
+	for _, p := range []bomberman.Position{{X: 4, Y: 1}, {X: 0, Y: 1}, {X: 1, Y: 0}} {
+		if got[p] {
+			t.Errorf("expected %v out of the blast", p)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	w := newWorld(t, bomberman.Position{X: 1, Y: 1})
+	goal := bomberman.Position{X: 3, Y: 3}
+
+	path, ok := w.Path(w.Me.Pos, func(p bomberman.Position) bool { return p == goal }, false, 10)
+	if !ok || len(path) != 4 {
+		t.Fatalf("expected a 4-tile path around the pillar, got %v", path)
+	}
+	pos := w.Me.Pos
+	for _, d := range path {
+		pos = bot.Step(pos, d)
+	}
+	if pos != goal {
+		t.Errorf("expected the path to end at %v, got %v", goal, pos)
+	}
+
+	if _, ok := w.Path(w.Me.Pos, func(p bomberman.Position) bool { return p == goal }, false, 3); ok {
+		t.Error("expected no path within 3 tiles")
+	}
+}
+
+func TestNearestSafe(t *testing.T) {
+	w := newWorld(t, bomberman.Position{X: 1, Y: 1},
+		&bomberman.Bomb{OwnerID: "me", Pos: bomberman.Position{X: 1, Y: 1}, Range: 2})
+	if w.Safe(w.Me.Pos) {
+		t.Fatal("expected standing on a bomb to be dangerous")
+	}
+
+	path, ok := w.NearestSafe(w.Me.Pos, 10)
+	if !ok || len(path) != 3 {
+		t.Fatalf("expected to outrun the blast in 3 tiles, got %v", path)
+	}
+
+	w.Danger = map[bomberman.Position]bool{}
+	if path, ok := w.NearestSafe(w.Me.Pos, 10); !ok || len(path) != 0 {
+		t.Errorf("expected no need to move from a safe tile, got %v", path)
+	}
+}
@@ -0,0 +1,585 @@
+# Code generated by protogen from internal/network. DO NOT EDIT.
+"""Typed bindings for the bomberman wire protocol, version 2.
+
+Payloads are plain dicts typed with TypedDict; use encode() to frame a message
+for sending and decode() to read one from a socket file (sock.makefile("rb")).
+"""
+
+from __future__ import annotations
+
+import json
+import struct
+from enum import IntEnum, StrEnum
+from typing import Any, BinaryIO, NotRequired, TypedDict
+
+PROTOCOL_VERSION = 2
+MAX_MESSAGE_SIZE = 1 << 20
+
+
+class ActionType(IntEnum):
+    MOVE = 0
+    PLACE_BOMB = 1
+    SPRINT = 2
+    LINE_BOMB = 3
+
+
+class BossAttack(IntEnum):
+    IDLE = 0
+    FIRE_SWEEP = 1
+    BOMBS = 2
+
+
+class Compression(StrEnum):
+    GZIP = "gzip"
+    SNAPPY = "snappy"
+
+
+class Direction(IntEnum):
+    UP = 0
+    DOWN = 1
+    LEFT = 2
+    RIGHT = 3
+
+
+class Effect(IntEnum):
+    REVERSE = 0
+    AUTO_BOMB = 1
+    SHORT_RANGE = 2
+
+
+class Encoding(StrEnum):
+    JSON = "json"
+    MSGPACK = "msgpack"
+
+
+class EventType(StrEnum):
+    PLAYER_KILLED = "player_killed"
+    BOMB_EXPLODED = "bomb_exploded"
+    WALL_DESTROYED = "wall_destroyed"
+    ITEM_PICKED_UP = "item_picked_up"
+    GAME_OVER = "game_over"
+
+
+class GameStatus(IntEnum):
+    LOBBY = 0
+    RUNNING = 1
+    OVER = 2
+    COUNTDOWN = 3
+    INTERMISSION = 4
+    LEVEL_COMPLETE = 5
+    PAUSED = 6
+
+
+class Mode(StrEnum):
+    CLASSIC = "classic"
+    TEAMS = "teams"
+    KOTH = "koth"
+    BATTLE_ROYALE = "battle_royale"
+    BOSS = "boss"
+    PVE = "pve"
+    CAMPAIGN = "campaign"
+    STUN = "stun"
+
+
+class MsgType(StrEnum):
+    JOIN = "join"
+    WELCOME = "welcome"
+    ACTION = "action"
+    STATE = "state"
+    ERROR = "error"
+    START = "start"
+    SYSTEM = "system"
+    COUNTDOWN = "countdown"
+    READY_FOR_START = "ready_for_start"
+    PING = "ping"
+    PONG = "pong"
+    SWITCH_TEAM = "switch_team"
+    SET_HANDICAP = "set_handicap"
+    EVENT = "event"
+    PAUSE = "pause"
+    STATE_DELTA = "state_delta"
+    RESYNC = "resync"
+    REJOIN = "rejoin"
+    CHAT = "chat"
+    KICK = "kick"
+    BAN = "ban"
+    UDP_HELLO = "udp_hello"
+    UDP_MOVE = "udp_move"
+    COMPRESSED_STATE = "compressed_state"
+    LIST_ROOMS = "list_rooms"
+    CREATE_ROOM = "create_room"
+    JOIN_ROOM = "join_room"
+    ROOMS = "rooms"
+    LEAVE = "leave"
+
+
+class OvertimeRule(StrEnum):
+    NONE = "none"
+    LAVA = "lava"
+    BOMB_RAIN = "bomb_rain"
+
+
+class PickupType(IntEnum):
+    BOMB = 0
+    RANGE = 1
+    AMMO = 2
+    SPEED = 3
+    KICK = 4
+    SHIELD = 5
+    SKULL = 6
+    FULL_FIRE = 7
+    LINE_BOMB = 8
+    MINE = 9
+    CLOAK = 10
+
+
+class SystemKind(StrEnum):
+    MOTD = "motd"
+    ANNOUNCE = "announce"
+    KICK = "kick"
+
+
+class Tiebreaker(StrEnum):
+    DRAW = "draw"
+    BOMB_OWNER = "bomb_owner"
+    KILLS = "kills"
+    SUDDEN_DEATH = "sudden_death"
+
+
+class TileType(IntEnum):
+    EMPTY = 0
+    HARD_WALL = 1
+    SOFT_WALL = 2
+    BARREL = 3
+    EXIT_DOOR = 4
+    ICE = 5
+    CRACKED_WALL = 6
+    CRACKED_WALL_HIT = 7
+    FOG = 8
+    TRAP = 9
+
+
+class TimeUpRule(StrEnum):
+    DRAW = "draw"
+    KILLS = "kills"
+    WALLS = "walls"
+
+
+class ActionMsg(TypedDict):
+    action_type: ActionType
+    direction: NotRequired[Direction]
+    seq: NotRequired[int]
+
+
+class Bomb(TypedDict):
+    owner_id: str
+    pos: Position
+    range: int
+    placed_at: str
+    expires_tick: int
+    velocity: Position
+    full_fire: NotRequired[bool]
+    owner_on: NotRequired[bool]
+    mine: NotRequired[bool]
+    hidden_at: NotRequired[int]
+    chained: NotRequired[bool]
+    fuse_left: int
+
+
+class Boss(TypedDict):
+    pos: Position
+    alive: bool
+    hp: int
+    max_hp: int
+    weak_point: Position
+    attack: BossAttack
+    warning: NotRequired[list[Position]]
+    timer: int
+    move_timer: int
+
+
+class CellChange(TypedDict):
+    x: int
+    y: int
+    tile: TileType
+
+
+class ChatMsg(TypedDict):
+    text: str
+    player_id: NotRequired[str]
+    name: NotRequired[str]
+    color: int
+    spectator: NotRequired[bool]
+
+
+class CompressedStateMsg(TypedDict):
+    data: list[int]
+
+
+class Cosmetics(TypedDict):
+    name_color: NotRequired[str]
+    banner: NotRequired[str]
+    glyph: NotRequired[str]
+
+
+class CountdownMsg(TypedDict):
+    starts_in: int
+
+
+class Enemy(TypedDict):
+    id: str
+    pos: Position
+    alive: bool
+    dir: Direction
+    move_timer: int
+
+
+class ErrorMsg(TypedDict):
+    message: str
+
+
+class Event(TypedDict):
+    type: EventType
+    tick: int
+    player_id: NotRequired[str]
+    by_id: NotRequired[str]
+    pos: Position
+    pickup: NotRequired[PickupType]
+    winner: NotRequired[str]
+    winning_team: NotRequired[int]
+
+
+class EventMsg(TypedDict):
+    event: Event
+
+
+class Fire(TypedDict):
+    pos: Position
+    expires_tick: int
+    owner_id: NotRequired[str]
+    permanent: NotRequired[bool]
+
+
+class GameConfig(TypedDict):
+    width: int
+    height: int
+    bomb_timer: int
+    fire_duration: int
+    chain_delay: int
+    tick_rate: int
+    max_players: int
+    seed: int
+    soft_wall_density: float
+    barrel_density: float
+    ice_density: float
+    cracked_walls: float
+    trap_density: float
+    overtime: OvertimeRule
+    lava: bool
+    lava_after: int
+    fog_radius: int
+    enemy_count: int
+    lobby_return: int
+    sprint_enabled: bool
+    ammo_mode: bool
+    start_ammo: int
+    tiebreaker: Tiebreaker
+    start_countdown: int
+    boss_mode: bool
+    boss_hp: int
+    lives: int
+    hearts: int
+    rounds: int
+    match_duration: int
+    time_up: TimeUpRule
+    mode: Mode
+    team_mode: bool
+    friendly_fire: bool
+    fill_with_bots: bool
+    bot_difficulty: str
+    pve_mode: bool
+    campaign: bool
+    hill_mode: bool
+    hill_score: int
+    stun_mode: bool
+    battle_royale: bool
+    zone_shrink: int
+    start_bombs: int
+    start_range: int
+    max_bombs: int
+    max_range: int
+    max_speed: int
+    drop_table: NotRequired[dict[str, float]]
+
+
+class GameState(TypedDict):
+    board: list[list[TileType]]
+    players: dict[str, Player]
+    bombs: list[Bomb]
+    fires: list[Fire]
+    enemies: list[Enemy]
+    pickups: list[Pickup]
+    width: int
+    height: int
+    status: GameStatus
+    winner: NotRequired[str]
+    tick: int
+    round: NotRequired[int]
+    level: NotRequired[int]
+    sudden_death: NotRequired[bool]
+    boss: NotRequired[Boss]
+    time_left: NotRequired[int]
+    winning_team: NotRequired[int]
+    pve: NotRequired[bool]
+    overtime_at: str
+    hill: NotRequired[Hill]
+    zone: NotRequired[Zone]
+    spectators: NotRequired[list[Spectator]]
+
+
+class Handicap(TypedDict):
+    bombs: NotRequired[int]
+    range: NotRequired[int]
+    speed: NotRequired[int]
+
+
+class HandicapMsg(TypedDict):
+    player_id: str
+    handicap: Handicap
+
+
+class Hill(TypedDict):
+    pos: Position
+    size: int
+
+
+class JoinMsg(TypedDict):
+    name: str
+    cosmetics: NotRequired[Cosmetics]
+    deltas: NotRequired[bool]
+    encodings: NotRequired[list[Encoding]]
+    version: NotRequired[int]
+    spectate: NotRequired[bool]
+    udp: NotRequired[bool]
+    compress: NotRequired[list[Compression]]
+
+
+class JoinRoomMsg(TypedDict):
+    room: str
+    join: JoinMsg
+
+
+class KickMsg(TypedDict):
+    player_id: str
+    reason: NotRequired[str]
+
+
+class PauseMsg(TypedDict):
+    paused: bool
+
+
+class Pickup(TypedDict):
+    pos: Position
+    type: PickupType
+
+
+class PingMsg(TypedDict):
+    sent_at: int
+
+
+class Player(TypedDict):
+    id: str
+    name: str
+    pos: Position
+    alive: bool
+    bomb_max: int
+    bomb_range: int
+    bombs_used: int
+    color: int
+    cosmetics: Cosmetics
+    stamina: int
+    sprinting: bool
+    ammo: int
+    kills: int
+    killed_by: NotRequired[str]
+    died_at: NotRequired[int]
+    move_speed: int
+    can_kick: NotRequired[bool]
+    full_fire: NotRequired[bool]
+    line_bomb: NotRequired[bool]
+    mines: NotRequired[int]
+    facing: Direction
+    sliding: NotRequired[bool]
+    out_of_sight: NotRequired[bool]
+    invulnerable_until: str
+    invisible_until: str
+    stunned_until: str
+    effects: NotRequired[list[StatusEffect]]
+    lives: int
+    hp: int
+    respawn_at: str
+    round_score: int
+    team_id: NotRequired[int]
+    walls_destroyed: int
+    hill_points: int
+    handicap: Handicap
+    disconnected: NotRequired[bool]
+    rtt: NotRequired[int]
+    last_seq: NotRequired[int]
+
+
+class PongMsg(TypedDict):
+    sent_at: int
+    version: int
+
+
+class Position(TypedDict):
+    x: int
+    y: int
+
+
+class RejoinMsg(TypedDict):
+    token: str
+    deltas: NotRequired[bool]
+    encodings: NotRequired[list[Encoding]]
+    version: NotRequired[int]
+    udp: NotRequired[bool]
+    compress: NotRequired[list[Compression]]
+
+
+class RoomListMsg(TypedDict):
+    rooms: list[RoomSummary]
+
+
+class RoomSummary(TypedDict):
+    id: str
+    players: int
+    max_players: int
+    status: GameStatus
+    mode: NotRequired[Mode]
+
+
+class Spectator(TypedDict):
+    id: str
+    name: str
+
+
+class StateDeltaMsg(TypedDict):
+    base: int
+    state: GameState
+    cells: NotRequired[list[CellChange]]
+    left: NotRequired[list[str]]
+
+
+class StateMsg(TypedDict):
+    state: GameState
+
+
+class StatusEffect(TypedDict):
+    kind: Effect
+    expires_at: str
+
+
+class SystemMsg(TypedDict):
+    kind: SystemKind
+    text: str
+
+
+class UDPHelloMsg(TypedDict):
+    key: str
+
+
+class UDPMoveMsg(TypedDict):
+    key: str
+    direction: Direction
+    seq: int
+
+
+class WelcomeMsg(TypedDict):
+    player_id: str
+    config: GameConfig
+    encoding: NotRequired[Encoding]
+    version: NotRequired[int]
+    token: NotRequired[str]
+    host_id: NotRequired[str]
+    compress: NotRequired[Compression]
+    udp_key: NotRequired[str]
+    motd: NotRequired[str]
+    rules: NotRequired[str]
+
+
+class Zone(TypedDict):
+    pos: Position
+    width: int
+    height: int
+    shrink_in: NotRequired[int]
+
+
+class Envelope(TypedDict):
+    type: MsgType
+    payload: Any
+
+
+# Payload type of each message sent by the client; None is an empty object.
+CLIENT_MESSAGES: dict[MsgType, type | None] = {
+    MsgType.JOIN: JoinMsg,  # First message on a connection: join the game.
+    MsgType.REJOIN: RejoinMsg,  # First message on a connection instead of a join: reclaim a player whose connection dropped.
+    MsgType.ACTION: ActionMsg,  # Move, place a bomb or toggle sprint.
+    MsgType.START: None,  # Start the match from the lobby.
+    MsgType.READY_FOR_START: None,  # Reply to a countdown with starts_in 0.
+    MsgType.SWITCH_TEAM: None,  # Move to the other team; lobby and team mode only.
+    MsgType.SET_HANDICAP: HandicapMsg,  # Override a player's starting stats; host and lobby only.
+    MsgType.PAUSE: PauseMsg,  # Pause or resume the match; host only.
+    MsgType.KICK: KickMsg,  # Remove a player from the game; host only.
+    MsgType.BAN: KickMsg,  # Remove a player and turn their address away from then on; host only.
+    MsgType.LIST_ROOMS: None,  # May open a connection to a multi-room server instead of a join: list its rooms. May be repeated.
+    MsgType.CREATE_ROOM: JoinRoomMsg,  # First message on a connection to a multi-room server instead of a join: create a room and join it.
+    MsgType.JOIN_ROOM: JoinRoomMsg,  # First message on a connection to a multi-room server instead of a join: join one of its rooms.
+    MsgType.UDP_HELLO: UDPHelloMsg,  # Over UDP: send states here instead of over TCP; repeat every second.
+    MsgType.UDP_MOVE: UDPMoveMsg,  # Over UDP: a move, dropped if one with a higher seq came first.
+    MsgType.RESYNC: None,  # Ask for a full state, after a state_delta whose base the client doesn't have.
+    MsgType.LEAVE: None,  # Quit for good: the player is removed at once, even mid-match, and the server hangs up.
+    MsgType.PING: PingMsg,  # Ask for a pong; may open a connection instead of a join. The server pings every second; a client that answers is dropped if it goes quiet.
+    MsgType.PONG: PongMsg,  # Reply to a ping.
+    MsgType.CHAT: ChatMsg,  # Chat line; clients send the text and the server relays it to everyone with the sender filled in.
+}
+
+
+# Payload type of each message sent by the server; None is an empty object.
+SERVER_MESSAGES: dict[MsgType, type | None] = {
+    MsgType.WELCOME: WelcomeMsg,  # Reply to a join with the player's ID and the game config.
+    MsgType.STATE: StateMsg,  # Full game state, sent every tick, or every KeyframeEvery ticks to clients taking deltas.
+    MsgType.COMPRESSED_STATE: CompressedStateMsg,  # A full state, compressed, to clients whose welcome names a compression.
+    MsgType.STATE_DELTA: StateDeltaMsg,  # Game state as the changes from the last one sent; only to clients that joined with deltas.
+    MsgType.ROOMS: RoomListMsg,  # Reply to list_rooms.
+    MsgType.SYSTEM: SystemMsg,  # Server notice shown to players.
+    MsgType.EVENT: EventMsg,  # Something happened in the game, sent after the tick's state.
+    MsgType.COUNTDOWN: CountdownMsg,  # Start countdown; see CountdownMsg.
+    MsgType.ERROR: ErrorMsg,  # The request failed; a rejected join closes the connection.
+    MsgType.PING: PingMsg,  # Ask for a pong; may open a connection instead of a join. The server pings every second; a client that answers is dropped if it goes quiet.
+    MsgType.PONG: PongMsg,  # Reply to a ping.
+    MsgType.CHAT: ChatMsg,  # Chat line; clients send the text and the server relays it to everyone with the sender filled in.
+}
+
+
+def encode(msg_type: MsgType, payload: Any = None) -> bytes:
+    """Frames a message: a 4-byte big-endian length, then the JSON envelope."""
+    body = json.dumps({"type": msg_type, "payload": {} if payload is None else payload}).encode()
+    return struct.pack(">I", len(body)) + body
+
+
+def decode(stream: BinaryIO) -> Envelope:
+    """Reads one framed message from stream; raises EOFError when it closes."""
+    (length,) = struct.unpack(">I", _read_exactly(stream, 4))
+    if length > MAX_MESSAGE_SIZE:
+        raise ValueError(f"message too large: {length} bytes")
+    env = json.loads(_read_exactly(stream, length))
+    env["type"] = MsgType(env["type"])
+    return env
+
+
+def _read_exactly(stream: BinaryIO, n: int) -> bytes:
+    data = stream.read(n)
+    if data is None or len(data) < n:
+        raise EOFError("connection closed")
+    return data
@@ -0,0 +1,95 @@
+#!/usr/bin/env python3
+"""Starter bot: joins a room, starts the match and wanders, dropping bombs.
+
+    python3 bot.py 192.168.1.20:9999 [name]
+"""
+
+import random
+import socket
+import sys
+import threading
+import time
+
+from bomberman_protocol import (
+    PROTOCOL_VERSION,
+    ActionType,
+    CountdownMsg,
+    Direction,
+    GameState,
+    GameStatus,
+    MsgType,
+    TileType,
+    decode,
+    encode,
+)
+
+STEPS = {
+    Direction.UP: (0, -1),
+    Direction.DOWN: (0, 1),
+    Direction.LEFT: (-1, 0),
+    Direction.RIGHT: (1, 0),
+}
+
+
+def choose(state: GameState, me: str) -> bytes | None:
+    """Picks the bot's next action from the latest state."""
+    player = state["players"].get(me)
+    if state["status"] != GameStatus.RUNNING or not player or not player["alive"]:
+        return None
+    if random.random() < 0.05:
+        return encode(MsgType.ACTION, {"action_type": ActionType.PLACE_BOMB})
+    x, y = player["pos"]["x"], player["pos"]["y"]
+    open_dirs = [
+        d for d, (dx, dy) in STEPS.items()
+        if state["board"][y + dy][x + dx] == TileType.EMPTY
+    ]
+    if not open_dirs:
+        return None
+    return encode(MsgType.ACTION, {"action_type": ActionType.MOVE, "direction": random.choice(open_dirs)})
+
+
+def main() -> None:
+    host, port = sys.argv[1].rsplit(":", 1)
+    name = sys.argv[2] if len(sys.argv) > 2 else "pybot"
+    sock = socket.create_connection((host, int(port)))
+    stream = sock.makefile("rb")
+    send_lock = threading.Lock()
+
+    def send(frame: bytes) -> None:
+        with send_lock:
+            sock.sendall(frame)
+
+    send(encode(MsgType.JOIN, {"name": name, "version": PROTOCOL_VERSION}))
+    welcome = decode(stream)
+    if welcome["type"] != MsgType.WELCOME:
+        sys.exit(f"join failed: {welcome['payload']}")
+    me = welcome["payload"]["player_id"]
+    print(f"joined as {me}")
+
+    latest: dict = {}
+
+    def act() -> None:
+        while True:
+            time.sleep(0.2)
+            if "state" in latest and (frame := choose(latest["state"], me)):
+                send(frame)
+
+    threading.Thread(target=act, daemon=True).start()
+    send(encode(MsgType.START))
+
+    while True:
+        env = decode(stream)
+        if env["type"] == MsgType.STATE:
+            latest["state"] = env["payload"]["state"]
+        elif env["type"] == MsgType.PING:
+            send(encode(MsgType.PONG, {"sent_at": env["payload"]["sent_at"], "version": PROTOCOL_VERSION}))
+        elif env["type"] == MsgType.COUNTDOWN:
+            countdown: CountdownMsg = env["payload"]
+            if countdown["starts_in"] == 0:
+                send(encode(MsgType.READY_FOR_START))
+        elif env["type"] in (MsgType.SYSTEM, MsgType.ERROR):
+            print(env["payload"])
+
+
+if __name__ == "__main__":
+    main()
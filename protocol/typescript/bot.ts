@@ -0,0 +1,80 @@
+// Starter bot: joins a room, starts the match and wanders, dropping bombs.
+//
+//   npx tsx bot.ts 192.168.1.20:9999 [name]
+
+import { connect } from "node:net";
+import { ActionType, Decoder, Direction, GameStatus, MsgType, PROTOCOL_VERSION, TileType, encode } from "./protocol";
+import type { ClientMessage, GameState } from "./protocol";
+
+const steps: [Direction, number, number][] = [
+  [Direction.Up, 0, -1],
+  [Direction.Down, 0, 1],
+  [Direction.Left, -1, 0],
+  [Direction.Right, 1, 0],
+];
+
+/** Picks the bot's next action from the latest state. */
+function choose(state: GameState, me: string): ClientMessage | null {
+  const player = state.players[me];
+  if (state.status !== GameStatus.Running || !player || !player.alive) {
+    return null;
+  }
+  if (Math.random() < 0.05) {
+    return { type: MsgType.Action, payload: { action_type: ActionType.PlaceBomb } };
+  }
+  const { x, y } = player.pos;
+  const open = steps.filter(([, dx, dy]) => state.board[y + dy][x + dx] === TileType.Empty);
+  if (open.length === 0) {
+    return null;
+  }
+  const [direction] = open[Math.floor(Math.random() * open.length)];
+  return { type: MsgType.Action, payload: { action_type: ActionType.Move, direction } };
+}
+
+const [host, port] = process.argv[2].split(":");
+const name = process.argv[3] ?? "tsbot";
+const sock = connect(Number(port), host);
+const send = (msg: ClientMessage) => sock.write(encode(msg));
+const decoder = new Decoder();
+let me = "";
+let latest: GameState | null = null;
+
+sock.on("connect", () => {
+  send({ type: MsgType.Join, payload: { name, version: PROTOCOL_VERSION } });
+  send({ type: MsgType.Start, payload: {} });
+});
+
+sock.on("data", (chunk) => {
+  for (const msg of decoder.push(chunk)) {
+    switch (msg.type) {
+      case MsgType.Welcome:
+        me = msg.payload.player_id;
+        console.log("joined as " + me);
+        break;
+      case MsgType.State:
+        latest = msg.payload.state;
+        break;
+      case MsgType.Ping:
+        send({ type: MsgType.Pong, payload: { sent_at: msg.payload.sent_at, version: PROTOCOL_VERSION } });
+        break;
+      case MsgType.Countdown:
+        if (msg.payload.starts_in === 0) {
+          send({ type: MsgType.ReadyForStart, payload: {} });
+        }
+        break;
+      case MsgType.System:
+      case MsgType.Error:
+        console.log(msg.payload);
+        break;
+    }
+  }
+});
+
+sock.on("close", () => process.exit(0));
+
+setInterval(() => {
+  const msg = latest && choose(latest, me);
+  if (msg) {
+    send(msg);
+  }
+}, 200);
@@ -0,0 +1,585 @@
+// Code generated by protogen from internal/network. DO NOT EDIT.
+//
+// Typed bindings for the bomberman wire protocol, version 2. Send messages
+// with encode() and feed received bytes to a Decoder.
+
+export const PROTOCOL_VERSION = 2;
+export const MAX_MESSAGE_SIZE = 1 << 20;
+
+/** Payload of messages that carry no data. */
+export type Empty = Record<string, never>;
+
+export enum ActionType {
+  Move = 0,
+  PlaceBomb = 1,
+  Sprint = 2,
+  LineBomb = 3,
+}
+
+export enum BossAttack {
+  Idle = 0,
+  FireSweep = 1,
+  Bombs = 2,
+}
+
+export enum Compression {
+  Gzip = "gzip",
+  Snappy = "snappy",
+}
+
+export enum Direction {
+  Up = 0,
+  Down = 1,
+  Left = 2,
+  Right = 3,
+}
+
+export enum Effect {
+  Reverse = 0,
+  AutoBomb = 1,
+  ShortRange = 2,
+}
+
+export enum Encoding {
+  Json = "json",
+  Msgpack = "msgpack",
+}
+
+export enum EventType {
+  PlayerKilled = "player_killed",
+  BombExploded = "bomb_exploded",
+  WallDestroyed = "wall_destroyed",
+  ItemPickedUp = "item_picked_up",
+  GameOver = "game_over",
+}
+
+export enum GameStatus {
+  Lobby = 0,
+  Running = 1,
+  Over = 2,
+  Countdown = 3,
+  Intermission = 4,
+  LevelComplete = 5,
+  Paused = 6,
+}
+
+export enum Mode {
+  Classic = "classic",
+  Teams = "teams",
+  Koth = "koth",
+  BattleRoyale = "battle_royale",
+  Boss = "boss",
+  Pve = "pve",
+  Campaign = "campaign",
+  Stun = "stun",
+}
+
+export enum MsgType {
+  Join = "join",
+  Welcome = "welcome",
+  Action = "action",
+  State = "state",
+  Error = "error",
+  Start = "start",
+  System = "system",
+  Countdown = "countdown",
+  ReadyForStart = "ready_for_start",
+  Ping = "ping",
+  Pong = "pong",
+  SwitchTeam = "switch_team",
+  SetHandicap = "set_handicap",
+  Event = "event",
+  Pause = "pause",
+  StateDelta = "state_delta",
+  Resync = "resync",
+  Rejoin = "rejoin",
+  Chat = "chat",
+  Kick = "kick",
+  Ban = "ban",
+  UdpHello = "udp_hello",
+  UdpMove = "udp_move",
+  CompressedState = "compressed_state",
+  ListRooms = "list_rooms",
+  CreateRoom = "create_room",
+  JoinRoom = "join_room",
+  Rooms = "rooms",
+  Leave = "leave",
+}
+
+export enum OvertimeRule {
+  None = "none",
+  Lava = "lava",
+  BombRain = "bomb_rain",
+}
+
+export enum PickupType {
+  Bomb = 0,
+  Range = 1,
+  Ammo = 2,
+  Speed = 3,
+  Kick = 4,
+  Shield = 5,
+  Skull = 6,
+  FullFire = 7,
+  LineBomb = 8,
+  Mine = 9,
+  Cloak = 10,
+}
+
+export enum SystemKind {
+  Motd = "motd",
+  Announce = "announce",
+  Kick = "kick",
+}
+
+export enum Tiebreaker {
+  Draw = "draw",
+  BombOwner = "bomb_owner",
+  Kills = "kills",
+  SuddenDeath = "sudden_death",
+}
+
+export enum TileType {
+  Empty = 0,
+  HardWall = 1,
+  SoftWall = 2,
+  Barrel = 3,
+  ExitDoor = 4,
+  Ice = 5,
+  CrackedWall = 6,
+  CrackedWallHit = 7,
+  Fog = 8,
+  Trap = 9,
+}
+
+export enum TimeUpRule {
+  Draw = "draw",
+  Kills = "kills",
+  Walls = "walls",
+}
+
+export interface ActionMsg {
+  action_type: ActionType;
+  direction?: Direction;
+  seq?: number;
+}
+
+export interface Bomb {
+  owner_id: string;
+  pos: Position;
+  range: number;
+  placed_at: string;
+  expires_tick: number;
+  velocity: Position;
+  full_fire?: boolean;
+  owner_on?: boolean;
+  mine?: boolean;
+  hidden_at?: number;
+  chained?: boolean;
+  fuse_left: number;
+}
+
+export interface Boss {
+  pos: Position;
+  alive: boolean;
+  hp: number;
+  max_hp: number;
+  weak_point: Position;
+  attack: BossAttack;
+  warning?: Position[];
+  timer: number;
+  move_timer: number;
+}
+
+export interface CellChange {
+  x: number;
+  y: number;
+  tile: TileType;
+}
+
+export interface ChatMsg {
+  text: string;
+  player_id?: string;
+  name?: string;
+  color: number;
+  spectator?: boolean;
+}
+
+export interface CompressedStateMsg {
+  data: number[];
+}
+
+export interface Cosmetics {
+  name_color?: string;
+  banner?: string;
+  glyph?: string;
+}
+
+export interface CountdownMsg {
+  starts_in: number;
+}
+
+export interface Enemy {
+  id: string;
+  pos: Position;
+  alive: boolean;
+  dir: Direction;
+  move_timer: number;
+}
+
+export interface ErrorMsg {
+  message: string;
+}
+
+export interface Event {
+  type: EventType;
+  tick: number;
+  player_id?: string;
+  by_id?: string;
+  pos: Position;
+  pickup?: PickupType;
+  winner?: string;
+  winning_team?: number;
+}
+
+export interface EventMsg {
+  event: Event;
+}
+
+export interface Fire {
+  pos: Position;
+  expires_tick: number;
+  owner_id?: string;
+  permanent?: boolean;
+}
+
+export interface GameConfig {
+  width: number;
+  height: number;
+  bomb_timer: number;
+  fire_duration: number;
+  chain_delay: number;
+  tick_rate: number;
+  max_players: number;
+  seed: number;
+  soft_wall_density: number;
+  barrel_density: number;
+  ice_density: number;
+  cracked_walls: number;
+  trap_density: number;
+  overtime: OvertimeRule;
+  lava: boolean;
+  lava_after: number;
+  fog_radius: number;
+  enemy_count: number;
+  lobby_return: number;
+  sprint_enabled: boolean;
+  ammo_mode: boolean;
+  start_ammo: number;
+  tiebreaker: Tiebreaker;
+  start_countdown: number;
+  boss_mode: boolean;
+  boss_hp: number;
+  lives: number;
+  hearts: number;
+  rounds: number;
+  match_duration: number;
+  time_up: TimeUpRule;
+  mode: Mode;
+  team_mode: boolean;
+  friendly_fire: boolean;
+  fill_with_bots: boolean;
+  bot_difficulty: string;
+  pve_mode: boolean;
+  campaign: boolean;
+  hill_mode: boolean;
+  hill_score: number;
+  stun_mode: boolean;
+  battle_royale: boolean;
+  zone_shrink: number;
+  start_bombs: number;
+  start_range: number;
+  max_bombs: number;
+  max_range: number;
+  max_speed: number;
+  drop_table?: Record<string, number>;
+}
+
+export interface GameState {
+  board: TileType[][];
+  players: Record<string, Player>;
+  bombs: Bomb[];
+  fires: Fire[];
+  enemies: Enemy[];
+  pickups: Pickup[];
+  width: number;
+  height: number;
+  status: GameStatus;
+  winner?: string;
+  tick: number;
+  round?: number;
+  level?: number;
+  sudden_death?: boolean;
+  boss?: Boss;
+  time_left?: number;
+  winning_team?: number;
+  pve?: boolean;
+  overtime_at: string;
+  hill?: Hill;
+  zone?: Zone;
+  spectators?: Spectator[];
+}
+
+export interface Handicap {
+  bombs?: number;
+  range?: number;
+  speed?: number;
+}
+
+export interface HandicapMsg {
+  player_id: string;
+  handicap: Handicap;
+}
+
+export interface Hill {
+  pos: Position;
+  size: number;
+}
+
+export interface JoinMsg {
+  name: string;
+  cosmetics?: Cosmetics;
+  deltas?: boolean;
+  encodings?: Encoding[];
+  version?: number;
+  spectate?: boolean;
+  udp?: boolean;
+  compress?: Compression[];
+}
+
+export interface JoinRoomMsg {
+  room: string;
+  join: JoinMsg;
+}
+
+export interface KickMsg {
+  player_id: string;
+  reason?: string;
+}
+
+export interface PauseMsg {
+  paused: boolean;
+}
+
+export interface Pickup {
+  pos: Position;
+  type: PickupType;
+}
+
+export interface PingMsg {
+  sent_at: number;
+}
+
+export interface Player {
+  id: string;
+  name: string;
+  pos: Position;
+  alive: boolean;
+  bomb_max: number;
+  bomb_range: number;
+  bombs_used: number;
+  color: number;
+  cosmetics: Cosmetics;
+  stamina: number;
+  sprinting: boolean;
+  ammo: number;
+  kills: number;
+  killed_by?: string;
+  died_at?: number;
+  move_speed: number;
+  can_kick?: boolean;
+  full_fire?: boolean;
+  line_bomb?: boolean;
+  mines?: number;
+  facing: Direction;
+  sliding?: boolean;
+  out_of_sight?: boolean;
+  invulnerable_until: string;
+  invisible_until: string;
+  stunned_until: string;
+  effects?: StatusEffect[];
+  lives: number;
+  hp: number;
+  respawn_at: string;
+  round_score: number;
+  team_id?: number;
+  walls_destroyed: number;
+  hill_points: number;
+  handicap: Handicap;
+  disconnected?: boolean;
+  rtt?: number;
+  last_seq?: number;
+}
+
+export interface PongMsg {
+  sent_at: number;
+  version: number;
+}
+
+export interface Position {
+  x: number;
+  y: number;
+}
+
+export interface RejoinMsg {
+  token: string;
+  deltas?: boolean;
+  encodings?: Encoding[];
+  version?: number;
+  udp?: boolean;
+  compress?: Compression[];
+}
+
+export interface RoomListMsg {
+  rooms: RoomSummary[];
+}
+
+export interface RoomSummary {
+  id: string;
+  players: number;
+  max_players: number;
+  status: GameStatus;
+  mode?: Mode;
+}
+
+export interface Spectator {
+  id: string;
+  name: string;
+}
+
+export interface StateDeltaMsg {
+  base: number;
+  state: GameState;
+  cells?: CellChange[];
+  left?: string[];
+}
+
+export interface StateMsg {
+  state: GameState;
+}
+
+export interface StatusEffect {
+  kind: Effect;
+  expires_at: string;
+}
+
+export interface SystemMsg {
+  kind: SystemKind;
+  text: string;
+}
+
+export interface UDPHelloMsg {
+  key: string;
+}
+
+export interface UDPMoveMsg {
+  key: string;
+  direction: Direction;
+  seq: number;
+}
+
+export interface WelcomeMsg {
+  player_id: string;
+  config: GameConfig;
+  encoding?: Encoding;
+  version?: number;
+  token?: string;
+  host_id?: string;
+  compress?: Compression;
+  udp_key?: string;
+  motd?: string;
+  rules?: string;
+}
+
+export interface Zone {
+  pos: Position;
+  width: number;
+  height: number;
+  shrink_in?: number;
+}
+
+/** Messages sent by the client. */
+export type ClientMessage =
+  | { type: MsgType.Join; payload: JoinMsg } // First message on a connection: join the game.
+  | { type: MsgType.Rejoin; payload: RejoinMsg } // First message on a connection instead of a join: reclaim a player whose connection dropped.
+  | { type: MsgType.Action; payload: ActionMsg } // Move, place a bomb or toggle sprint.
+  | { type: MsgType.Start; payload: Empty } // Start the match from the lobby.
+  | { type: MsgType.ReadyForStart; payload: Empty } // Reply to a countdown with starts_in 0.
+  | { type: MsgType.SwitchTeam; payload: Empty } // Move to the other team; lobby and team mode only.
+  | { type: MsgType.SetHandicap; payload: HandicapMsg } // Override a player's starting stats; host and lobby only.
+  | { type: MsgType.Pause; payload: PauseMsg } // Pause or resume the match; host only.
+  | { type: MsgType.Kick; payload: KickMsg } // Remove a player from the game; host only.
+  | { type: MsgType.Ban; payload: KickMsg } // Remove a player and turn their address away from then on; host only.
+  | { type: MsgType.ListRooms; payload: Empty } // May open a connection to a multi-room server instead of a join: list its rooms. May be repeated.
+  | { type: MsgType.CreateRoom; payload: JoinRoomMsg } // First message on a connection to a multi-room server instead of a join: create a room and join it.
+  | { type: MsgType.JoinRoom; payload: JoinRoomMsg } // First message on a connection to a multi-room server instead of a join: join one of its rooms.
+  | { type: MsgType.UdpHello; payload: UDPHelloMsg } // Over UDP: send states here instead of over TCP; repeat every second.
+  | { type: MsgType.UdpMove; payload: UDPMoveMsg } // Over UDP: a move, dropped if one with a higher seq came first.
+  | { type: MsgType.Resync; payload: Empty } // Ask for a full state, after a state_delta whose base the client doesn't have.
+  | { type: MsgType.Leave; payload: Empty } // Quit for good: the player is removed at once, even mid-match, and the server hangs up.
+  | { type: MsgType.Ping; payload: PingMsg } // Ask for a pong; may open a connection instead of a join. The server pings every second; a client that answers is dropped if it goes quiet.
+  | { type: MsgType.Pong; payload: PongMsg } // Reply to a ping.
+  | { type: MsgType.Chat; payload: ChatMsg } // Chat line; clients send the text and the server relays it to everyone with the sender filled in.
+;
+
+/** Messages sent by the server. */
+export type ServerMessage =
+  | { type: MsgType.Welcome; payload: WelcomeMsg } // Reply to a join with the player's ID and the game config.
+  | { type: MsgType.State; payload: StateMsg } // Full game state, sent every tick, or every KeyframeEvery ticks to clients taking deltas.
+  | { type: MsgType.CompressedState; payload: CompressedStateMsg } // A full state, compressed, to clients whose welcome names a compression.
+  | { type: MsgType.StateDelta; payload: StateDeltaMsg } // Game state as the changes from the last one sent; only to clients that joined with deltas.
+  | { type: MsgType.Rooms; payload: RoomListMsg } // Reply to list_rooms.
+  | { type: MsgType.System; payload: SystemMsg } // Server notice shown to players.
+  | { type: MsgType.Event; payload: EventMsg } // Something happened in the game, sent after the tick's state.
+  | { type: MsgType.Countdown; payload: CountdownMsg } // Start countdown; see CountdownMsg.
+  | { type: MsgType.Error; payload: ErrorMsg } // The request failed; a rejected join closes the connection.
+  | { type: MsgType.Ping; payload: PingMsg } // Ask for a pong; may open a connection instead of a join. The server pings every second; a client that answers is dropped if it goes quiet.
+  | { type: MsgType.Pong; payload: PongMsg } // Reply to a ping.
+  | { type: MsgType.Chat; payload: ChatMsg } // Chat line; clients send the text and the server relays it to everyone with the sender filled in.
+;
+
+/** Frames a message: a 4-byte big-endian length, then the JSON envelope. */
+export function encode(msg: ClientMessage): Uint8Array {
+  const body = new TextEncoder().encode(JSON.stringify(msg));
+  const out = new Uint8Array(4 + body.length);
+  new DataView(out.buffer).setUint32(0, body.length);
+  out.set(body, 4);
+  return out;
+}
+
+/** Reassembles framed messages from a byte stream. */
+export class Decoder {
+  private buf = new Uint8Array(0);
+
+  /** Adds received bytes and returns the messages they complete. */
+  push(chunk: Uint8Array): ServerMessage[] {
+    const joined = new Uint8Array(this.buf.length + chunk.length);
+    joined.set(this.buf);
+    joined.set(chunk, this.buf.length);
+    this.buf = joined;
+
+    const out: ServerMessage[] = [];
+    while (this.buf.length >= 4) {
+      const length = new DataView(this.buf.buffer, this.buf.byteOffset).getUint32(0);
+      if (length > MAX_MESSAGE_SIZE) {
+        throw new Error("message too large: " + length + " bytes");
+      }
+      if (this.buf.length < 4 + length) {
+        break;
+      }
+      out.push(JSON.parse(new TextDecoder().decode(this.buf.subarray(4, 4 + length))));
+      this.buf = this.buf.subarray(4 + length);
+    }
+    return out;
+  }
+}